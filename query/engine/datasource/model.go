@@ -28,8 +28,8 @@ type Database interface {
 
 	// GetTable returns an instance of the Table inteface for the table
 	// specified by name.  If writeAccessReq is true, the Table needs
-	// to support the Delete function.  If it cannot, the syncql.NotWritable
-	// error should be returned.
+	// to support the Delete and Put functions.  If it cannot, the
+	// syncql.NotWritable error should be returned.
 	GetTable(name string, writeAccessReq bool) (Table, error)
 }
 
@@ -67,6 +67,44 @@ type Table interface {
 	// Delete is called anyway (logic error), the syncql.OperationNotSupported error
 	// should be returned.
 	Delete(k string) (bool, error)
+
+	// Put writes the k/v pair, replacing the value of key k (if any).
+	// It is used by update and insert statements; the query engine checks
+	// (by calling Scan) that the key does not exist before inserting it.
+	// Unless the Table is an InsertTable, that check is best-effort: a row
+	// written concurrently with the same key may be replaced.
+	// As with Delete, this will only be called if GetTable was called with
+	// writeAccessReq == true.  If Put is not supported, the
+	// syncql.OperationNotSupported error should be returned.
+	Put(k string, v *vom.RawBytes) error
+}
+
+// An InsertTable is a Table that can atomically write a k/v pair only if
+// key k does not exist.  Insert statements use PutIfAbsent, rather than Put,
+// to write the rows of tables that implement it.
+type InsertTable interface {
+	Table
+
+	// PutIfAbsent writes the k/v pair and returns true if key k does not
+	// exist; otherwise it writes nothing and returns false.
+	// As with Put, this will only be called if GetTable was called with
+	// writeAccessReq == true.
+	PutIfAbsent(k string, v *vom.RawBytes) (bool, error)
+}
+
+// A TypedTable is a Table whose rows all have values of the same type.
+// Insert statements convert the values of the rows they insert into a
+// TypedTable to that type, as update statements convert assigned values to
+// the types of the fields they are assigned to, and fail if a value cannot be
+// converted.  The values of rows inserted into other tables are converted to
+// the type of the value of an existing row, if any.
+type TypedTable interface {
+	Table
+
+	// ValueType returns the type of the values of the rows.  A table whose
+	// rows have values of different types returns vdl.AnyType, in which
+	// case the values of inserted rows are not converted.
+	ValueType() *vdl.Type
 }
 
 type KeyValueStream interface {
@@ -6,6 +6,8 @@ package conversions
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"math/big"
 	"strings"
 
@@ -170,3 +172,306 @@ func ConvertValueToUint(o *query_parser.Operand) (*query_parser.Operand, error)
 	}
 	return &c, nil
 }
+
+// CoerceValues converts two operands to a common type so that they can be
+// compared.  An error is returned if there is no such type.
+func CoerceValues(lhsValue, rhsValue *query_parser.Operand) (*query_parser.Operand, *query_parser.Operand, error) {
+	// TODO(jkline): explore using vdl for coercions ( https://vanadium.github.io/designdocs/vdl-spec.html#conversions ).
+	var err error
+	// If either operand is a string, convert the other to a string.
+	if lhsValue.Type == query_parser.TypStr || rhsValue.Type == query_parser.TypStr {
+		if lhsValue, err = ConvertValueToString(lhsValue); err != nil {
+			return nil, nil, err
+		}
+		if rhsValue, err = ConvertValueToString(rhsValue); err != nil {
+			return nil, nil, err
+		}
+		return lhsValue, rhsValue, nil
+	}
+	// If either operand is a big rat, convert both to a big rat.
+	// Also, if one operand is a float and the other is a big int,
+	// convert both to big rats.
+	if lhsValue.Type == query_parser.TypBigRat || rhsValue.Type == query_parser.TypBigRat || (lhsValue.Type == query_parser.TypBigInt && rhsValue.Type == query_parser.TypFloat) || (lhsValue.Type == query_parser.TypFloat && rhsValue.Type == query_parser.TypBigInt) {
+		if lhsValue, err = ConvertValueToBigRat(lhsValue); err != nil {
+			return nil, nil, err
+		}
+		if rhsValue, err = ConvertValueToBigRat(rhsValue); err != nil {
+			return nil, nil, err
+		}
+		return lhsValue, rhsValue, nil
+	}
+	// If either operand is a float, convert the other to a float.
+	if lhsValue.Type == query_parser.TypFloat || rhsValue.Type == query_parser.TypFloat {
+		if lhsValue, err = ConvertValueToFloat(lhsValue); err != nil {
+			return nil, nil, err
+		}
+		if rhsValue, err = ConvertValueToFloat(rhsValue); err != nil {
+			return nil, nil, err
+		}
+		return lhsValue, rhsValue, nil
+	}
+	// If either operand is a big int, convert both to a big int.
+	// Also, if one operand is a uint64 and the other is an int64, convert both to big ints.
+	if lhsValue.Type == query_parser.TypBigInt || rhsValue.Type == query_parser.TypBigInt || (lhsValue.Type == query_parser.TypUint && rhsValue.Type == query_parser.TypInt) || (lhsValue.Type == query_parser.TypInt && rhsValue.Type == query_parser.TypUint) {
+		if lhsValue, err = ConvertValueToBigInt(lhsValue); err != nil {
+			return nil, nil, err
+		}
+		if rhsValue, err = ConvertValueToBigInt(rhsValue); err != nil {
+			return nil, nil, err
+		}
+		return lhsValue, rhsValue, nil
+	}
+	// If either operand is an int64, convert the other to int64.
+	if lhsValue.Type == query_parser.TypInt || rhsValue.Type == query_parser.TypInt {
+		if lhsValue, err = ConvertValueToInt(lhsValue); err != nil {
+			return nil, nil, err
+		}
+		if rhsValue, err = ConvertValueToInt(rhsValue); err != nil {
+			return nil, nil, err
+		}
+		return lhsValue, rhsValue, nil
+	}
+	// If either operand is an uint64, convert the other to uint64.
+	if lhsValue.Type == query_parser.TypUint || rhsValue.Type == query_parser.TypUint {
+		if lhsValue, err = ConvertValueToUint(lhsValue); err != nil {
+			return nil, nil, err
+		}
+		if rhsValue, err = ConvertValueToUint(rhsValue); err != nil {
+			return nil, nil, err
+		}
+		return lhsValue, rhsValue, nil
+	}
+	// Must be the same at this point.
+	if lhsValue.Type != rhsValue.Type {
+		return nil, nil, fmt.Errorf("Logic error: expected like types, got: %v, %v", lhsValue, rhsValue)
+	}
+
+	return lhsValue, rhsValue, nil
+}
+
+// CompareOperands compares two resolved operands, returning -1, 0 or 1.
+// Unresolved (nil) operands sort before all others.  Numeric operands are
+// compared by value, after being coerced to a common type with the same rules
+// as used to evaluate comparison expressions, as are operands of the same
+// type.  Otherwise, operands are ordered by type, with all numeric types
+// ranked together, so that the order is consistent across mixed types.
+func CompareOperands(lhs, rhs *query_parser.Operand) int {
+	switch {
+	case lhs == nil && rhs == nil:
+		return 0
+	case lhs == nil:
+		return -1
+	case rhs == nil:
+		return 1
+	}
+	if c := compareInts64(typeRank(lhs.Type), typeRank(rhs.Type)); c != 0 {
+		return c
+	}
+	l, r, err := CoerceValues(lhs, rhs)
+	if err != nil {
+		return compareInts64(int64(lhs.Type), int64(rhs.Type))
+	}
+	switch l.Type {
+	case query_parser.TypBigInt:
+		return l.BigInt.Cmp(r.BigInt)
+	case query_parser.TypBigRat:
+		return l.BigRat.Cmp(r.BigRat)
+	case query_parser.TypBool:
+		switch {
+		case l.Bool == r.Bool:
+			return 0
+		case r.Bool:
+			return -1
+		default:
+			return 1
+		}
+	case query_parser.TypFloat:
+		switch {
+		case l.Float < r.Float:
+			return -1
+		case l.Float > r.Float:
+			return 1
+		default:
+			return 0
+		}
+	case query_parser.TypInt:
+		return compareInts64(l.Int, r.Int)
+	case query_parser.TypStr:
+		return strings.Compare(l.Str, r.Str)
+	case query_parser.TypUint:
+		switch {
+		case l.Uint < r.Uint:
+			return -1
+		case l.Uint > r.Uint:
+			return 1
+		default:
+			return 0
+		}
+	case query_parser.TypTime:
+		switch {
+		case l.Time.Before(r.Time):
+			return -1
+		case l.Time.After(r.Time):
+			return 1
+		default:
+			return 0
+		}
+	}
+	// Objects have no natural order; order them by their string
+	// representation so that equal objects sort together.
+	return strings.Compare(fmt.Sprint(l.Object), fmt.Sprint(r.Object))
+}
+
+// typeRank returns the rank used by CompareOperands to order operands of
+// different types.  All numeric types share the rank of TypBigInt.
+func typeRank(t query_parser.OperandType) int64 {
+	switch t {
+	case query_parser.TypBigInt, query_parser.TypBigRat, query_parser.TypFloat, query_parser.TypInt, query_parser.TypUint:
+		return int64(query_parser.TypBigInt)
+	}
+	return int64(t)
+}
+
+func compareInts64(lhs, rhs int64) int {
+	switch {
+	case lhs < rhs:
+		return -1
+	case lhs > rhs:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Arithmetic applies an arithmetic operator (Plus, Minus, Times, Divide or
+// Modulo) to two resolved operands.  The operands are coerced to a common type
+// as they are for comparisons.  int64 and uint64 results that do not fit in
+// the common type are returned as big ints.  Integer division truncates.
+func Arithmetic(lhs, rhs *query_parser.Operand, op query_parser.BinaryOperatorType) (*query_parser.Operand, error) {
+	if !IsNumeric(lhs) || !IsNumeric(rhs) {
+		return nil, errors.New("Arithmetic operands must be numeric.")
+	}
+	l, r, err := CoerceValues(lhs, rhs)
+	if err != nil {
+		return nil, err
+	}
+	var c query_parser.Operand
+	c.Type = l.Type
+	c.Off = lhs.Off
+	switch l.Type {
+	case query_parser.TypBigInt:
+		if c.BigInt, err = bigIntArithmetic(l.BigInt, r.BigInt, op); err != nil {
+			return nil, err
+		}
+	case query_parser.TypBigRat:
+		if c.BigRat, err = bigRatArithmetic(l.BigRat, r.BigRat, op); err != nil {
+			return nil, err
+		}
+	case query_parser.TypFloat:
+		if c.Float, err = floatArithmetic(l.Float, r.Float, op); err != nil {
+			return nil, err
+		}
+	case query_parser.TypInt:
+		result, err := bigIntArithmetic(big.NewInt(l.Int), big.NewInt(r.Int), op)
+		if err != nil {
+			return nil, err
+		}
+		if result.IsInt64() {
+			c.Int = result.Int64()
+		} else {
+			c.Type = query_parser.TypBigInt
+			c.BigInt = result
+		}
+	case query_parser.TypUint:
+		var lb, rb big.Int
+		result, err := bigIntArithmetic(lb.SetUint64(l.Uint), rb.SetUint64(r.Uint), op)
+		if err != nil {
+			return nil, err
+		}
+		if result.IsUint64() {
+			c.Uint = result.Uint64()
+		} else {
+			c.Type = query_parser.TypBigInt
+			c.BigInt = result
+		}
+	default:
+		return nil, errors.New("Arithmetic operands must be numeric.")
+	}
+	return &c, nil
+}
+
+// IsNumeric returns true if the resolved operand is a number.
+func IsNumeric(o *query_parser.Operand) bool {
+	switch o.Type {
+	case query_parser.TypBigInt, query_parser.TypBigRat, query_parser.TypFloat, query_parser.TypInt, query_parser.TypUint:
+		return true
+	default:
+		return false
+	}
+}
+
+var errDivideByZero = errors.New("Division by zero.")
+
+func bigIntArithmetic(l, r *big.Int, op query_parser.BinaryOperatorType) (*big.Int, error) {
+	var result big.Int
+	switch op {
+	case query_parser.Plus:
+		return result.Add(l, r), nil
+	case query_parser.Minus:
+		return result.Sub(l, r), nil
+	case query_parser.Times:
+		return result.Mul(l, r), nil
+	case query_parser.Divide, query_parser.Modulo:
+		if r.Sign() == 0 {
+			return nil, errDivideByZero
+		}
+		if op == query_parser.Divide {
+			return result.Quo(l, r), nil
+		}
+		return result.Rem(l, r), nil
+	default:
+		return nil, fmt.Errorf("Logic error: expected arithmetic operator, got: %v", op)
+	}
+}
+
+func bigRatArithmetic(l, r *big.Rat, op query_parser.BinaryOperatorType) (*big.Rat, error) {
+	var result big.Rat
+	switch op {
+	case query_parser.Plus:
+		return result.Add(l, r), nil
+	case query_parser.Minus:
+		return result.Sub(l, r), nil
+	case query_parser.Times:
+		return result.Mul(l, r), nil
+	case query_parser.Divide:
+		if r.Sign() == 0 {
+			return nil, errDivideByZero
+		}
+		return result.Quo(l, r), nil
+	case query_parser.Modulo:
+		return nil, errors.New("Modulo is not defined for big.Rat.")
+	default:
+		return nil, fmt.Errorf("Logic error: expected arithmetic operator, got: %v", op)
+	}
+}
+
+func floatArithmetic(l, r float64, op query_parser.BinaryOperatorType) (float64, error) {
+	switch op {
+	case query_parser.Plus:
+		return l + r, nil
+	case query_parser.Minus:
+		return l - r, nil
+	case query_parser.Times:
+		return l * r, nil
+	case query_parser.Divide, query_parser.Modulo:
+		if r == 0 {
+			return 0, errDivideByZero
+		}
+		if op == query_parser.Divide {
+			return l / r, nil
+		}
+		return math.Mod(l, r), nil
+	default:
+		return 0, fmt.Errorf("Logic error: expected arithmetic operator, got: %v", op)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conversions_test
+
+import (
+	"math/big"
+	"testing"
+
+	"v.io/v23/query/engine/internal/conversions"
+	"v.io/v23/query/engine/internal/query_parser"
+)
+
+func TestCompareOperands(t *testing.T) {
+	operands := []*query_parser.Operand{
+		nil,
+		{Type: query_parser.TypInt, Int: -3},
+		{Type: query_parser.TypFloat, Float: -1.5},
+		{Type: query_parser.TypUint, Uint: 2},
+		{Type: query_parser.TypBigInt, BigInt: big.NewInt(9)},
+		{Type: query_parser.TypBigRat, BigRat: big.NewRat(19, 2)},
+		{Type: query_parser.TypInt, Int: 10},
+		{Type: query_parser.TypBool, Bool: false},
+		{Type: query_parser.TypBool, Bool: true},
+		{Type: query_parser.TypStr, Str: "10"},
+		{Type: query_parser.TypStr, Str: "9"},
+	}
+	// The operands are in order, so every pair must compare consistently
+	// with their positions.
+	for i, lhs := range operands {
+		for j, rhs := range operands {
+			want := 0
+			switch {
+			case i < j:
+				want = -1
+			case i > j:
+				want = 1
+			}
+			if got := conversions.CompareOperands(lhs, rhs); got != want {
+				t.Errorf("CompareOperands(%v, %v): got %d, want %d", lhs, rhs, got, want)
+			}
+		}
+	}
+	// Numeric operands of different types compare by value.
+	lhs, rhs := &query_parser.Operand{Type: query_parser.TypUint, Uint: 7}, &query_parser.Operand{Type: query_parser.TypFloat, Float: 7}
+	if got := conversions.CompareOperands(lhs, rhs); got != 0 {
+		t.Errorf("CompareOperands(%v, %v): got %d, want 0", lhs, rhs, got)
+	}
+}
@@ -5,8 +5,6 @@
 package internal
 
 import (
-	"errors"
-	"fmt"
 	"reflect"
 
 	ds "v.io/v23/query/engine/datasource"
@@ -16,6 +14,7 @@ import (
 	"v.io/v23/query/engine/internal/query_parser"
 	"v.io/v23/query/syncql"
 	"v.io/v23/vdl"
+	"v.io/v23/vom"
 )
 
 func Eval(db ds.Database, k string, v *vdl.Value, e *query_parser.Expression) bool {
@@ -39,6 +38,12 @@ func evalLogicalOperators(db ds.Database, k string, v *vdl.Value, e *query_parse
 }
 
 func evalComparisonOperators(db ds.Database, k string, v *vdl.Value, e *query_parser.Expression) bool {
+	switch e.Operator.Type {
+	case query_parser.In, query_parser.NotIn:
+		return evalIn(db, k, v, e)
+	case query_parser.Between, query_parser.NotBetween:
+		return evalBetween(db, k, v, e)
+	}
 	lhsValue := resolveOperand(db, k, v, e.Operand1)
 	// Check for an is nil expression (i.e., v[.<field>...] is nil).
 	// These expressions evaluate to true if the field cannot be resolved.
@@ -57,110 +62,80 @@ func evalComparisonOperators(db ds.Database, k string, v *vdl.Value, e *query_pa
 	if rhsValue == nil {
 		return false
 	}
+	return compareResolved(lhsValue, rhsValue, e.Operator)
+}
+
+// evalIn evaluates <operand> [not] in (<values>).  The operand is in the list
+// if it is equal to any of the values; values that cannot be resolved or
+// compared with the operand are ignored.  If the operand cannot be resolved,
+// the expression is false.
+func evalIn(db ds.Database, k string, v *vdl.Value, e *query_parser.Expression) bool {
+	lhsValue := resolveOperand(db, k, v, e.Operand1)
+	if lhsValue == nil {
+		return false
+	}
+	equal := &query_parser.BinaryOperator{Type: query_parser.Equal, Node: e.Operator.Node}
+	found := false
+	for _, value := range e.Operand2.List {
+		if rhsValue := resolveOperand(db, k, v, value); rhsValue != nil && compareResolved(lhsValue, rhsValue, equal) {
+			found = true
+			break
+		}
+	}
+	return found == (e.Operator.Type == query_parser.In)
+}
+
+// evalBetween evaluates <operand> [not] between <lower> and <upper>.  If the
+// operand or either bound cannot be resolved, the expression is false.
+func evalBetween(db ds.Database, k string, v *vdl.Value, e *query_parser.Expression) bool {
+	lhsValue := resolveOperand(db, k, v, e.Operand1)
+	if lhsValue == nil {
+		return false
+	}
+	lower := resolveOperand(db, k, v, e.Operand2.List[0])
+	upper := resolveOperand(db, k, v, e.Operand2.List[1])
+	if lower == nil || upper == nil {
+		return false
+	}
+	greaterThanOrEqual := &query_parser.BinaryOperator{Type: query_parser.GreaterThanOrEqual, Node: e.Operator.Node}
+	lessThanOrEqual := &query_parser.BinaryOperator{Type: query_parser.LessThanOrEqual, Node: e.Operator.Node}
+	between := compareResolved(lhsValue, lower, greaterThanOrEqual) && compareResolved(lhsValue, upper, lessThanOrEqual)
+	return between == (e.Operator.Type == query_parser.Between)
+}
+
+// compareResolved compares two resolved operands with a comparison operator.
+// Operands that cannot be coerced to a common type compare false.
+func compareResolved(lhsValue, rhsValue *query_parser.Operand, oper *query_parser.BinaryOperator) bool {
 	// coerce operands so they are comparable
 	var err error
-	lhsValue, rhsValue, err = coerceValues(lhsValue, rhsValue)
+	lhsValue, rhsValue, err = conversions.CoerceValues(lhsValue, rhsValue)
 	if err != nil {
 		return false // If operands can't be coerced to compare, expr evals to false.
 	}
 	// Do the compare
 	switch lhsValue.Type {
 	case query_parser.TypBigInt:
-		return compareBigInts(lhsValue, rhsValue, e.Operator)
+		return compareBigInts(lhsValue, rhsValue, oper)
 	case query_parser.TypBigRat:
-		return compareBigRats(lhsValue, rhsValue, e.Operator)
+		return compareBigRats(lhsValue, rhsValue, oper)
 	case query_parser.TypBool:
-		return compareBools(lhsValue, rhsValue, e.Operator)
+		return compareBools(lhsValue, rhsValue, oper)
 	case query_parser.TypFloat:
-		return compareFloats(lhsValue, rhsValue, e.Operator)
+		return compareFloats(lhsValue, rhsValue, oper)
 	case query_parser.TypInt:
-		return compareInts(lhsValue, rhsValue, e.Operator)
+		return compareInts(lhsValue, rhsValue, oper)
 	case query_parser.TypStr:
-		return compareStrings(lhsValue, rhsValue, e.Operator)
+		return compareStrings(lhsValue, rhsValue, oper)
 	case query_parser.TypUint:
-		return compareUints(lhsValue, rhsValue, e.Operator)
+		return compareUints(lhsValue, rhsValue, oper)
 	case query_parser.TypTime:
-		return compareTimes(lhsValue, rhsValue, e.Operator)
+		return compareTimes(lhsValue, rhsValue, oper)
 	case query_parser.TypObject:
-		return compareObjects(lhsValue, rhsValue, e.Operator)
+		return compareObjects(lhsValue, rhsValue, oper)
 	}
 	return false
 }
 
-func coerceValues(lhsValue, rhsValue *query_parser.Operand) (*query_parser.Operand, *query_parser.Operand, error) {
-	// TODO(jkline): explore using vdl for coercions ( https://vanadium.github.io/designdocs/vdl-spec.html#conversions ).
-	var err error
-	// If either operand is a string, convert the other to a string.
-	if lhsValue.Type == query_parser.TypStr || rhsValue.Type == query_parser.TypStr {
-		if lhsValue, err = conversions.ConvertValueToString(lhsValue); err != nil {
-			return nil, nil, err
-		}
-		if rhsValue, err = conversions.ConvertValueToString(rhsValue); err != nil {
-			return nil, nil, err
-		}
-		return lhsValue, rhsValue, nil
-	}
-	// If either operand is a big rat, convert both to a big rat.
-	// Also, if one operand is a float and the other is a big int,
-	// convert both to big rats.
-	if lhsValue.Type == query_parser.TypBigRat || rhsValue.Type == query_parser.TypBigRat || (lhsValue.Type == query_parser.TypBigInt && rhsValue.Type == query_parser.TypFloat) || (lhsValue.Type == query_parser.TypFloat && rhsValue.Type == query_parser.TypBigInt) {
-		if lhsValue, err = conversions.ConvertValueToBigRat(lhsValue); err != nil {
-			return nil, nil, err
-		}
-		if rhsValue, err = conversions.ConvertValueToBigRat(rhsValue); err != nil {
-			return nil, nil, err
-		}
-		return lhsValue, rhsValue, nil
-	}
-	// If either operand is a float, convert the other to a float.
-	if lhsValue.Type == query_parser.TypFloat || rhsValue.Type == query_parser.TypFloat {
-		if lhsValue, err = conversions.ConvertValueToFloat(lhsValue); err != nil {
-			return nil, nil, err
-		}
-		if rhsValue, err = conversions.ConvertValueToFloat(rhsValue); err != nil {
-			return nil, nil, err
-		}
-		return lhsValue, rhsValue, nil
-	}
-	// If either operand is a big int, convert both to a big int.
-	// Also, if one operand is a uint64 and the other is an int64, convert both to big ints.
-	if lhsValue.Type == query_parser.TypBigInt || rhsValue.Type == query_parser.TypBigInt || (lhsValue.Type == query_parser.TypUint && rhsValue.Type == query_parser.TypInt) || (lhsValue.Type == query_parser.TypInt && rhsValue.Type == query_parser.TypUint) {
-		if lhsValue, err = conversions.ConvertValueToBigInt(lhsValue); err != nil {
-			return nil, nil, err
-		}
-		if rhsValue, err = conversions.ConvertValueToBigInt(rhsValue); err != nil {
-			return nil, nil, err
-		}
-		return lhsValue, rhsValue, nil
-	}
-	// If either operand is an int64, convert the other to int64.
-	if lhsValue.Type == query_parser.TypInt || rhsValue.Type == query_parser.TypInt {
-		if lhsValue, err = conversions.ConvertValueToInt(lhsValue); err != nil {
-			return nil, nil, err
-		}
-		if rhsValue, err = conversions.ConvertValueToInt(rhsValue); err != nil {
-			return nil, nil, err
-		}
-		return lhsValue, rhsValue, nil
-	}
-	// If either operand is an uint64, convert the other to uint64.
-	if lhsValue.Type == query_parser.TypUint || rhsValue.Type == query_parser.TypUint {
-		if lhsValue, err = conversions.ConvertValueToUint(lhsValue); err != nil {
-			return nil, nil, err
-		}
-		if rhsValue, err = conversions.ConvertValueToUint(rhsValue); err != nil {
-			return nil, nil, err
-		}
-		return lhsValue, rhsValue, nil
-	}
-	// Must be the same at this point.
-	if lhsValue.Type != rhsValue.Type {
-		return nil, nil, errors.New(fmt.Sprintf("Logic error: expected like types, got: %v, %v", lhsValue, rhsValue))
-	}
-
-	return lhsValue, rhsValue, nil
-}
-
 func compareBools(lhsValue, rhsValue *query_parser.Operand, oper *query_parser.BinaryOperator) bool {
 	switch oper.Type {
 	case query_parser.Equal:
@@ -334,12 +309,15 @@ func resolveArgsAndExecFunction(db ds.Database, k string, v *vdl.Value, f *query
 	for _, arg := range f.Args {
 		resolvedArg := resolveOperand(db, k, v, arg)
 		if resolvedArg == nil {
-			return nil, syncql.NewErrFunctionArgBad(db.GetContext(), arg.Off, f.Name, arg.String())
+			if !query_functions.NilArgsAllowed(f) {
+				return nil, syncql.NewErrFunctionArgBad(db.GetContext(), arg.Off, f.Name, arg.String())
+			}
+			resolvedArg = &query_parser.Operand{Off: arg.Off, Type: query_parser.TypNil}
 		}
 		callingArgs = append(callingArgs, resolvedArg)
 	}
 	// Exec the function
-	retValue, err := query_functions.ExecFunction(db, f, callingArgs)
+	retValue, err := query_functions.ExecFunction(db, k, v, f, callingArgs)
 	if err != nil {
 		return nil, err
 	}
@@ -351,13 +329,36 @@ func resolveOperand(db ds.Database, k string, v *vdl.Value, o *query_parser.Oper
 		// Note: if the function was computed at check time, the operand is replaced
 		// in the parse tree with the return value.  As such, thre is no need to check
 		// the computed field.
+		// Aggregate functions, however, are computed for each group of rows
+		// and resolved by the group's database.
+		// Functions that return nil (TypNil) resolve to nil.
+		if result, ok := aggregateResult(db, o.Function); ok {
+			return nilIfTypNil(result)
+		}
+		if o.Function.Computed {
+			return nilIfTypNil(o.Function.RetValue)
+		}
 		if retValue, err := resolveArgsAndExecFunction(db, k, v, o.Function); err == nil {
-			return retValue
+			return nilIfTypNil(retValue)
 		} else {
 			// Per spec, function errors resolve to nil
 			return nil
 		}
 	}
+	if o.Type == query_parser.TypArithmetic {
+		lhs := resolveOperand(db, k, v, o.Expr.Operand1)
+		rhs := resolveOperand(db, k, v, o.Expr.Operand2)
+		if lhs == nil || rhs == nil {
+			return nil
+		}
+		if result, err := conversions.Arithmetic(lhs, rhs, o.Expr.Operator.Type); err == nil {
+			return result
+		} else {
+			// As with function errors, arithmetic errors (e.g., division by zero)
+			// resolve to nil.
+			return nil
+		}
+	}
 	if o.Type != query_parser.TypField {
 		return o
 	}
@@ -373,6 +374,13 @@ func resolveOperand(db ds.Database, k string, v *vdl.Value, o *query_parser.Oper
 	}
 }
 
+func nilIfTypNil(o *query_parser.Operand) *query_parser.Operand {
+	if o != nil && o.Type == query_parser.TypNil {
+		return nil
+	}
+	return o
+}
+
 // Auto-dereference Any and Optional values
 func autoDereference(o *vdl.Value) *vdl.Value {
 	for o.Kind() == vdl.Any || o.Kind() == vdl.Optional {
@@ -473,16 +481,24 @@ func valueFromResolvedOperand(o *query_parser.Operand) interface{} {
 	return nil
 }
 
-// Resolve a field.
+// Resolve a field.  For select statements with joins, v is a joinedRow and
+// the (qualified) field is resolved with the key and value of its table's row.
 func ResolveField(db ds.Database, k string, v *vdl.Value, f *query_parser.Field) *vdl.Value {
-	if query_checker.IsKeyField(f) {
-		return vdl.StringValue(nil, k)
+	tableK, tableV := k, v
+	if f.Qualified {
+		var ok bool
+		if tableK, tableV, ok = joinedRowEntryAt(v, f.Table); !ok {
+			// The table has no row (i.e., a left join matched no row).
+			return vdl.ValueOf(nil)
+		}
+	}
+	segments := f.Path()
+	if segments[0].Value == "k" {
+		return vdl.StringValue(nil, tableK)
 	}
 	// Auto-dereference Any and Optional values
-	v = autoDereference(v)
+	object := autoDereference(tableV)
 
-	object := v
-	segments := f.Segments
 	// Does v contain a key?
 	object = resolveWithKey(db, k, v, object, segments[0])
 
@@ -580,3 +596,45 @@ func evalExprUsingOnlyKey(db ds.Database, e *query_parser.Expression, k string)
 		}
 	}
 }
+
+// KeyOnlyPredicates returns the comparisons of the where clause that
+// EvalWhereUsingOnlyKey evaluates with only the key (i.e., those that compare
+// the key with a literal).  Any other comparison requires the value.
+func KeyOnlyPredicates(w *query_parser.WhereClause) (keyOnly, other []*query_parser.Expression) {
+	if w != nil {
+		collectPredicates(w.Expr, &keyOnly, &other)
+	}
+	return keyOnly, other
+}
+
+func collectPredicates(e *query_parser.Expression, keyOnly, other *[]*query_parser.Expression) {
+	switch e.Operator.Type {
+	case query_parser.And, query_parser.Or:
+		collectPredicates(e.Operand1.Expr, keyOnly, other)
+		collectPredicates(e.Operand2.Expr, keyOnly, other)
+	default:
+		if query_checker.ContainsKeyOperand(e) && !query_checker.ContainsFunctionOperand(e) && !query_checker.ContainsValueFieldOperand(e) {
+			*keyOnly = append(*keyOnly, e)
+		} else {
+			*other = append(*other, e)
+		}
+	}
+}
+
+// Evaluate the where clause to determine if the row with key k and value v
+// should be selected, fetching the value only if the key alone does not
+// determine the result.
+func evalWhere(db ds.Database, w *query_parser.WhereClause, k string, v *vom.RawBytes) bool {
+	// EvalWhereUsingOnlyKey
+	// INCLUDE: the row should be included in the results
+	// EXCLUDE: the row should NOT be included
+	// FETCH_VALUE: the value and/or type of the value are required to make determination.
+	switch EvalWhereUsingOnlyKey(db, w, k) {
+	case INCLUDE:
+		return true
+	case EXCLUDE:
+		return false
+	default:
+		return Eval(db, k, vdl.ValueOf(v), w.Expr)
+	}
+}
@@ -0,0 +1,474 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	ds "v.io/v23/query/engine/datasource"
+	"v.io/v23/query/engine/internal/query_checker"
+	"v.io/v23/query/engine/internal/query_parser"
+	"v.io/v23/query/syncql"
+	"v.io/v23/vdl"
+	"v.io/v23/vom"
+)
+
+// The estimated cost of a statement is the estimated number of rows read.
+// As tables keep no statistics, a range of a single key is estimated to
+// read one row, any other range rangeScanCost rows and a scan of the entire
+// table tableScanCost rows.  Each table of a join is read once per row of
+// the preceding tables.
+const (
+	keyLookupCost = 1
+	rangeScanCost = 100
+	tableScanCost = 10000
+)
+
+// explainHeadings are the column headings of the result of an explain
+// statement.  Each row of the result is a property of the plan; the table
+// is empty for properties of the statement as a whole.
+var explainHeadings = []string{"Table", "Property", "Value"}
+
+// explain returns the plan for executing the statement s as the rows of the
+// result of an explain statement.  The statement is not executed.
+func explain(db ds.Database, s query_parser.Statement) ([][]*vom.RawBytes, error) {
+	var p plan
+	switch st := s.(type) {
+	case query_parser.SelectStatement:
+		if _, err := p.explainSelect(db, &st); err != nil {
+			return nil, err
+		}
+	case query_parser.UnionStatement:
+		if err := p.explainUnion(db, &st); err != nil {
+			return nil, err
+		}
+	case query_parser.DeleteStatement:
+		if err := p.explainDelete(db, &st); err != nil {
+			return nil, err
+		}
+	case query_parser.UpdateStatement:
+		if err := p.explainUpdate(db, &st); err != nil {
+			return nil, err
+		}
+	case query_parser.InsertStatement:
+		p.explainInsert(&st)
+	default:
+		return nil, syncql.NewErrExecOfUnknownStatementType(db.GetContext(), s.Offset(), fmt.Sprintf("%T", s))
+	}
+	return p.rows, nil
+}
+
+type plan struct {
+	rows [][]*vom.RawBytes
+}
+
+func (p *plan) add(table, property, value string) {
+	p.rows = append(p.rows, []*vom.RawBytes{vom.RawBytesOf(table), vom.RawBytesOf(property), vom.RawBytesOf(value)})
+}
+
+// explainSelect adds the plan for executing a select statement and returns
+// its estimated cost.
+func (p *plan) explainSelect(db ds.Database, st *query_parser.SelectStatement) (float64, error) {
+	var cost float64
+	if len(st.From.Joins) > 0 {
+		levels, err := newJoinLevels(db, st)
+		if err != nil {
+			return 0, err
+		}
+		// Rows of each table are read once per row of the preceding tables.
+		reads := float64(1)
+		for _, level := range levels {
+			table := level.table.Qualifier()
+			if level.join != nil {
+				joinType := "inner join"
+				if level.join.Type == query_parser.LeftJoin {
+					joinType = "left join"
+				}
+				p.add(table, "Join", joinType+" on "+formatExpression(level.join.On))
+			}
+			var levelCost float64
+			if level.lookup != nil {
+				p.add(table, "Access", "key lookup: k = "+formatOperand(level.lookup))
+				levelCost = keyLookupCost
+			} else {
+				levelCost = p.explainIndexRanges(table, level.indexes)
+			}
+			reads *= levelCost
+			cost += reads
+		}
+		p.explainWhere(st.Where)
+	} else {
+		indexes, err := getIndexRanges(db, st.From.Table.Name, st.From.Table.Off, "", st.From.Table.DBTable.GetIndexFields(), st.Where)
+		if err != nil {
+			return 0, err
+		}
+		cost = p.explainIndexRanges(st.From.Table.Name, indexes)
+		// Without sorting, grouping or eliminating duplicates, the scan stops
+		// once the limit is reached.  Only if the key alone determines which
+		// rows are returned is it known how many rows that requires.
+		if keyOnly := p.explainWhere(st.Where); keyOnly && st.Limit != nil && st.OrderBy == nil && !st.Select.Distinct && !query_checker.IsGrouped(st) {
+			if n := float64(st.Limit.Limit.Value + resultsOffset(st)); n < cost {
+				cost = n
+			}
+		}
+	}
+
+	for _, selector := range st.Select.Selectors {
+		p.add("", "Projection", formatSelector(selector))
+	}
+	if st.Select.Distinct {
+		p.add("", "Distinct", "duplicate rows are eliminated")
+	}
+	if st.GroupBy != nil {
+		var keys []string
+		for _, key := range st.GroupBy.Keys {
+			keys = append(keys, formatOperand(key))
+		}
+		p.add("", "GroupBy", strings.Join(keys, ", "))
+	}
+	if st.Having != nil {
+		p.add("", "Having", formatExpression(st.Having.Expr))
+	}
+	if st.OrderBy != nil {
+		var keys []string
+		for _, key := range st.OrderBy.Keys {
+			sortKey := formatOperand(key.Operand)
+			if key.Direction == query_parser.Descending {
+				sortKey += " desc"
+			}
+			keys = append(keys, sortKey)
+		}
+		p.add("", "OrderBy", strings.Join(keys, ", "))
+	}
+	if st.Limit != nil || st.ResultsOffset != nil {
+		if st.Limit != nil {
+			p.add("", "Limit", strconv.FormatInt(st.Limit.Limit.Value, 10))
+		}
+		if st.ResultsOffset != nil {
+			p.add("", "Offset", strconv.FormatInt(st.ResultsOffset.ResultsOffset.Value, 10))
+		}
+		switch {
+		case query_checker.IsGrouped(st):
+			p.add("", "LimitOffset", "applied to the groups after all rows are grouped")
+		case st.OrderBy != nil:
+			p.add("", "LimitOffset", "applied after all rows are sorted")
+		default:
+			p.add("", "LimitOffset", "applied while scanning; the scan stops once the limit is reached")
+		}
+	}
+	p.add("", "EstimatedCost", formatCost(cost))
+	return cost, nil
+}
+
+// explainUnion adds the plans of the selects of the union in turn.  The
+// estimated cost of the union is the sum of the costs of its selects.
+func (p *plan) explainUnion(db ds.Database, st *query_parser.UnionStatement) error {
+	var cost float64
+	for i := range st.Selects {
+		if i > 0 {
+			union := "union"
+			if st.All[i-1] {
+				union = "union all"
+			}
+			p.add("", "Union", union)
+		}
+		selectCost, err := p.explainSelect(db, &st.Selects[i])
+		if err != nil {
+			return err
+		}
+		cost += selectCost
+	}
+	if st.Limit != nil {
+		p.add("", "Limit", strconv.FormatInt(st.Limit.Limit.Value, 10))
+	}
+	if st.ResultsOffset != nil {
+		p.add("", "Offset", strconv.FormatInt(st.ResultsOffset.ResultsOffset.Value, 10))
+	}
+	if st.Limit != nil || st.ResultsOffset != nil {
+		p.add("", "LimitOffset", "applied to the results of the union")
+	}
+	p.add("", "EstimatedCost", formatCost(cost))
+	return nil
+}
+
+func (p *plan) explainDelete(db ds.Database, st *query_parser.DeleteStatement) error {
+	cost, err := p.explainScan(db, &st.From.Table, st.Where, st.Limit)
+	if err != nil {
+		return err
+	}
+	p.add("", "EstimatedCost", formatCost(cost))
+	return nil
+}
+
+// explainUpdate adds the plan for executing an update statement.  All of
+// the rows to be updated are read before any is written.
+func (p *plan) explainUpdate(db ds.Database, st *query_parser.UpdateStatement) error {
+	cost, err := p.explainScan(db, st.Table, st.Where, st.Limit)
+	if err != nil {
+		return err
+	}
+	for _, a := range st.Set.Assignments {
+		p.add("", "Assignment", formatField(a.Field)+" = "+formatOperand(a.Operand))
+	}
+	p.add("", "Write", "after all rows to be updated are read")
+	p.add("", "EstimatedCost", formatCost(cost))
+	return nil
+}
+
+// explainInsert adds the plan for executing an insert statement.  The key of
+// each row is looked up before any row is written.
+func (p *plan) explainInsert(st *query_parser.InsertStatement) {
+	keyColumn := 0
+	if st.Columns[0].Segments[0].Value == "v" {
+		keyColumn = 1
+	}
+	p.add(st.Table.Name, "Access", "key lookup")
+	for _, r := range st.Rows {
+		p.add(st.Table.Name, "Row", "k = "+formatOperand(r.Values[keyColumn])+", v = "+formatOperand(r.Values[1-keyColumn]))
+	}
+	if tt, ok := st.Table.DBTable.(ds.TypedTable); !ok {
+		p.add(st.Table.Name, "ValueType", "type of the value of an existing row, if any")
+	} else if vt := tt.ValueType(); vt != vdl.AnyType {
+		p.add(st.Table.Name, "ValueType", vt.String())
+	} else {
+		p.add(st.Table.Name, "ValueType", "any (values are not converted)")
+	}
+	if _, ok := st.Table.DBTable.(ds.InsertTable); ok {
+		p.add("", "Write", "each row only if its key does not exist")
+	} else {
+		p.add("", "Write", "after the keys of all rows are found not to exist")
+	}
+	p.add("", "EstimatedCost", formatCost(float64(keyLookupCost*len(st.Rows))))
+}
+
+// explainScan adds the plan for reading the rows of the table of a delete or
+// update statement and returns its estimated cost.
+func (p *plan) explainScan(db ds.Database, table *query_parser.TableEntry, where *query_parser.WhereClause, limit *query_parser.LimitClause) (float64, error) {
+	indexes, err := getIndexRanges(db, table.Name, table.Off, "", table.DBTable.GetIndexFields(), where)
+	if err != nil {
+		return 0, err
+	}
+	cost := p.explainIndexRanges(table.Name, indexes)
+	keyOnly := p.explainWhere(where)
+	if limit != nil {
+		p.add("", "Limit", strconv.FormatInt(limit.Limit.Value, 10))
+		p.add("", "LimitOffset", "applied while scanning; the scan stops once the limit is reached")
+		if n := float64(limit.Limit.Value); keyOnly && n < cost {
+			cost = n
+		}
+	}
+	return cost, nil
+}
+
+// explainIndexRanges adds the access method and the index ranges of the
+// table to the plan and returns the estimated cost of scanning it.  The
+// cost is that of the index that restricts the scan the most.
+func (p *plan) explainIndexRanges(table string, indexes []ds.IndexRanges) float64 {
+	best := -1
+	var cost float64
+	for i, idx := range indexes {
+		if c := indexRangesCost(idx); best < 0 || c < cost {
+			best, cost = i, c
+		}
+	}
+	var access string
+	switch {
+	case cost == 0:
+		access = "none (no row can satisfy the where clause)"
+	case cost >= tableScanCost:
+		access = "table scan"
+	case best == 0 && cost == float64(keyLookupCost*len(*indexes[0].StringRanges)):
+		access = "key lookup"
+	case best == 0:
+		access = "key range scan"
+	default:
+		access = "index range scan: " + indexes[best].FieldName
+	}
+	p.add(table, "Access", access)
+	for _, idx := range indexes {
+		p.add(table, "IndexRanges", formatIndexRanges(idx))
+	}
+	return cost
+}
+
+// explainWhere adds the predicates of the where clause to the plan and
+// returns true if they can all be evaluated with only the key (see
+// EvalWhereUsingOnlyKey).
+func (p *plan) explainWhere(w *query_parser.WhereClause) bool {
+	keyOnly, other := KeyOnlyPredicates(w)
+	for _, e := range keyOnly {
+		p.add("", "KeyOnlyPredicate", formatExpression(e))
+	}
+	for _, e := range other {
+		p.add("", "ValuePredicate", formatExpression(e))
+	}
+	switch {
+	case w == nil:
+		p.add("", "WhereEvaluation", "none")
+	case len(other) == 0:
+		p.add("", "WhereEvaluation", "key only")
+	case len(keyOnly) == 0:
+		p.add("", "WhereEvaluation", "value")
+	default:
+		p.add("", "WhereEvaluation", "key; the value is fetched only if the key does not determine the result")
+	}
+	return len(other) == 0
+}
+
+// indexRangesCost returns the estimated cost of scanning the ranges of an
+// index.  An index whose ranges include nil values does not restrict the
+// scan.
+func indexRangesCost(idx ds.IndexRanges) float64 {
+	if idx.StringRanges == nil || idx.NilAllowed {
+		return tableScanCost
+	}
+	var cost float64
+	for _, r := range *idx.StringRanges {
+		switch {
+		case r.Start == "" && r.Limit == "":
+			return tableScanCost
+		case r.Limit == r.Start+"\x00":
+			cost += keyLookupCost
+		default:
+			cost += rangeScanCost
+		}
+	}
+	if cost > tableScanCost {
+		cost = tableScanCost
+	}
+	return cost
+}
+
+func resultsOffset(st *query_parser.SelectStatement) int64 {
+	if st.ResultsOffset == nil {
+		return 0
+	}
+	return st.ResultsOffset.ResultsOffset.Value
+}
+
+func formatCost(cost float64) string {
+	return strconv.FormatFloat(cost, 'f', -1, 64)
+}
+
+// formatIndexRanges returns the index ranges as, e.g., k: ["001", "002").
+// An empty limit is the end of the table.
+func formatIndexRanges(idx ds.IndexRanges) string {
+	var ranges []string
+	if idx.StringRanges != nil {
+		for _, r := range *idx.StringRanges {
+			limit := "<end>"
+			if r.Limit != "" {
+				limit = strconv.Quote(r.Limit)
+			}
+			ranges = append(ranges, fmt.Sprintf("[%s, %s)", strconv.Quote(r.Start), limit))
+		}
+	}
+	if len(ranges) == 0 {
+		ranges = append(ranges, "none")
+	}
+	if idx.NilAllowed {
+		ranges = append(ranges, "nil")
+	}
+	return idx.FieldName + ": " + strings.Join(ranges, ", ")
+}
+
+func formatSelector(selector query_parser.Selector) string {
+	var val string
+	switch selector.Type {
+	case query_parser.TypSelField:
+		val = formatField(selector.Field)
+	case query_parser.TypSelFunc:
+		val = formatFunction(selector.Function)
+	}
+	if selector.As != nil {
+		val += " as " + selector.As.AltName.Value
+	}
+	return val
+}
+
+var operators = map[query_parser.BinaryOperatorType]string{
+	query_parser.And:                "and",
+	query_parser.Between:            "between",
+	query_parser.Equal:              "=",
+	query_parser.GreaterThan:        ">",
+	query_parser.GreaterThanOrEqual: ">=",
+	query_parser.In:                 "in",
+	query_parser.Is:                 "is",
+	query_parser.IsNot:              "is not",
+	query_parser.LessThan:           "<",
+	query_parser.LessThanOrEqual:    "<=",
+	query_parser.Like:               "like",
+	query_parser.NotBetween:         "not between",
+	query_parser.NotEqual:           "<>",
+	query_parser.NotIn:              "not in",
+	query_parser.NotLike:            "not like",
+	query_parser.Or:                 "or",
+	query_parser.Plus:               "+",
+	query_parser.Minus:              "-",
+	query_parser.Times:              "*",
+	query_parser.Divide:             "/",
+	query_parser.Modulo:             "%",
+}
+
+// formatExpression returns the expression in the syntax of a statement.
+func formatExpression(e *query_parser.Expression) string {
+	if e.Operator.Type == query_parser.Between || e.Operator.Type == query_parser.NotBetween {
+		return formatOperand(e.Operand1) + " " + operators[e.Operator.Type] + " " + formatOperand(e.Operand2.List[0]) + " and " + formatOperand(e.Operand2.List[1])
+	}
+	return formatOperand(e.Operand1) + " " + operators[e.Operator.Type] + " " + formatOperand(e.Operand2)
+}
+
+func formatOperand(o *query_parser.Operand) string {
+	switch o.Type {
+	case query_parser.TypExpr, query_parser.TypArithmetic:
+		return "(" + formatExpression(o.Expr) + ")"
+	case query_parser.TypList:
+		var values []string
+		for _, value := range o.List {
+			values = append(values, formatOperand(value))
+		}
+		return "(" + strings.Join(values, ", ") + ")"
+	case query_parser.TypField:
+		return formatField(o.Column)
+	case query_parser.TypFunction:
+		return formatFunction(o.Function)
+	case query_parser.TypStr:
+		return strconv.Quote(o.Str)
+	case query_parser.TypBool:
+		return strconv.FormatBool(o.Bool)
+	case query_parser.TypInt:
+		return strconv.FormatInt(o.Int, 10)
+	case query_parser.TypFloat:
+		return strconv.FormatFloat(o.Float, 'f', -1, 64)
+	case query_parser.TypNil:
+		return "nil"
+	case query_parser.TypParameter:
+		return "?"
+	default:
+		return "<?>"
+	}
+}
+
+func formatField(f *query_parser.Field) string {
+	var segments []string
+	for _, segment := range f.Segments {
+		val := segment.Value
+		for _, key := range segment.Keys {
+			val += "[" + formatOperand(key) + "]"
+		}
+		segments = append(segments, val)
+	}
+	return strings.Join(segments, ".")
+}
+
+func formatFunction(f *query_parser.Function) string {
+	var args []string
+	for _, arg := range f.Args {
+		args = append(args, formatOperand(arg))
+	}
+	return f.Name + "(" + strings.Join(args, ", ") + ")"
+}
@@ -0,0 +1,256 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	ds "v.io/v23/query/engine/datasource"
+	"v.io/v23/query/engine/internal/conversions"
+	"v.io/v23/query/engine/internal/query_checker"
+	"v.io/v23/query/engine/internal/query_functions"
+	"v.io/v23/query/engine/internal/query_parser"
+	"v.io/v23/query/syncql"
+	"v.io/v23/vdl"
+	"v.io/v23/vom"
+)
+
+// groupingResultStream is the result stream of a select statement whose rows
+// are grouped (see query_checker.IsGrouped).  The key value stream is
+// consumed on the first call to Advance.  Only the first row of each group
+// and the accumulators of the group's aggregate functions are kept in memory.
+// Groups are returned in the order of their group by keys, unless the
+// statement has an order by clause.
+type groupingResultStream struct {
+	db              ds.Database
+	selectStatement *query_parser.SelectStatement
+	keyValueStream  ds.KeyValueStream
+	aggregates      []*query_parser.Function
+	started         bool
+	groups          []*group // groups remaining to be returned
+	cur             *group
+	err             error
+}
+
+type group struct {
+	k            string                        // key of the first row of the group
+	v            *vdl.Value                    // value of the first row of the group
+	keys         []*query_parser.Operand       // resolved group by keys
+	accumulators []query_functions.Accumulator // nil if the aggregate failed
+	db           *groupDatabase                // resolves the aggregates to their results
+	sortKeys     []*query_parser.Operand       // resolved order by keys
+}
+
+// groupDatabase is the database with which the clauses of a statement are
+// evaluated for a group, as if for its first row: it resolves the statement's
+// aggregate functions to their results for the group.  The results are not
+// stored in the statement as it may be shared by concurrent executions of a
+// prepared statement.
+type groupDatabase struct {
+	ds.Database
+	results map[*query_parser.Function]*query_parser.Operand
+}
+
+func (db *groupDatabase) UserFunctions() *query_functions.UserFunctions {
+	if udb, ok := db.Database.(interface {
+		UserFunctions() *query_functions.UserFunctions
+	}); ok {
+		return udb.UserFunctions()
+	}
+	return nil
+}
+
+// aggregateResult returns the result of the aggregate function f if db is a
+// groupDatabase.  The boolean is false if f is not resolved by db.
+func aggregateResult(db ds.Database, f *query_parser.Function) (*query_parser.Operand, bool) {
+	if gdb, ok := db.(*groupDatabase); ok {
+		result, ok := gdb.results[f]
+		return result, ok
+	}
+	return nil, false
+}
+
+func newGroupingResultStream(db ds.Database, st *query_parser.SelectStatement, keyValueStream ds.KeyValueStream) *groupingResultStream {
+	return &groupingResultStream{
+		db:              db,
+		selectStatement: st,
+		keyValueStream:  keyValueStream,
+		aggregates:      query_checker.AggregateFunctions(st),
+	}
+}
+
+func (rs *groupingResultStream) Advance() bool {
+	if !rs.started {
+		rs.started = true
+		if rs.err = rs.groupRows(); rs.err != nil {
+			return false
+		}
+	}
+	if len(rs.groups) == 0 {
+		return false
+	}
+	rs.cur, rs.groups = rs.groups[0], rs.groups[1:]
+	return true
+}
+
+func (rs *groupingResultStream) Result() []*vom.RawBytes {
+	return ComposeProjection(rs.cur.db, rs.cur.k, rs.cur.v, rs.selectStatement.Select)
+}
+
+func (rs *groupingResultStream) Err() error {
+	return rs.err
+}
+
+func (rs *groupingResultStream) Cancel() {
+	rs.keyValueStream.Cancel()
+	rs.groups = nil
+}
+
+// groupRows reads the rows that satisfy the where clause, adds each to its
+// group and then computes the groups to be returned, applying the having,
+// order by, distinct, limit and offset clauses.
+func (rs *groupingResultStream) groupRows() error {
+	st := rs.selectStatement
+	index := make(map[string]*group)
+	var groups []*group
+	for rs.keyValueStream.Advance() {
+		k, rb := rs.keyValueStream.KeyValue()
+		if !evalWhere(rs.db, st.Where, k, rb) {
+			continue
+		}
+		v := vdl.ValueOf(rb)
+		var keys []*query_parser.Operand
+		if st.GroupBy != nil {
+			for _, key := range st.GroupBy.Keys {
+				keys = append(keys, resolveOperand(rs.db, k, v, key))
+			}
+		}
+		gk := groupKey(keys)
+		g, ok := index[gk]
+		if !ok {
+			var err error
+			if g, err = rs.newGroup(k, v, keys); err != nil {
+				rs.keyValueStream.Cancel()
+				return err
+			}
+			index[gk] = g
+			groups = append(groups, g)
+		}
+		for i, f := range rs.aggregates {
+			if err := g.accumulators[i].Add(resolveOperand(rs.db, k, v, f.Args[0])); err != nil {
+				rs.keyValueStream.Cancel()
+				return err
+			}
+		}
+	}
+	if err := rs.keyValueStream.Err(); err != nil {
+		return syncql.NewErrKeyValueStreamError(rs.db.GetContext(), st.Off, err)
+	}
+	// Without a group by clause, all of the rows are in a single group, even
+	// if there are none.
+	if st.GroupBy == nil && len(groups) == 0 {
+		g, err := rs.newGroup("", vdl.ValueOf(nil), nil)
+		if err != nil {
+			return err
+		}
+		groups = append(groups, g)
+	}
+
+	rs.groups = groups[:0]
+	for _, g := range groups {
+		g.db = &groupDatabase{rs.db, make(map[*query_parser.Function]*query_parser.Operand)}
+		for i, acc := range g.accumulators {
+			g.db.results[rs.aggregates[i]] = acc.Result()
+		}
+		g.accumulators = nil
+		if st.Having != nil && !Eval(g.db, g.k, g.v, st.Having.Expr) {
+			continue
+		}
+		if st.OrderBy != nil {
+			g.sortKeys = resolveSortKeys(g.db, g.k, g.v, st.OrderBy)
+		}
+		rs.groups = append(rs.groups, g)
+	}
+	sort.Stable(groupSorter{rs.groups, st.OrderBy})
+
+	if st.Select.Distinct {
+		distinct := distinctFilter{}
+		groups = rs.groups
+		rs.groups = groups[:0]
+		for _, g := range groups {
+			isNew, err := distinct.add(ComposeProjection(g.db, g.k, g.v, st.Select))
+			if err != nil {
+				return syncql.NewErrKeyValueStreamError(rs.db.GetContext(), st.Off, err)
+			}
+			if isNew {
+				rs.groups = append(rs.groups, g)
+			}
+		}
+	}
+	if st.ResultsOffset != nil {
+		if off := st.ResultsOffset.ResultsOffset.Value; off < int64(len(rs.groups)) {
+			rs.groups = rs.groups[off:]
+		} else {
+			rs.groups = nil
+		}
+	}
+	if st.Limit != nil && st.Limit.Limit.Value < int64(len(rs.groups)) {
+		rs.groups = rs.groups[:st.Limit.Limit.Value]
+	}
+	return nil
+}
+
+func (rs *groupingResultStream) newGroup(k string, v *vdl.Value, keys []*query_parser.Operand) (*group, error) {
+	g := &group{k: k, v: v, keys: keys}
+	for _, f := range rs.aggregates {
+		acc, err := query_functions.NewAccumulator(rs.db, f)
+		if err != nil {
+			return nil, err
+		}
+		g.accumulators = append(g.accumulators, acc)
+	}
+	return g, nil
+}
+
+// groupKey returns a string that is equal for rows with equal group by keys.
+func groupKey(keys []*query_parser.Operand) string {
+	var buf bytes.Buffer
+	for _, key := range keys {
+		if key == nil {
+			buf.WriteString("nil;")
+			continue
+		}
+		s := fmt.Sprint(valueFromResolvedOperand(key))
+		fmt.Fprintf(&buf, "%d:%d:%s;", key.Type, len(s), s)
+	}
+	return buf.String()
+}
+
+// groupSorter sorts groups by the order by clause (if any) and then by their
+// group by keys.
+type groupSorter struct {
+	groups  []*group
+	orderBy *query_parser.OrderByClause
+}
+
+func (s groupSorter) Len() int { return len(s.groups) }
+
+func (s groupSorter) Less(i, j int) bool {
+	if s.orderBy != nil {
+		if c := compareSortKeys(s.groups[i].sortKeys, s.groups[j].sortKeys, s.orderBy); c != 0 {
+			return c < 0
+		}
+	}
+	for k, key := range s.groups[i].keys {
+		if c := conversions.CompareOperands(key, s.groups[j].keys[k]); c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+func (s groupSorter) Swap(i, j int) { s.groups[i], s.groups[j] = s.groups[j], s.groups[i] }
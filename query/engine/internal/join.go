@@ -0,0 +1,263 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	ds "v.io/v23/query/engine/datasource"
+	"v.io/v23/query/engine/internal/query_checker"
+	"v.io/v23/query/engine/internal/query_parser"
+	"v.io/v23/query/syncql"
+	"v.io/v23/vdl"
+	"v.io/v23/vom"
+)
+
+// A joinedRow is the value of a row of a select statement with joins.  It
+// holds the key and value of the row of each table, in the order of the
+// tables in the from clause.  The entry for a table is nil if the table has
+// no row (i.e., a left join matched no row of the table).
+type joinedRow []*joinedRowEntry
+
+type joinedRowEntry struct {
+	K string
+	V *vdl.Value
+}
+
+// joinedRowEntryAt returns the key and value of the ith table's row in a
+// joinedRow.  ok is false if the table has no row.
+func joinedRowEntryAt(row *vdl.Value, i int) (k string, v *vdl.Value, ok bool) {
+	row = autoDereference(row)
+	if row.Kind() != vdl.List || i >= row.Len() {
+		return "", nil, false
+	}
+	entry := autoDereference(row.Index(i))
+	if entry.Kind() != vdl.Struct {
+		return "", nil, false
+	}
+	return entry.StructField(0).RawString(), entry.StructField(1), true
+}
+
+// joinStream is the KeyValueStream of the rows of a select statement with
+// joins.  Tables are joined with nested loops: for each row of a table, the
+// next table in the from clause is scanned for the rows that satisfy the on
+// clause of its join.  If the on clause compares the key of the joined table
+// with an operand of the preceding tables, only the row with that key is
+// looked up.  The key of each row is the key of the first table's row; the
+// value is a joinedRow.
+type joinStream struct {
+	db     ds.Database
+	st     *query_parser.SelectStatement
+	levels []*joinLevel // one per table
+	depth  int          // the level being advanced
+	row    joinedRow
+	k      string
+	v      *vom.RawBytes
+	err    error
+}
+
+type joinLevel struct {
+	table   *query_parser.TableEntry
+	join    *query_parser.JoinClause // nil for the first table
+	indexes []ds.IndexRanges
+	lookup  *query_parser.Operand // operand compared with the key of the table (if any)
+	stream  ds.KeyValueStream
+	matched bool // true if a row of the table has been joined (else, left joins are padded with nil)
+}
+
+func newJoinStream(db ds.Database, st *query_parser.SelectStatement) (*joinStream, error) {
+	levels, err := newJoinLevels(db, st)
+	if err != nil {
+		return nil, err
+	}
+	s := &joinStream{db: db, st: st, levels: levels}
+	s.row = make(joinedRow, len(s.levels))
+	if err := s.open(0); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// newJoinLevels returns the levels of the join, one per table of the from
+// clause, with the index ranges to scan for each table.
+func newJoinLevels(db ds.Database, st *query_parser.SelectStatement) ([]*joinLevel, error) {
+	levels := []*joinLevel{&joinLevel{table: &st.From.Table}}
+	var err error
+	if levels[0].indexes, err = getIndexRanges(db, st.From.Table.Name, st.From.Table.Off, st.From.Table.Qualifier(), st.From.Table.DBTable.GetIndexFields(), st.Where); err != nil {
+		return nil, err
+	}
+	for i, join := range st.From.Joins {
+		// The on clause must be true for rows of the joined table.  For inner
+		// joins, so must the where clause.
+		cond := &query_parser.WhereClause{Expr: join.On, Node: join.Node}
+		if join.Type == query_parser.InnerJoin && st.Where != nil {
+			cond.Expr = conjunction(join.On, st.Where.Expr)
+		}
+		level := &joinLevel{table: &join.Table, join: join, lookup: keyLookup(join.On, i+1)}
+		if level.indexes, err = getIndexRanges(db, join.Table.Name, join.Table.Off, join.Table.Qualifier(), join.Table.DBTable.GetIndexFields(), cond); err != nil {
+			return nil, err
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+func (s *joinStream) Advance() bool {
+	for s.depth >= 0 {
+		ok, err := s.next(s.depth)
+		if err != nil {
+			s.err = err
+			s.Cancel()
+			return false
+		}
+		if !ok {
+			s.depth--
+			continue
+		}
+		if s.depth == len(s.levels)-1 {
+			s.k = s.row[0].K
+			s.v = vom.RawBytesOf(s.row)
+			return true
+		}
+		s.depth++
+		if err := s.open(s.depth); err != nil {
+			s.err = err
+			s.Cancel()
+			return false
+		}
+	}
+	return false
+}
+
+func (s *joinStream) KeyValue() (string, *vom.RawBytes) {
+	return s.k, s.v
+}
+
+func (s *joinStream) Err() error {
+	return s.err
+}
+
+func (s *joinStream) Cancel() {
+	for _, level := range s.levels {
+		if level.stream != nil {
+			level.stream.Cancel()
+		}
+	}
+	s.depth = -1
+}
+
+// open scans the table of level i for the rows to be joined with the current
+// rows of the preceding tables.
+func (s *joinStream) open(i int) error {
+	level := s.levels[i]
+	for j := i; j < len(s.row); j++ {
+		s.row[j] = nil
+	}
+	level.matched = false
+	indexes := level.indexes
+	if level.lookup != nil {
+		indexes = append([]ds.IndexRanges{}, level.indexes...)
+		kRanges := &ds.StringFieldRanges{}
+		if o := resolveOperand(s.db, s.row[0].K, vdl.ValueOf(s.row), level.lookup); o != nil && o.Type == query_parser.TypStr {
+			kRanges = &ds.StringFieldRanges{ds.StringFieldRange{Start: o.Str, Limit: string(append([]byte(o.Str), 0))}}
+		}
+		indexes[0].StringRanges = kRanges
+	}
+	var err error
+	if level.stream, err = level.table.DBTable.Scan(indexes...); err != nil {
+		return syncql.NewErrScanError(s.db.GetContext(), s.st.Off, err)
+	}
+	return nil
+}
+
+// next advances level i to its next row (for levels other than the first,
+// a row that satisfies the on clause).  It returns false if there are no
+// more rows.
+func (s *joinStream) next(i int) (bool, error) {
+	level := s.levels[i]
+	for level.stream.Advance() {
+		k, v := level.stream.KeyValue()
+		s.row[i] = &joinedRowEntry{k, vdl.ValueOf(v)}
+		if level.join == nil || Eval(s.db, s.row[0].K, vdl.ValueOf(s.row), level.join.On) {
+			level.matched = true
+			return true, nil
+		}
+	}
+	if err := level.stream.Err(); err != nil {
+		return false, err
+	}
+	s.row[i] = nil
+	if level.join != nil && level.join.Type == query_parser.LeftJoin && !level.matched {
+		level.matched = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// keyLookup returns the operand that the key of table i is compared with
+// for equality in the on clause of its join, provided the operand depends
+// only on the preceding tables.  Equality can be a term of a conjunction.
+func keyLookup(e *query_parser.Expression, i int) *query_parser.Operand {
+	switch e.Operator.Type {
+	case query_parser.And:
+		if o := keyLookup(e.Operand1.Expr, i); o != nil {
+			return o
+		}
+		return keyLookup(e.Operand2.Expr, i)
+	case query_parser.Equal:
+		if isKeyOfTable(e.Operand1, i) && precedesTable(e.Operand2, i) {
+			return e.Operand2
+		}
+		if isKeyOfTable(e.Operand2, i) && precedesTable(e.Operand1, i) {
+			return e.Operand1
+		}
+	}
+	return nil
+}
+
+func isKeyOfTable(o *query_parser.Operand, i int) bool {
+	if !query_checker.IsField(o) || !o.Column.Qualified || o.Column.Table != i {
+		return false
+	}
+	path := o.Column.Path()
+	return len(path) == 1 && path[0].Value == "k"
+}
+
+// precedesTable returns true if the operand depends only on tables preceding
+// table i.
+func precedesTable(o *query_parser.Operand, i int) bool {
+	switch o.Type {
+	case query_parser.TypExpr:
+		return false
+	case query_parser.TypField:
+		if !o.Column.Qualified || o.Column.Table >= i {
+			return false
+		}
+		for _, segment := range o.Column.Segments {
+			for _, key := range segment.Keys {
+				if !precedesTable(key, i) {
+					return false
+				}
+			}
+		}
+	case query_parser.TypFunction:
+		for _, arg := range o.Function.Args {
+			if !precedesTable(arg, i) {
+				return false
+			}
+		}
+	case query_parser.TypArithmetic:
+		return precedesTable(o.Expr.Operand1, i) && precedesTable(o.Expr.Operand2, i)
+	}
+	return true
+}
+
+// conjunction returns the expression: lhs and rhs.
+func conjunction(lhs, rhs *query_parser.Expression) *query_parser.Expression {
+	return &query_parser.Expression{
+		Operand1: &query_parser.Operand{Type: query_parser.TypExpr, Expr: lhs, Node: lhs.Node},
+		Operator: &query_parser.BinaryOperator{Type: query_parser.And, Node: lhs.Node},
+		Operand2: &query_parser.Operand{Type: query_parser.TypExpr, Expr: rhs, Node: rhs.Node},
+		Node:     lhs.Node,
+	}
+}
@@ -20,7 +20,8 @@ import (
 )
 
 type queryEngineImpl struct {
-	db                      ds.Database
+	db                      ds.Database // an engineDatabase
+	userFunctions           *query_functions.UserFunctions
 	mutexNextID             sync.Mutex
 	nextID                  int64
 	mutexPreparedStatements sync.Mutex
@@ -37,8 +38,25 @@ type paramInfo struct {
 	cursor      int64
 }
 
+// engineDatabase is the datasource's database as seen by the checker and
+// executor of a query engine: it adds the query engine's user-defined
+// functions.
+type engineDatabase struct {
+	ds.Database
+	userFunctions *query_functions.UserFunctions
+}
+
+func (db *engineDatabase) UserFunctions() *query_functions.UserFunctions {
+	return db.userFunctions
+}
+
 func Create(db ds.Database) public.QueryEngine {
-	return &queryEngineImpl{db: db, nextID: 0, preparedStatements: map[int64]*query_parser.Statement{}}
+	userFunctions := query_functions.NewUserFunctions()
+	return &queryEngineImpl{db: &engineDatabase{db, userFunctions}, userFunctions: userFunctions, nextID: 0, preparedStatements: map[int64]*query_parser.Statement{}}
+}
+
+func (qe *queryEngineImpl) RegisterFunction(name string, f public.Function) error {
+	return qe.userFunctions.Register(qe.db.GetContext(), name, f)
 }
 
 func (qe *queryEngineImpl) Exec(q string) ([]string, syncql.ResultStream, error) {
@@ -118,7 +136,11 @@ func checkAndExec(db ds.Database, s *query_parser.Statement) ([]string, syncql.R
 		return nil, nil, err
 	}
 	switch (*s).(type) {
-	case query_parser.SelectStatement, query_parser.DeleteStatement:
+	case query_parser.SelectStatement, query_parser.UnionStatement, query_parser.DeleteStatement:
+		return execStatement(db, s)
+	case query_parser.UpdateStatement, query_parser.InsertStatement:
+		return execStatement(db, s)
+	case query_parser.ExplainStatement:
 		return execStatement(db, s)
 	default:
 		return nil, nil, syncql.NewErrExecOfUnknownStatementType(db.GetContext(), (*s).Offset(), reflect.TypeOf(*s).Name())
@@ -136,7 +158,9 @@ func ComposeProjection(db ds.Database, k string, v *vdl.Value, s *query_parser.S
 			f := ResolveField(db, k, v, selector.Field)
 			projection = append(projection, vom.RawBytesOf(f))
 		case query_parser.TypSelFunc:
-			if selector.Function.Computed {
+			if result, ok := aggregateResult(db, selector.Function); ok {
+				projection = append(projection, query_functions.ConvertFunctionRetValueToRawBytes(result))
+			} else if selector.Function.Computed {
 				projection = append(projection, query_functions.ConvertFunctionRetValueToRawBytes(selector.Function.RetValue))
 			} else {
 				// need to exec function
@@ -234,12 +258,17 @@ func getSegmentKeyAsHeading(segKey *query_parser.Operand) string {
 	return val
 }
 
-func getIndexRanges(db ds.Database, tableName string, tableOff int64, indexFields []ds.Index, w *query_parser.WhereClause) ([]ds.IndexRanges, error) {
+// getIndexRanges returns the index ranges (the first of which is for k) of the
+// table that can satisfy the where clause.  If qualifier is not empty, the
+// fields of the where clause are qualified (see query_parser.Field) and only
+// those qualified with qualifier are fields of the table.
+func getIndexRanges(db ds.Database, tableName string, tableOff int64, qualifier string, indexFields []ds.Index, w *query_parser.WhereClause) ([]ds.IndexRanges, error) {
 	indexes := []ds.IndexRanges{}
 
 	// Get IndexRanges for k
 	kField := &query_parser.Field{Segments: []query_parser.Segment{query_parser.Segment{Value: "k"}}}
-	idxRanges := *query_checker.CompileIndexRanges(kField, vdl.String, w)
+	idxRanges := *query_checker.CompileIndexRanges(qualifyField(qualifier, kField), vdl.String, w)
+	idxRanges.FieldName = "k"
 	indexes = append(indexes, idxRanges)
 
 	// Get IndexRanges for secondary indexes.
@@ -253,35 +282,93 @@ func getIndexRanges(db ds.Database, tableName string, tableOff int64, indexField
 		if idxField, err = query_parser.ParseIndexField(db, idx.FieldName, tableName); err != nil {
 			return nil, err
 		}
-		idxRanges := *query_checker.CompileIndexRanges(idxField, idx.Kind, w)
+		idxRanges := *query_checker.CompileIndexRanges(qualifyField(qualifier, idxField), idx.Kind, w)
+		idxRanges.FieldName = idx.FieldName
 		indexes = append(indexes, idxRanges)
 	}
 	return indexes, nil
 }
 
-func execStatement(db ds.Database, s *query_parser.Statement) ([]string, syncql.ResultStream, error) {
-	switch st := (*s).(type) {
+// qualifyField returns the field qualified with qualifier (if not empty).
+func qualifyField(qualifier string, f *query_parser.Field) *query_parser.Field {
+	if qualifier == "" {
+		return f
+	}
+	qualified := *f
+	qualified.Qualified = true
+	qualified.Segments = append([]query_parser.Segment{query_parser.Segment{Value: qualifier}}, f.Segments...)
+	return &qualified
+}
 
-	// Select
-	case query_parser.SelectStatement:
-		indexes, err := getIndexRanges(db, st.From.Table.Name, st.From.Table.Off, st.From.Table.DBTable.GetIndexFields(), st.Where)
-		if err != nil {
+// execSelect returns the column headings and the result stream of a select
+// statement.
+func execSelect(db ds.Database, st *query_parser.SelectStatement) ([]string, syncql.ResultStream, error) {
+	var keyValueStream ds.KeyValueStream
+	if len(st.From.Joins) > 0 {
+		var err error
+		if keyValueStream, err = newJoinStream(db, st); err != nil {
 			return nil, nil, err
 		}
-
-		keyValueStream, err := st.From.Table.DBTable.Scan(indexes...)
+	} else {
+		indexes, err := getIndexRanges(db, st.From.Table.Name, st.From.Table.Off, "", st.From.Table.DBTable.GetIndexFields(), st.Where)
 		if err != nil {
+			return nil, nil, err
+		}
+		if keyValueStream, err = st.From.Table.DBTable.Scan(indexes...); err != nil {
 			return nil, nil, syncql.NewErrScanError(db.GetContext(), st.Off, err)
 		}
-		var resultStream selectResultStreamImpl
-		resultStream.db = db
-		resultStream.selectStatement = &st
+	}
+	if query_checker.IsGrouped(st) {
+		return getColumnHeadings(st), newGroupingResultStream(db, st, keyValueStream), nil
+	}
+	var resultStream selectResultStreamImpl
+	resultStream.db = db
+	resultStream.selectStatement = st
+	if st.Select.Distinct {
+		resultStream.distinct = distinctFilter{}
+	}
+	if st.OrderBy != nil {
+		resultStream.keyValueStream = newSortingStream(db, st, keyValueStream)
+		resultStream.sorted = true
+	} else {
 		resultStream.keyValueStream = keyValueStream
-		return getColumnHeadings(&st), &resultStream, nil
+	}
+	return getColumnHeadings(st), &resultStream, nil
+}
+
+// execUnion executes each of the selects of the union.  The column headings
+// are those of the first select.
+func execUnion(db ds.Database, st *query_parser.UnionStatement) ([]string, syncql.ResultStream, error) {
+	var headings []string
+	resultStream := newUnionResultStream(db, st)
+	for i := range st.Selects {
+		h, rs, err := execSelect(db, &st.Selects[i])
+		if err != nil {
+			resultStream.Cancel()
+			return nil, nil, err
+		}
+		if i == 0 {
+			headings = h
+		}
+		resultStream.streams = append(resultStream.streams, rs)
+	}
+	return headings, resultStream, nil
+}
+
+func execStatement(db ds.Database, s *query_parser.Statement) ([]string, syncql.ResultStream, error) {
+	switch st := (*s).(type) {
+
+	// Select
+	case query_parser.SelectStatement:
+		return execSelect(db, &st)
+
+	// Union
+	case query_parser.UnionStatement:
+		return execUnion(db, &st)
 
 	// Delete
 	case query_parser.DeleteStatement:
-		indexes, err := getIndexRanges(db, st.From.Table.Name, st.From.Table.Off, st.From.Table.DBTable.GetIndexFields(), st.Where)
+		indexes, err := getIndexRanges(db, st.From.Table.Name, st.From.Table.Off, "", st.From.Table.DBTable.GetIndexFields(), st.Where)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -325,12 +412,40 @@ func execStatement(db ds.Database, s *query_parser.Statement) ([]string, syncql.
 			return nil, nil, syncql.NewErrKeyValueStreamError(db.GetContext(), st.Off, err)
 		}
 
-		var resultStream deleteResultStreamImpl
+		var resultStream countResultStreamImpl
 		resultStream.db = db
-		resultStream.deleteStatement = &st
-		resultStream.deleteCursor = 0
-		resultStream.deleteCount = deleteCount
+		resultStream.count = deleteCount
 		return []string{"Count"}, &resultStream, nil
+
+	// Update
+	case query_parser.UpdateStatement:
+		updateCount, err := execUpdate(db, &st)
+		if err != nil {
+			return nil, nil, err
+		}
+		var resultStream countResultStreamImpl
+		resultStream.db = db
+		resultStream.count = updateCount
+		return []string{"Count"}, &resultStream, nil
+
+	// Insert
+	case query_parser.InsertStatement:
+		insertCount, err := execInsert(db, &st)
+		if err != nil {
+			return nil, nil, err
+		}
+		var resultStream countResultStreamImpl
+		resultStream.db = db
+		resultStream.count = insertCount
+		return []string{"Count"}, &resultStream, nil
+
+	// Explain
+	case query_parser.ExplainStatement:
+		rows, err := explain(db, st.Statement)
+		if err != nil {
+			return nil, nil, err
+		}
+		return explainHeadings, &explainResultStreamImpl{rows: rows}, nil
 	}
 	return nil, nil, syncql.NewErrOperationNotSupported(db.GetContext(), "")
 }
@@ -5,9 +5,12 @@
 package query_checker
 
 import (
+	"reflect"
 	"sort"
 
+	"v.io/v23/context"
 	ds "v.io/v23/query/engine/datasource"
+	"v.io/v23/query/engine/internal/conversions"
 	"v.io/v23/query/engine/internal/query_functions"
 	"v.io/v23/query/engine/internal/query_parser"
 	"v.io/v23/query/pattern"
@@ -27,8 +30,16 @@ func Check(db ds.Database, s *query_parser.Statement) error {
 	switch sel := (*s).(type) {
 	case query_parser.SelectStatement:
 		return checkSelectStatement(db, &sel)
+	case query_parser.UnionStatement:
+		return checkUnionStatement(db, &sel)
 	case query_parser.DeleteStatement:
 		return checkDeleteStatement(db, &sel)
+	case query_parser.ExplainStatement:
+		return Check(db, &sel.Statement)
+	case query_parser.UpdateStatement:
+		return checkUpdateStatement(db, &sel)
+	case query_parser.InsertStatement:
+		return checkInsertStatement(db, &sel)
 	default:
 		return syncql.NewErrCheckOfUnknownStatementType(db.GetContext(), (*s).Offset())
 	}
@@ -44,9 +55,49 @@ func checkSelectStatement(db ds.Database, s *query_parser.SelectStatement) error
 	if err := checkEscapeClause(db, s.Escape); err != nil {
 		return err
 	}
+	if err := checkJoinClauses(db, s.From.Joins, s.Escape); err != nil {
+		return err
+	}
 	if err := checkWhereClause(db, s.Where, s.Escape); err != nil {
 		return err
 	}
+	if err := checkGroupByClause(db, s.GroupBy, s.Escape); err != nil {
+		return err
+	}
+	if err := checkHavingClause(db, s.Having, s.Escape); err != nil {
+		return err
+	}
+	if err := checkOrderByClause(db, s.OrderBy, s.Escape); err != nil {
+		return err
+	}
+	if err := checkAggregates(db, s); err != nil {
+		return err
+	}
+	if err := checkLimitClause(db, s.Limit); err != nil {
+		return err
+	}
+	if err := checkResultsOffsetClause(db, s.ResultsOffset); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Each select of a union must have the same number of columns as the first.
+// Since the results of the selects are combined as they are, order by is not
+// allowed in any of them.
+func checkUnionStatement(db ds.Database, s *query_parser.UnionStatement) error {
+	for i := range s.Selects {
+		sel := &s.Selects[i]
+		if err := checkSelectStatement(db, sel); err != nil {
+			return err
+		}
+		if sel.OrderBy != nil {
+			return syncql.NewErrUnionOrderBy(db.GetContext(), sel.OrderBy.Off)
+		}
+		if expected, found := len(s.Selects[0].Select.Selectors), len(sel.Select.Selectors); found != expected {
+			return syncql.NewErrUnionColumnCount(db.GetContext(), sel.Select.Off, int64(expected), int64(found))
+		}
+	}
 	if err := checkLimitClause(db, s.Limit); err != nil {
 		return err
 	}
@@ -72,26 +123,151 @@ func checkDeleteStatement(db ds.Database, s *query_parser.DeleteStatement) error
 	return nil
 }
 
+func checkUpdateStatement(db ds.Database, s *query_parser.UpdateStatement) error {
+	if err := checkTableEntry(db, s.Table, true); err != nil {
+		return err
+	}
+	if err := checkEscapeClause(db, s.Escape); err != nil {
+		return err
+	}
+	if err := checkSetClause(db, s.Set, s.Escape); err != nil {
+		return err
+	}
+	if err := checkWhereClause(db, s.Where, s.Escape); err != nil {
+		return err
+	}
+	if s.Where != nil {
+		if f := findAggregateInExpression(s.Where.Expr); f != nil {
+			return syncql.NewErrAggregateNotAllowed(db.GetContext(), f.Off, f.Name)
+		}
+	}
+	if err := checkLimitClause(db, s.Limit); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Check set clause.  Fields can only be v[{.<ident>}...] (as the key of a row
+// cannot be changed).  Values can be any operand but aggregates.
+func checkSetClause(db ds.Database, s *query_parser.SetClause, ec *query_parser.EscapeClause) error {
+	for _, a := range s.Assignments {
+		if err := checkField(db, a.Field, syncql.NewErrInvalidSetField); err != nil {
+			return err
+		}
+		if IsKeyField(a.Field) {
+			return syncql.NewErrInvalidSetField(db.GetContext(), a.Field.Off)
+		}
+		if err := checkOperand(db, a.Operand, ec); err != nil {
+			return err
+		}
+		if f := findAggregate(a.Operand); f != nil {
+			return syncql.NewErrAggregateNotAllowed(db.GetContext(), f.Off, f.Name)
+		}
+	}
+	return nil
+}
+
+// Check insert statement.  The columns must be k and v; the values, which
+// cannot refer to fields (there being no row), must be literals, parameters
+// or functions of them.
+func checkInsertStatement(db ds.Database, s *query_parser.InsertStatement) error {
+	if err := checkTableEntry(db, s.Table, true); err != nil {
+		return err
+	}
+	keyColumn, err := checkInsertColumns(db, s.Columns)
+	if err != nil {
+		return err
+	}
+	for _, row := range s.Rows {
+		if len(row.Values) != len(s.Columns) {
+			return syncql.NewErrInsertValueCount(db.GetContext(), row.Off, int64(len(s.Columns)), int64(len(row.Values)))
+		}
+		// Functions computed early are replaced with their return values;
+		// as such, take note of the key's offset.
+		keyOff := row.Values[keyColumn].Off
+		for _, value := range row.Values {
+			if o := findField(value); o != nil {
+				return syncql.NewErrInvalidInsertValue(db.GetContext(), o.Off)
+			}
+			if err := checkOperand(db, value, nil); err != nil {
+				return err
+			}
+			if f := findAggregate(value); f != nil {
+				return syncql.NewErrAggregateNotAllowed(db.GetContext(), f.Off, f.Name)
+			}
+		}
+		// Functions not computed early (and parameters substituted with
+		// non-string values) are checked when the statement is executed.
+		if key := row.Values[keyColumn]; isLiteral(key) && key.Type != query_parser.TypStr {
+			return syncql.NewErrInsertKeyNotString(db.GetContext(), keyOff)
+		}
+	}
+	return nil
+}
+
+// Check the columns of an insert statement, which must be k and v (in either
+// order).  Return the index of k.
+func checkInsertColumns(db ds.Database, columns []*query_parser.Field) (int, error) {
+	var found [2]bool
+	keyColumn := -1
+	for i, column := range columns {
+		if i > 1 || column.Qualified || len(column.Segments) != 1 || len(column.Segments[0].Keys) != 0 {
+			return -1, syncql.NewErrInvalidInsertColumns(db.GetContext(), column.Off)
+		}
+		var j int
+		switch column.Segments[0].Value {
+		case "k":
+			j = 0
+			keyColumn = i
+		case "v":
+			j = 1
+		default:
+			return -1, syncql.NewErrInvalidInsertColumns(db.GetContext(), column.Off)
+		}
+		if found[j] {
+			return -1, syncql.NewErrInvalidInsertColumns(db.GetContext(), column.Off)
+		}
+		found[j] = true
+	}
+	if len(columns) != 2 {
+		return -1, syncql.NewErrInvalidInsertColumns(db.GetContext(), columns[0].Off)
+	}
+	return keyColumn, nil
+}
+
+// findField returns the first operand that is (or contains) a field.
+func findField(o *query_parser.Operand) *query_parser.Operand {
+	switch o.Type {
+	case query_parser.TypField:
+		return o
+	case query_parser.TypExpr, query_parser.TypArithmetic:
+		if f := findField(o.Expr.Operand1); f != nil {
+			return f
+		}
+		return findField(o.Expr.Operand2)
+	case query_parser.TypFunction:
+		for _, arg := range o.Function.Args {
+			if f := findField(arg); f != nil {
+				return f
+			}
+		}
+	case query_parser.TypList:
+		for _, value := range o.List {
+			if f := findField(value); f != nil {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
 // Check select clause.  Fields can be 'k' and v[{.<ident>}...]
 func checkSelectClause(db ds.Database, s *query_parser.SelectClause) error {
 	for _, selector := range s.Selectors {
 		switch selector.Type {
 		case query_parser.TypSelField:
-			switch selector.Field.Segments[0].Value {
-			case "k":
-				if len(selector.Field.Segments) > 1 {
-					return syncql.NewErrDotNotationDisallowedForKey(db.GetContext(), selector.Field.Segments[1].Off)
-				}
-			case "v":
-				// Nothing to check.
-			case "K":
-				// Be nice and warn of mistakenly capped 'K'.
-				return syncql.NewErrDidYouMeanLowercaseK(db.GetContext(), selector.Field.Segments[0].Off)
-			case "V":
-				// Be nice and warn of mistakenly capped 'V'.
-				return syncql.NewErrDidYouMeanLowercaseV(db.GetContext(), selector.Field.Segments[0].Off)
-			default:
-				return syncql.NewErrInvalidSelectField(db.GetContext(), selector.Field.Segments[0].Off)
+			if err := checkField(db, selector.Field, syncql.NewErrInvalidSelectField); err != nil {
+				return err
 			}
 		case query_parser.TypSelFunc:
 			err := query_functions.CheckFunction(db, selector.Function)
@@ -103,12 +279,34 @@ func checkSelectClause(db ds.Database, s *query_parser.SelectClause) error {
 	return nil
 }
 
-// Check from clause.  Table must exist in the database.
+// Check from clause.  Tables (including those joined) must exist in the database.
 func checkFromClause(db ds.Database, f *query_parser.FromClause, writeAccessReq bool) error {
+	if err := checkTableEntry(db, &f.Table, writeAccessReq); err != nil {
+		return err
+	}
+	for _, join := range f.Joins {
+		if err := checkTableEntry(db, &join.Table, writeAccessReq); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkTableEntry(db ds.Database, t *query_parser.TableEntry, writeAccessReq bool) error {
 	var err error
-	f.Table.DBTable, err = db.GetTable(f.Table.Name, writeAccessReq)
+	t.DBTable, err = db.GetTable(t.Name, writeAccessReq)
 	if err != nil {
-		return syncql.NewErrTableCantAccess(db.GetContext(), f.Table.Off, f.Table.Name, err)
+		return syncql.NewErrTableCantAccess(db.GetContext(), t.Off, t.Name, err)
+	}
+	return nil
+}
+
+// Check the on clauses of joins.
+func checkJoinClauses(db ds.Database, joins []*query_parser.JoinClause, ec *query_parser.EscapeClause) error {
+	for _, join := range joins {
+		if err := checkExpression(db, join.On, ec); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -176,9 +374,22 @@ func checkExpression(db ds.Database, e *query_parser.Expression, ec *query_parse
 		}
 		return syncql.NewErrKeyExpressionLiteral(db.GetContext(), off)
 	}
+	// Likewise for the values of in expressions and bounds of between expressions.
+	if IsKey(e.Operand1) && e.Operand2.Type == query_parser.TypList {
+		for _, value := range e.Operand2.List {
+			if isLiteral(value) && !isStringLiteral(value) {
+				return syncql.NewErrKeyExpressionLiteral(db.GetContext(), value.Off)
+			}
+		}
+	}
 
-	// If either operand is a bool, only = and <> operators are allowed.
-	if (e.Operand1.Type == query_parser.TypBool || e.Operand2.Type == query_parser.TypBool) && e.Operator.Type != query_parser.Equal && e.Operator.Type != query_parser.NotEqual {
+	// If either operand is a bool, only = and <> operators (and in) are allowed.
+	if (e.Operand1.Type == query_parser.TypBool || e.Operand2.Type == query_parser.TypBool) && e.Operator.Type != query_parser.Equal && e.Operator.Type != query_parser.NotEqual &&
+		e.Operator.Type != query_parser.In && e.Operator.Type != query_parser.NotIn {
+		return syncql.NewErrBoolInvalidExpression(db.GetContext(), e.Operator.Off)
+	}
+	if (e.Operator.Type == query_parser.Between || e.Operator.Type == query_parser.NotBetween) &&
+		(e.Operand2.List[0].Type == query_parser.TypBool || e.Operand2.List[1].Type == query_parser.TypBool) {
 		return syncql.NewErrBoolInvalidExpression(db.GetContext(), e.Operator.Off)
 	}
 
@@ -189,24 +400,16 @@ func checkOperand(db ds.Database, o *query_parser.Operand, ec *query_parser.Esca
 	switch o.Type {
 	case query_parser.TypExpr:
 		return checkExpression(db, o.Expr, ec)
-	case query_parser.TypField:
-		switch o.Column.Segments[0].Value {
-		case "k":
-			if len(o.Column.Segments) > 1 {
-				return syncql.NewErrDotNotationDisallowedForKey(db.GetContext(), o.Column.Segments[1].Off)
+	case query_parser.TypArithmetic:
+		return checkArithmetic(db, o, ec)
+	case query_parser.TypList:
+		for _, value := range o.List {
+			if err := checkOperand(db, value, ec); err != nil {
+				return err
 			}
-		case "v":
-			// Nothing to do.
-		case "K":
-			// Be nice and warn of mistakenly capped 'K'.
-			return syncql.NewErrDidYouMeanLowercaseK(db.GetContext(), o.Column.Segments[0].Off)
-		case "V":
-			// Be nice and warn of mistakenly capped 'V'.
-			return syncql.NewErrDidYouMeanLowercaseV(db.GetContext(), o.Column.Segments[0].Off)
-		default:
-			return syncql.NewErrBadFieldInWhere(db.GetContext(), o.Column.Segments[0].Off)
 		}
-		return nil
+	case query_parser.TypField:
+		return checkField(db, o.Column, syncql.NewErrBadFieldInWhere)
 	case query_parser.TypFunction:
 		// Each of the functions args needs to be checked first.
 		for _, arg := range o.Function.Args {
@@ -233,6 +436,330 @@ func checkOperand(db ds.Database, o *query_parser.Operand, ec *query_parser.Esca
 	return nil
 }
 
+// Check an arithmetic operand.  Its operands cannot be k or literals other
+// than numbers.  If both operands are numeric literals (including functions
+// computed early), the arithmetic is done now and the operand is replaced
+// with the result.
+func checkArithmetic(db ds.Database, o *query_parser.Operand, ec *query_parser.EscapeClause) error {
+	for _, operand := range []*query_parser.Operand{o.Expr.Operand1, o.Expr.Operand2} {
+		if err := checkOperand(db, operand, ec); err != nil {
+			return err
+		}
+		if IsKey(operand) || operand.Type == query_parser.TypNil || (isLiteral(operand) && !isNumericLiteral(operand)) {
+			return syncql.NewErrInvalidArithmeticOperand(db.GetContext(), operand.Off)
+		}
+	}
+	if isNumericLiteral(o.Expr.Operand1) && isNumericLiteral(o.Expr.Operand2) {
+		result, err := conversions.Arithmetic(o.Expr.Operand1, o.Expr.Operand2, o.Expr.Operator.Type)
+		if err != nil {
+			return syncql.NewErrArithmeticError(db.GetContext(), o.Expr.Operator.Off, err)
+		}
+		result.Off = o.Off
+		*o = *result
+	}
+	return nil
+}
+
+// Check a field.  Fields can be 'k' and v[{.<ident>}...] (following the table
+// name or alias of a qualified field).  invalidField returns the error for
+// other fields.
+func checkField(db ds.Database, f *query_parser.Field, invalidField func(*context.T, int64) error) error {
+	path := f.Path()
+	if len(path) == 0 {
+		return invalidField(db.GetContext(), f.Off)
+	}
+	switch path[0].Value {
+	case "k":
+		if len(path) > 1 {
+			return syncql.NewErrDotNotationDisallowedForKey(db.GetContext(), path[1].Off)
+		}
+	case "v":
+		// Nothing to check.
+	case "K":
+		// Be nice and warn of mistakenly capped 'K'.
+		return syncql.NewErrDidYouMeanLowercaseK(db.GetContext(), path[0].Off)
+	case "V":
+		// Be nice and warn of mistakenly capped 'V'.
+		return syncql.NewErrDidYouMeanLowercaseV(db.GetContext(), path[0].Off)
+	default:
+		return invalidField(db.GetContext(), path[0].Off)
+	}
+	return nil
+}
+
+// Check group by clause.  Keys can be 'k', v[{.<ident>}...] or functions.
+func checkGroupByClause(db ds.Database, g *query_parser.GroupByClause, ec *query_parser.EscapeClause) error {
+	if g == nil {
+		return nil
+	}
+	for _, key := range g.Keys {
+		if err := checkKey(db, key, ec, syncql.NewErrInvalidGroupByKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check having clause.
+func checkHavingClause(db ds.Database, h *query_parser.HavingClause, ec *query_parser.EscapeClause) error {
+	if h == nil {
+		return nil
+	}
+	return checkExpression(db, h.Expr, ec)
+}
+
+// Check order by clause.  Keys can be 'k', v[{.<ident>}...] or functions.
+func checkOrderByClause(db ds.Database, o *query_parser.OrderByClause, ec *query_parser.EscapeClause) error {
+	if o == nil {
+		return nil
+	}
+	for _, key := range o.Keys {
+		if err := checkKey(db, key.Operand, ec, syncql.NewErrInvalidOrderByKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check a group by or order by key.  invalidKey returns the error for keys
+// that are neither fields nor functions.
+func checkKey(db ds.Database, o *query_parser.Operand, ec *query_parser.EscapeClause, invalidKey func(*context.T, int64) error) error {
+	switch o.Type {
+	case query_parser.TypField:
+		if err := checkField(db, o.Column, invalidKey); err != nil {
+			return err
+		}
+	case query_parser.TypFunction:
+		// Note: if the function is computed early, the key is replaced with
+		// its (constant) return value.
+		if err := checkOperand(db, o, ec); err != nil {
+			return err
+		}
+	default:
+		return invalidKey(db.GetContext(), o.Off)
+	}
+	return nil
+}
+
+// Check the use of aggregate functions.  Aggregates cannot be used in the
+// where and group by clauses, nor as arguments of other aggregates.  If the
+// statement is grouped, fields used outside of aggregates in the select,
+// having and order by clauses must be group by keys (as the values of other
+// fields may vary across the rows of a group).
+func checkAggregates(db ds.Database, s *query_parser.SelectStatement) error {
+	if s.Where != nil {
+		if f := findAggregateInExpression(s.Where.Expr); f != nil {
+			return syncql.NewErrAggregateNotAllowed(db.GetContext(), f.Off, f.Name)
+		}
+	}
+	var groupKeys []*query_parser.Operand
+	if s.GroupBy != nil {
+		for _, key := range s.GroupBy.Keys {
+			if f := findAggregate(key); f != nil {
+				return syncql.NewErrAggregateNotAllowed(db.GetContext(), f.Off, f.Name)
+			}
+		}
+		groupKeys = s.GroupBy.Keys
+	}
+	if !IsGrouped(s) {
+		return nil
+	}
+	for _, selector := range s.Select.Selectors {
+		switch selector.Type {
+		case query_parser.TypSelField:
+			o := query_parser.Operand{Type: query_parser.TypField, Column: selector.Field, Node: selector.Field.Node}
+			if err := checkGroupedOperand(db, &o, groupKeys); err != nil {
+				return err
+			}
+		case query_parser.TypSelFunc:
+			o := query_parser.Operand{Type: query_parser.TypFunction, Function: selector.Function, Node: selector.Function.Node}
+			if err := checkGroupedOperand(db, &o, groupKeys); err != nil {
+				return err
+			}
+		}
+	}
+	if s.Having != nil {
+		if err := checkGroupedExpression(db, s.Having.Expr, groupKeys); err != nil {
+			return err
+		}
+	}
+	if s.OrderBy != nil {
+		for _, key := range s.OrderBy.Keys {
+			if err := checkGroupedOperand(db, key.Operand, groupKeys); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkGroupedExpression(db ds.Database, e *query_parser.Expression, groupKeys []*query_parser.Operand) error {
+	if err := checkGroupedOperand(db, e.Operand1, groupKeys); err != nil {
+		return err
+	}
+	return checkGroupedOperand(db, e.Operand2, groupKeys)
+}
+
+func checkGroupedOperand(db ds.Database, o *query_parser.Operand, groupKeys []*query_parser.Operand) error {
+	for _, key := range groupKeys {
+		if sameOperand(o, key) {
+			return nil
+		}
+	}
+	switch o.Type {
+	case query_parser.TypExpr, query_parser.TypArithmetic:
+		return checkGroupedExpression(db, o.Expr, groupKeys)
+	case query_parser.TypList:
+		for _, value := range o.List {
+			if err := checkGroupedOperand(db, value, groupKeys); err != nil {
+				return err
+			}
+		}
+	case query_parser.TypField:
+		return syncql.NewErrFieldNotGrouped(db.GetContext(), o.Off)
+	case query_parser.TypFunction:
+		if query_functions.IsAggregate(o.Function) {
+			for _, arg := range o.Function.Args {
+				if f := findAggregate(arg); f != nil {
+					return syncql.NewErrAggregateNotAllowed(db.GetContext(), f.Off, f.Name)
+				}
+			}
+			return nil
+		}
+		for _, arg := range o.Function.Args {
+			if err := checkGroupedOperand(db, arg, groupKeys); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// IsGrouped returns true if the rows selected by the statement are grouped,
+// that is, if the statement has a group by or having clause or if it uses
+// aggregate functions.  Without a group by clause, all of the selected rows
+// are in a single group.
+func IsGrouped(s *query_parser.SelectStatement) bool {
+	return s.GroupBy != nil || s.Having != nil || len(AggregateFunctions(s)) > 0
+}
+
+// AggregateFunctions returns the aggregate functions used in the select,
+// having and order by clauses of the statement.
+func AggregateFunctions(s *query_parser.SelectStatement) []*query_parser.Function {
+	var fs []*query_parser.Function
+	for _, selector := range s.Select.Selectors {
+		if selector.Type == query_parser.TypSelFunc {
+			fs = appendAggregates(fs, selector.Function)
+		}
+	}
+	if s.Having != nil {
+		fs = appendAggregatesInExpression(fs, s.Having.Expr)
+	}
+	if s.OrderBy != nil {
+		for _, key := range s.OrderBy.Keys {
+			fs = appendAggregatesInOperand(fs, key.Operand)
+		}
+	}
+	return fs
+}
+
+func appendAggregates(fs []*query_parser.Function, f *query_parser.Function) []*query_parser.Function {
+	if query_functions.IsAggregate(f) {
+		// Aggregates cannot be nested.
+		return append(fs, f)
+	}
+	for _, arg := range f.Args {
+		fs = appendAggregatesInOperand(fs, arg)
+	}
+	return fs
+}
+
+func appendAggregatesInExpression(fs []*query_parser.Function, e *query_parser.Expression) []*query_parser.Function {
+	fs = appendAggregatesInOperand(fs, e.Operand1)
+	return appendAggregatesInOperand(fs, e.Operand2)
+}
+
+func appendAggregatesInOperand(fs []*query_parser.Function, o *query_parser.Operand) []*query_parser.Function {
+	switch o.Type {
+	case query_parser.TypExpr, query_parser.TypArithmetic:
+		return appendAggregatesInExpression(fs, o.Expr)
+	case query_parser.TypList:
+		for _, value := range o.List {
+			fs = appendAggregatesInOperand(fs, value)
+		}
+	case query_parser.TypFunction:
+		return appendAggregates(fs, o.Function)
+	}
+	return fs
+}
+
+func findAggregateInExpression(e *query_parser.Expression) *query_parser.Function {
+	if fs := appendAggregatesInExpression(nil, e); len(fs) > 0 {
+		return fs[0]
+	}
+	return nil
+}
+
+func findAggregate(o *query_parser.Operand) *query_parser.Function {
+	if fs := appendAggregatesInOperand(nil, o); len(fs) > 0 {
+		return fs[0]
+	}
+	return nil
+}
+
+// sameOperand returns true if the operands are equal but for their offsets.
+func sameOperand(lhs, rhs *query_parser.Operand) bool {
+	if lhs.Type != rhs.Type {
+		return false
+	}
+	switch lhs.Type {
+	case query_parser.TypExpr, query_parser.TypArithmetic:
+		return lhs.Expr.Operator.Type == rhs.Expr.Operator.Type &&
+			sameOperand(lhs.Expr.Operand1, rhs.Expr.Operand1) &&
+			sameOperand(lhs.Expr.Operand2, rhs.Expr.Operand2)
+	case query_parser.TypList:
+		if len(lhs.List) != len(rhs.List) {
+			return false
+		}
+		for i := range lhs.List {
+			if !sameOperand(lhs.List[i], rhs.List[i]) {
+				return false
+			}
+		}
+		return true
+	case query_parser.TypField:
+		if len(lhs.Column.Segments) != len(rhs.Column.Segments) {
+			return false
+		}
+		for i, seg := range lhs.Column.Segments {
+			rseg := rhs.Column.Segments[i]
+			if seg.Value != rseg.Value || len(seg.Keys) != len(rseg.Keys) {
+				return false
+			}
+			for j := range seg.Keys {
+				if !sameOperand(seg.Keys[j], rseg.Keys[j]) {
+					return false
+				}
+			}
+		}
+		return true
+	case query_parser.TypFunction:
+		if lhs.Function.Name != rhs.Function.Name || len(lhs.Function.Args) != len(rhs.Function.Args) {
+			return false
+		}
+		for i := range lhs.Function.Args {
+			if !sameOperand(lhs.Function.Args[i], rhs.Function.Args[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		l, r := *lhs, *rhs
+		l.Off, r.Off = 0, 0
+		return reflect.DeepEqual(l, r)
+	}
+}
+
 func parseLikePattern(db ds.Database, off int64, s string, ec *query_parser.EscapeClause) (*pattern.Pattern, error) {
 	escChar := '\x00' // nul is ignored as an escape char
 	if ec != nil {
@@ -265,20 +792,45 @@ func ContainsFieldOperand(f *query_parser.Field, expr *query_parser.Expression)
 	return IsExactField(f, expr.Operand1) || IsExactField(f, expr.Operand2)
 }
 
+// ContainsFunctionOperand returns true if either operand is, or is an
+// arithmetic expression or list containing, a function.
 func ContainsFunctionOperand(expr *query_parser.Expression) bool {
-	return IsFunction(expr.Operand1) || IsFunction(expr.Operand2)
+	return containsOperand(expr.Operand1, IsFunction) || containsOperand(expr.Operand2, IsFunction)
 }
 
+// ContainsValueFieldOperand returns true if either operand is, or is an
+// arithmetic expression or list containing, a value field.
 func ContainsValueFieldOperand(expr *query_parser.Expression) bool {
-	return (expr.Operand1.Type == query_parser.TypField && IsValueField(expr.Operand1.Column)) ||
-		(expr.Operand2.Type == query_parser.TypField && IsValueField(expr.Operand2.Column))
+	isValueField := func(o *query_parser.Operand) bool {
+		return o.Type == query_parser.TypField && IsValueField(o.Column)
+	}
+	return containsOperand(expr.Operand1, isValueField) || containsOperand(expr.Operand2, isValueField)
+}
 
+func containsOperand(o *query_parser.Operand, match func(*query_parser.Operand) bool) bool {
+	switch o.Type {
+	case query_parser.TypArithmetic:
+		return containsOperand(o.Expr.Operand1, match) || containsOperand(o.Expr.Operand2, match)
+	case query_parser.TypList:
+		for _, value := range o.List {
+			if containsOperand(value, match) {
+				return true
+			}
+		}
+		return false
+	default:
+		return match(o)
+	}
 }
 
 func isStringLiteral(o *query_parser.Operand) bool {
 	return o.Type == query_parser.TypStr
 }
 
+func isNumericLiteral(o *query_parser.Operand) bool {
+	return isLiteral(o) && conversions.IsNumeric(o)
+}
+
 func isLiteral(o *query_parser.Operand) bool {
 	return o.Type == query_parser.TypBigInt ||
 		o.Type == query_parser.TypBigRat || // currently, no way to specify as literal
@@ -311,8 +863,10 @@ func IsExactField(f *query_parser.Field, o *query_parser.Operand) bool {
 	return true
 }
 
+// IsKeyField returns true if f is the key of the row (qualified fields, which
+// are keys of the rows of joined tables, are not).
 func IsKeyField(f *query_parser.Field) bool {
-	return f.Segments[0].Value == "k"
+	return !f.Qualified && f.Segments[0].Value == "k"
 }
 
 func IsValueField(f *query_parser.Field) bool {
@@ -442,7 +996,7 @@ func collectStringFieldRanges(idxField *query_parser.Field, expr *query_parser.E
 			return lhsStringFieldRanges
 		}
 	} else if ContainsFieldOperand(idxField, expr) { // true if either operand is idxField
-		if IsField(expr.Operand1) && IsField(expr.Operand2) {
+		if IsExactField(idxField, expr.Operand1) && IsExactField(idxField, expr.Operand2) {
 			//<idx_field> <op> <idx_field>
 			switch expr.Operator.Type {
 			case query_parser.Equal, query_parser.GreaterThanOrEqual, query_parser.LessThanOrEqual:
@@ -462,6 +1016,9 @@ func collectStringFieldRanges(idxField *query_parser.Field, expr *query_parser.E
 			// k is not nil
 			// True for all all values of indexField.
 			return &ds.StringFieldRanges{StringFieldRangeAll}
+		} else if expr.Operand2.Type == query_parser.TypList {
+			// indexField [not] in (<values>) or indexField [not] between <lower> and <upper>
+			return collectStringFieldRangesForList(expr.Operator.Type, expr.Operand2.List)
 		} else if isStringLiteral(expr.Operand2) {
 			// indexField <op> <string-literal>
 			switch expr.Operator.Type {
@@ -516,6 +1073,46 @@ func collectStringFieldRanges(idxField *query_parser.Field, expr *query_parser.E
 	}
 }
 
+// collectStringFieldRangesForList returns the ranges of an index field (or k)
+// for which <field> <op> <list> may be true, where op is [not] in or [not]
+// between.  Unless all of the values of an in expression or the bounds of a
+// between expression are string literals, the entire range is returned.
+func collectStringFieldRangesForList(op query_parser.BinaryOperatorType, list []*query_parser.Operand) *ds.StringFieldRanges {
+	for _, value := range list {
+		if !isStringLiteral(value) {
+			return &ds.StringFieldRanges{StringFieldRangeAll}
+		}
+	}
+	switch op {
+	case query_parser.In:
+		var fieldRanges ds.StringFieldRanges
+		for _, value := range list {
+			addStringFieldRange(computeStringFieldRangeForSingleValue(value.Str), &fieldRanges)
+		}
+		return &fieldRanges
+	case query_parser.Between:
+		lower, upper := list[0].Str, list[1].Str
+		if lower > upper {
+			// False for all values of indexField
+			return &ds.StringFieldRanges{}
+		}
+		return &ds.StringFieldRanges{ds.StringFieldRange{Start: lower, Limit: string(append([]byte(upper), 0))}}
+	case query_parser.NotBetween:
+		lower, upper := list[0].Str, list[1].Str
+		if lower > upper {
+			// True for all values of indexField
+			return &ds.StringFieldRanges{StringFieldRangeAll}
+		}
+		return &ds.StringFieldRanges{
+			ds.StringFieldRange{Start: "", Limit: lower},
+			ds.StringFieldRange{Start: string(append([]byte(upper), 0)), Limit: MaxRangeLimit},
+		}
+	default: // case query_parser.NotIn:
+		// As the values may be sparse, just allow the full range.
+		return &ds.StringFieldRanges{StringFieldRangeAll}
+	}
+}
+
 func determineIfNilAllowed(idxField *query_parser.Field, expr *query_parser.Expression) bool {
 	if IsExpr(expr.Operand1) { // then both operands must be expressions
 		lhsNilAllowed := determineIfNilAllowed(idxField, expr.Operand1.Expr)
@@ -8,7 +8,9 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
 	"testing"
+	"time"
 
 	"v.io/v23"
 	"v.io/v23/context"
@@ -19,6 +21,7 @@ import (
 	"v.io/v23/query/syncql"
 	"v.io/v23/vdl"
 	"v.io/v23/verror"
+	"v.io/v23/vom"
 	_ "v.io/x/ref/runtime/factories/roaming"
 	"v.io/x/ref/test"
 )
@@ -55,6 +58,10 @@ func (t invoiceTable) Delete(k string) (bool, error) {
 	return false, errors.New("unimplemented")
 }
 
+func (t invoiceTable) Put(k string, v *vom.RawBytes) error {
+	return errors.New("unimplemented")
+}
+
 func (t customerTable) GetIndexFields() []ds.Index {
 	return []ds.Index{}
 }
@@ -67,6 +74,10 @@ func (t customerTable) Delete(k string) (bool, error) {
 	return false, errors.New("unimplemented")
 }
 
+func (t customerTable) Put(k string, v *vom.RawBytes) error {
+	return errors.New("unimplemented")
+}
+
 func (db *mockDB) GetTable(table string, writeAccessReq bool) (ds.Table, error) {
 	if table == "Customer" {
 		var t customerTable
@@ -113,6 +124,30 @@ func TestQueryChecker(t *testing.T) {
 		{"select k, v.name from Customer"},
 		{"select k, v.name from Customer limit 200"},
 		{"select k, v.name from Customer offset 100"},
+		{"select k, v.name from Customer order by v.name"},
+		{"select k, v.name from Customer order by v.name desc, k asc limit 10 offset 5"},
+		{"select k from Customer where k like \"a%\" order by Len(v.name) desc"},
+		{"select Count(k) from Customer"},
+		{"select Count(k), Sum(v.A), Avg(v.A), Min(v.B), Max(v.B), CountDistinct(v.C) from Customer where k > \"a\""},
+		{"select v.A, Count(k) from Customer group by v.A"},
+		{"select v.A, Len(v.B), Count(k) from Customer group by v.A, Len(v.B)"},
+		{"select v.A, Count(k) from Customer group by v.A having Count(k) > 1 and v.A <> \"x\""},
+		{"select Count(k) from Customer having Max(v.A) > 10"},
+		{"select Uppercase(v.A), Count(k) from Customer group by v.A order by Count(k) desc, v.A limit 10"},
+		{"select k, Str(Count(v.A)) from Customer group by k"},
+		{"select k from Customer where v.Status in (\"a\", \"b\") and v.Age between 18 and 65"},
+		{"select k from Customer where v.Price * v.Qty > 100 and -v.A % 3 <> 1.5 / 2 and k not in (\"a\", v.B)"},
+		{"select v.A, Count(k) from Customer group by v.A having Sum(v.B) * 2 not between 10 and 20"},
+		{"select a.k, b.v.Amount from Customer a join Invoice b on b.v.CustId = a.v.Id"},
+		{"explain select k from Customer where k like \"001%\" limit 10"},
+		{"explain delete from Customer where Type(v) like \"%.Invoice\""},
+		{"update Customer set v.A = 10, v.B = v.A where k = \"001\" limit 5"},
+		{"update Invoice set v.Items[0].Price = v.Items[1].Price where v.Amount > 10"},
+		{"insert into Customer (k, v) values (\"001\", 10), (\"002\", Lowercase(\"ABC\"))"},
+		{"insert into Customer (v, k) values (true, Uppercase(\"a\"))"},
+		{"select a.k, b.k from Customer a left outer join Invoice b on b.k = a.k and b.v.Amount > 10 where a.k like \"00%\" order by b.v.Amount desc"},
+		{"select a.v.Name, Count(b.k) from Customer a inner join Invoice b on b.v.CustId = a.v.Id group by a.v.Name having Count(b.k) > 1"},
+		{"select Type(b.v) from Customer a join Customer b on b.k = Lowercase(a.k) join Invoice on Invoice.k = b.k"},
 		{"select k, v.name from Customer where k = \"foo\""},
 		{"select v.z from Customer where k = v.y"},
 		{"select v.z from Customer where k <> v.y"},
@@ -298,6 +333,72 @@ func TestKeyRanges(t *testing.T) {
 				},
 			},
 		},
+		{
+			"select k, v from Customer where k in (\"def\", \"abc\", \"abc\")",
+			&ds.IndexRanges{
+				FieldName:  "k",
+				Kind:       vdl.String,
+				NilAllowed: false,
+				StringRanges: &ds.StringFieldRanges{
+					ds.StringFieldRange{Start: "abc", Limit: appendZeroByte("abc")},
+					ds.StringFieldRange{Start: "def", Limit: appendZeroByte("def")},
+				},
+			},
+		},
+		{
+			"select k, v from Customer where k in (\"abc\", v.A)",
+			&ds.IndexRanges{
+				FieldName:  "k",
+				Kind:       vdl.String,
+				NilAllowed: false,
+				StringRanges: &ds.StringFieldRanges{
+					ds.StringFieldRange{Start: "", Limit: ""},
+				},
+			},
+		},
+		{
+			"select k, v from Customer where k not in (\"abc\")",
+			&ds.IndexRanges{
+				FieldName:  "k",
+				Kind:       vdl.String,
+				NilAllowed: false,
+				StringRanges: &ds.StringFieldRanges{
+					ds.StringFieldRange{Start: "", Limit: ""},
+				},
+			},
+		},
+		{
+			"select k, v from Customer where k between \"foo\" and \"goo\"",
+			&ds.IndexRanges{
+				FieldName:  "k",
+				Kind:       vdl.String,
+				NilAllowed: false,
+				StringRanges: &ds.StringFieldRanges{
+					ds.StringFieldRange{Start: "foo", Limit: appendZeroByte("goo")},
+				},
+			},
+		},
+		{
+			"select k, v from Customer where k between \"goo\" and \"foo\"",
+			&ds.IndexRanges{
+				FieldName:    "k",
+				Kind:         vdl.String,
+				NilAllowed:   false,
+				StringRanges: &ds.StringFieldRanges{},
+			},
+		},
+		{
+			"select k, v from Customer where k not between \"foo\" and \"goo\" and k in (\"abc\", \"foo\", \"goo\", \"hoo\")",
+			&ds.IndexRanges{
+				FieldName:  "k",
+				Kind:       vdl.String,
+				NilAllowed: false,
+				StringRanges: &ds.StringFieldRanges{
+					ds.StringFieldRange{Start: "abc", Limit: appendZeroByte("abc")},
+					ds.StringFieldRange{Start: "hoo", Limit: appendZeroByte("hoo")},
+				},
+			},
+		},
 		{
 			"select k, v from Customer where k >= \"foo\" and k < \"goo\"",
 			&ds.IndexRanges{
@@ -728,6 +829,18 @@ func TestKeyRanges(t *testing.T) {
 				},
 			},
 		},
+		{
+			// The key is compared with a field; the value must be fetched.
+			"select k from Customer where k <> v.Name",
+			&ds.IndexRanges{
+				FieldName:  "k",
+				Kind:       vdl.String,
+				NilAllowed: false,
+				StringRanges: &ds.StringFieldRanges{
+					ds.StringFieldRange{Start: "", Limit: ""},
+				},
+			},
+		},
 		{
 			"delete from Customer where k not like \"002%\"",
 			&ds.IndexRanges{
@@ -813,6 +926,21 @@ func TestIndexRanges(t *testing.T) {
 				},
 			},
 		},
+		{
+			"select k, v from Customer where v.InterfaceName in (\"Foo\", \"Bar\")",
+			[]string{"v.InterfaceName"},
+			[]*ds.IndexRanges{
+				&ds.IndexRanges{
+					FieldName:  "v.InterfaceName",
+					Kind:       vdl.String,
+					NilAllowed: false,
+					StringRanges: &ds.StringFieldRanges{
+						ds.StringFieldRange{Start: "Bar", Limit: appendZeroByte("Bar")},
+						ds.StringFieldRange{Start: "Foo", Limit: appendZeroByte("Foo")},
+					},
+				},
+			},
+		},
 		{
 			"select k, v from Customer where v.InterfaceName = \"Foo\" or v.InterfaceName = \"Bar\"",
 			[]string{"v.InterfaceName"},
@@ -1707,10 +1835,60 @@ func TestQueryCheckerErrors(t *testing.T) {
 		{"select k from Customer where t.a = \"Foo.Bar\"", syncql.NewErrBadFieldInWhere(db.GetContext(), 29)},
 		{"select v from Customer where a=1", syncql.NewErrBadFieldInWhere(db.GetContext(), 29)},
 		{"select v from Customer limit 0", syncql.NewErrLimitMustBeGt0(db.GetContext(), 29)},
+		{"select k, v from Customer union select k from Invoice", syncql.NewErrUnionColumnCount(db.GetContext(), 32, 2, 1)},
+		{"select k from Customer union all select k from Invoice union select k, v from Invoice", syncql.NewErrUnionColumnCount(db.GetContext(), 61, 1, 2)},
+		{"select k from Customer union select a from Invoice", syncql.NewErrInvalidSelectField(db.GetContext(), 36)},
+		{"select k from Customer union select k from Invoice limit 0", syncql.NewErrLimitMustBeGt0(db.GetContext(), 57)},
+		{"select k from Customer union select k from Invoice order by k limit 1", syncql.NewErrUnionOrderBy(db.GetContext(), 51)},
+		{"select k from Customer order by k union all select k from Invoice", syncql.NewErrUnionOrderBy(db.GetContext(), 23)},
+		{"select v from Customer order by a", syncql.NewErrInvalidOrderByKey(db.GetContext(), 32)},
+		{"select v from Customer order by 10", syncql.NewErrInvalidOrderByKey(db.GetContext(), 32)},
+		{"select v from Customer order by k.a", syncql.NewErrDotNotationDisallowedForKey(db.GetContext(), 34)},
+		{"select v from Customer order by K", syncql.NewErrDidYouMeanLowercaseK(db.GetContext(), 32)},
+		{"select v from Customer order by v.A, Foo(v.B)", syncql.NewErrFunctionNotFound(db.GetContext(), 37, "Foo")},
+		{"select v from Customer group by 10", syncql.NewErrInvalidGroupByKey(db.GetContext(), 32)},
+		{"select v from Customer group by a", syncql.NewErrInvalidGroupByKey(db.GetContext(), 32)},
+		{"select Count(v) from Customer group by v.A, k.a", syncql.NewErrDotNotationDisallowedForKey(db.GetContext(), 46)},
+		{"select count(k) from Customer", syncql.NewErrDidYouMeanFunction(db.GetContext(), 7, "Count")},
+		{"select Count(k, v) from Customer", syncql.NewErrFunctionArgCount(db.GetContext(), 7, "Count", 1, 2)},
+		{"select Sum(\"abc\") from Customer", syncql.NewErrAggregateArgNotNumeric(db.GetContext(), 11, "Sum")},
+		{"select Avg(\"1.5\") from Customer", syncql.NewErrAggregateArgNotNumeric(db.GetContext(), 11, "Avg")},
+		{"select Count(k) from Customer having Sum(Lowercase(k)) > 1", syncql.NewErrAggregateArgNotNumeric(db.GetContext(), 41, "Sum")},
+		{"select k from Customer where Count(k) > 1", syncql.NewErrAggregateNotAllowed(db.GetContext(), 29, "Count")},
+		{"select Count(k) from Customer group by Len(Max(v.A))", syncql.NewErrAggregateNotAllowed(db.GetContext(), 43, "Max")},
+		{"select Max(Count(k)) from Customer", syncql.NewErrAggregateNotAllowed(db.GetContext(), 11, "Count")},
+		{"select k, Count(k) from Customer", syncql.NewErrFieldNotGrouped(db.GetContext(), 7)},
+		{"select v.A, v.B from Customer group by v.A", syncql.NewErrFieldNotGrouped(db.GetContext(), 12)},
+		{"select v.A from Customer group by v.A having v.B > 1", syncql.NewErrFieldNotGrouped(db.GetContext(), 45)},
+		{"select v.A from Customer group by v.A order by Len(v.B)", syncql.NewErrFieldNotGrouped(db.GetContext(), 51)},
+		{"select v.A[1] from Customer group by v.A[0]", syncql.NewErrFieldNotGrouped(db.GetContext(), 7)},
+		{"explain select v from Bob", syncql.NewErrTableCantAccess(db.GetContext(), 22, "Bob", errors.New("No such table: Bob"))},
+		{"explain delete from Customer where x = 1", syncql.NewErrBadFieldInWhere(db.GetContext(), 35)},
+		{"select a.k from Customer a join Bob b on a.k = b.k", syncql.NewErrTableCantAccess(db.GetContext(), 32, "Bob", errors.New("No such table: Bob"))},
+		{"select a from Customer a join Invoice b on a.k = b.k", syncql.NewErrInvalidSelectField(db.GetContext(), 7)},
+		{"select a.x from Customer a join Invoice b on a.k = b.k", syncql.NewErrInvalidSelectField(db.GetContext(), 9)},
+		{"select a.k from Customer a join Invoice b on a.k.x = b.k", syncql.NewErrDotNotationDisallowedForKey(db.GetContext(), 49)},
+		{"select a.k from Customer a join Invoice b on a.K = b.k", syncql.NewErrDidYouMeanLowercaseK(db.GetContext(), 47)},
+		{"select a.k from Customer a join Invoice b on b.x = a.k", syncql.NewErrBadFieldInWhere(db.GetContext(), 47)},
+		{"select a.k from Customer a join Invoice b on a.k = b.k where a.V.Name = \"x\"", syncql.NewErrDidYouMeanLowercaseV(db.GetContext(), 63)},
+		{"select a.k from Customer a join Invoice b on a.k = b.k order by b.y", syncql.NewErrInvalidOrderByKey(db.GetContext(), 66)},
+		{"select Type(a.k) from Customer a join Invoice b on a.k = b.k", syncql.NewErrArgMustBeField(db.GetContext(), 12)},
 		{"select v.z from Customer where v.x like v.y", syncql.NewErrLikeExpressionsRequireRhsString(db.GetContext(), 40)},
 		{"select v.z from Customer where k like \"a^bc%\" escape '^'", syncql.NewErrInvalidLikePattern(db.GetContext(), 38, pattern.NewErrInvalidEscape(nil, "b"))},
+		{"select v from Customer where RegexpMatch(v.Name, \"a(b\") = true", syncql.NewErrInvalidRegularExpression(db.GetContext(), 49, regexpError("a(b"))},
+		{"select v from Customer where RegexpExtract(v.Name, StrCat(\"[\", \"a\")) = \"\"", syncql.NewErrInvalidRegularExpression(db.GetContext(), 51, regexpError("[a"))},
+		{"select RegexpReplace(v.Name, \"*\", \"x\") from Customer", syncql.NewErrInvalidRegularExpression(db.GetContext(), 29, regexpError("*"))},
+		{"select v from Customer where DateAdd(v.InvoiceDate, \"1 day\") > Now()", syncql.NewErrDurationConversionError(db.GetContext(), 52, durationError("1 day"))},
+		{"select DateTrunc(v.InvoiceDate, \"year\", \"UTC\") from Customer", syncql.NewErrInvalidTimeUnit(db.GetContext(), 32, "year")},
 		{"select v from Customer where v.A > false", syncql.NewErrBoolInvalidExpression(db.GetContext(), 33)},
 		{"select v from Customer where true <= v.A", syncql.NewErrBoolInvalidExpression(db.GetContext(), 34)},
+		{"select v from Customer where v.A between false and true", syncql.NewErrBoolInvalidExpression(db.GetContext(), 33)},
+		{"select v from Customer where k in (\"a\", 1)", syncql.NewErrKeyExpressionLiteral(db.GetContext(), 40)},
+		{"select v from Customer where k between \"a\" and 9", syncql.NewErrKeyExpressionLiteral(db.GetContext(), 47)},
+		{"select v from Customer where v.A + \"x\" > 1", syncql.NewErrInvalidArithmeticOperand(db.GetContext(), 35)},
+		{"select v from Customer where k * 2 > 1", syncql.NewErrInvalidArithmeticOperand(db.GetContext(), 29)},
+		{"select v from Customer where v.A in (1, true - 1)", syncql.NewErrInvalidArithmeticOperand(db.GetContext(), 40)},
+		{"select v from Customer where v.A > 1 / 0", syncql.NewErrArithmeticError(db.GetContext(), 37, errors.New("Division by zero."))},
 		{"select v from Customer where Foo(\"2015/07/22\", true, 3.14157) = true", syncql.NewErrFunctionNotFound(db.GetContext(), 29, "Foo")},
 		{"select v from Customer where nil is v.ZipCode", syncql.NewErrIsIsNotRequireLhsValue(db.GetContext(), 29)},
 		{"select v from Customer where v.ZipCode is \"94303\"", syncql.NewErrIsIsNotRequireRhsNil(db.GetContext(), 42)},
@@ -1739,6 +1917,26 @@ func TestQueryCheckerErrors(t *testing.T) {
 		{"select k from Customer where K = \"001\"", syncql.NewErrDidYouMeanLowercaseK(db.GetContext(), 29)},
 		{"select v from Customer where Type(V) = \"Invoice\"", syncql.NewErrDidYouMeanLowercaseV(db.GetContext(), 34)},
 		{"select K, V from Customer where Type(V) = \"Invoice\" and K = \"001\"", syncql.NewErrDidYouMeanLowercaseK(db.GetContext(), 7)},
+		// Update
+		{"update Bob set v.A = 1", syncql.NewErrTableCantAccess(db.GetContext(), 7, "Bob", errors.New("No such table: Bob"))},
+		{"update Customer set k = \"a\"", syncql.NewErrInvalidSetField(db.GetContext(), 20)},
+		{"update Customer set x.A = 1", syncql.NewErrInvalidSetField(db.GetContext(), 20)},
+		{"update Customer set V.A = 1", syncql.NewErrDidYouMeanLowercaseV(db.GetContext(), 20)},
+		{"update Customer set v.A = a", syncql.NewErrBadFieldInWhere(db.GetContext(), 26)},
+		{"update Customer set v.A = Count(k)", syncql.NewErrAggregateNotAllowed(db.GetContext(), 26, "Count")},
+		{"update Customer set v.A = 1 where Sum(v.B) > 1", syncql.NewErrAggregateNotAllowed(db.GetContext(), 34, "Sum")},
+		{"update Customer set v.A = 1 limit 0", syncql.NewErrLimitMustBeGt0(db.GetContext(), 34)},
+		// Insert
+		{"insert into Bob (k, v) values (\"a\", 1)", syncql.NewErrTableCantAccess(db.GetContext(), 12, "Bob", errors.New("No such table: Bob"))},
+		{"insert into Customer (k) values (\"a\")", syncql.NewErrInvalidInsertColumns(db.GetContext(), 22)},
+		{"insert into Customer (k, k) values (\"a\", \"b\")", syncql.NewErrInvalidInsertColumns(db.GetContext(), 25)},
+		{"insert into Customer (k, v.A) values (\"a\", 1)", syncql.NewErrInvalidInsertColumns(db.GetContext(), 25)},
+		{"insert into Customer (k, v, v) values (\"a\", 1, 1)", syncql.NewErrInvalidInsertColumns(db.GetContext(), 28)},
+		{"insert into Customer (k, v) values (\"a\")", syncql.NewErrInsertValueCount(db.GetContext(), 35, 2, 1)},
+		{"insert into Customer (k, v) values (\"a\", v.A)", syncql.NewErrInvalidInsertValue(db.GetContext(), 41)},
+		{"insert into Customer (k, v) values (\"a\", Len(k))", syncql.NewErrInvalidInsertValue(db.GetContext(), 45)},
+		{"insert into Customer (k, v) values (1, 2)", syncql.NewErrInsertKeyNotString(db.GetContext(), 36)},
+		{"insert into Customer (k, v) values (Len(\"abc\"), 2)", syncql.NewErrInsertKeyNotString(db.GetContext(), 36)},
 		// Delete
 		{"delete from Bob", syncql.NewErrTableCantAccess(db.GetContext(), 12, "Bob", errors.New("No such table: Bob"))},
 		{"delete from Customer where k.a = \"a\"", syncql.NewErrDotNotationDisallowedForKey(db.GetContext(), 29)},
@@ -1794,3 +1992,13 @@ func TestQueryCheckerErrors(t *testing.T) {
 		}
 	}
 }
+
+func regexpError(re string) error {
+	_, err := regexp.Compile(re)
+	return err
+}
+
+func durationError(d string) error {
+	_, err := time.ParseDuration(d)
+	return err
+}
@@ -0,0 +1,264 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query_functions
+
+import (
+	"fmt"
+	"math/big"
+
+	ds "v.io/v23/query/engine/datasource"
+	"v.io/v23/query/engine/internal/conversions"
+	"v.io/v23/query/engine/internal/query_parser"
+	"v.io/v23/query/syncql"
+)
+
+// An Accumulator computes the value of an aggregate function over the rows
+// of a group, one row at a time.
+type Accumulator interface {
+	// Add adds the value of the aggregate function's argument for a row.
+	// Nil (i.e., unresolved) values are ignored.
+	Add(arg *query_parser.Operand) error
+	// Result returns the value of the aggregate function over the rows
+	// added so far.  The result is nil if it is undefined (e.g., the Sum of
+	// no values).
+	Result() *query_parser.Operand
+}
+
+// IsAggregate returns true if f is an aggregate function (e.g., Count or Sum).
+func IsAggregate(f *query_parser.Function) bool {
+	_, ok := aggregates[f.Name]
+	return ok
+}
+
+// NewAccumulator returns an Accumulator to compute the aggregate function f.
+func NewAccumulator(db ds.Database, f *query_parser.Function) (Accumulator, error) {
+	entry, ok := aggregates[f.Name]
+	if !ok {
+		return nil, syncql.NewErrFunctionNotFound(db.GetContext(), f.Off, f.Name)
+	}
+	return entry.newAccumulator(db, f.Off), nil
+}
+
+// Aggregate functions take exactly one arg.  Unlike other functions, they are
+// never executed at check time, even if the arg is a literal.
+func checkAggregate(db ds.Database, f *query_parser.Function, entry *aggregate) error {
+	f.ArgTypes = []query_parser.OperandType{entry.argType}
+	f.RetType = entry.returnType
+	if len(f.Args) != 1 {
+		return syncql.NewErrFunctionArgCount(db.GetContext(), f.Off, f.Name, 1, int64(len(f.Args)))
+	}
+	if entry.argType == query_parser.TypFloat {
+		// The args of numeric aggregates are not converted (e.g., Sum("1.5")
+		// is an error when the rows are read), so reject an arg that is
+		// known not to be numeric now.
+		arg := f.Args[0]
+		argType := arg.Type
+		if argType == query_parser.TypFunction {
+			if arg.Function.Computed {
+				argType = arg.Function.RetValue.Type
+			} else {
+				argType = arg.Function.RetType
+			}
+		}
+		switch argType {
+		case query_parser.TypBool, query_parser.TypStr, query_parser.TypTime:
+			return syncql.NewErrAggregateArgNotNumeric(db.GetContext(), arg.Off, f.Name)
+		}
+	}
+	return checkArg(db, f.Off, entry.argType, f.Args[0])
+}
+
+func newCount(db ds.Database, off int64) Accumulator {
+	return &countAccumulator{off: off}
+}
+
+func newCountDistinct(db ds.Database, off int64) Accumulator {
+	return &countDistinctAccumulator{off: off, seen: make(map[string]bool)}
+}
+
+func newSum(db ds.Database, off int64) Accumulator {
+	return &sumAccumulator{db: db, off: off, name: "Sum"}
+}
+
+func newAvg(db ds.Database, off int64) Accumulator {
+	return &avgAccumulator{sumAccumulator{db: db, off: off, name: "Avg"}, 0}
+}
+
+func newMin(db ds.Database, off int64) Accumulator {
+	return &minMaxAccumulator{sign: -1}
+}
+
+func newMax(db ds.Database, off int64) Accumulator {
+	return &minMaxAccumulator{sign: 1}
+}
+
+// Count returns the number of non-nil values.
+type countAccumulator struct {
+	off   int64
+	count int64
+}
+
+func (a *countAccumulator) Add(arg *query_parser.Operand) error {
+	if arg != nil {
+		a.count++
+	}
+	return nil
+}
+
+func (a *countAccumulator) Result() *query_parser.Operand {
+	return makeIntOp(a.off, a.count)
+}
+
+// CountDistinct returns the number of distinct non-nil values.
+type countDistinctAccumulator struct {
+	off  int64
+	seen map[string]bool
+}
+
+func (a *countDistinctAccumulator) Add(arg *query_parser.Operand) error {
+	if arg != nil {
+		a.seen[distinctKey(arg)] = true
+	}
+	return nil
+}
+
+func (a *countDistinctAccumulator) Result() *query_parser.Operand {
+	return makeIntOp(a.off, int64(len(a.seen)))
+}
+
+// distinctKey returns a string that is equal for equal operands of the same type.
+func distinctKey(o *query_parser.Operand) string {
+	switch o.Type {
+	case query_parser.TypBigInt:
+		return fmt.Sprintf("%d:%v", o.Type, o.BigInt)
+	case query_parser.TypBigRat:
+		return fmt.Sprintf("%d:%v", o.Type, o.BigRat)
+	case query_parser.TypBool:
+		return fmt.Sprintf("%d:%v", o.Type, o.Bool)
+	case query_parser.TypFloat:
+		return fmt.Sprintf("%d:%v", o.Type, o.Float)
+	case query_parser.TypInt:
+		return fmt.Sprintf("%d:%v", o.Type, o.Int)
+	case query_parser.TypStr:
+		return fmt.Sprintf("%d:%s", o.Type, o.Str)
+	case query_parser.TypTime:
+		return fmt.Sprintf("%d:%d", o.Type, o.Time.UnixNano())
+	case query_parser.TypUint:
+		return fmt.Sprintf("%d:%v", o.Type, o.Uint)
+	default:
+		return fmt.Sprintf("%d:%v", o.Type, o.Object)
+	}
+}
+
+// Sum returns the sum of the values, which must be numeric.  The type of the
+// sum is the type that all of the values can be coerced to.
+type sumAccumulator struct {
+	db   ds.Database
+	off  int64
+	name string // of the function, for errors
+	sum  *query_parser.Operand
+}
+
+func (a *sumAccumulator) Add(arg *query_parser.Operand) error {
+	if arg == nil {
+		return nil
+	}
+	switch arg.Type {
+	case query_parser.TypBigInt, query_parser.TypBigRat, query_parser.TypFloat, query_parser.TypInt, query_parser.TypUint:
+	default:
+		return syncql.NewErrAggregateArgNotNumeric(a.db.GetContext(), arg.Off, a.name)
+	}
+	if a.sum == nil {
+		sum := *arg
+		sum.Off = a.off
+		a.sum = &sum
+		return nil
+	}
+	sum, val, err := conversions.CoerceValues(a.sum, arg)
+	if err != nil {
+		return syncql.NewErrFloatConversionError(a.db.GetContext(), arg.Off, err)
+	}
+	var c query_parser.Operand
+	c.Off = a.off
+	c.Type = sum.Type
+	switch sum.Type {
+	case query_parser.TypBigInt:
+		var b big.Int
+		c.BigInt = b.Add(sum.BigInt, val.BigInt)
+	case query_parser.TypBigRat:
+		var b big.Rat
+		c.BigRat = b.Add(sum.BigRat, val.BigRat)
+	case query_parser.TypFloat:
+		c.Float = sum.Float + val.Float
+	case query_parser.TypInt:
+		c.Int = sum.Int + val.Int
+	case query_parser.TypUint:
+		c.Uint = sum.Uint + val.Uint
+	}
+	a.sum = &c
+	return nil
+}
+
+func (a *sumAccumulator) Result() *query_parser.Operand {
+	return a.sum
+}
+
+// Avg returns the mean of the values, which must be numeric, as a float.
+type avgAccumulator struct {
+	sumAccumulator
+	count int64
+}
+
+func (a *avgAccumulator) Add(arg *query_parser.Operand) error {
+	if arg == nil {
+		return nil
+	}
+	if err := a.sumAccumulator.Add(arg); err != nil {
+		return err
+	}
+	a.count++
+	return nil
+}
+
+func (a *avgAccumulator) Result() *query_parser.Operand {
+	if a.count == 0 {
+		return nil
+	}
+	sum := a.sum
+	if sum.Type == query_parser.TypBigInt || sum.Type == query_parser.TypBigRat {
+		r, err := conversions.ConvertValueToBigRat(sum)
+		if err != nil {
+			return nil
+		}
+		f, _ := r.BigRat.Float64()
+		return makeFloatOp(a.off, f/float64(a.count))
+	}
+	f, err := conversions.ConvertValueToFloat(sum)
+	if err != nil {
+		return nil
+	}
+	return makeFloatOp(a.off, f.Float/float64(a.count))
+}
+
+// Min and Max return the least and greatest values, respectively, as ordered
+// by an order by clause.
+type minMaxAccumulator struct {
+	sign int // -1 for Min, 1 for Max
+	cur  *query_parser.Operand
+}
+
+func (a *minMaxAccumulator) Add(arg *query_parser.Operand) error {
+	if arg == nil {
+		return nil
+	}
+	if a.cur == nil || conversions.CompareOperands(arg, a.cur)*a.sign > 0 {
+		a.cur = arg
+	}
+	return nil
+}
+
+func (a *minMaxAccumulator) Result() *query_parser.Operand {
+	return a.cur
+}
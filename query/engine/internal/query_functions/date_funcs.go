@@ -7,6 +7,7 @@ package query_functions
 // TODO(jkline): Probably rename this file to time_functions.go
 
 import (
+	"strings"
 	"time"
 
 	ds "v.io/v23/query/engine/datasource"
@@ -79,13 +80,18 @@ func now(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser
 	return makeTimeOp(off, time.Now()), nil
 }
 func timeInLocation(db ds.Database, off int64, args []*query_parser.Operand) (time.Time, error) {
+	return timeArgInLocation(args[0], args[1])
+}
+
+// timeArgInLocation converts timeArg to a time in the location named by locArg.
+func timeArgInLocation(timeArg, locArg *query_parser.Operand) (time.Time, error) {
 	var timeOp *query_parser.Operand
 	var locOp *query_parser.Operand
 	var err error
-	if timeOp, err = conversions.ConvertValueToTime(args[0]); err != nil {
+	if timeOp, err = conversions.ConvertValueToTime(timeArg); err != nil {
 		return time.Time{}, err
 	}
-	if locOp, err = conversions.ConvertValueToString(args[1]); err != nil {
+	if locOp, err = conversions.ConvertValueToString(locArg); err != nil {
 		return time.Time{}, err
 	}
 	var loc *time.Location
@@ -193,3 +199,218 @@ func secondArgLocationCheck(db ds.Database, off int64, args []*query_parser.Oper
 	}
 	return nil
 }
+
+// DateAdd(v.InvoiceDate, "24h")
+// DateAdd returns the time plus the duration.  The duration is either a string
+// in the syntax of golang's time.ParseDuration (e.g., "1h30m", "-90s") or an
+// integer number of nanoseconds (e.g., the result of DateDiff).
+func dateAdd(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	timeOp, err := conversions.ConvertValueToTime(args[0])
+	if err != nil {
+		return nil, err
+	}
+	d, err := durationArg(db, args[1])
+	if err != nil {
+		return nil, err
+	}
+	return makeTimeOp(off, timeOp.Time.Add(d)), nil
+}
+
+// DateSub(v.InvoiceDate, "24h")
+// DateSub returns the time minus the duration (see DateAdd).
+func dateSub(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	timeOp, err := conversions.ConvertValueToTime(args[0])
+	if err != nil {
+		return nil, err
+	}
+	d, err := durationArg(db, args[1])
+	if err != nil {
+		return nil, err
+	}
+	return makeTimeOp(off, timeOp.Time.Add(-d)), nil
+}
+
+// DateDiff(v.ShipDate, v.InvoiceDate)
+// DateDiff returns the duration from the second time to the first time in
+// nanoseconds.
+// e.g., DateDiff(v.ShipDate, v.InvoiceDate) / 1000000000 returns the number of
+// seconds from invoice to shipment.
+func dateDiff(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	t1, err := conversions.ConvertValueToTime(args[0])
+	if err != nil {
+		return nil, err
+	}
+	t2, err := conversions.ConvertValueToTime(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return makeIntOp(off, int64(t1.Time.Sub(t2.Time))), nil
+}
+
+// DateTrunc(v.InvoiceDate, "day", "America/Los_Angeles")
+// DateTrunc returns the start of the hour, day, week (beginning on Monday) or month
+// of the time in the location.
+func dateTrunc(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	tim, err := timeArgInLocation(args[0], args[2])
+	if err != nil {
+		return nil, err
+	}
+	unit, err := timeUnitArg(db, args[1])
+	if err != nil {
+		return nil, err
+	}
+	y, m, d := tim.Date()
+	switch unit {
+	case "hour":
+		tim = time.Date(y, m, d, tim.Hour(), 0, 0, 0, tim.Location())
+	case "day":
+		tim = time.Date(y, m, d, 0, 0, 0, 0, tim.Location())
+	case "week":
+		daysSinceMonday := (int(tim.Weekday()) + 6) % 7
+		tim = time.Date(y, m, d-daysSinceMonday, 0, 0, 0, 0, tim.Location())
+	case "month":
+		tim = time.Date(y, m, 1, 0, 0, 0, 0, tim.Location())
+	}
+	return makeTimeOp(off, tim), nil
+}
+
+// FormatTime(v.InvoiceDate, "2006-01-02 15:04 MST", "America/Los_Angeles")
+// FormatTime returns the time in the location formatted with golang's time.Format
+// layout.
+func formatTime(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	tim, err := timeArgInLocation(args[0], args[2])
+	if err != nil {
+		return nil, err
+	}
+	layoutOp, err := conversions.ConvertValueToString(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return makeStrOp(off, tim.Format(layoutOp.Str)), nil
+}
+
+// ParseDuration("1h30m")
+// ParseDuration returns the duration in nanoseconds.
+func parseDuration(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	d, err := durationArg(db, args[0])
+	if err != nil {
+		return nil, err
+	}
+	return makeIntOp(off, int64(d)), nil
+}
+
+// Unix(v.InvoiceDate)
+// Unix returns the time as the number of seconds elapsed since January 1, 1970 UTC.
+func unix(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	timeOp, err := conversions.ConvertValueToTime(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return makeIntOp(off, timeOp.Time.Unix()), nil
+}
+
+// UnixNano(v.InvoiceDate)
+// UnixNano returns the time as the number of nanoseconds elapsed since
+// January 1, 1970 UTC.
+func unixNano(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	timeOp, err := conversions.ConvertValueToTime(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return makeIntOp(off, timeOp.Time.UnixNano()), nil
+}
+
+// TimeFromUnix(v.Timestamp)
+// TimeFromUnix returns the time (in UTC) that is the number of seconds elapsed since
+// January 1, 1970 UTC.
+func timeFromUnix(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	secOp, err := conversions.ConvertValueToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return makeTimeOp(off, time.Unix(secOp.Int, 0).UTC()), nil
+}
+
+// TimeFromUnixNano(v.Timestamp)
+// TimeFromUnixNano returns the time (in UTC) that is the number of nanoseconds
+// elapsed since January 1, 1970 UTC.
+func timeFromUnixNano(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	nsecOp, err := conversions.ConvertValueToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return makeTimeOp(off, time.Unix(0, nsecOp.Int).UTC()), nil
+}
+
+// durationArg converts arg to a duration.  Strings are parsed with
+// time.ParseDuration; numbers are nanoseconds.
+func durationArg(db ds.Database, arg *query_parser.Operand) (time.Duration, error) {
+	if arg.Type == query_parser.TypStr {
+		d, err := time.ParseDuration(arg.Str)
+		if err != nil {
+			return 0, syncql.NewErrDurationConversionError(db.GetContext(), arg.Off, err)
+		}
+		return d, nil
+	}
+	nsecOp, err := conversions.ConvertValueToInt(arg)
+	if err != nil {
+		return 0, syncql.NewErrDurationConversionError(db.GetContext(), arg.Off, err)
+	}
+	return time.Duration(nsecOp.Int), nil
+}
+
+// timeUnitArg converts arg to a (lowercase) unit for DateTrunc.
+func timeUnitArg(db ds.Database, arg *query_parser.Operand) (string, error) {
+	unitOp, err := conversions.ConvertValueToString(arg)
+	if err != nil {
+		return "", err
+	}
+	unit := strings.ToLower(unitOp.Str)
+	switch unit {
+	case "hour", "day", "week", "month":
+		return unit, nil
+	}
+	return "", syncql.NewErrInvalidTimeUnit(db.GetContext(), arg.Off, unitOp.Str)
+}
+
+// knownArgValue returns the value of arg if it is known at check time (i.e.,
+// arg is a literal or an already computed function), else nil.
+func knownArgValue(arg *query_parser.Operand) *query_parser.Operand {
+	switch arg.Type {
+	case query_parser.TypBigInt, query_parser.TypBigRat, query_parser.TypBool, query_parser.TypFloat, query_parser.TypInt, query_parser.TypStr, query_parser.TypTime, query_parser.TypUint:
+		return arg
+	case query_parser.TypFunction:
+		if arg.Function.Computed {
+			return arg.Function.RetValue
+		}
+	}
+	return nil
+}
+
+func secondArgDurationCheck(db ds.Database, off int64, args []*query_parser.Operand) error {
+	// At this point, for the args that can be evaluated before execution, it is known that
+	// there are two args, a time followed by a duration.
+	// Just need to check that the 2nd arg is convertible to a duration.
+	if arg := knownArgValue(args[1]); arg != nil {
+		if _, err := durationArg(db, arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func thirdArgLocationCheck(db ds.Database, off int64, args []*query_parser.Operand) error {
+	// Just need to check that the 3rd arg is convertible to a location.
+	return checkIfPossibleThatArgIsConvertibleToLocation(db, args[2])
+}
+
+func dateTruncArgsCheck(db ds.Database, off int64, args []*query_parser.Operand) error {
+	// Check that the 2nd arg is a valid unit and that the 3rd arg is convertible
+	// to a location.
+	if arg := knownArgValue(args[1]); arg != nil {
+		if _, err := timeUnitArg(db, arg); err != nil {
+			return err
+		}
+	}
+	return thirdArgLocationCheck(db, off, args)
+}
@@ -48,4 +48,18 @@
 //               is called at checker time rather than this function.
 //               DO NOT sepecify a checkArgsAddr if all that is to be checked is the number
 //               and types of args. These checks are standard.
+//
+// Aggregate functions (e.g., Count, Sum) are listed in the aggregates map in
+// query_functions.go.  They take a single argument and are never executed at
+// check time.  Instead, the query package creates an Accumulator (see
+// agg_funcs.go) for each aggregate function and group of rows, adds the value
+// of the argument for each row of the group and then substitutes the result
+// for the function.
+//
+// User-defined functions (see public.Function) are registered with a
+// query engine (QueryEngine.RegisterFunction) and held in a UserFunctions
+// registry, which CheckFunction and ExecFunction find by way of the database
+// they are passed.  Their args are checked and converted like those of
+// the built-in functions, but, as they are passed the key and value of the
+// row, they are never executed at check time.
 package query_functions
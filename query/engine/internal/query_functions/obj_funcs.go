@@ -5,7 +5,12 @@
 package query_functions
 
 import (
+	"sort"
+	"strconv"
+	"strings"
+
 	ds "v.io/v23/query/engine/datasource"
+	"v.io/v23/query/engine/internal/conversions"
 	"v.io/v23/query/engine/internal/query_parser"
 	"v.io/v23/query/syncql"
 	"v.io/v23/vdl"
@@ -33,3 +38,316 @@ func lenFunc(db ds.Database, off int64, args []*query_parser.Operand) (*query_pa
 	}
 	return nil, syncql.NewErrFunctionLenInvalidArg(db.GetContext(), args[0].Off)
 }
+
+// Contains(container, x) bool
+// Contains returns true if x is an element of the array, list or set container or a
+// key of the map container.  Elements and keys are compared with x as in a where
+// clause (e.g., 1 is equal to 1.0).  If container is a string, Contains returns true
+// if x is a substring of container.
+// e.g., Contains(v.PhoneNumbers, "555-1212")
+// e.g., Contains("abcdef", "cd") returns true
+func contains(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	if args[0].Type == query_parser.TypStr {
+		x, err := conversions.ConvertValueToString(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return makeBoolOp(off, strings.Contains(args[0].Str, x.Str)), nil
+	}
+	var elems []*vdl.Value
+	if args[0].Type == query_parser.TypObject {
+		switch container := autoDereference(args[0].Object); container.Kind() {
+		case vdl.Array, vdl.List:
+			for i := 0; i < container.Len(); i++ {
+				elems = append(elems, container.Index(i))
+			}
+		case vdl.Map, vdl.Set:
+			elems = container.Keys()
+		default:
+			return nil, syncql.NewErrFunctionInvalidArg(db.GetContext(), args[0].Off, "Contains", "array, list, set, map or string")
+		}
+	} else {
+		return nil, syncql.NewErrFunctionInvalidArg(db.GetContext(), args[0].Off, "Contains", "array, list, set, map or string")
+	}
+	for _, elem := range elems {
+		if op, err := valueToOperand(elem, off); err == nil && operandsEqual(op, args[1]) {
+			return makeBoolOp(off, true), nil
+		}
+	}
+	return makeBoolOp(off, false), nil
+}
+
+// Keys(container) list
+// Keys returns a list of the keys of the map or set container, in ascending order.
+// e.g., Keys(v.Ratings)
+func keys(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	container, err := mapOrSetArg(db, "Keys", args[0], true)
+	if err != nil {
+		return nil, err
+	}
+	sortedKeys := sortKeys(container.Keys())
+	return makeListOp(off, container.Type().Key(), sortedKeys), nil
+}
+
+// Values(m map) list
+// Values returns a list of the values of map m, in the order of their keys.
+// e.g., Values(v.Ratings)
+func values(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	m, err := mapOrSetArg(db, "Values", args[0], false)
+	if err != nil {
+		return nil, err
+	}
+	sortedKeys := sortKeys(m.Keys())
+	elems := make([]*vdl.Value, len(sortedKeys))
+	for i, key := range sortedKeys {
+		elems[i] = m.MapIndex(key)
+	}
+	return makeListOp(off, m.Type().Elem(), elems), nil
+}
+
+// Index(container, i) any
+// Index returns the ith (zero based) element of the array or list container or
+// the value of key i in the map container.  Map keys are compared with i as in a
+// where clause (e.g., 1 is equal to 1.0).  If there is no such element, nil is
+// returned.
+// e.g., Index(Split("a.b.c", "."), 1) returns "b"
+// e.g., Index(v.Ratings, "Hotel")
+func index(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	if args[0].Type != query_parser.TypObject {
+		return nil, syncql.NewErrFunctionInvalidArg(db.GetContext(), args[0].Off, "Index", "array, list or map")
+	}
+	switch container := autoDereference(args[0].Object); container.Kind() {
+	case vdl.Array, vdl.List:
+		i, err := conversions.ConvertValueToInt(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if i.Int < 0 || i.Int >= int64(container.Len()) {
+			return makeNilOp(off), nil
+		}
+		return valueToOperand(container.Index(int(i.Int)), off)
+	case vdl.Map:
+		for _, key := range container.Keys() {
+			if op, err := valueToOperand(key, off); err == nil && operandsEqual(op, args[1]) {
+				return valueToOperand(container.MapIndex(key), off)
+			}
+		}
+		return makeNilOp(off), nil
+	}
+	return nil, syncql.NewErrFunctionInvalidArg(db.GetContext(), args[0].Off, "Index", "array, list or map")
+}
+
+// JsonPath(x, path string) any
+// JsonPath returns the part of x selected by path, written in the dot and
+// bracket notation of JSONPath: an optional leading $ (which stands for x),
+// followed by steps of the form .Name, selecting the struct field or union
+// field Name or the value of map key "Name", [i], selecting the ith (zero
+// based) element of an array or list or the value of map key i, and ["key"],
+// selecting the value of map key "key".  Map keys are compared as in Index.
+// Any and optional values are dereferenced at each step.  If there is no such
+// part of x, nil is returned.  The path "$" returns x.
+// e.g., JsonPath(v, "$.Address.City")
+// e.g., JsonPath(v.Invoices, "[0].Items[1].Description")
+// e.g., JsonPath(v, "$.Ratings[\"Hotel\"]")
+func jsonPath(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	steps, err := jsonPathArg(db, args[1])
+	if err != nil {
+		return nil, err
+	}
+	if args[0].Type != query_parser.TypObject {
+		if len(steps) == 0 {
+			return args[0], nil
+		}
+		return makeNilOp(off), nil
+	}
+	v := args[0].Object
+	for _, step := range steps {
+		if v = jsonPathStep(autoDereference(v), step); v == nil {
+			return makeNilOp(off), nil
+		}
+	}
+	return valueToOperand(v, off)
+}
+
+// jsonPathStep returns the part of v selected by step, or nil if there is
+// none.
+func jsonPathStep(v *vdl.Value, step *query_parser.Operand) *vdl.Value {
+	switch v.Kind() {
+	case vdl.Struct:
+		if step.Type == query_parser.TypStr {
+			return v.StructFieldByName(step.Str)
+		}
+	case vdl.Union:
+		if idx, field := v.UnionField(); step.Type == query_parser.TypStr && v.Type().Field(idx).Name == step.Str {
+			return field
+		}
+	case vdl.Array, vdl.List:
+		if step.Type == query_parser.TypInt && step.Int < int64(v.Len()) {
+			return v.Index(int(step.Int))
+		}
+	case vdl.Map:
+		for _, key := range v.Keys() {
+			if op, err := valueToOperand(key, step.Off); err == nil && operandsEqual(op, step) {
+				return v.MapIndex(key)
+			}
+		}
+	}
+	return nil
+}
+
+// jsonPathArg parses the path in arg into its steps: names and quoted keys
+// become TypStr operands and indexes become TypInt operands.
+func jsonPathArg(db ds.Database, arg *query_parser.Operand) ([]*query_parser.Operand, error) {
+	pathOp, err := conversions.ConvertValueToString(arg)
+	if err != nil {
+		return nil, err
+	}
+	path := strings.TrimPrefix(pathOp.Str, "$")
+	if path != "" && path[0] != '.' && path[0] != '[' {
+		path = "." + path
+	}
+	var steps []*query_parser.Operand
+	for path != "" {
+		var step *query_parser.Operand
+		switch {
+		case path[0] == '.':
+			end := strings.IndexAny(path[1:], ".[") + 1
+			if end == 0 {
+				end = len(path)
+			}
+			if end > 1 {
+				step = makeStrOp(arg.Off, path[1:end])
+				path = path[end:]
+			}
+		case strings.HasPrefix(path, `["`):
+			if end := strings.Index(path[2:], `"]`) + 2; end > 1 {
+				step = makeStrOp(arg.Off, path[2:end])
+				path = path[end+2:]
+			}
+		case path[0] == '[':
+			if end := strings.IndexByte(path, ']'); end > 0 {
+				if i, err := strconv.ParseUint(path[1:end], 10, 31); err == nil {
+					step = makeIntOp(arg.Off, int64(i))
+					path = path[end+1:]
+				}
+			}
+		}
+		if step == nil {
+			return nil, syncql.NewErrInvalidJsonPath(db.GetContext(), arg.Off, pathOp.Str)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func secondArgJsonPathCheck(db ds.Database, off int64, args []*query_parser.Operand) error {
+	// At this point, it is known that the 2nd arg is convertible to a string
+	// if it is a literal (or an already computed function).  Parse it now to
+	// check for errors.  Other args can't be checked until execution.
+	arg := args[1]
+	if arg.Type == query_parser.TypFunction && arg.Function.Computed {
+		arg = arg.Function.RetValue
+	}
+	if arg.Type != query_parser.TypStr {
+		return nil
+	}
+	_, err := jsonPathArg(db, arg)
+	return err
+}
+
+// Coalesce(x, ...) any
+// Coalesce returns the first of its args that is not nil (i.e., that can be
+// resolved).  If all of the args are nil, nil is returned.
+// e.g., Coalesce(v.Nickname, v.Name, "anonymous")
+func coalesce(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	for _, arg := range args {
+		if arg.Type != query_parser.TypNil {
+			return arg, nil
+		}
+	}
+	return makeNilOp(off), nil
+}
+
+// IfNull(x, y) any
+// IfNull returns x if it is not nil (i.e., if it can be resolved), else y.
+// e.g., IfNull(v.Discount, 0)
+func ifNull(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	return coalesce(db, off, args)
+}
+
+// mapOrSetArg returns the map (or, if setAllowed, set) value of arg.
+func mapOrSetArg(db ds.Database, funcName string, arg *query_parser.Operand, setAllowed bool) (*vdl.Value, error) {
+	expected := "map"
+	if setAllowed {
+		expected = "map or set"
+	}
+	if arg.Type != query_parser.TypObject {
+		return nil, syncql.NewErrFunctionInvalidArg(db.GetContext(), arg.Off, funcName, expected)
+	}
+	container := autoDereference(arg.Object)
+	if container.Kind() != vdl.Map && (!setAllowed || container.Kind() != vdl.Set) {
+		return nil, syncql.NewErrFunctionInvalidArg(db.GetContext(), arg.Off, funcName, expected)
+	}
+	return container, nil
+}
+
+// valueToOperand converts a vdl value (e.g., an element of a list) to an operand.
+// Any and optional values are dereferenced; nil values are converted to TypNil.
+func valueToOperand(v *vdl.Value, off int64) (*query_parser.Operand, error) {
+	v = autoDereference(v)
+	if v.IsNil() {
+		return makeNilOp(off), nil
+	}
+	return query_parser.ConvertValueToAnOperand(v, off)
+}
+
+// operandsEqual returns true if lhs and rhs are equal when coerced to a
+// common type.
+func operandsEqual(lhs, rhs *query_parser.Operand) bool {
+	l, r, err := conversions.CoerceValues(lhs, rhs)
+	if err != nil {
+		return false
+	}
+	if l.Type == query_parser.TypObject {
+		return vdl.EqualValue(l.Object, r.Object)
+	}
+	return conversions.CompareOperands(l, r) == 0
+}
+
+// sortKeys sorts map or set keys by their (coerced) values.
+func sortKeys(keys []*vdl.Value) []*vdl.Value {
+	ops := make([]*query_parser.Operand, len(keys))
+	for i, key := range keys {
+		ops[i], _ = valueToOperand(key, 0)
+	}
+	sort.Stable(keySorter{keys, ops})
+	return keys
+}
+
+type keySorter struct {
+	keys []*vdl.Value
+	ops  []*query_parser.Operand
+}
+
+func (s keySorter) Len() int {
+	return len(s.keys)
+}
+
+func (s keySorter) Less(i, j int) bool {
+	return conversions.CompareOperands(s.ops[i], s.ops[j]) < 0
+}
+
+func (s keySorter) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.ops[i], s.ops[j] = s.ops[j], s.ops[i]
+}
+
+func autoDereference(v *vdl.Value) *vdl.Value {
+	for v.Kind() == vdl.Any || v.Kind() == vdl.Optional {
+		if v.IsNil() {
+			return v
+		}
+		v = v.Elem()
+	}
+	return v
+}
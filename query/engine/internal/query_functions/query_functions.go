@@ -11,6 +11,7 @@ import (
 	"v.io/v23/query/engine/internal/conversions"
 	"v.io/v23/query/engine/internal/query_parser"
 	"v.io/v23/query/syncql"
+	"v.io/v23/vdl"
 	"v.io/v23/vom"
 )
 
@@ -26,9 +27,22 @@ type function struct {
 	checkArgsAddr checkArgsFunc
 }
 
+type newAccumulatorFunc func(ds.Database, int64) Accumulator
+
+type aggregate struct {
+	argType        query_parser.OperandType // TypNil allows any.
+	returnType     query_parser.OperandType // TypNil if the type depends on the arg.
+	newAccumulator newAccumulatorFunc
+}
+
 var functions map[string]function
+var aggregates map[string]aggregate
 var lowercaseFunctions map[string]string // map of lowercase(funcName)->funcName
 
+// Functions which are passed nil (TypNil) for args that cannot be resolved.
+// Other functions fail (and so return nil) if an arg cannot be resolved.
+var nilArgFunctions = map[string]bool{"Coalesce": true, "IfNull": true}
+
 func init() {
 	functions = make(map[string]function)
 
@@ -44,6 +58,16 @@ func init() {
 	functions["Nanosecond"] = function{[]query_parser.OperandType{query_parser.TypTime, query_parser.TypStr}, false, query_parser.TypNil, query_parser.TypInt, nanosecond, secondArgLocationCheck}
 	functions["Weekday"] = function{[]query_parser.OperandType{query_parser.TypTime, query_parser.TypStr}, false, query_parser.TypNil, query_parser.TypInt, weekday, secondArgLocationCheck}
 	functions["YearDay"] = function{[]query_parser.OperandType{query_parser.TypTime, query_parser.TypStr}, false, query_parser.TypNil, query_parser.TypInt, yearDay, secondArgLocationCheck}
+	functions["DateAdd"] = function{[]query_parser.OperandType{query_parser.TypTime, query_parser.TypNil}, false, query_parser.TypNil, query_parser.TypTime, dateAdd, secondArgDurationCheck}
+	functions["DateSub"] = function{[]query_parser.OperandType{query_parser.TypTime, query_parser.TypNil}, false, query_parser.TypNil, query_parser.TypTime, dateSub, secondArgDurationCheck}
+	functions["DateDiff"] = function{[]query_parser.OperandType{query_parser.TypTime, query_parser.TypTime}, false, query_parser.TypNil, query_parser.TypInt, dateDiff, nil}
+	functions["DateTrunc"] = function{[]query_parser.OperandType{query_parser.TypTime, query_parser.TypStr, query_parser.TypStr}, false, query_parser.TypNil, query_parser.TypTime, dateTrunc, dateTruncArgsCheck}
+	functions["FormatTime"] = function{[]query_parser.OperandType{query_parser.TypTime, query_parser.TypStr, query_parser.TypStr}, false, query_parser.TypNil, query_parser.TypStr, formatTime, thirdArgLocationCheck}
+	functions["ParseDuration"] = function{[]query_parser.OperandType{query_parser.TypStr}, false, query_parser.TypNil, query_parser.TypInt, parseDuration, nil}
+	functions["Unix"] = function{[]query_parser.OperandType{query_parser.TypTime}, false, query_parser.TypNil, query_parser.TypInt, unix, nil}
+	functions["UnixNano"] = function{[]query_parser.OperandType{query_parser.TypTime}, false, query_parser.TypNil, query_parser.TypInt, unixNano, nil}
+	functions["TimeFromUnix"] = function{[]query_parser.OperandType{query_parser.TypInt}, false, query_parser.TypNil, query_parser.TypTime, timeFromUnix, nil}
+	functions["TimeFromUnixNano"] = function{[]query_parser.OperandType{query_parser.TypInt}, false, query_parser.TypNil, query_parser.TypTime, timeFromUnixNano, nil}
 
 	// String Functions
 	functions["Atoi"] = function{[]query_parser.OperandType{query_parser.TypStr}, false, query_parser.TypNil, query_parser.TypInt, atoi, nil}
@@ -61,6 +85,9 @@ func init() {
 	functions["StrIndex"] = function{[]query_parser.OperandType{query_parser.TypStr, query_parser.TypStr}, false, query_parser.TypNil, query_parser.TypInt, strIndex, nil}
 	functions["StrRepeat"] = function{[]query_parser.OperandType{query_parser.TypStr, query_parser.TypInt}, false, query_parser.TypNil, query_parser.TypStr, strRepeat, nil}
 	functions["StrReplace"] = function{[]query_parser.OperandType{query_parser.TypStr, query_parser.TypStr, query_parser.TypStr}, false, query_parser.TypNil, query_parser.TypStr, strReplace, nil}
+	functions["RegexpMatch"] = function{[]query_parser.OperandType{query_parser.TypStr, query_parser.TypStr}, false, query_parser.TypNil, query_parser.TypBool, regexpMatch, secondArgRegexpCheck}
+	functions["RegexpExtract"] = function{[]query_parser.OperandType{query_parser.TypStr, query_parser.TypStr}, false, query_parser.TypNil, query_parser.TypStr, regexpExtract, secondArgRegexpCheck}
+	functions["RegexpReplace"] = function{[]query_parser.OperandType{query_parser.TypStr, query_parser.TypStr, query_parser.TypStr}, false, query_parser.TypNil, query_parser.TypStr, regexpReplace, secondArgRegexpCheck}
 	functions["StrLastIndex"] = function{[]query_parser.OperandType{query_parser.TypStr, query_parser.TypStr}, false, query_parser.TypNil, query_parser.TypInt, strLastIndex, nil}
 	functions["Trim"] = function{[]query_parser.OperandType{query_parser.TypStr}, false, query_parser.TypNil, query_parser.TypStr, trim, nil}
 	functions["TrimLeft"] = function{[]query_parser.OperandType{query_parser.TypStr}, false, query_parser.TypNil, query_parser.TypStr, trimLeft, nil}
@@ -84,11 +111,34 @@ func init() {
 	// TODO(jkline): Make len work with more types.
 	functions["Len"] = function{[]query_parser.OperandType{query_parser.TypObject}, false, query_parser.TypNil, query_parser.TypInt, lenFunc, nil}
 
+	// List, Map and Set Functions
+	functions["Contains"] = function{[]query_parser.OperandType{query_parser.TypObject, query_parser.TypNil}, false, query_parser.TypNil, query_parser.TypBool, contains, nil}
+	functions["Keys"] = function{[]query_parser.OperandType{query_parser.TypObject}, false, query_parser.TypNil, query_parser.TypObject, keys, nil}
+	functions["Values"] = function{[]query_parser.OperandType{query_parser.TypObject}, false, query_parser.TypNil, query_parser.TypObject, values, nil}
+	functions["Index"] = function{[]query_parser.OperandType{query_parser.TypObject, query_parser.TypNil}, false, query_parser.TypNil, query_parser.TypNil, index, nil}
+	functions["JsonPath"] = function{[]query_parser.OperandType{query_parser.TypObject, query_parser.TypStr}, false, query_parser.TypNil, query_parser.TypNil, jsonPath, secondArgJsonPathCheck}
+
+	// Nil Functions (see nilArgFunctions)
+	functions["Coalesce"] = function{[]query_parser.OperandType{query_parser.TypNil}, true, query_parser.TypNil, query_parser.TypNil, coalesce, nil}
+	functions["IfNull"] = function{[]query_parser.OperandType{query_parser.TypNil, query_parser.TypNil}, false, query_parser.TypNil, query_parser.TypNil, ifNull, nil}
+
+	// Aggregate Functions
+	aggregates = make(map[string]aggregate)
+	aggregates["Avg"] = aggregate{query_parser.TypFloat, query_parser.TypFloat, newAvg}
+	aggregates["Count"] = aggregate{query_parser.TypNil, query_parser.TypInt, newCount}
+	aggregates["CountDistinct"] = aggregate{query_parser.TypNil, query_parser.TypInt, newCountDistinct}
+	aggregates["Max"] = aggregate{query_parser.TypNil, query_parser.TypNil, newMax}
+	aggregates["Min"] = aggregate{query_parser.TypNil, query_parser.TypNil, newMin}
+	aggregates["Sum"] = aggregate{query_parser.TypFloat, query_parser.TypNil, newSum}
+
 	// Build lowercaseFuncName->funcName
 	lowercaseFunctions = make(map[string]string)
 	for f := range functions {
 		lowercaseFunctions[strings.ToLower(f)] = f
 	}
+	for f := range aggregates {
+		lowercaseFunctions[strings.ToLower(f)] = f
+	}
 }
 
 // Check that function exists and that the number of args passed matches the spec.
@@ -98,16 +148,19 @@ func init() {
 // early).  CheckFunction will fill in arg types, return types and may fill in
 // Computed and RetValue.
 func CheckFunction(db ds.Database, f *query_parser.Function) error {
+	if entry, ok := aggregates[f.Name]; ok {
+		return checkAggregate(db, f, &entry)
+	}
+	if u := lookupUserFunction(db, f); u != nil {
+		return checkUserFunction(db, f, u)
+	}
 	if entry, err := lookupFuncName(db, f); err != nil {
 		return err
 	} else {
 		f.ArgTypes = entry.argTypes
 		f.RetType = entry.returnType
-		if !entry.hasVarArgs && len(f.Args) != len(entry.argTypes) {
-			return syncql.NewErrFunctionArgCount(db.GetContext(), f.Off, f.Name, int64(len(f.ArgTypes)), int64(len(f.Args)))
-		}
-		if entry.hasVarArgs && len(f.Args) < len(entry.argTypes) {
-			return syncql.NewErrFunctionAtLeastArgCount(db.GetContext(), f.Off, f.Name, int64(len(f.ArgTypes)), int64(len(f.Args)))
+		if err := checkArgCount(db, f, entry); err != nil {
+			return err
 		}
 		// Standard check for types of fixed and var args
 		if err = argsStandardCheck(db, f.Off, entry, f.Args); err != nil {
@@ -134,7 +187,7 @@ func CheckFunction(db ds.Database, f *query_parser.Function) error {
 		// If all of the functions args are literals or already computed functions,
 		// execute this function now and save the result.
 		if okToExecuteNow {
-			op, err := ExecFunction(db, f, f.Args)
+			op, err := ExecFunction(db, "", nil, f, f.Args)
 			if err != nil {
 				return err
 			}
@@ -149,21 +202,45 @@ func CheckFunction(db ds.Database, f *query_parser.Function) error {
 	}
 }
 
+// Check the number of args passed against the function's spec.
+func checkArgCount(db ds.Database, f *query_parser.Function, entry *function) error {
+	if !entry.hasVarArgs && len(f.Args) != len(entry.argTypes) {
+		return syncql.NewErrFunctionArgCount(db.GetContext(), f.Off, f.Name, int64(len(entry.argTypes)), int64(len(f.Args)))
+	}
+	if entry.hasVarArgs && len(f.Args) < len(entry.argTypes) {
+		return syncql.NewErrFunctionAtLeastArgCount(db.GetContext(), f.Off, f.Name, int64(len(entry.argTypes)), int64(len(f.Args)))
+	}
+	return nil
+}
+
 func lookupFuncName(db ds.Database, f *query_parser.Function) (*function, error) {
 	if entry, ok := functions[f.Name]; !ok {
 		// No such function, is the case wrong?
-		if correctCase, ok := lowercaseFunctions[strings.ToLower(f.Name)]; !ok {
-			return nil, syncql.NewErrFunctionNotFound(db.GetContext(), f.Off, f.Name)
-		} else {
+		if correctCase, ok := lowercaseFunctions[strings.ToLower(f.Name)]; ok {
 			// the case is wrong
 			return nil, syncql.NewErrDidYouMeanFunction(db.GetContext(), f.Off, correctCase)
 		}
+		if uf := userFunctionsOf(db); uf != nil {
+			if correctCase, ok := uf.correctCase(f.Name); ok {
+				return nil, syncql.NewErrDidYouMeanFunction(db.GetContext(), f.Off, correctCase)
+			}
+		}
+		return nil, syncql.NewErrFunctionNotFound(db.GetContext(), f.Off, f.Name)
 	} else {
 		return &entry, nil
 	}
 }
 
+// NilArgsAllowed returns true if f is to be passed nil (TypNil) for args
+// that cannot be resolved.
+func NilArgsAllowed(f *query_parser.Function) bool {
+	return nilArgFunctions[f.Name]
+}
+
 func FuncCheck(db ds.Database, f *query_parser.Function, args []*query_parser.Operand) error {
+	if u := lookupUserFunction(db, f); u != nil {
+		return checkUserFunctionArgs(db, f, u, args)
+	}
 	if entry, err := lookupFuncName(db, f); err != nil {
 		return err
 	} else {
@@ -176,7 +253,12 @@ func FuncCheck(db ds.Database, f *query_parser.Function, args []*query_parser.Op
 	return nil
 }
 
-func ExecFunction(db ds.Database, f *query_parser.Function, args []*query_parser.Operand) (*query_parser.Operand, error) {
+// ExecFunction executes function f with the resolved args.  k and v are the
+// key and value of the row, which are passed to user-defined functions.
+func ExecFunction(db ds.Database, k string, v *vdl.Value, f *query_parser.Function, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	if u := lookupUserFunction(db, f); u != nil {
+		return execUserFunction(db, k, v, f, u, args)
+	}
 	if entry, err := lookupFuncName(db, f); err != nil {
 		return nil, err
 	} else {
@@ -248,6 +330,26 @@ func makeFloatOp(off int64, r float64) *query_parser.Operand {
 	return &o
 }
 
+func makeNilOp(off int64) *query_parser.Operand {
+	var o query_parser.Operand
+	o.Off = off
+	o.Type = query_parser.TypNil
+	return &o
+}
+
+func makeListOp(off int64, elemType *vdl.Type, elems []*vdl.Value) *query_parser.Operand {
+	list := vdl.ZeroValue(vdl.ListType(elemType))
+	list.AssignLen(len(elems))
+	for i, elem := range elems {
+		list.Index(i).Assign(elem)
+	}
+	var o query_parser.Operand
+	o.Off = off
+	o.Type = query_parser.TypObject
+	o.Object = list
+	return &o
+}
+
 func checkArg(db ds.Database, off int64, argType query_parser.OperandType, arg *query_parser.Operand) error {
 	// We can't check unless the arg is a literal or an already computed function,
 	var operandToConvert *query_parser.Operand
@@ -264,48 +366,54 @@ func checkArg(db ds.Database, off int64, argType query_parser.OperandType, arg *
 		return nil // arg is not yet resolved, we can't check
 	}
 	// make sure it can be converted to argType.
-	var err error
-	switch argType {
+	if _, err := convertOperand(argType, operandToConvert); err != nil {
+		return conversionError(db, argType, arg.Off, err)
+	}
+	return nil
+}
+
+// convertOperand converts o to t.  TypNil and TypObject leave o unchanged.
+func convertOperand(t query_parser.OperandType, o *query_parser.Operand) (*query_parser.Operand, error) {
+	switch t {
 	case query_parser.TypBigInt:
-		_, err = conversions.ConvertValueToBigInt(operandToConvert)
-		if err != nil {
-			err = syncql.NewErrBigIntConversionError(db.GetContext(), arg.Off, err)
-		}
+		return conversions.ConvertValueToBigInt(o)
 	case query_parser.TypBigRat:
-		_, err = conversions.ConvertValueToBigRat(operandToConvert)
-		if err != nil {
-			err = syncql.NewErrBigRatConversionError(db.GetContext(), arg.Off, err)
-		}
+		return conversions.ConvertValueToBigRat(o)
 	case query_parser.TypBool:
-		_, err = conversions.ConvertValueToBool(operandToConvert)
-		if err != nil {
-			err = syncql.NewErrBoolConversionError(db.GetContext(), arg.Off, err)
-		}
+		return conversions.ConvertValueToBool(o)
 	case query_parser.TypFloat:
-		_, err = conversions.ConvertValueToFloat(operandToConvert)
-		if err != nil {
-			err = syncql.NewErrFloatConversionError(db.GetContext(), arg.Off, err)
-		}
+		return conversions.ConvertValueToFloat(o)
 	case query_parser.TypInt:
-		_, err = conversions.ConvertValueToInt(operandToConvert)
-		if err != nil {
-			err = syncql.NewErrIntConversionError(db.GetContext(), arg.Off, err)
-		}
+		return conversions.ConvertValueToInt(o)
 	case query_parser.TypStr:
-		_, err = conversions.ConvertValueToString(operandToConvert)
-		if err != nil {
-			err = syncql.NewErrStringConversionError(db.GetContext(), arg.Off, err)
-		}
+		return conversions.ConvertValueToString(o)
 	case query_parser.TypTime:
-		_, err = conversions.ConvertValueToTime(operandToConvert)
-		if err != nil {
-			err = syncql.NewErrTimeConversionError(db.GetContext(), arg.Off, err)
-		}
+		return conversions.ConvertValueToTime(o)
 	case query_parser.TypUint:
-		_, err = conversions.ConvertValueToUint(operandToConvert)
-		if err != nil {
-			err = syncql.NewErrUintConversionError(db.GetContext(), arg.Off, err)
-		}
+		return conversions.ConvertValueToUint(o)
+	}
+	return o, nil
+}
+
+// conversionError returns the syncql error for a failed conversion to t.
+func conversionError(db ds.Database, t query_parser.OperandType, off int64, err error) error {
+	switch t {
+	case query_parser.TypBigInt:
+		return syncql.NewErrBigIntConversionError(db.GetContext(), off, err)
+	case query_parser.TypBigRat:
+		return syncql.NewErrBigRatConversionError(db.GetContext(), off, err)
+	case query_parser.TypBool:
+		return syncql.NewErrBoolConversionError(db.GetContext(), off, err)
+	case query_parser.TypFloat:
+		return syncql.NewErrFloatConversionError(db.GetContext(), off, err)
+	case query_parser.TypInt:
+		return syncql.NewErrIntConversionError(db.GetContext(), off, err)
+	case query_parser.TypStr:
+		return syncql.NewErrStringConversionError(db.GetContext(), off, err)
+	case query_parser.TypTime:
+		return syncql.NewErrTimeConversionError(db.GetContext(), off, err)
+	case query_parser.TypUint:
+		return syncql.NewErrUintConversionError(db.GetContext(), off, err)
 	}
 	return err
 }
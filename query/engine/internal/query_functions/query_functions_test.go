@@ -7,7 +7,9 @@ package query_functions_test
 import (
 	"errors"
 	"math"
+	"math/big"
 	"reflect"
+	"regexp"
 	"testing"
 	"time"
 
@@ -53,6 +55,12 @@ type functionsTest struct {
 	result *query_parser.Operand
 }
 
+type aggregateTest struct {
+	name   string
+	args   []*query_parser.Operand
+	result *query_parser.Operand
+}
+
 type functionsErrorTest struct {
 	f    *query_parser.Function
 	args []*query_parser.Operand
@@ -2243,7 +2251,7 @@ func TestFunctions(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		r, err := query_functions.ExecFunction(&db, test.f, test.args)
+		r, err := query_functions.ExecFunction(&db, "", nil, test.f, test.args)
 		if err != nil {
 			t.Errorf("function: %v; unexpected error: got %v, want nil", test.f, err)
 		}
@@ -2292,7 +2300,310 @@ func TestErrorFunctions(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		_, err := query_functions.ExecFunction(&db, test.f, test.args)
+		_, err := query_functions.ExecFunction(&db, "", nil, test.f, test.args)
+		if verror.ErrorID(err) != verror.ErrorID(test.err) || err.Error() != test.err.Error() {
+			t.Errorf("function: %v; got %v, want %v", test.f, err, test.err)
+		}
+	}
+}
+
+func intOp(i int64) *query_parser.Operand {
+	return &query_parser.Operand{Type: query_parser.TypInt, Int: i}
+}
+
+func uintOp(u uint64) *query_parser.Operand {
+	return &query_parser.Operand{Type: query_parser.TypUint, Uint: u}
+}
+
+func floatOp(f float64) *query_parser.Operand {
+	return &query_parser.Operand{Type: query_parser.TypFloat, Float: f}
+}
+
+func strOp(s string) *query_parser.Operand {
+	return &query_parser.Operand{Type: query_parser.TypStr, Str: s}
+}
+
+func TestAggregates(t *testing.T) {
+	tests := []aggregateTest{
+		{"Count", []*query_parser.Operand{intOp(1), nil, strOp("a")}, intOp(2)},
+		{"Count", nil, intOp(0)},
+		{"CountDistinct", []*query_parser.Operand{strOp("a"), strOp("b"), nil, strOp("a"), intOp(1)}, intOp(3)},
+		{"Sum", []*query_parser.Operand{intOp(1), intOp(2), nil, intOp(3)}, intOp(6)},
+		{"Sum", []*query_parser.Operand{intOp(1), floatOp(2.5)}, floatOp(3.5)},
+		{"Sum", []*query_parser.Operand{uintOp(1), intOp(2)}, &query_parser.Operand{Type: query_parser.TypBigInt, BigInt: big.NewInt(3)}},
+		{"Sum", nil, nil},
+		{"Avg", []*query_parser.Operand{intOp(1), uintOp(2), nil, intOp(6)}, floatOp(3)},
+		{"Avg", []*query_parser.Operand{nil}, nil},
+		{"Min", []*query_parser.Operand{intOp(3), intOp(1), nil, intOp(2)}, intOp(1)},
+		{"Min", []*query_parser.Operand{intOp(2), floatOp(1.5)}, floatOp(1.5)},
+		{"Max", []*query_parser.Operand{strOp("b"), strOp("c"), strOp("a")}, strOp("c")},
+		{"Max", nil, nil},
+	}
+
+	for _, test := range tests {
+		f := &query_parser.Function{Name: test.name}
+		if !query_functions.IsAggregate(f) {
+			t.Errorf("function: %s; not an aggregate", test.name)
+		}
+		acc, err := query_functions.NewAccumulator(&db, f)
+		if err != nil {
+			t.Fatalf("function: %s; unexpected error: got %v, want nil", test.name, err)
+		}
+		for _, arg := range test.args {
+			if err := acc.Add(arg); err != nil {
+				t.Errorf("function: %s; unexpected error: got %v, want nil", test.name, err)
+			}
+		}
+		if r := acc.Result(); !reflect.DeepEqual(test.result, r) {
+			t.Errorf("function: %s, args: %v; got %v, want %v", test.name, test.args, r, test.result)
+		}
+	}
+}
+
+func TestAggregateErrors(t *testing.T) {
+	if query_functions.IsAggregate(&query_parser.Function{Name: "Len"}) {
+		t.Errorf("function: Len; unexpectedly an aggregate")
+	}
+	for _, name := range []string{"Sum", "Avg"} {
+		acc, err := query_functions.NewAccumulator(&db, &query_parser.Function{Name: name})
+		if err != nil {
+			t.Fatalf("function: %s; unexpected error: got %v, want nil", name, err)
+		}
+		// Strings are not numeric, even if they can be converted to numbers.
+		for _, str := range []string{"abc", "1.5"} {
+			err = acc.Add(&query_parser.Operand{Type: query_parser.TypStr, Str: str, Node: query_parser.Node{Off: 4}})
+			if want := syncql.NewErrAggregateArgNotNumeric(db.GetContext(), 4, name); verror.ErrorID(err) != verror.ErrorID(want) || err.Error() != want.Error() {
+				t.Errorf("function: %s, arg: %q; got %v, want %v", name, str, err, want)
+			}
+		}
+	}
+}
+
+func objOp(v interface{}) *query_parser.Operand {
+	return &query_parser.Operand{Type: query_parser.TypObject, Object: vdl.ValueOf(v)}
+}
+
+func nilOp() *query_parser.Operand {
+	return &query_parser.Operand{Type: query_parser.TypNil}
+}
+
+func boolOp(b bool) *query_parser.Operand {
+	return &query_parser.Operand{Type: query_parser.TypBool, Bool: b}
+}
+
+type pathTestAddress struct {
+	City string
+}
+
+type pathTestCustomerType struct {
+	Name    string
+	Address pathTestAddress
+	Phones  []string
+	Ratings map[string]int64
+}
+
+var pathTestCustomer = pathTestCustomerType{
+	Name:    "John Smith",
+	Address: pathTestAddress{City: "Palo Alto"},
+	Phones:  []string{"555-1212", "555-1213"},
+	Ratings: map[string]int64{"Hotel A": 4, "Motel": 2},
+}
+
+func TestRegexpAndObjectFunctions(t *testing.T) {
+	tests := []aggregateTest{
+		{"RegexpMatch", []*query_parser.Operand{strOp("abc123"), strOp("[0-9]+$")}, boolOp(true)},
+		{"RegexpMatch", []*query_parser.Operand{strOp("abc123"), strOp("^[0-9]+")}, boolOp(false)},
+		{"RegexpExtract", []*query_parser.Operand{strOp("abc123def"), strOp("[0-9]+")}, strOp("123")},
+		{"RegexpExtract", []*query_parser.Operand{strOp("key=value"), strOp("=(.*)")}, strOp("value")},
+		{"RegexpExtract", []*query_parser.Operand{strOp("abc"), strOp("[0-9]+")}, strOp("")},
+		{"RegexpReplace", []*query_parser.Operand{strOp("a1b22c333"), strOp("[0-9]+"), strOp("#")}, strOp("a#b#c#")},
+		{"RegexpReplace", []*query_parser.Operand{strOp("John Smith"), strOp("(\\w+) (\\w+)"), strOp("${2}, $1")}, strOp("Smith, John")},
+		{"Contains", []*query_parser.Operand{objOp([]string{"a", "b"}), strOp("b")}, boolOp(true)},
+		{"Contains", []*query_parser.Operand{objOp([]int64{1, 2}), floatOp(2)}, boolOp(true)},
+		{"Contains", []*query_parser.Operand{objOp([]int64{1, 2}), intOp(3)}, boolOp(false)},
+		{"Contains", []*query_parser.Operand{objOp(map[string]int64{"a": 1}), strOp("a")}, boolOp(true)},
+		{"Contains", []*query_parser.Operand{objOp(map[int64]struct{}{7: {}}), intOp(7)}, boolOp(true)},
+		{"Contains", []*query_parser.Operand{strOp("abcdef"), strOp("cd")}, boolOp(true)},
+		{"Keys", []*query_parser.Operand{objOp(map[int64]string{10: "x", 2: "y"})}, objOp([]int64{2, 10})},
+		{"Keys", []*query_parser.Operand{objOp(map[string]struct{}{"b": {}, "a": {}})}, objOp([]string{"a", "b"})},
+		{"Values", []*query_parser.Operand{objOp(map[string]int64{"b": 2, "a": 1})}, objOp([]int64{1, 2})},
+		{"Index", []*query_parser.Operand{objOp([]string{"a", "b", "c"}), intOp(1)}, strOp("b")},
+		{"Index", []*query_parser.Operand{objOp([]string{"a", "b", "c"}), intOp(3)}, nilOp()},
+		{"Index", []*query_parser.Operand{objOp(map[string]float64{"pi": 3.14}), strOp("pi")}, floatOp(3.14)},
+		{"Index", []*query_parser.Operand{objOp(map[string]float64{"pi": 3.14}), strOp("e")}, nilOp()},
+		{"JsonPath", []*query_parser.Operand{objOp(pathTestCustomer), strOp("$.Address.City")}, strOp("Palo Alto")},
+		{"JsonPath", []*query_parser.Operand{objOp(pathTestCustomer), strOp("Phones[1]")}, strOp("555-1213")},
+		{"JsonPath", []*query_parser.Operand{objOp(pathTestCustomer), strOp(`$.Ratings["Hotel A"]`)}, intOp(4)},
+		{"JsonPath", []*query_parser.Operand{objOp(pathTestCustomer), strOp("$.Ratings.Motel")}, intOp(2)},
+		{"JsonPath", []*query_parser.Operand{objOp(pathTestCustomer), strOp("$.Phones[2]")}, nilOp()},
+		{"JsonPath", []*query_parser.Operand{objOp(pathTestCustomer), strOp("$.Address.Zip")}, nilOp()},
+		{"JsonPath", []*query_parser.Operand{objOp(pathTestCustomer), strOp("$.Name[0]")}, nilOp()},
+		{"JsonPath", []*query_parser.Operand{objOp([][]int64{{1, 2}, {3}}), strOp("[0][1]")}, intOp(2)},
+		{"JsonPath", []*query_parser.Operand{objOp(map[int64]string{7: "seven"}), strOp("[7]")}, strOp("seven")},
+		{"JsonPath", []*query_parser.Operand{strOp("abc"), strOp("$")}, strOp("abc")},
+		{"Coalesce", []*query_parser.Operand{nilOp(), nilOp(), strOp("c"), intOp(4)}, strOp("c")},
+		{"Coalesce", []*query_parser.Operand{nilOp()}, nilOp()},
+		{"IfNull", []*query_parser.Operand{intOp(1), intOp(2)}, intOp(1)},
+		{"IfNull", []*query_parser.Operand{nilOp(), intOp(2)}, intOp(2)},
+	}
+
+	for _, test := range tests {
+		r, err := query_functions.ExecFunction(&db, "", nil, &query_parser.Function{Name: test.name}, test.args)
+		if err != nil {
+			t.Errorf("function: %s, args: %v; unexpected error: got %v, want nil", test.name, test.args, err)
+			continue
+		}
+		if r.Type == query_parser.TypObject && test.result.Type == query_parser.TypObject {
+			if !vdl.EqualValue(r.Object, test.result.Object) {
+				t.Errorf("function: %s, args: %v; got %v, want %v", test.name, test.args, r.Object, test.result.Object)
+			}
+		} else if !reflect.DeepEqual(test.result, r) {
+			t.Errorf("function: %s, args: %v; got %v, want %v", test.name, test.args, r, test.result)
+		}
+	}
+}
+
+func TestRegexpAndObjectFunctionErrors(t *testing.T) {
+	badRegexp := &query_parser.Operand{Type: query_parser.TypStr, Str: "a(b", Node: query_parser.Node{Off: 20}}
+	_, reErr := regexp.Compile("a(b")
+	badPath := &query_parser.Operand{Type: query_parser.TypStr, Str: "$.Phones[-1]", Node: query_parser.Node{Off: 30}}
+	field := &query_parser.Operand{Type: query_parser.TypField, Column: &query_parser.Field{Segments: []query_parser.Segment{{Value: "v"}}}}
+	tests := []aggregateTest{
+		{"RegexpMatch", []*query_parser.Operand{strOp("abc"), badRegexp}, nil},
+		{"Keys", []*query_parser.Operand{strOp("abc")}, nil},
+		{"Values", []*query_parser.Operand{objOp(map[string]struct{}{"a": {}})}, nil},
+		{"Index", []*query_parser.Operand{intOp(1), intOp(0)}, nil},
+		{"Contains", []*query_parser.Operand{intOp(1), intOp(1)}, nil},
+		{"JsonPath", []*query_parser.Operand{objOp(pathTestCustomer), badPath}, nil},
+	}
+	errs := []error{
+		syncql.NewErrInvalidRegularExpression(db.GetContext(), 20, reErr),
+		syncql.NewErrFunctionInvalidArg(db.GetContext(), 0, "Keys", "map or set"),
+		syncql.NewErrFunctionInvalidArg(db.GetContext(), 0, "Values", "map"),
+		syncql.NewErrFunctionInvalidArg(db.GetContext(), 0, "Index", "array, list or map"),
+		syncql.NewErrFunctionInvalidArg(db.GetContext(), 0, "Contains", "array, list, set, map or string"),
+		syncql.NewErrInvalidJsonPath(db.GetContext(), 30, "$.Phones[-1]"),
+	}
+	for i, test := range tests {
+		_, err := query_functions.ExecFunction(&db, "", nil, &query_parser.Function{Name: test.name}, test.args)
+		if verror.ErrorID(err) != verror.ErrorID(errs[i]) || err.Error() != errs[i].Error() {
+			t.Errorf("function: %s; got %v, want %v", test.name, err, errs[i])
+		}
+	}
+
+	// A literal regular expression is checked (and compiled) by the checker
+	// even if the function can't be executed until a row is read.
+	f := &query_parser.Function{Name: "RegexpReplace", Args: []*query_parser.Operand{field, badRegexp, strOp("x")}}
+	if err := query_functions.CheckFunction(&db, f); verror.ErrorID(err) != verror.ErrorID(errs[0]) || err.Error() != errs[0].Error() {
+		t.Errorf("function: %s; got %v, want %v", f.Name, err, errs[0])
+	}
+	goodRegexp := strOp("[0-9]+")
+	f = &query_parser.Function{Name: "RegexpMatch", Args: []*query_parser.Operand{field, goodRegexp}}
+	if err := query_functions.CheckFunction(&db, f); err != nil {
+		t.Errorf("function: %s; unexpected error: got %v, want nil", f.Name, err)
+	}
+	if f.Computed || goodRegexp.Regexp == nil {
+		t.Errorf("function: %s; got computed: %v, regexp: %v; want false, compiled regexp", f.Name, f.Computed, goodRegexp.Regexp)
+	}
+	// Likewise, a literal JSON path is checked by the checker.
+	for _, path := range []string{"$.Phones[-1]", "$..Name", "$.Ratings[\"Hotel", "Phones[x]"} {
+		f = &query_parser.Function{Name: "JsonPath", Args: []*query_parser.Operand{field, strOp(path)}}
+		if err := query_functions.CheckFunction(&db, f); verror.ErrorID(err) != syncql.ErrInvalidJsonPath.ID {
+			t.Errorf("path: %s; got %v, want %v", path, err, syncql.ErrInvalidJsonPath.ID)
+		}
+	}
+	f = &query_parser.Function{Name: "JsonPath", Args: []*query_parser.Operand{field, strOp(`$.Ratings["Hotel A"]`)}}
+	if err := query_functions.CheckFunction(&db, f); err != nil {
+		t.Errorf("function: %s; unexpected error: got %v, want nil", f.Name, err)
+	}
+}
+
+func timeOp(t time.Time) *query_parser.Operand {
+	return &query_parser.Operand{Type: query_parser.TypTime, Time: t}
+}
+
+func TestDateFunctions(t *testing.T) {
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	tim := t_2015_06_09_01_23_45_8327 // a Tuesday
+	tests := []aggregateTest{
+		{"DateAdd", []*query_parser.Operand{timeOp(tim), strOp("1h30m")}, timeOp(time.Date(2015, 6, 9, 2, 53, 45, 8327, la))},
+		{"DateAdd", []*query_parser.Operand{timeOp(tim), intOp(1000)}, timeOp(time.Date(2015, 6, 9, 1, 23, 45, 9327, la))},
+		{"DateSub", []*query_parser.Operand{timeOp(tim), strOp("24h")}, timeOp(time.Date(2015, 6, 8, 1, 23, 45, 8327, la))},
+		{"DateDiff", []*query_parser.Operand{timeOp(tim.Add(time.Hour)), timeOp(tim)}, intOp(int64(time.Hour))},
+		{"DateDiff", []*query_parser.Operand{timeOp(tim), timeOp(tim.Add(time.Second))}, intOp(-int64(time.Second))},
+		{"DateTrunc", []*query_parser.Operand{timeOp(tim), strOp("hour"), strOp("America/Los_Angeles")}, timeOp(time.Date(2015, 6, 9, 1, 0, 0, 0, la))},
+		{"DateTrunc", []*query_parser.Operand{timeOp(tim), strOp("Day"), strOp("America/Los_Angeles")}, timeOp(time.Date(2015, 6, 9, 0, 0, 0, 0, la))},
+		{"DateTrunc", []*query_parser.Operand{timeOp(tim), strOp("day"), strOp("Asia/Tokyo")}, timeOp(time.Date(2015, 6, 9, 0, 0, 0, 0, time.FixedZone("JST", 9*60*60)))},
+		{"DateTrunc", []*query_parser.Operand{timeOp(tim), strOp("week"), strOp("America/Los_Angeles")}, timeOp(time.Date(2015, 6, 8, 0, 0, 0, 0, la))},
+		{"DateTrunc", []*query_parser.Operand{timeOp(tim), strOp("month"), strOp("America/Los_Angeles")}, timeOp(time.Date(2015, 6, 1, 0, 0, 0, 0, la))},
+		{"FormatTime", []*query_parser.Operand{timeOp(tim), strOp("2006-01-02 15:04 MST"), strOp("America/New_York")}, strOp("2015-06-09 04:23 EDT")},
+		{"ParseDuration", []*query_parser.Operand{strOp("1m30s")}, intOp(int64(90 * time.Second))},
+		{"Unix", []*query_parser.Operand{timeOp(tim)}, intOp(tim.Unix())},
+		{"UnixNano", []*query_parser.Operand{timeOp(tim)}, intOp(tim.UnixNano())},
+		{"TimeFromUnix", []*query_parser.Operand{intOp(tim.Unix())}, timeOp(time.Date(2015, 6, 9, 1, 23, 45, 0, la))},
+		{"TimeFromUnixNano", []*query_parser.Operand{intOp(tim.UnixNano())}, timeOp(tim)},
+	}
+
+	for _, test := range tests {
+		r, err := query_functions.ExecFunction(&db, "", nil, &query_parser.Function{Name: test.name}, test.args)
+		if err != nil {
+			t.Errorf("function: %s, args: %v; unexpected error: got %v, want nil", test.name, test.args, err)
+			continue
+		}
+		if r.Type == query_parser.TypTime && test.result.Type == query_parser.TypTime {
+			if !r.Time.Equal(test.result.Time) {
+				t.Errorf("function: %s, args: %v; got %v, want %v", test.name, test.args, r.Time, test.result.Time)
+			}
+		} else if !reflect.DeepEqual(test.result, r) {
+			t.Errorf("function: %s, args: %v; got %v, want %v", test.name, test.args, r, test.result)
+		}
+	}
+}
+
+func TestDateFunctionErrors(t *testing.T) {
+	_, durationErr := time.ParseDuration("1 hour")
+	_, locErr := time.LoadLocation("Mars/Olympus_Mons")
+	badDuration := &query_parser.Operand{Type: query_parser.TypStr, Str: "1 hour", Node: query_parser.Node{Off: 30}}
+	badUnit := &query_parser.Operand{Type: query_parser.TypStr, Str: "year", Node: query_parser.Node{Off: 31}}
+	badLoc := &query_parser.Operand{Type: query_parser.TypStr, Str: "Mars/Olympus_Mons", Node: query_parser.Node{Off: 32}}
+	field := &query_parser.Operand{Type: query_parser.TypField, Column: &query_parser.Field{Segments: []query_parser.Segment{{Value: "v"}}}}
+	tests := []functionsErrorTest{
+		{
+			&query_parser.Function{Name: "DateAdd", Args: []*query_parser.Operand{timeOp(t_2015_06_21), badDuration}},
+			nil,
+			syncql.NewErrDurationConversionError(db.GetContext(), 30, durationErr),
+		},
+		{
+			&query_parser.Function{Name: "DateTrunc", Args: []*query_parser.Operand{timeOp(t_2015_06_21), badUnit, strOp("UTC")}},
+			nil,
+			syncql.NewErrInvalidTimeUnit(db.GetContext(), 31, "year"),
+		},
+		// Literal args are checked even if the function can't be executed until
+		// a row is read.
+		{
+			&query_parser.Function{Name: "DateSub", Args: []*query_parser.Operand{field, badDuration}},
+			nil,
+			syncql.NewErrDurationConversionError(db.GetContext(), 30, durationErr),
+		},
+		{
+			&query_parser.Function{Name: "DateTrunc", Args: []*query_parser.Operand{field, badUnit, strOp("UTC")}},
+			nil,
+			syncql.NewErrInvalidTimeUnit(db.GetContext(), 31, "year"),
+		},
+		{
+			&query_parser.Function{Name: "DateTrunc", Args: []*query_parser.Operand{field, strOp("day"), badLoc}},
+			nil,
+			syncql.NewErrLocationConversionError(db.GetContext(), 32, locErr),
+		},
+		{
+			&query_parser.Function{Name: "FormatTime", Args: []*query_parser.Operand{field, strOp("15:04"), badLoc}},
+			nil,
+			syncql.NewErrLocationConversionError(db.GetContext(), 32, locErr),
+		},
+	}
+
+	for _, test := range tests {
+		err := query_functions.CheckFunction(&db, test.f)
 		if verror.ErrorID(err) != verror.ErrorID(test.err) || err.Error() != test.err.Error() {
 			t.Errorf("function: %v; got %v, want %v", test.f, err, test.err)
 		}
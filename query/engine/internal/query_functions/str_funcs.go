@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"html"
+	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
@@ -131,8 +132,9 @@ func typeFunc(db ds.Database, off int64, args []*query_parser.Operand) (*query_p
 
 func typeFuncFieldCheck(db ds.Database, off int64, args []*query_parser.Operand) error {
 	// At this point, it is known that there is one arg. Make sure it is of type field
-	// and is a value field (i.e., it must begin with a v segment).
-	if args[0].Type != query_parser.TypField || len(args[0].Column.Segments) < 1 || args[0].Column.Segments[0].Value != "v" {
+	// and is a value field (i.e., it must begin with a v segment, following the
+	// table name or alias of a qualified field).
+	if args[0].Type != query_parser.TypField || len(args[0].Column.Path()) < 1 || args[0].Column.Path()[0].Value != "v" {
 		return syncql.NewErrArgMustBeField(db.GetContext(), args[0].Off)
 	}
 	return nil
@@ -318,3 +320,104 @@ func runeCount(db ds.Database, off int64, args []*query_parser.Operand) (*query_
 	}
 	return makeIntOp(off, int64(utf8.RuneCountInString(s.Str))), nil
 }
+
+// RegexpMatch(s, re string) bool
+// RegexpMatch returns true if s contains a match of the regular expression re
+// (in the syntax of golang's regexp package).
+// e.g., RegexpMatch("abc123", "[0-9]+$") returns true.
+func regexpMatch(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	s, err := conversions.ConvertValueToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexpArg(db, args[1])
+	if err != nil {
+		return nil, err
+	}
+	return makeBoolOp(off, re.MatchString(s.Str)), nil
+}
+
+// RegexpExtract(s, re string) string
+// RegexpExtract returns the leftmost match of the regular expression re in s or,
+// if re contains a parenthesized subexpression, the text matched by the first
+// subexpression.  If there is no match, the empty string is returned.
+// e.g., RegexpExtract("abc123def", "[0-9]+") returns "123".
+// e.g., RegexpExtract("key=value", "=(.*)") returns "value".
+func regexpExtract(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	s, err := conversions.ConvertValueToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexpArg(db, args[1])
+	if err != nil {
+		return nil, err
+	}
+	match := re.FindStringSubmatch(s.Str)
+	switch {
+	case match == nil:
+		return makeStrOp(off, ""), nil
+	case len(match) > 1:
+		return makeStrOp(off, match[1]), nil
+	default:
+		return makeStrOp(off, match[0]), nil
+	}
+}
+
+// RegexpReplace(s, re, repl string) string
+// RegexpReplace returns a copy of s with all matches of the regular expression re
+// replaced by repl.  In repl, $1 (or ${1}) stands for the text matched by the first
+// parenthesized subexpression (and so on).
+// e.g., RegexpReplace("a1b22c333", "[0-9]+", "#") returns "a#b#c#".
+// e.g., RegexpReplace("John Smith", "(\\w+) (\\w+)", "${2}, $1") returns "Smith, John".
+func regexpReplace(db ds.Database, off int64, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	s, err := conversions.ConvertValueToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexpArg(db, args[1])
+	if err != nil {
+		return nil, err
+	}
+	repl, err := conversions.ConvertValueToString(args[2])
+	if err != nil {
+		return nil, err
+	}
+	return makeStrOp(off, re.ReplaceAllString(s.Str, repl.Str)), nil
+}
+
+// regexpArg returns the compiled regular expression of arg.  If arg is a
+// literal, it was compiled by secondArgRegexpCheck.
+func regexpArg(db ds.Database, arg *query_parser.Operand) (*regexp.Regexp, error) {
+	if arg.Regexp != nil {
+		return arg.Regexp, nil
+	}
+	reStr, err := conversions.ConvertValueToString(arg)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(reStr.Str)
+	if err != nil {
+		return nil, syncql.NewErrInvalidRegularExpression(db.GetContext(), arg.Off, err)
+	}
+	return re, nil
+}
+
+func secondArgRegexpCheck(db ds.Database, off int64, args []*query_parser.Operand) error {
+	// At this point, it is known that the 2nd arg is convertible to a string
+	// if it is a literal (or an already computed function).  Compile it now to
+	// check for errors (and so that it is compiled only once).  Other args can't
+	// be checked until execution.
+	arg := args[1]
+	if arg.Type == query_parser.TypFunction && arg.Function.Computed {
+		arg = arg.Function.RetValue
+	}
+	if arg.Type != query_parser.TypStr {
+		return nil
+	}
+	re, err := regexpArg(db, arg)
+	if err != nil {
+		return err
+	}
+	arg.Regexp = re
+	return nil
+}
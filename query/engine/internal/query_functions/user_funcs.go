@@ -0,0 +1,265 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query_functions
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"v.io/v23/context"
+	ds "v.io/v23/query/engine/datasource"
+	"v.io/v23/query/engine/internal/conversions"
+	"v.io/v23/query/engine/internal/query_parser"
+	"v.io/v23/query/engine/public"
+	"v.io/v23/query/syncql"
+	"v.io/v23/vdl"
+)
+
+// UserFunctions holds the user-defined functions registered with a query
+// engine.  The functions are found by way of the database passed to
+// CheckFunction and ExecFunction, which must implement
+// userFunctionsDatabase.
+type UserFunctions struct {
+	mutex              sync.RWMutex
+	functions          map[string]*userFunction
+	lowercaseFunctions map[string]string // map of lowercase(funcName)->funcName
+}
+
+type userFunction struct {
+	entry function // funcAddr and checkArgsAddr are nil.
+	def   public.Function
+}
+
+// userFunctionsDatabase is implemented by databases that carry the
+// user-defined functions of a query engine.
+type userFunctionsDatabase interface {
+	UserFunctions() *UserFunctions
+}
+
+func NewUserFunctions() *UserFunctions {
+	return &UserFunctions{
+		functions:          map[string]*userFunction{},
+		lowercaseFunctions: map[string]string{},
+	}
+}
+
+// Register adds a user-defined function.  The name must be an identifier
+// and must not match (ignoring case) a built-in function or an already
+// registered function.
+func (uf *UserFunctions) Register(ctx *context.T, name string, f public.Function) error {
+	if !isIdentifier(name) {
+		return syncql.NewErrInvalidUserFunction(ctx, name, errors.New("name is not an identifier"))
+	}
+	if f.Impl == nil {
+		return syncql.NewErrInvalidUserFunction(ctx, name, errors.New("Impl is nil"))
+	}
+	entry := function{hasVarArgs: f.VarArgs}
+	for _, t := range f.ArgTypes {
+		argType, err := operandTypeOf(t)
+		if err != nil {
+			return syncql.NewErrInvalidUserFunction(ctx, name, err)
+		}
+		entry.argTypes = append(entry.argTypes, argType)
+	}
+	var err error
+	if entry.varArgsType, err = operandTypeOf(f.VarArgsType); err != nil {
+		return syncql.NewErrInvalidUserFunction(ctx, name, err)
+	}
+	if entry.returnType, err = operandTypeOf(f.ReturnType); err != nil {
+		return syncql.NewErrInvalidUserFunction(ctx, name, err)
+	}
+	if entry.returnType == query_parser.TypNil {
+		entry.returnType = query_parser.TypObject
+	}
+	lowercaseName := strings.ToLower(name)
+	if existing, ok := lowercaseFunctions[lowercaseName]; ok {
+		return syncql.NewErrInvalidUserFunction(ctx, name, fmt.Errorf("conflicts with built-in function %s", existing))
+	}
+	uf.mutex.Lock()
+	defer uf.mutex.Unlock()
+	if existing, ok := uf.lowercaseFunctions[lowercaseName]; ok {
+		return syncql.NewErrInvalidUserFunction(ctx, name, fmt.Errorf("conflicts with function %s", existing))
+	}
+	uf.functions[name] = &userFunction{entry, f}
+	uf.lowercaseFunctions[lowercaseName] = name
+	return nil
+}
+
+func (uf *UserFunctions) lookup(name string) *userFunction {
+	uf.mutex.RLock()
+	defer uf.mutex.RUnlock()
+	return uf.functions[name]
+}
+
+func (uf *UserFunctions) correctCase(name string) (string, bool) {
+	uf.mutex.RLock()
+	defer uf.mutex.RUnlock()
+	correctCase, ok := uf.lowercaseFunctions[strings.ToLower(name)]
+	return correctCase, ok
+}
+
+// userFunctionsOf returns the user-defined functions of db (nil if none).
+func userFunctionsOf(db ds.Database) *UserFunctions {
+	if udb, ok := db.(userFunctionsDatabase); ok {
+		return udb.UserFunctions()
+	}
+	return nil
+}
+
+// lookupUserFunction returns the user-defined function called by f, or nil if
+// f doesn't call a user-defined function.
+func lookupUserFunction(db ds.Database, f *query_parser.Function) *userFunction {
+	if uf := userFunctionsOf(db); uf != nil {
+		return uf.lookup(f.Name)
+	}
+	return nil
+}
+
+// User-defined functions are never executed at check time as they may
+// depend on the key and value of the row.
+func checkUserFunction(db ds.Database, f *query_parser.Function, u *userFunction) error {
+	f.ArgTypes = u.entry.argTypes
+	f.RetType = u.entry.returnType
+	if err := checkArgCount(db, f, &u.entry); err != nil {
+		return err
+	}
+	if err := argsStandardCheck(db, f.Off, &u.entry, f.Args); err != nil {
+		return err
+	}
+	return checkUserFunctionArgs(db, f, u, f.Args)
+}
+
+// checkUserFunctionArgs calls the function's CheckArgs with the args that
+// are known at check time; the others are passed as nil.
+func checkUserFunctionArgs(db ds.Database, f *query_parser.Function, u *userFunction, args []*query_parser.Operand) error {
+	if u.def.CheckArgs == nil {
+		return nil
+	}
+	values := make([]*vdl.Value, len(args))
+	for i, arg := range args {
+		switch arg.Type {
+		case query_parser.TypBigInt, query_parser.TypBigRat, query_parser.TypBool, query_parser.TypFloat, query_parser.TypInt, query_parser.TypStr, query_parser.TypTime, query_parser.TypUint:
+		case query_parser.TypFunction:
+			if !arg.Function.Computed {
+				continue
+			}
+			arg = arg.Function.RetValue
+		default:
+			continue
+		}
+		value, err := userFunctionArg(db, u.entry.argType(i), arg)
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+	if err := u.def.CheckArgs(db.GetContext(), values); err != nil {
+		return syncql.NewErrUserFunctionError(db.GetContext(), f.Off, f.Name, err)
+	}
+	return nil
+}
+
+func execUserFunction(db ds.Database, k string, v *vdl.Value, f *query_parser.Function, u *userFunction, args []*query_parser.Operand) (*query_parser.Operand, error) {
+	values := make([]*vdl.Value, len(args))
+	for i, arg := range args {
+		value, err := userFunctionArg(db, u.entry.argType(i), arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	retValue, err := u.def.Impl(db.GetContext(), k, v, values)
+	if err != nil {
+		return nil, syncql.NewErrUserFunctionError(db.GetContext(), f.Off, f.Name, err)
+	}
+	if retValue == nil {
+		return &query_parser.Operand{Off: f.Off, Type: query_parser.TypNil}, nil
+	}
+	op, err := query_parser.ConvertValueToAnOperand(retValue, f.Off)
+	if err != nil {
+		return nil, syncql.NewErrUserFunctionError(db.GetContext(), f.Off, f.Name, err)
+	}
+	if op, err = convertOperand(u.entry.returnType, op); err != nil {
+		return nil, conversionError(db, u.entry.returnType, f.Off, err)
+	}
+	return op, nil
+}
+
+// userFunctionArg converts arg to argType and then to a *vdl.Value.
+func userFunctionArg(db ds.Database, argType query_parser.OperandType, arg *query_parser.Operand) (*vdl.Value, error) {
+	if arg.Type == query_parser.TypNil {
+		return vdl.ValueOf(nil), nil
+	}
+	op, err := convertOperand(argType, arg)
+	if err != nil {
+		return nil, conversionError(db, argType, arg.Off, err)
+	}
+	switch op.Type {
+	case query_parser.TypBool:
+		return vdl.ValueOf(op.Bool), nil
+	case query_parser.TypFloat:
+		return vdl.ValueOf(op.Float), nil
+	case query_parser.TypInt:
+		return vdl.ValueOf(op.Int), nil
+	case query_parser.TypStr:
+		return vdl.ValueOf(op.Str), nil
+	case query_parser.TypTime:
+		return vdl.ValueOf(op.Time), nil
+	case query_parser.TypUint:
+		return vdl.ValueOf(op.Uint), nil
+	case query_parser.TypObject:
+		return op.Object, nil
+	case query_parser.TypBigInt, query_parser.TypBigRat:
+		// Big numbers are passed as floats.
+		if op, err = conversions.ConvertValueToFloat(op); err != nil {
+			return nil, syncql.NewErrFloatConversionError(db.GetContext(), arg.Off, err)
+		}
+		return vdl.ValueOf(op.Float), nil
+	}
+	return vdl.ValueOf(nil), nil
+}
+
+// argType returns the type of the ith arg.
+func (f *function) argType(i int) query_parser.OperandType {
+	if i < len(f.argTypes) {
+		return f.argTypes[i]
+	}
+	return f.varArgsType
+}
+
+func operandTypeOf(t public.ValueType) (query_parser.OperandType, error) {
+	switch t {
+	case public.TypeAny:
+		return query_parser.TypNil, nil
+	case public.TypeBool:
+		return query_parser.TypBool, nil
+	case public.TypeFloat:
+		return query_parser.TypFloat, nil
+	case public.TypeInt:
+		return query_parser.TypInt, nil
+	case public.TypeStr:
+		return query_parser.TypStr, nil
+	case public.TypeTime:
+		return query_parser.TypTime, nil
+	case public.TypeUint:
+		return query_parser.TypUint, nil
+	}
+	return query_parser.TypNil, fmt.Errorf("unknown type %d", t)
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -9,19 +9,91 @@
 //
 // <query_specification> ::=
 //   <select_statement>
+//   | <union_statement>
 //   | <delete_statement>
+//   | <explain_statement>
+//   | <update_statement>
+//   | <insert_statement>
 //
 // <select_statement> ::=
-//   <select_clause> <from_clause> [<where_clause>] [<escape_limit_offset_clause>...]
+//   <select_clause> <from_clause> [<where_clause>] [<group_by_clause>] [<having_clause>]
+//   [<order_by_clause>] [<escape_limit_offset_clause>...]
+//
+// <union_statement> ::=
+//   <select_statement> {UNION [ALL] <select_statement>}...
+//
+// The select statements of a union must have the same number of columns; the
+// column headings are those of the first.  Union eliminates duplicate rows
+// from the results of the select statements it combines, union all does
+// not.  The limit and offset clauses following the last select statement
+// apply to the union; those of the other select statements apply to them
+// alone.  Order by is not allowed in the select statements of a union.
 //
 // <delete_statement> ::=
 //   delete <from_clause> [<where_clause>] [<escape_limit_clause>...]
 //
-// <select_clause> ::= SELECT <selector> [{<comma><selector>}...]
+// <explain_statement> ::=
+//   EXPLAIN <select_statement>
+//   | EXPLAIN <union_statement>
+//   | EXPLAIN <delete_statement>
+//   | EXPLAIN <update_statement>
+//   | EXPLAIN <insert_statement>
+//
+// An explain statement does not execute the statement it explains; rather, it
+// returns the plan for executing it (e.g., the key and index ranges to scan).
+//
+// <update_statement> ::=
+//   UPDATE <identifier> <set_clause> [<where_clause>] [<escape_limit_clause>...]
+//
+// <set_clause> ::= SET <assignment> [{<comma><assignment>}...]
+//
+// <assignment> ::= v[<period><field>] = <operand>
+//
+// The operands of the assignments are evaluated with the row before it is
+// updated and must be convertible to the type of the field (i.e., its type in
+// the value stored in the table).  Rows whose value has no such field are not
+// updated.
+//
+// <insert_statement> ::=
+//   INSERT INTO <identifier> <left_paren> <column_list> <right_paren>
+//   VALUES <values_row> [{<comma><values_row>}...]
+//
+// <column_list> ::= k <comma> v | v <comma> k
+//
+// <values_row> ::= <left_paren> <operand> <comma> <operand> <right_paren>
+//
+// The values of an insert statement must be literals, parameters or functions
+// of them.  The key must be a string that is not already in the table.
+// Unless the table supports inserting a row only if its key does not exist
+// (see datasource.InsertTable), a row written concurrently with the same key
+// may be replaced.  As with the operand of an assignment, the value is
+// converted to the type of the values of the table: the type reported by the
+// table (see datasource.TypedTable) or, if the table reports none, the type of
+// the value of an existing row.  The statement fails if a value cannot be
+// converted.
 //
-// <from_clause> ::= FROM <table>
+// <select_clause> ::= SELECT [DISTINCT] <selector> [{<comma><selector>}...]
 //
-// <where_clause> ::= WHERE <expression>
+// Select distinct eliminates duplicate rows (i.e., rows whose columns have
+// equal vom encodings) before the limit and offset clauses are applied.
+//
+// <from_clause> ::= FROM <table> [{<join_clause>}...]
+//
+// <join_clause> ::= [INNER | LEFT [OUTER]] JOIN <table> ON <expression>
+//
+// <where_clause> ::= WHERE <expression> [ESCAPE <char_literal>]
+//
+// The escape character applies to all like expressions of the statement; in a
+// select statement, it may be given right after the where clause or among the
+// clauses following the order by clause.
+//
+// <group_by_clause> ::= GROUP BY <operand> [{<comma><operand>}...]
+//
+// <having_clause> ::= HAVING <expression>
+//
+// <order_by_clause> ::= ORDER BY <sort_key> [{<comma><sort_key>}...]
+//
+// <sort_key> ::= <operand> [ASC | DESC]
 //
 // <escape_limit_offset_clause> ::=
 //   ESCAPE <char_literal>
@@ -49,7 +121,13 @@
 //
 // <function> ::= <identifier><left_paren>[<operand>[{<comma><operand>}...]<right_paren>
 //
-// <table> ::= <identifier>
+// <table> ::= <identifier> [<alias>]
+//
+// <alias> ::= <identifier>
+//
+// In select statements with joins (and only in such statements), a table may
+// be given an alias and every field (k or v[<period><field>] above) must be
+// qualified with the name or alias of its table, e.g., c.k or c.v.Name.
 //
 // <expression> ::=
 //   <left_paren> <expression> <right_paren>
@@ -65,6 +143,8 @@
 //
 // <binary_expression> ::=
 //   <operand> <binary_op> <operand>
+//   | <operand> [NOT] IN <left_paren> <operand> [{<comma><operand>}...] <right_paren>
+//   | <operand> [NOT] BETWEEN <operand> AND <operand>
 //   | v[<period><field>] IS [NOT] NIL
 //
 // <operand> ::=
@@ -72,6 +152,21 @@
 //   | v[<period><field>]
 //   | <literal>
 //   | <function>
+//   | <left_paren> <operand> <right_paren>
+//   | <minus> <operand>
+//   | <operand> <arithmetic_op> <operand>
+//
+// <arithmetic_op> ::=
+//   *
+//   | /
+//   | %
+//   | +
+//   | -
+//
+// *, / and % take precedence over + and -.  The operands of arithmetic
+// operators must be numeric; they are converted to a common type as they are
+// for comparisons (e.g., int64 and float64 operands to float64).  Integer
+// division truncates and int or uint results that overflow become big ints.
 //
 // <binary_op> ::=
 //   =
@@ -93,4 +188,10 @@
 //
 // Example:
 // select v.Foo.Far, v.Baz[2] from Foobarbaz where Type(v) like "%.Customer" and (v.Foo = 42 and v.Bar not like "abc%) or (k >= "100" and  k < "200")
+// select c.v.Name, i.v.Amount from Customer c left join Invoice i on i.v.CustID = c.v.ID
+// select k from Order where v.Status in ("new", "paid") and v.Price * v.Qty between 100 and 1000
+// explain select k from Customer where k like "001%" limit 10
+// select distinct v.City from Customer union select v.City from Supplier limit 10
+// update Customer set v.Active = false where Type(v) like "%.Customer" and v.Id = 2
+// insert into Customer (k, v) values ("004", ?)
 package query_parser
@@ -7,6 +7,7 @@ package query_parser
 import (
 	"fmt"
 	"math/big"
+	"regexp"
 	"strconv"
 	"strings"
 	"text/scanner"
@@ -22,7 +23,8 @@ import (
 type TokenType int
 
 const (
-	TokCHAR TokenType = 1 + iota
+	TokASTERISK TokenType = 1 + iota
+	TokCHAR
 	TokCOMMA
 	TokEOF
 	TokEQUAL
@@ -34,10 +36,13 @@ const (
 	TokLEFTPAREN
 	TokMINUS
 	TokParameter // allowed only in prepared statements
+	TokPERCENT
 	TokPERIOD
+	TokPLUS
 	TokRIGHTANGLEBRACKET
 	TokRIGHTBRACKET
 	TokRIGHTPAREN
+	TokSLASH
 	TokSTRING
 	TokERROR
 )
@@ -70,6 +75,11 @@ type Segment struct {
 
 type Field struct {
 	Segments []Segment
+	// In select statements with joins, fields begin with the name or alias
+	// of a table.  Such fields are qualified and Table is the index of the
+	// table in the from clause (0 for the first table, i for the ith join).
+	Qualified bool
+	Table     int
 	Node
 }
 
@@ -88,6 +98,18 @@ const (
 	NotEqual
 	NotLike
 	Or
+	// In, NotIn, Between and NotBetween expressions have a TypList Operand2
+	// (the values or the lower and upper bounds, respectively).
+	Between
+	In
+	NotBetween
+	NotIn
+	// Arithmetic operators (i.e., the operators of TypArithmetic operands).
+	Plus
+	Minus
+	Times
+	Divide
+	Modulo
 )
 
 type BinaryOperator struct {
@@ -110,8 +132,10 @@ const (
 	TypParameter
 	TypStr
 	TypTime
-	TypObject // Only as the result of a ResolveOperand
-	TypUint   // Only as the result of a ResolveOperand
+	TypObject     // Only as the result of a ResolveOperand
+	TypUint       // Only as the result of a ResolveOperand
+	TypArithmetic // Expr is an arithmetic expression (e.g., v.Price * v.Qty)
+	TypList       // List is the values of an in expression or the bounds of a between expression
 )
 
 type Operand struct {
@@ -127,9 +151,11 @@ type Operand struct {
 	Time     time.Time
 	Prefix   string           // Computed by checker for Like expressions
 	Pattern  *pattern.Pattern // Computed by checker for Like expressions
+	Regexp   *regexp.Regexp   // Computed by checker for regular expression args
 	Uint     uint64
 	Expr     *Expression
 	Object   *vdl.Value
+	List     []*Operand
 	Node
 }
 
@@ -180,21 +206,38 @@ type Name struct {
 }
 
 type SelectClause struct {
+	Distinct  bool // Duplicate rows are eliminated from the results.
 	Selectors []Selector
 	Node
 }
 
 type FromClause struct {
 	Table TableEntry
+	Joins []*JoinClause
 	Node
 }
 
 type TableEntry struct {
 	Name    string
+	Alias   string   // Empty if no alias is specified.
 	DBTable ds.Table // Checker gets table from db and sets this.
 	Node
 }
 
+type JoinType int
+
+const (
+	InnerJoin JoinType = 1 + iota
+	LeftJoin
+)
+
+type JoinClause struct {
+	Type  JoinType
+	Table TableEntry
+	On    *Expression
+	Node
+}
+
 type WhereClause struct {
 	Expr *Expression
 	Node
@@ -225,16 +268,61 @@ type ResultsOffsetClause struct {
 	Node
 }
 
+// GroupByClause: the keys can be fields (k or v[{.<ident>}...]) or functions.
+type GroupByClause struct {
+	Keys []*Operand
+	Node
+}
+
+type HavingClause struct {
+	Expr *Expression
+	Node
+}
+
+type SortDirection int
+
+const (
+	Ascending SortDirection = 1 + iota
+	Descending
+)
+
+// SortKey: entries in the order by clause.
+// Keys can be fields (k or v[{.<ident>}...]) or functions.
+type SortKey struct {
+	Operand   *Operand
+	Direction SortDirection
+	Node
+}
+
+type OrderByClause struct {
+	Keys []SortKey
+	Node
+}
+
 type SelectStatement struct {
 	Select        *SelectClause
 	From          *FromClause
 	Where         *WhereClause
+	GroupBy       *GroupByClause
+	Having        *HavingClause
+	OrderBy       *OrderByClause
 	Escape        *EscapeClause
 	Limit         *LimitClause
 	ResultsOffset *ResultsOffsetClause
 	Node
 }
 
+// A UnionStatement combines the results of two or more select statements,
+// each of which must have the same number of columns.  The limit and offset
+// clauses following the last select statement apply to the union.
+type UnionStatement struct {
+	Selects       []SelectStatement
+	All           []bool // All[i] is true if Selects[i+1] is preceded by union all.
+	Limit         *LimitClause
+	ResultsOffset *ResultsOffsetClause
+	Node
+}
+
 type DeleteStatement struct {
 	From   *FromClause
 	Where  *WhereClause
@@ -243,6 +331,48 @@ type DeleteStatement struct {
 	Node
 }
 
+// An ExplainStatement is a statement to be explained rather than executed.
+type ExplainStatement struct {
+	Statement Statement // any statement but an ExplainStatement
+	Node
+}
+
+// An UpdateStatement sets fields of the values of the rows of a table.
+type UpdateStatement struct {
+	Table  *TableEntry
+	Set    *SetClause
+	Where  *WhereClause
+	Escape *EscapeClause
+	Limit  *LimitClause
+	Node
+}
+
+type SetClause struct {
+	Assignments []*Assignment
+	Node
+}
+
+// An Assignment sets a field of the value (v[{.<ident>}...]) to an operand.
+type Assignment struct {
+	Field   *Field
+	Operand *Operand
+	Node
+}
+
+// An InsertStatement inserts rows into a table.
+type InsertStatement struct {
+	Table   *TableEntry
+	Columns []*Field // k and v (in either order)
+	Rows    []*InsertRow
+	Node
+}
+
+// InsertRow: a row of the values of an insert statement (one per column).
+type InsertRow struct {
+	Values []*Operand
+	Node
+}
+
 func scanToken(s *scanner.Scanner) *Token {
 	// TODO(jkline): Replace golang text/scanner.
 	var token Token
@@ -262,6 +392,14 @@ func scanToken(s *scanner.Scanner) *Token {
 		token.Tok = TokCOMMA
 	case '-':
 		token.Tok = TokMINUS
+	case '+':
+		token.Tok = TokPLUS
+	case '*':
+		token.Tok = TokASTERISK
+	case '/':
+		token.Tok = TokSLASH
+	case '%':
+		token.Tok = TokPERCENT
 	case '(':
 		token.Tok = TokLEFTPAREN
 	case ')':
@@ -336,13 +474,87 @@ func Parse(db ds.Database, src string) (*Statement, error) {
 		var err error
 		st, token, err = deleteStatement(db, &s, token)
 		return &st, err
+	case "explain":
+		var st Statement
+		var err error
+		st, token, err = explainStatement(db, &s, token)
+		return &st, err
+	case "update":
+		var st Statement
+		var err error
+		st, token, err = updateStatement(db, &s, token)
+		return &st, err
+	case "insert":
+		var st Statement
+		var err error
+		st, token, err = insertStatement(db, &s, token)
+		return &st, err
 	default:
 		return nil, syncql.NewErrUnknownIdentifier(db.GetContext(), token.Off, token.Value)
 	}
 }
 
-// Parse select.
+// Parse select (or a union of selects).
 func selectStatement(db ds.Database, s *scanner.Scanner, token *Token) (Statement, *Token, error) {
+	st, token, err := parseSelect(db, s, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if isKeyword(token, "union") {
+		return unionStatement(db, s, st, token)
+	}
+
+	// There can be nothing remaining for the current statement
+	if token.Tok != TokEOF {
+		return nil, nil, syncql.NewErrUnexpected(db.GetContext(), token.Off, token.Value)
+	}
+	return *st, token, nil
+}
+
+// Parse the select statements following the first select of a union.
+func unionStatement(db ds.Database, s *scanner.Scanner, first *SelectStatement, token *Token) (Statement, *Token, error) {
+	var st UnionStatement
+	st.Off = first.Off
+	st.Selects = append(st.Selects, *first)
+	for isKeyword(token, "union") {
+		token = scanToken(s) // eat the union
+		all := isKeyword(token, "all")
+		if all {
+			token = scanToken(s)
+		}
+		if token.Tok == TokEOF {
+			return nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
+		}
+		if !isKeyword(token, "select") {
+			return nil, nil, syncql.NewErrExpected(db.GetContext(), token.Off, "select")
+		}
+		sel, t, err := parseSelect(db, s, token)
+		if err != nil {
+			return nil, nil, err
+		}
+		token = t
+		st.Selects = append(st.Selects, *sel)
+		st.All = append(st.All, all)
+	}
+
+	// There can be nothing remaining for the current statement
+	if token.Tok != TokEOF {
+		return nil, nil, syncql.NewErrUnexpected(db.GetContext(), token.Off, token.Value)
+	}
+
+	// The limit and offset clauses of the last select apply to the union.
+	last := &st.Selects[len(st.Selects)-1]
+	st.Limit, st.ResultsOffset = last.Limit, last.ResultsOffset
+	last.Limit, last.ResultsOffset = nil, nil
+	return st, token, nil
+}
+
+func isKeyword(token *Token, word string) bool {
+	return token.Tok == TokIDENT && strings.ToLower(token.Value) == word
+}
+
+// Parse a single select statement, which may be followed by union.
+func parseSelect(db ds.Database, s *scanner.Scanner, token *Token) (*SelectStatement, *Token, error) {
 	var st SelectStatement
 	st.Off = token.Off
 
@@ -358,22 +570,62 @@ func selectStatement(db ds.Database, s *scanner.Scanner, token *Token) (Statemen
 		return nil, nil, err
 	}
 
+	// Aliases are only allowed in select statements with joins.
+	aliasOff := token.Off
+	st.From.Table.Alias, token = parseTableAlias(s, token)
+
+	st.From.Joins, token, err = parseJoinClauses(db, s, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(st.From.Joins) == 0 && st.From.Table.Alias != "" {
+		return nil, nil, syncql.NewErrUnexpected(db.GetContext(), aliasOff, st.From.Table.Alias)
+	}
+
 	st.Where, token, err = parseWhereClause(db, s, token)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	st.Escape, st.Limit, st.ResultsOffset, token, err = parseEscapeLimitResultsOffsetClauses(db, s, token)
+	// The escape clause may directly follow the where clause.
+	if st.Where != nil && token.Tok == TokIDENT && strings.ToLower(token.Value) == "escape" {
+		st.Escape, token, err = parseEscapeClause(db, s, token)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	st.GroupBy, token, err = parseGroupByClause(db, s, token)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// There can be nothing remaining for the current statement
-	if token.Tok != TokEOF {
-		return nil, nil, syncql.NewErrUnexpected(db.GetContext(), token.Off, token.Value)
+	st.Having, token, err = parseHavingClause(db, s, token)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return st, token, nil
+	st.OrderBy, token, err = parseOrderByClause(db, s, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var escape *EscapeClause
+	escape, st.Limit, st.ResultsOffset, token, err = parseEscapeLimitResultsOffsetClauses(db, s, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if escape != nil {
+		st.Escape = escape
+	}
+
+	if len(st.From.Joins) > 0 {
+		if err := st.qualifyFields(db); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return &st, token, nil
 }
 
 // Parse delete.
@@ -412,6 +664,235 @@ func deleteStatement(db ds.Database, s *scanner.Scanner, token *Token) (Statemen
 	return st, token, nil
 }
 
+// Parse explain.
+func explainStatement(db ds.Database, s *scanner.Scanner, token *Token) (Statement, *Token, error) {
+	var st ExplainStatement
+	st.Off = token.Off
+
+	token = scanToken(s) // eat the explain
+	if token.Tok == TokEOF {
+		return nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
+	}
+	if token.Tok != TokIDENT {
+		return nil, nil, syncql.NewErrExpectedIdentifier(db.GetContext(), token.Off, token.Value)
+	}
+	var err error
+	switch strings.ToLower(token.Value) {
+	case "select":
+		st.Statement, token, err = selectStatement(db, s, token)
+	case "delete":
+		st.Statement, token, err = deleteStatement(db, s, token)
+	case "update":
+		st.Statement, token, err = updateStatement(db, s, token)
+	case "insert":
+		st.Statement, token, err = insertStatement(db, s, token)
+	default:
+		return nil, nil, syncql.NewErrUnknownIdentifier(db.GetContext(), token.Off, token.Value)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return st, token, nil
+}
+
+// Parse update.
+func updateStatement(db ds.Database, s *scanner.Scanner, token *Token) (Statement, *Token, error) {
+	var st UpdateStatement
+	st.Off = token.Off
+
+	token = scanToken(s) // eat the update
+	var err error
+	if st.Table, token, err = parseTableName(db, s, token); err != nil {
+		return nil, nil, err
+	}
+
+	if st.Set, token, err = parseSetClause(db, s, token); err != nil {
+		return nil, nil, err
+	}
+
+	// parse WhereClause
+	st.Where, token, err = parseWhereClause(db, s, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	st.Escape, st.Limit, token, err = parseEscapeLimitClauses(db, s, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// There can be nothing remaining for the current statement
+	if token.Tok != TokEOF {
+		return nil, nil, syncql.NewErrUnexpected(db.GetContext(), token.Off, token.Value)
+	}
+
+	return st, token, nil
+}
+
+// Parse insert.
+func insertStatement(db ds.Database, s *scanner.Scanner, token *Token) (Statement, *Token, error) {
+	var st InsertStatement
+	st.Off = token.Off
+
+	token = scanToken(s) // eat the insert
+	var err error
+	if token, err = expectWord(db, s, token, "into"); err != nil {
+		return nil, nil, err
+	}
+	if st.Table, token, err = parseTableName(db, s, token); err != nil {
+		return nil, nil, err
+	}
+
+	// parse the columns
+	if token, err = expectToken(db, s, token, TokLEFTPAREN, "("); err != nil {
+		return nil, nil, err
+	}
+	for {
+		var column *Field
+		if column, token, err = parseField(db, s, token); err != nil {
+			return nil, nil, err
+		}
+		st.Columns = append(st.Columns, column)
+		if token.Tok != TokCOMMA {
+			break
+		}
+		token = scanToken(s) // eat the comma
+	}
+	if token, err = expectToken(db, s, token, TokRIGHTPAREN, ")"); err != nil {
+		return nil, nil, err
+	}
+
+	// parse the rows of values
+	if token, err = expectWord(db, s, token, "values"); err != nil {
+		return nil, nil, err
+	}
+	for {
+		var row InsertRow
+		row.Off = token.Off
+		if token, err = expectToken(db, s, token, TokLEFTPAREN, "("); err != nil {
+			return nil, nil, err
+		}
+		for {
+			var value *Operand
+			if value, token, err = parseOperand(db, s, token); err != nil {
+				return nil, nil, err
+			}
+			row.Values = append(row.Values, value)
+			if token.Tok != TokCOMMA {
+				break
+			}
+			token = scanToken(s) // eat the comma
+		}
+		if token, err = expectToken(db, s, token, TokRIGHTPAREN, ")"); err != nil {
+			return nil, nil, err
+		}
+		st.Rows = append(st.Rows, &row)
+		if token.Tok != TokCOMMA {
+			break
+		}
+		token = scanToken(s) // eat the comma
+	}
+
+	// There can be nothing remaining for the current statement
+	if token.Tok != TokEOF {
+		return nil, nil, syncql.NewErrUnexpected(db.GetContext(), token.Off, token.Value)
+	}
+
+	return st, token, nil
+}
+
+// Parse the name of the table of an update or insert statement.  Return the
+// TableEntry and next Token (or error).
+func parseTableName(db ds.Database, s *scanner.Scanner, token *Token) (*TableEntry, *Token, error) {
+	if token.Tok == TokEOF {
+		return nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
+	}
+	if token.Tok != TokIDENT {
+		return nil, nil, syncql.NewErrExpectedIdentifier(db.GetContext(), token.Off, token.Value)
+	}
+	var table TableEntry
+	table.Off = token.Off
+	table.Name = token.Value
+	token = scanToken(s)
+	return &table, token, nil
+}
+
+// Parse the set clause of an update statement.  Return SetClause and next Token (or error).
+func parseSetClause(db ds.Database, s *scanner.Scanner, token *Token) (*SetClause, *Token, error) {
+	var setClause SetClause
+	setClause.Off = token.Off
+	var err error
+	if token, err = expectWord(db, s, token, "set"); err != nil {
+		return nil, nil, err
+	}
+	for {
+		var assignment Assignment
+		assignment.Off = token.Off
+		if assignment.Field, token, err = parseField(db, s, token); err != nil {
+			return nil, nil, err
+		}
+		if token, err = expectToken(db, s, token, TokEQUAL, "="); err != nil {
+			return nil, nil, err
+		}
+		if assignment.Operand, token, err = parseOperand(db, s, token); err != nil {
+			return nil, nil, err
+		}
+		setClause.Assignments = append(setClause.Assignments, &assignment)
+		if token.Tok != TokCOMMA {
+			break
+		}
+		token = scanToken(s) // eat the comma
+	}
+	return &setClause, token, nil
+}
+
+// Parse a field (i.e., <segment>[{<period><segment>}...]).  Return the Field
+// and next Token (or error).
+func parseField(db ds.Database, s *scanner.Scanner, token *Token) (*Field, *Token, error) {
+	var field Field
+	field.Off = token.Off
+	for {
+		if token.Tok == TokEOF {
+			return nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
+		}
+		if token.Tok != TokIDENT {
+			return nil, nil, syncql.NewErrExpectedIdentifier(db.GetContext(), token.Off, token.Value)
+		}
+		var segment *Segment
+		var err error
+		if segment, token, err = parseSegment(db, s, token); err != nil {
+			return nil, nil, err
+		}
+		field.Segments = append(field.Segments, *segment)
+		if token.Tok != TokPERIOD {
+			return &field, token, nil
+		}
+		token = scanToken(s) // eat the period
+	}
+}
+
+// Expect the (case insensitive) word.  Return the next Token (or error).
+func expectWord(db ds.Database, s *scanner.Scanner, token *Token, word string) (*Token, error) {
+	if token.Tok == TokEOF {
+		return nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
+	}
+	if token.Tok != TokIDENT || strings.ToLower(token.Value) != word {
+		return nil, syncql.NewErrExpected(db.GetContext(), token.Off, word)
+	}
+	return scanToken(s), nil
+}
+
+// Expect a token of type tok (which is, e.g., "(").  Return the next Token (or error).
+func expectToken(db ds.Database, s *scanner.Scanner, token *Token, tok TokenType, expected string) (*Token, error) {
+	if token.Tok == TokEOF {
+		return nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
+	}
+	if token.Tok != tok {
+		return nil, syncql.NewErrExpected(db.GetContext(), token.Off, expected)
+	}
+	return scanToken(s), nil
+}
+
 // Parse the select clause (fields). Return *SelectClause, next token (or error).
 func parseSelectClause(db ds.Database, s *scanner.Scanner, token *Token) (*SelectClause, *Token, error) {
 	// must be at least one selector or it is an error
@@ -420,6 +901,10 @@ func parseSelectClause(db ds.Database, s *scanner.Scanner, token *Token) (*Selec
 	var selectClause SelectClause
 	selectClause.Off = token.Off
 	token = scanToken(s) // eat the select
+	if isKeyword(token, "distinct") {
+		selectClause.Distinct = true
+		token = scanToken(s)
+	}
 	if token.Tok == TokEOF {
 		return nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
 	}
@@ -553,6 +1038,84 @@ func parseFromClause(db ds.Database, s *scanner.Scanner, token *Token) (*FromCla
 	return &fromClause, token, nil
 }
 
+// Words that can follow a table name in a from clause and, as such, cannot
+// be used as table aliases.
+var reservedAfterTable = map[string]bool{
+	"escape": true,
+	"group":  true,
+	"having": true,
+	"inner":  true,
+	"join":   true,
+	"left":   true,
+	"limit":  true,
+	"offset": true,
+	"on":     true,
+	"order":  true,
+	"union":  true,
+	"where":  true,
+}
+
+// Parse the alias (if any) of a table.  Return the alias (could be empty) and next Token.
+func parseTableAlias(s *scanner.Scanner, token *Token) (string, *Token) {
+	if token.Tok != TokIDENT || reservedAfterTable[strings.ToLower(token.Value)] {
+		return "", token
+	}
+	return token.Value, scanToken(s)
+}
+
+// Parse the join clauses (if any).  Return the JoinClauses (could be empty) and next Token or error.
+func parseJoinClauses(db ds.Database, s *scanner.Scanner, token *Token) ([]*JoinClause, *Token, error) {
+	var joins []*JoinClause
+	for token.Tok == TokIDENT {
+		var join JoinClause
+		join.Off = token.Off
+		join.Type = InnerJoin
+		switch strings.ToLower(token.Value) {
+		case "inner":
+			token = scanToken(s) // eat inner
+		case "left":
+			join.Type = LeftJoin
+			token = scanToken(s) // eat left
+			if token.Tok == TokIDENT && strings.ToLower(token.Value) == "outer" {
+				token = scanToken(s) // eat outer
+			}
+		case "join":
+		default:
+			return joins, token, nil
+		}
+		if token.Tok == TokEOF {
+			return nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
+		}
+		if token.Tok != TokIDENT || strings.ToLower(token.Value) != "join" {
+			return nil, nil, syncql.NewErrExpected(db.GetContext(), token.Off, "join")
+		}
+		token = scanToken(s) // eat join
+		if token.Tok == TokEOF {
+			return nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
+		}
+		if token.Tok != TokIDENT {
+			return nil, nil, syncql.NewErrExpectedIdentifier(db.GetContext(), token.Off, token.Value)
+		}
+		join.Table.Off = token.Off
+		join.Table.Name = token.Value
+		token = scanToken(s)
+		join.Table.Alias, token = parseTableAlias(s, token)
+		if token.Tok == TokEOF {
+			return nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
+		}
+		if token.Tok != TokIDENT || strings.ToLower(token.Value) != "on" {
+			return nil, nil, syncql.NewErrExpected(db.GetContext(), token.Off, "on")
+		}
+		token = scanToken(s) // eat on
+		var err error
+		if join.On, token, err = parseExpression(db, s, token); err != nil {
+			return nil, nil, err
+		}
+		joins = append(joins, &join)
+	}
+	return joins, token, nil
+}
+
 // Parse the where clause (if any).  Return WhereClause (could be nil) and and next Token or error.
 func parseWhereClause(db ds.Database, s *scanner.Scanner, token *Token) (*WhereClause, *Token, error) {
 	// parse Optional where clause
@@ -575,50 +1138,147 @@ func parseWhereClause(db ds.Database, s *scanner.Scanner, token *Token) (*WhereC
 	}
 }
 
-// Parse a parenthesized expression.  Return expression and next token (or error)
-func parseParenthesizedExpression(db ds.Database, s *scanner.Scanner, token *Token) (*Expression, *Token, error) {
+// Parse the group by clause (if any).  Return GroupByClause (could be nil) and next Token or error.
+func parseGroupByClause(db ds.Database, s *scanner.Scanner, token *Token) (*GroupByClause, *Token, error) {
+	if token.Tok != TokIDENT || strings.ToLower(token.Value) != "group" {
+		return nil, token, nil
+	}
+	var groupBy GroupByClause
+	groupBy.Off = token.Off
+	token = scanToken(s) // eat group
+	if token.Tok == TokEOF {
+		return nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
+	}
+	if token.Tok != TokIDENT || strings.ToLower(token.Value) != "by" {
+		return nil, nil, syncql.NewErrExpected(db.GetContext(), token.Off, "by")
+	}
+	token = scanToken(s) // eat by
+	for {
+		var key *Operand
+		var err error
+		if key, token, err = parseOperand(db, s, token); err != nil {
+			return nil, nil, err
+		}
+		groupBy.Keys = append(groupBy.Keys, key)
+		if token.Tok != TokCOMMA {
+			break
+		}
+		token = scanToken(s) // eat comma
+	}
+	return &groupBy, token, nil
+}
+
+// Parse the having clause (if any).  Return HavingClause (could be nil) and next Token or error.
+func parseHavingClause(db ds.Database, s *scanner.Scanner, token *Token) (*HavingClause, *Token, error) {
+	if token.Tok != TokIDENT || strings.ToLower(token.Value) != "having" {
+		return nil, token, nil
+	}
+	var having HavingClause
+	having.Off = token.Off
+	token = scanToken(s) // eat having
+	var err error
+	if having.Expr, token, err = parseExpression(db, s, token); err != nil {
+		return nil, nil, err
+	}
+	return &having, token, nil
+}
+
+// Parse the order by clause (if any).  Return OrderByClause (could be nil) and next Token or error.
+func parseOrderByClause(db ds.Database, s *scanner.Scanner, token *Token) (*OrderByClause, *Token, error) {
+	if token.Tok != TokIDENT || strings.ToLower(token.Value) != "order" {
+		return nil, token, nil
+	}
+	var orderBy OrderByClause
+	orderBy.Off = token.Off
+	token = scanToken(s) // eat order
+	if token.Tok == TokEOF {
+		return nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
+	}
+	if token.Tok != TokIDENT || strings.ToLower(token.Value) != "by" {
+		return nil, nil, syncql.NewErrExpected(db.GetContext(), token.Off, "by")
+	}
+	token = scanToken(s) // eat by
+	for {
+		var key SortKey
+		key.Off = token.Off
+		key.Direction = Ascending
+		var err error
+		if key.Operand, token, err = parseOperand(db, s, token); err != nil {
+			return nil, nil, err
+		}
+		if token.Tok == TokIDENT {
+			switch strings.ToLower(token.Value) {
+			case "asc":
+				token = scanToken(s)
+			case "desc":
+				key.Direction = Descending
+				token = scanToken(s)
+			}
+		}
+		orderBy.Keys = append(orderBy.Keys, key)
+		if token.Tok != TokCOMMA {
+			break
+		}
+		token = scanToken(s) // eat comma
+	}
+	return &orderBy, token, nil
+}
+
+// Parse a parenthesized expression.  As parenthesized arithmetic expressions
+// can also begin a comparison (e.g., (v.A + 1) * 2 > 10), the parentheses may
+// instead contain an operand, in which case the expression returned is nil.
+// Return expression or operand and next token (or error)
+func parseParenthesizedExpression(db ds.Database, s *scanner.Scanner, token *Token) (*Expression, *Operand, *Token, error) {
 	// Only called when token == TokLEFTPAREN
 	token = scanToken(s) // eat '('
-	var expr *Expression
-	var err error
-	expr, token, err = parseExpression(db, s, token)
+	expr, operand, token, err := parseExpressionOrOperand(db, s, token)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	// Expect right paren
 	if token.Tok == TokEOF {
-		return nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
+		return nil, nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
 	}
 	if token.Tok != TokRIGHTPAREN {
-		return nil, nil, syncql.NewErrExpected(db.GetContext(), token.Off, ")")
+		return nil, nil, nil, syncql.NewErrExpected(db.GetContext(), token.Off, ")")
 	}
 	token = scanToken(s) // eat ')'
-	return expr, token, nil
+	return expr, operand, token, nil
 }
 
 // Parse an expression.  Return expression and next token (or error)
 func parseExpression(db ds.Database, s *scanner.Scanner, token *Token) (*Expression, *Token, error) {
-	if token.Tok == TokEOF {
-		return nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
+	expr, _, token, err := parseExpressionOrOperand(db, s, token)
+	if err != nil {
+		return nil, nil, err
 	}
+	if expr == nil {
+		// An operand followed by ')' (with no matching '(').
+		return nil, nil, syncql.NewErrExpectedOperator(db.GetContext(), token.Off, token.Value)
+	}
+	return expr, token, nil
+}
 
-	var err error
-	var expr *Expression
-
-	if token.Tok == TokLEFTPAREN {
-		expr, token, err = parseParenthesizedExpression(db, s, token)
-	} else {
-		// We expect a like/equal expression
-		expr, token, err = parseLikeEqualExpression(db, s, token)
+// Parse an expression or, if followed by ')', an operand (see
+// parseParenthesizedExpression).  Return expression or operand and next
+// token (or error)
+func parseExpressionOrOperand(db ds.Database, s *scanner.Scanner, token *Token) (*Expression, *Operand, *Token, error) {
+	if token.Tok == TokEOF {
+		return nil, nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
 	}
+
+	expr, operand, token, err := parseLikeEqualExpression(db, s, token)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+	if expr == nil {
+		return nil, operand, token, nil
 	}
 
 	for token.Tok != TokEOF && token.Tok != TokRIGHTPAREN {
 		// There is more.  If not 'and', 'or' or ')', the where is over.
 		if strings.ToLower(token.Value) != "and" && strings.ToLower(token.Value) != "or" {
-			return expr, token, nil
+			return expr, nil, token, nil
 		}
 		var newExpression Expression
 		var operand1 Operand
@@ -630,64 +1290,135 @@ func parseExpression(db ds.Database, s *scanner.Scanner, token *Token) (*Express
 
 		newExpression.Operator, token, err = parseLogicalOperator(db, s, token)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 
 		expr = &newExpression
 		// Need to set operand2.
 		var operand2 Operand
 		expr.Operand2 = &operand2
-		if token.Tok == TokLEFTPAREN {
-			expr.Operand2.Type = TypExpr
-			expr.Operand2.Expr, token, err = parseParenthesizedExpression(db, s, token)
-		} else {
-			expr.Operand2.Type = TypExpr
-			expr.Operand2.Expr, token, err = parseLikeEqualExpression(db, s, token)
-		}
+		expr.Operand2.Type = TypExpr
+		var rhsOperand *Operand
+		expr.Operand2.Expr, rhsOperand, token, err = parseLikeEqualExpression(db, s, token)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
+		}
+		if rhsOperand != nil {
+			// An operand followed by ')'
+			return nil, nil, nil, syncql.NewErrExpectedOperator(db.GetContext(), token.Off, token.Value)
 		}
 		expr.Operand2.Off = expr.Operand2.Expr.Off
 	}
 
-	return expr, token, nil
+	return expr, nil, token, nil
 }
 
-// Parse a binary expression.  Return expression and next token (or error)
-func parseLikeEqualExpression(db ds.Database, s *scanner.Scanner, token *Token) (*Expression, *Token, error) {
+// Parse a binary expression (which may be parenthesized).  If the first
+// operand is followed by ')', return it rather than an expression.
+// Return expression or operand and next token (or error)
+func parseLikeEqualExpression(db ds.Database, s *scanner.Scanner, token *Token) (*Expression, *Operand, *Token, error) {
 	var expression Expression
 	expression.Off = token.Off
 
 	// operand 1
 	var operand1 *Operand
 	var err error
-	operand1, token, err = parseOperand(db, s, token)
-	if err != nil {
-		return nil, nil, err
+	if token.Tok == TokLEFTPAREN {
+		var expr *Expression
+		if expr, operand1, token, err = parseParenthesizedExpression(db, s, token); err != nil {
+			return nil, nil, nil, err
+		}
+		if expr != nil {
+			return expr, nil, token, nil
+		}
+		// A parenthesized operand, which may be the first of an arithmetic expression.
+		if operand1, token, err = parseArithmetic(db, s, operand1, token); err != nil {
+			return nil, nil, nil, err
+		}
+	} else if operand1, token, err = parseOperand(db, s, token); err != nil {
+		return nil, nil, nil, err
+	}
+	if token.Tok == TokRIGHTPAREN {
+		return nil, operand1, token, nil
 	}
 
 	// operator
 	var operator *BinaryOperator
 	operator, token, err = parseBinaryOperator(db, s, token)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// operand 2
 	var operand2 *Operand
 	if token.Tok == TokEOF {
-		return nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
+		return nil, nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
+	}
+	switch operator.Type {
+	case In, NotIn:
+		operand2, token, err = parseInList(db, s, token)
+	case Between, NotBetween:
+		operand2, token, err = parseBetweenBounds(db, s, token)
+	default:
+		operand2, token, err = parseOperand(db, s, token)
 	}
-	operand2, token, err = parseOperand(db, s, token)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	expression.Operand1 = operand1
 	expression.Operator = operator
 	expression.Operand2 = operand2
 
-	return &expression, token, nil
+	return &expression, nil, token, nil
+}
+
+// Parse the parenthesized list of values of an in expression.  Return the
+// (TypList) operand and next token (or error)
+func parseInList(db ds.Database, s *scanner.Scanner, token *Token) (*Operand, *Token, error) {
+	var list Operand
+	list.Type = TypList
+	list.Off = token.Off
+	var err error
+	if token, err = expectToken(db, s, token, TokLEFTPAREN, "("); err != nil {
+		return nil, nil, err
+	}
+	for {
+		var value *Operand
+		if value, token, err = parseOperand(db, s, token); err != nil {
+			return nil, nil, err
+		}
+		list.List = append(list.List, value)
+		if token.Tok != TokCOMMA {
+			break
+		}
+		token = scanToken(s) // eat the comma
+	}
+	if token, err = expectToken(db, s, token, TokRIGHTPAREN, ")"); err != nil {
+		return nil, nil, err
+	}
+	return &list, token, nil
+}
+
+// Parse the bounds (<operand> AND <operand>) of a between expression.
+// Return the (TypList) operand and next token (or error)
+func parseBetweenBounds(db ds.Database, s *scanner.Scanner, token *Token) (*Operand, *Token, error) {
+	var bounds Operand
+	bounds.Type = TypList
+	bounds.Off = token.Off
+	lower, token, err := parseOperand(db, s, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if token, err = expectWord(db, s, token, "and"); err != nil {
+		return nil, nil, err
+	}
+	upper, token, err := parseOperand(db, s, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	bounds.List = []*Operand{lower, upper}
+	return &bounds, token, nil
 }
 
 func parseFunction(db ds.Database, s *scanner.Scanner, funcName string, funcOffset int64, token *Token) (*Function, *Token, error) {
@@ -721,8 +1452,76 @@ func parseFunction(db ds.Database, s *scanner.Scanner, funcName string, funcOffs
 	return &function, token, nil
 }
 
-// Parse an operand (field or literal) and return it and the next Token (or error)
+// Parse an operand (field, literal, function or arithmetic expression of them)
+// and return it and the next Token (or error)
 func parseOperand(db ds.Database, s *scanner.Scanner, token *Token) (*Operand, *Token, error) {
+	operand, token, err := parseSimpleOperand(db, s, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseArithmetic(db, s, operand, token)
+}
+
+var arithmeticOperators = map[TokenType]BinaryOperatorType{
+	TokPLUS:     Plus,
+	TokMINUS:    Minus,
+	TokASTERISK: Times,
+	TokSLASH:    Divide,
+	TokPERCENT:  Modulo,
+}
+
+// Parse the rest of an arithmetic expression whose first operand has been
+// parsed.  *, / and % take precedence over + and -; operators of equal
+// precedence are left associative.  If there is no arithmetic operator, the
+// first operand is returned.  Return the operand and the next Token (or error)
+func parseArithmetic(db ds.Database, s *scanner.Scanner, operand *Operand, token *Token) (*Operand, *Token, error) {
+	operand, token, err := parseArithmeticTerm(db, s, operand, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	for token.Tok == TokPLUS || token.Tok == TokMINUS {
+		operator := BinaryOperator{Type: arithmeticOperators[token.Tok], Node: Node{Off: token.Off}}
+		token = scanToken(s) // eat the operator
+		var rhs *Operand
+		if rhs, token, err = parseSimpleOperand(db, s, token); err != nil {
+			return nil, nil, err
+		}
+		if rhs, token, err = parseArithmeticTerm(db, s, rhs, token); err != nil {
+			return nil, nil, err
+		}
+		operand = arithmeticOperand(operand, &operator, rhs)
+	}
+	return operand, token, nil
+}
+
+// Parse the rest of a term (i.e., operands separated by *, / or %) whose
+// first operand has been parsed.  Return the operand and the next Token (or error)
+func parseArithmeticTerm(db ds.Database, s *scanner.Scanner, operand *Operand, token *Token) (*Operand, *Token, error) {
+	for token.Tok == TokASTERISK || token.Tok == TokSLASH || token.Tok == TokPERCENT {
+		operator := BinaryOperator{Type: arithmeticOperators[token.Tok], Node: Node{Off: token.Off}}
+		token = scanToken(s) // eat the operator
+		rhs, nextToken, err := parseSimpleOperand(db, s, token)
+		if err != nil {
+			return nil, nil, err
+		}
+		token = nextToken
+		operand = arithmeticOperand(operand, &operator, rhs)
+	}
+	return operand, token, nil
+}
+
+// arithmeticOperand returns the TypArithmetic operand: lhs <operator> rhs.
+func arithmeticOperand(lhs *Operand, operator *BinaryOperator, rhs *Operand) *Operand {
+	var operand Operand
+	operand.Type = TypArithmetic
+	operand.Off = lhs.Off
+	operand.Expr = &Expression{Operand1: lhs, Operator: operator, Operand2: rhs, Node: Node{Off: lhs.Off}}
+	return &operand
+}
+
+// Parse a simple operand (field, literal, function, parenthesized operand or
+// negated operand) and return it and the next Token (or error)
+func parseSimpleOperand(db ds.Database, s *scanner.Scanner, token *Token) (*Operand, *Token, error) {
 	if token.Tok == TokEOF {
 		return nil, nil, syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), token.Off)
 	}
@@ -817,9 +1616,26 @@ func parseOperand(db ds.Database, s *scanner.Scanner, token *Token) (*Operand, *
 			}
 			operand.Float = f
 		default:
-			return nil, nil, syncql.NewErrExpected(db.GetContext(), token.Off, "int or float")
+			// Unary minus, which is equivalent to 0 - <operand>.
+			negated, nextToken, err := parseSimpleOperand(db, s, token)
+			if err != nil {
+				return nil, nil, err
+			}
+			zero := Operand{Type: TypInt, Node: Node{Off: off}}
+			operator := BinaryOperator{Type: Minus, Node: Node{Off: off}}
+			return arithmeticOperand(&zero, &operator, negated), nextToken, nil
 		}
 		token = scanToken(s)
+	case TokLEFTPAREN:
+		var parenthesized *Operand
+		var err error
+		if parenthesized, token, err = parseOperand(db, s, scanToken(s)); err != nil {
+			return nil, nil, err
+		}
+		if token, err = expectToken(db, s, token, TokRIGHTPAREN, ")"); err != nil {
+			return nil, nil, err
+		}
+		return parenthesized, token, nil
 	case TokParameter:
 		operand.Type = TypParameter
 		token = scanToken(s)
@@ -852,16 +1668,25 @@ func parseBinaryOperator(db ds.Database, s *scanner.Scanner, token *Token) (*Bin
 		case "like":
 			operator.Type = Like
 			token = scanToken(s)
+		case "in":
+			operator.Type = In
+			token = scanToken(s)
+		case "between":
+			operator.Type = Between
+			token = scanToken(s)
 		case "not":
 			token = scanToken(s)
-			if token.Tok == TokEOF || (strings.ToLower(token.Value) != "equal" && strings.ToLower(token.Value) != "like") {
-				return nil, nil, syncql.NewErrExpected(db.GetContext(), token.Off, "'equal' or 'like'")
-			}
 			switch strings.ToLower(token.Value) {
 			case "equal":
 				operator.Type = NotEqual
-			default: //case "like":
+			case "like":
 				operator.Type = NotLike
+			case "in":
+				operator.Type = NotIn
+			case "between":
+				operator.Type = NotBetween
+			default:
+				return nil, nil, syncql.NewErrExpected(db.GetContext(), token.Off, "'equal', 'like', 'in' or 'between'")
 			}
 			token = scanToken(s)
 		default:
@@ -1041,10 +1866,153 @@ func (st SelectStatement) Offset() int64 {
 	return st.Off
 }
 
+func (st UnionStatement) Offset() int64 {
+	return st.Off
+}
+
 func (st DeleteStatement) Offset() int64 {
 	return st.Off
 }
 
+func (st ExplainStatement) Offset() int64 {
+	return st.Off
+}
+
+func (st UpdateStatement) Offset() int64 {
+	return st.Off
+}
+
+func (st InsertStatement) Offset() int64 {
+	return st.Off
+}
+
+// Path returns the segments of the field that follow the table name or alias
+// of a qualified field (i.e., the segments beginning with k or v).
+func (f Field) Path() []Segment {
+	if f.Qualified {
+		return f.Segments[1:]
+	}
+	return f.Segments
+}
+
+// qualifyFields qualifies the fields of a select statement with joins.  Each
+// field must begin with the name or alias of one of the statement's tables.
+func (st *SelectStatement) qualifyFields(db ds.Database) error {
+	tables := map[string]int{}
+	entries := []*TableEntry{&st.From.Table}
+	for _, join := range st.From.Joins {
+		entries = append(entries, &join.Table)
+	}
+	for i, t := range entries {
+		name := t.Qualifier()
+		if _, ok := tables[name]; ok {
+			return syncql.NewErrDuplicateTableName(db.GetContext(), t.Off, name)
+		}
+		tables[name] = i
+	}
+	q := qualifier{db, tables}
+	for _, selector := range st.Select.Selectors {
+		var err error
+		switch selector.Type {
+		case TypSelField:
+			err = q.field(selector.Field)
+		case TypSelFunc:
+			err = q.function(selector.Function)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, join := range st.From.Joins {
+		if err := q.expression(join.On); err != nil {
+			return err
+		}
+	}
+	if st.Where != nil {
+		if err := q.expression(st.Where.Expr); err != nil {
+			return err
+		}
+	}
+	if st.GroupBy != nil {
+		for _, key := range st.GroupBy.Keys {
+			if err := q.operand(key); err != nil {
+				return err
+			}
+		}
+	}
+	if st.Having != nil {
+		if err := q.expression(st.Having.Expr); err != nil {
+			return err
+		}
+	}
+	if st.OrderBy != nil {
+		for _, key := range st.OrderBy.Keys {
+			if err := q.operand(key.Operand); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// qualifier qualifies fields with the tables (by name or alias) of a select
+// statement.
+type qualifier struct {
+	db     ds.Database
+	tables map[string]int
+}
+
+func (q qualifier) field(f *Field) error {
+	table, ok := q.tables[f.Segments[0].Value]
+	if !ok {
+		return syncql.NewErrFieldNotQualified(q.db.GetContext(), f.Off)
+	}
+	f.Qualified = true
+	f.Table = table
+	for _, segment := range f.Segments {
+		for _, key := range segment.Keys {
+			if err := q.operand(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (q qualifier) function(f *Function) error {
+	for _, arg := range f.Args {
+		if err := q.operand(arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q qualifier) expression(e *Expression) error {
+	if err := q.operand(e.Operand1); err != nil {
+		return err
+	}
+	return q.operand(e.Operand2)
+}
+
+func (q qualifier) operand(o *Operand) error {
+	switch o.Type {
+	case TypExpr, TypArithmetic:
+		return q.expression(o.Expr)
+	case TypList:
+		for _, value := range o.List {
+			if err := q.operand(value); err != nil {
+				return err
+			}
+		}
+	case TypField:
+		return q.field(o.Column)
+	case TypFunction:
+		return q.function(o.Function)
+	}
+	return nil
+}
+
 // Pretty string of select statement.
 func (st SelectStatement) String() string {
 	val := fmt.Sprintf("Off(%d):", st.Off)
@@ -1057,6 +2025,15 @@ func (st SelectStatement) String() string {
 	if st.Where != nil {
 		val += " " + st.Where.String()
 	}
+	if st.GroupBy != nil {
+		val += " " + st.GroupBy.String()
+	}
+	if st.Having != nil {
+		val += " " + st.Having.String()
+	}
+	if st.OrderBy != nil {
+		val += " " + st.OrderBy.String()
+	}
 	if st.Escape != nil {
 		val += " " + st.Escape.String()
 	}
@@ -1069,6 +2046,28 @@ func (st SelectStatement) String() string {
 	return val
 }
 
+// Pretty string of union statement.
+func (st UnionStatement) String() string {
+	val := fmt.Sprintf("Off(%d):", st.Off)
+	for i, sel := range st.Selects {
+		if i > 0 {
+			val += " UNION"
+			if st.All[i-1] {
+				val += " ALL"
+			}
+			val += " "
+		}
+		val += "(" + sel.String() + ")"
+	}
+	if st.Limit != nil {
+		val += " " + st.Limit.String()
+	}
+	if st.ResultsOffset != nil {
+		val += " " + st.ResultsOffset.String()
+	}
+	return val
+}
+
 // Pretty string of delete statement.
 func (st DeleteStatement) String() string {
 	val := fmt.Sprintf("Off(%d):", st.Off)
@@ -1088,25 +2087,149 @@ func (st DeleteStatement) String() string {
 	return val
 }
 
+func (st ExplainStatement) String() string {
+	return fmt.Sprintf("Off(%d):EXPLAIN %s", st.Off, st.Statement.String())
+}
+
+func (st UpdateStatement) String() string {
+	val := fmt.Sprintf("Off(%d):UPDATE %s", st.Off, st.Table.String())
+	if st.Set != nil {
+		val += " " + st.Set.String()
+	}
+	if st.Where != nil {
+		val += " " + st.Where.String()
+	}
+	if st.Escape != nil {
+		val += " " + st.Escape.String()
+	}
+	if st.Limit != nil {
+		val += " " + st.Limit.String()
+	}
+	return val
+}
+
+func (st InsertStatement) String() string {
+	val := fmt.Sprintf("Off(%d):INSERT INTO %s (", st.Off, st.Table.String())
+	sep := ""
+	for _, column := range st.Columns {
+		val += sep + column.String()
+		sep = ","
+	}
+	val += ") VALUES"
+	sep = " "
+	for _, row := range st.Rows {
+		val += sep + row.String()
+		sep = ","
+	}
+	return val
+}
+
+func (s SetClause) String() string {
+	val := fmt.Sprintf("Off(%d):SET", s.Off)
+	sep := " "
+	for _, a := range s.Assignments {
+		val += sep + a.String()
+		sep = ","
+	}
+	return val
+}
+
+func (a Assignment) String() string {
+	return fmt.Sprintf("Off(%d):%s = %s", a.Off, a.Field.String(), a.Operand.String())
+}
+
+func (r InsertRow) String() string {
+	val := fmt.Sprintf("Off(%d):(", r.Off)
+	sep := ""
+	for _, value := range r.Values {
+		val += sep + value.String()
+		sep = ","
+	}
+	return val + ")"
+}
+
 func (st SelectStatement) CopyAndSubstitute(db ds.Database, paramValues []*vdl.Value) (Statement, error) {
+	pi := paramInfo{paramValues: paramValues, cursor: 0}
+	copy, tooManyOff, err := st.copyAndSubstitute(db, &pi)
+	if err != nil {
+		return nil, err
+	}
+	// Did any of the supplied values go unused?
+	if pi.cursor < len(paramValues) {
+		return nil, syncql.NewErrTooManyParamValuesSpecified(db.GetContext(), tooManyOff)
+	}
+	return *copy, nil
+}
+
+// copyAndSubstitute returns a copy of the select statement, substituting
+// values from pi, and the offset at which to report unused values.
+func (st SelectStatement) copyAndSubstitute(db ds.Database, pi *paramInfo) (*SelectStatement, int64, error) {
 	var copy SelectStatement
 	copy.Off = st.Off
-	copy.Select = st.Select
-	copy.From = st.From
+	copy.Select = st.Select.Copy()
+	// Parameters are substituted in the order they appear in the statement:
+	// first those in the join clauses, then those in the where clause and
+	// finally those in the group by, having and order by clauses.
+	tooManyOff := copy.Off
+	var err error
+	if copy.From, err = st.From.CopyAndSubstitute(db, pi); err != nil {
+		return nil, 0, err
+	}
 	if st.Where != nil {
-		var err error
-		if copy.Where, err = st.Where.CopyAndSubstitute(db, paramValues); err != nil {
-			return nil, err
+		var where WhereClause
+		where.Off = st.Where.Off
+		if where.Expr, err = st.Where.Expr.CopyAndSubstitute(db, pi); err != nil {
+			return nil, 0, err
 		}
-	} else {
-		// There is no where clause.  If any paramValues suppied, we have too many.
-		if len(paramValues) > 0 {
-			return nil, syncql.NewErrTooManyParamValuesSpecified(db.GetContext(), copy.Off)
+		copy.Where = &where
+		tooManyOff = where.Off
+	}
+	if st.GroupBy != nil {
+		if copy.GroupBy, err = st.GroupBy.CopyAndSubstitute(db, pi); err != nil {
+			return nil, 0, err
+		}
+	}
+	if st.Having != nil {
+		var having HavingClause
+		having.Off = st.Having.Off
+		if having.Expr, err = st.Having.Expr.CopyAndSubstitute(db, pi); err != nil {
+			return nil, 0, err
+		}
+		copy.Having = &having
+	}
+	if st.OrderBy != nil {
+		if copy.OrderBy, err = st.OrderBy.CopyAndSubstitute(db, pi); err != nil {
+			return nil, 0, err
 		}
 	}
 	copy.Escape = st.Escape
 	copy.Limit = st.Limit
 	copy.ResultsOffset = st.ResultsOffset
+	return &copy, tooManyOff, nil
+}
+
+// Parameters are substituted in the order the select statements appear in
+// the union.
+func (st UnionStatement) CopyAndSubstitute(db ds.Database, paramValues []*vdl.Value) (Statement, error) {
+	var copy UnionStatement
+	copy.Off = st.Off
+	pi := paramInfo{paramValues: paramValues, cursor: 0}
+	tooManyOff := copy.Off
+	for _, sel := range st.Selects {
+		selCopy, off, err := sel.copyAndSubstitute(db, &pi)
+		if err != nil {
+			return nil, err
+		}
+		copy.Selects = append(copy.Selects, *selCopy)
+		tooManyOff = off
+	}
+	// Did any of the supplied values go unused?
+	if pi.cursor < len(paramValues) {
+		return nil, syncql.NewErrTooManyParamValuesSpecified(db.GetContext(), tooManyOff)
+	}
+	copy.All = st.All
+	copy.Limit = st.Limit
+	copy.ResultsOffset = st.ResultsOffset
 	return copy, nil
 }
 
@@ -1130,8 +2253,84 @@ func (st DeleteStatement) CopyAndSubstitute(db ds.Database, paramValues []*vdl.V
 	return copy, nil
 }
 
+func (st UpdateStatement) CopyAndSubstitute(db ds.Database, paramValues []*vdl.Value) (Statement, error) {
+	var copy UpdateStatement
+	copy.Off = st.Off
+	copy.Table = st.Table
+	// Parameters in the set clause precede those in the where clause.
+	pi := paramInfo{paramValues: paramValues, cursor: 0}
+	tooManyOff := copy.Off
+	var set SetClause
+	set.Off = st.Set.Off
+	for _, a := range st.Set.Assignments {
+		assignment := *a
+		var err error
+		if assignment.Operand, err = a.Operand.CopyAndSubstitute(db, &pi); err != nil {
+			return nil, err
+		}
+		set.Assignments = append(set.Assignments, &assignment)
+	}
+	copy.Set = &set
+	if st.Where != nil {
+		var where WhereClause
+		where.Off = st.Where.Off
+		var err error
+		if where.Expr, err = st.Where.Expr.CopyAndSubstitute(db, &pi); err != nil {
+			return nil, err
+		}
+		copy.Where = &where
+		tooManyOff = where.Off
+	}
+	// Did any of the supplied values go unused?
+	if pi.cursor < len(paramValues) {
+		return nil, syncql.NewErrTooManyParamValuesSpecified(db.GetContext(), tooManyOff)
+	}
+	copy.Escape = st.Escape
+	copy.Limit = st.Limit
+	return copy, nil
+}
+
+func (st InsertStatement) CopyAndSubstitute(db ds.Database, paramValues []*vdl.Value) (Statement, error) {
+	var copy InsertStatement
+	copy.Off = st.Off
+	copy.Table = st.Table
+	copy.Columns = st.Columns
+	pi := paramInfo{paramValues: paramValues, cursor: 0}
+	for _, r := range st.Rows {
+		var row InsertRow
+		row.Off = r.Off
+		for _, v := range r.Values {
+			value, err := v.CopyAndSubstitute(db, &pi)
+			if err != nil {
+				return nil, err
+			}
+			row.Values = append(row.Values, value)
+		}
+		copy.Rows = append(copy.Rows, &row)
+	}
+	// Did any of the supplied values go unused?
+	if pi.cursor < len(paramValues) {
+		return nil, syncql.NewErrTooManyParamValuesSpecified(db.GetContext(), copy.Off)
+	}
+	return copy, nil
+}
+
+func (st ExplainStatement) CopyAndSubstitute(db ds.Database, paramValues []*vdl.Value) (Statement, error) {
+	var copy ExplainStatement
+	copy.Off = st.Off
+	var err error
+	if copy.Statement, err = st.Statement.CopyAndSubstitute(db, paramValues); err != nil {
+		return nil, err
+	}
+	return copy, nil
+}
+
 func (sel SelectClause) String() string {
-	val := fmt.Sprintf(" Off(%d):SELECT Columns(", sel.Off)
+	val := fmt.Sprintf(" Off(%d):SELECT ", sel.Off)
+	if sel.Distinct {
+		val += "DISTINCT "
+	}
+	val += "Columns("
 	sep := ""
 	for _, selector := range sel.Selectors {
 		val += sep + selector.String()
@@ -1200,13 +2399,40 @@ func (s Segment) String() string {
 }
 
 func (f FromClause) String() string {
-	return fmt.Sprintf("Off(%d):FROM %s", f.Off, f.Table.String())
+	val := fmt.Sprintf("Off(%d):FROM %s", f.Off, f.Table.String())
+	for _, join := range f.Joins {
+		val += join.String()
+	}
+	return val
 }
 
 func (t TableEntry) String() string {
+	if t.Alias != "" {
+		return fmt.Sprintf("Off(%d):%s %s", t.Off, t.Name, t.Alias)
+	}
 	return fmt.Sprintf("Off(%d):%s", t.Off, t.Name)
 }
 
+// Qualifier returns the name that qualifies the fields of the table in a
+// select statement with joins: the table's alias, if any, else its name.
+func (t TableEntry) Qualifier() string {
+	if t.Alias != "" {
+		return t.Alias
+	}
+	return t.Name
+}
+
+func (j JoinClause) String() string {
+	val := fmt.Sprintf(" Off(%d):", j.Off)
+	switch j.Type {
+	case InnerJoin:
+		val += "INNER JOIN "
+	case LeftJoin:
+		val += "LEFT JOIN "
+	}
+	return val + j.Table.String() + " ON " + j.On.String()
+}
+
 func (w WhereClause) String() string {
 	return fmt.Sprintf(" Off(%d):WHERE %s", w.Off, w.Expr.String())
 }
@@ -1231,6 +2457,41 @@ func (l ResultsOffsetClause) String() string {
 	return fmt.Sprintf(" Off(%d):OFFSET %s", l.Off, l.ResultsOffset.String())
 }
 
+func (g GroupByClause) String() string {
+	val := fmt.Sprintf(" Off(%d):GROUP BY", g.Off)
+	sep := " "
+	for _, key := range g.Keys {
+		val += sep + key.String()
+		sep = ","
+	}
+	return val
+}
+
+func (h HavingClause) String() string {
+	return fmt.Sprintf(" Off(%d):HAVING %s", h.Off, h.Expr.String())
+}
+
+func (o OrderByClause) String() string {
+	val := fmt.Sprintf(" Off(%d):ORDER BY", o.Off)
+	sep := " "
+	for _, key := range o.Keys {
+		val += sep + key.String()
+		sep = ","
+	}
+	return val
+}
+
+func (k SortKey) String() string {
+	val := fmt.Sprintf("Off(%d):%s", k.Off, k.Operand.String())
+	switch k.Direction {
+	case Ascending:
+		val += " ASC"
+	case Descending:
+		val += " DESC"
+	}
+	return val
+}
+
 func (o Operand) String() string {
 	val := fmt.Sprintf("Off(%d):", o.Off)
 	switch o.Type {
@@ -1261,6 +2522,17 @@ func (o Operand) String() string {
 	case TypExpr:
 		val += "(expr)"
 		val += o.Expr.String()
+	case TypArithmetic:
+		val += "(arithmetic)"
+		val += o.Expr.String()
+	case TypList:
+		val += "(list)("
+		sep := ""
+		for _, value := range o.List {
+			val += sep + value.String()
+			sep = ","
+		}
+		val += ")"
 	case TypTime:
 		val += "(time)"
 		val += o.Time.Format("Mon Jan 2 15:04:05 -0700 MST 2006")
@@ -1305,6 +2577,24 @@ func (o BinaryOperator) String() string {
 		val += "NOT LIKE"
 	case Or:
 		val += "OR"
+	case Between:
+		val += "BETWEEN"
+	case In:
+		val += "IN"
+	case NotBetween:
+		val += "NOT BETWEEN"
+	case NotIn:
+		val += "NOT IN"
+	case Plus:
+		val += "+"
+	case Minus:
+		val += "-"
+	case Times:
+		val += "*"
+	case Divide:
+		val += "/"
+	case Modulo:
+		val += "%"
 	default:
 		val += "<operator-undefined>"
 	}
@@ -1355,15 +2645,27 @@ func (e Expression) CopyAndSubstitute(db ds.Database, pi *paramInfo) (*Expressio
 
 func (o Operand) CopyAndSubstitute(db ds.Database, pi *paramInfo) (*Operand, error) {
 	switch o.Type {
-	case TypExpr:
+	case TypExpr, TypArithmetic:
 		var copy Operand
-		copy.Type = TypExpr
+		copy.Type = o.Type
 		copy.Off = o.Off
 		var err error
 		if copy.Expr, err = o.Expr.CopyAndSubstitute(db, pi); err != nil {
 			return nil, err
 		}
 		return &copy, nil
+	case TypList:
+		var copy Operand
+		copy.Type = TypList
+		copy.Off = o.Off
+		for _, value := range o.List {
+			valueCopy, err := value.CopyAndSubstitute(db, pi)
+			if err != nil {
+				return nil, err
+			}
+			copy.List = append(copy.List, valueCopy)
+		}
+		return &copy, nil
 	case TypFunction:
 		var copy Operand
 		copy.Type = TypFunction
@@ -1390,6 +2692,76 @@ func (o Operand) CopyAndSubstitute(db ds.Database, pi *paramInfo) (*Operand, err
 	}
 }
 
+// Copy returns a copy of the select clause.  Parameters cannot appear in the
+// select clause, but its functions are copied as the checker annotates them
+// (e.g., with their arg types) for each execution of a prepared statement.
+func (sc SelectClause) Copy() *SelectClause {
+	var copy SelectClause
+	copy.Off = sc.Off
+	copy.Distinct = sc.Distinct
+	for _, selector := range sc.Selectors {
+		if selector.Function != nil {
+			selector.Function = selector.Function.copy()
+		}
+		copy.Selectors = append(copy.Selectors, selector)
+	}
+	return &copy
+}
+
+func (f Function) copy() *Function {
+	copy := f
+	copy.Args = nil
+	for _, a := range f.Args {
+		newArg := *a
+		if newArg.Type == TypFunction {
+			newArg.Function = a.Function.copy()
+		}
+		copy.Args = append(copy.Args, &newArg)
+	}
+	return &copy
+}
+
+func (f FromClause) CopyAndSubstitute(db ds.Database, pi *paramInfo) (*FromClause, error) {
+	copy := f
+	copy.Joins = nil
+	for _, join := range f.Joins {
+		newJoin := *join
+		var err error
+		if newJoin.On, err = join.On.CopyAndSubstitute(db, pi); err != nil {
+			return nil, err
+		}
+		copy.Joins = append(copy.Joins, &newJoin)
+	}
+	return &copy, nil
+}
+
+func (g GroupByClause) CopyAndSubstitute(db ds.Database, pi *paramInfo) (*GroupByClause, error) {
+	var copy GroupByClause
+	copy.Off = g.Off
+	for _, key := range g.Keys {
+		newKey, err := key.CopyAndSubstitute(db, pi)
+		if err != nil {
+			return nil, err
+		}
+		copy.Keys = append(copy.Keys, newKey)
+	}
+	return &copy, nil
+}
+
+func (o OrderByClause) CopyAndSubstitute(db ds.Database, pi *paramInfo) (*OrderByClause, error) {
+	var copy OrderByClause
+	copy.Off = o.Off
+	for _, key := range o.Keys {
+		newKey := key
+		var err error
+		if newKey.Operand, err = key.Operand.CopyAndSubstitute(db, pi); err != nil {
+			return nil, err
+		}
+		copy.Keys = append(copy.Keys, newKey)
+	}
+	return &copy, nil
+}
+
 func (f Function) CopyAndSubstitute(db ds.Database, pi *paramInfo) (*Function, error) {
 	var copy Function
 	copy.Name = f.Name
@@ -33,6 +33,11 @@ type parseDeleteTest struct {
 	err       error
 }
 
+type parseWriteTest struct {
+	query     string
+	statement query_parser.Statement
+}
+
 type parseErrorTest struct {
 	query string
 	err   error
@@ -55,6 +60,12 @@ type copyAndSubstituteDeleteTest struct {
 	statement query_parser.DeleteStatement
 }
 
+type copyAndSubstituteUnionTest struct {
+	query     string
+	subValues []*vdl.Value
+	s         string
+}
+
 type copyAndSubstituteErrorTest struct {
 	query     string
 	subValues []*vdl.Value
@@ -2827,6 +2838,187 @@ func TestSelectParser(t *testing.T) {
 			},
 			nil,
 		},
+		{
+			"select k from Customer order by v.A desc, k limit 10",
+			query_parser.SelectStatement{
+				Select: &query_parser.SelectClause{
+					Selectors: []query_parser.Selector{
+						query_parser.Selector{
+							Type: query_parser.TypSelField,
+							Field: &query_parser.Field{
+								Segments: []query_parser.Segment{
+									query_parser.Segment{
+										Value: "k",
+										Node:  query_parser.Node{Off: 7},
+									},
+								},
+								Node: query_parser.Node{Off: 7},
+							},
+							Node: query_parser.Node{Off: 7},
+						},
+					},
+					Node: query_parser.Node{Off: 0},
+				},
+				From: &query_parser.FromClause{
+					Table: query_parser.TableEntry{
+						Name: "Customer",
+						Node: query_parser.Node{Off: 14},
+					},
+					Node: query_parser.Node{Off: 9},
+				},
+				OrderBy: &query_parser.OrderByClause{
+					Keys: []query_parser.SortKey{
+						query_parser.SortKey{
+							Operand: &query_parser.Operand{
+								Type: query_parser.TypField,
+								Column: &query_parser.Field{
+									Segments: []query_parser.Segment{
+										query_parser.Segment{
+											Value: "v",
+											Node:  query_parser.Node{Off: 32},
+										},
+										query_parser.Segment{
+											Value: "A",
+											Node:  query_parser.Node{Off: 34},
+										},
+									},
+									Node: query_parser.Node{Off: 32},
+								},
+								Node: query_parser.Node{Off: 32},
+							},
+							Direction: query_parser.Descending,
+							Node:      query_parser.Node{Off: 32},
+						},
+						query_parser.SortKey{
+							Operand: &query_parser.Operand{
+								Type: query_parser.TypField,
+								Column: &query_parser.Field{
+									Segments: []query_parser.Segment{
+										query_parser.Segment{
+											Value: "k",
+											Node:  query_parser.Node{Off: 42},
+										},
+									},
+									Node: query_parser.Node{Off: 42},
+								},
+								Node: query_parser.Node{Off: 42},
+							},
+							Direction: query_parser.Ascending,
+							Node:      query_parser.Node{Off: 42},
+						},
+					},
+					Node: query_parser.Node{Off: 23},
+				},
+				Limit: &query_parser.LimitClause{
+					Limit: &query_parser.Int64Value{
+						Value: 10,
+						Node:  query_parser.Node{Off: 50},
+					},
+					Node: query_parser.Node{Off: 44},
+				},
+				Node: query_parser.Node{Off: 0},
+			},
+			nil,
+		},
+		{
+			"select c.k from Customer c left join Invoice i on i.v.CustId = c.k",
+			query_parser.SelectStatement{
+				Select: &query_parser.SelectClause{
+					Selectors: []query_parser.Selector{
+						query_parser.Selector{
+							Type: query_parser.TypSelField,
+							Field: &query_parser.Field{
+								Segments: []query_parser.Segment{
+									query_parser.Segment{
+										Value: "c",
+										Node:  query_parser.Node{Off: 7},
+									},
+									query_parser.Segment{
+										Value: "k",
+										Node:  query_parser.Node{Off: 9},
+									},
+								},
+								Qualified: true,
+								Table:     0,
+								Node:      query_parser.Node{Off: 7},
+							},
+							Node: query_parser.Node{Off: 7},
+						},
+					},
+					Node: query_parser.Node{Off: 0},
+				},
+				From: &query_parser.FromClause{
+					Table: query_parser.TableEntry{
+						Name:  "Customer",
+						Alias: "c",
+						Node:  query_parser.Node{Off: 16},
+					},
+					Joins: []*query_parser.JoinClause{
+						&query_parser.JoinClause{
+							Type: query_parser.LeftJoin,
+							Table: query_parser.TableEntry{
+								Name:  "Invoice",
+								Alias: "i",
+								Node:  query_parser.Node{Off: 37},
+							},
+							On: &query_parser.Expression{
+								Operand1: &query_parser.Operand{
+									Type: query_parser.TypField,
+									Column: &query_parser.Field{
+										Segments: []query_parser.Segment{
+											query_parser.Segment{
+												Value: "i",
+												Node:  query_parser.Node{Off: 50},
+											},
+											query_parser.Segment{
+												Value: "v",
+												Node:  query_parser.Node{Off: 52},
+											},
+											query_parser.Segment{
+												Value: "CustId",
+												Node:  query_parser.Node{Off: 54},
+											},
+										},
+										Qualified: true,
+										Table:     1,
+										Node:      query_parser.Node{Off: 50},
+									},
+									Node: query_parser.Node{Off: 50},
+								},
+								Operator: &query_parser.BinaryOperator{
+									Type: query_parser.Equal,
+									Node: query_parser.Node{Off: 61},
+								},
+								Operand2: &query_parser.Operand{
+									Type: query_parser.TypField,
+									Column: &query_parser.Field{
+										Segments: []query_parser.Segment{
+											query_parser.Segment{
+												Value: "c",
+												Node:  query_parser.Node{Off: 63},
+											},
+											query_parser.Segment{
+												Value: "k",
+												Node:  query_parser.Node{Off: 65},
+											},
+										},
+										Qualified: true,
+										Table:     0,
+										Node:      query_parser.Node{Off: 63},
+									},
+									Node: query_parser.Node{Off: 63},
+								},
+								Node: query_parser.Node{Off: 50},
+							},
+							Node: query_parser.Node{Off: 27},
+						},
+					},
+					Node: query_parser.Node{Off: 11},
+				},
+				Node: query_parser.Node{Off: 0},
+			},
+			nil,
+		},
 	}
 
 	for _, test := range basic {
@@ -4639,6 +4831,133 @@ func TestDeleteParser(t *testing.T) {
 	}
 }
 
+func TestUpdateInsertParser(t *testing.T) {
+	basic := []parseWriteTest{
+		{
+			"update Customer set v.A = 10 where k = \"001\"",
+			query_parser.UpdateStatement{
+				Table: &query_parser.TableEntry{
+					Name: "Customer",
+					Node: query_parser.Node{Off: 7},
+				},
+				Set: &query_parser.SetClause{
+					Assignments: []*query_parser.Assignment{
+						&query_parser.Assignment{
+							Field: &query_parser.Field{
+								Segments: []query_parser.Segment{
+									query_parser.Segment{
+										Value: "v",
+										Node:  query_parser.Node{Off: 20},
+									},
+									query_parser.Segment{
+										Value: "A",
+										Node:  query_parser.Node{Off: 22},
+									},
+								},
+								Node: query_parser.Node{Off: 20},
+							},
+							Operand: &query_parser.Operand{
+								Type: query_parser.TypInt,
+								Int:  10,
+								Node: query_parser.Node{Off: 26},
+							},
+							Node: query_parser.Node{Off: 20},
+						},
+					},
+					Node: query_parser.Node{Off: 16},
+				},
+				Where: &query_parser.WhereClause{
+					Expr: &query_parser.Expression{
+						Operand1: &query_parser.Operand{
+							Type: query_parser.TypField,
+							Column: &query_parser.Field{
+								Segments: []query_parser.Segment{
+									query_parser.Segment{
+										Value: "k",
+										Node:  query_parser.Node{Off: 35},
+									},
+								},
+								Node: query_parser.Node{Off: 35},
+							},
+							Node: query_parser.Node{Off: 35},
+						},
+						Operator: &query_parser.BinaryOperator{
+							Type: query_parser.Equal,
+							Node: query_parser.Node{Off: 37},
+						},
+						Operand2: &query_parser.Operand{
+							Type: query_parser.TypStr,
+							Str:  "001",
+							Node: query_parser.Node{Off: 39},
+						},
+						Node: query_parser.Node{Off: 35},
+					},
+					Node: query_parser.Node{Off: 29},
+				},
+				Node: query_parser.Node{Off: 0},
+			},
+		},
+		{
+			"insert into Customer (k, v) values (\"001\", true)",
+			query_parser.InsertStatement{
+				Table: &query_parser.TableEntry{
+					Name: "Customer",
+					Node: query_parser.Node{Off: 12},
+				},
+				Columns: []*query_parser.Field{
+					&query_parser.Field{
+						Segments: []query_parser.Segment{
+							query_parser.Segment{
+								Value: "k",
+								Node:  query_parser.Node{Off: 22},
+							},
+						},
+						Node: query_parser.Node{Off: 22},
+					},
+					&query_parser.Field{
+						Segments: []query_parser.Segment{
+							query_parser.Segment{
+								Value: "v",
+								Node:  query_parser.Node{Off: 25},
+							},
+						},
+						Node: query_parser.Node{Off: 25},
+					},
+				},
+				Rows: []*query_parser.InsertRow{
+					&query_parser.InsertRow{
+						Values: []*query_parser.Operand{
+							&query_parser.Operand{
+								Type: query_parser.TypStr,
+								Str:  "001",
+								Node: query_parser.Node{Off: 36},
+							},
+							&query_parser.Operand{
+								Type: query_parser.TypBool,
+								Bool: true,
+								Node: query_parser.Node{Off: 43},
+							},
+						},
+						Node: query_parser.Node{Off: 35},
+					},
+				},
+				Node: query_parser.Node{Off: 0},
+			},
+		},
+	}
+
+	for _, test := range basic {
+		st, err := query_parser.Parse(&db, test.query)
+		if err != nil {
+			t.Errorf("query: %s; unexpected error: got %v, want nil", test.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.statement, *st) {
+			t.Errorf("query: %s;\nGOT  %s\nWANT %s", test.query, *st, test.statement)
+		}
+	}
+}
+
 func TestQueryParserErrors(t *testing.T) {
 	basic := []parseErrorTest{
 		{"", syncql.NewErrNoStatementFound(db.GetContext(), 0)},
@@ -4646,6 +4965,33 @@ func TestQueryParserErrors(t *testing.T) {
 		{"foo", syncql.NewErrUnknownIdentifier(db.GetContext(), 0, "foo")},
 		{"(foo)", syncql.NewErrExpectedIdentifier(db.GetContext(), 0, "(")},
 		{"create table Customer (CustRecord cust_pkg.Cust, primary key(CustRecord.CustID))", syncql.NewErrUnknownIdentifier(db.GetContext(), 0, "create")},
+		{"explain", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 7)},
+		{"explain 42", syncql.NewErrExpectedIdentifier(db.GetContext(), 8, "42")},
+		{"explain foo", syncql.NewErrUnknownIdentifier(db.GetContext(), 8, "foo")},
+		{"explain explain select k from Customer", syncql.NewErrUnknownIdentifier(db.GetContext(), 8, "explain")},
+		{"explain select k from Customer where", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 36)},
+		{"explain delete from Customer limit", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 34)},
+		{"select distinct", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 15)},
+		{"select k from Customer union", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 28)},
+		{"select k from Customer union all", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 32)},
+		{"select k from Customer union delete from Customer", syncql.NewErrExpected(db.GetContext(), 29, "select")},
+		{"select k from Customer union all all select k from Invoice", syncql.NewErrExpected(db.GetContext(), 33, "select")},
+		{"select k from Customer union select k from Invoice where", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 56)},
+		{"update", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 6)},
+		{"update Customer v.A = 1", syncql.NewErrExpected(db.GetContext(), 16, "set")},
+		{"update Customer set 5 = 1", syncql.NewErrExpectedIdentifier(db.GetContext(), 20, "5")},
+		{"update Customer set v.A 1", syncql.NewErrExpected(db.GetContext(), 24, "=")},
+		{"update Customer set v.A =", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 25)},
+		{"update Customer set v.A = 1 v.B = 2", syncql.NewErrUnexpected(db.GetContext(), 28, "v")},
+		{"update Customer set v.A = 1 order by k", syncql.NewErrUnexpected(db.GetContext(), 28, "order")},
+		{"insert Customer", syncql.NewErrExpected(db.GetContext(), 7, "into")},
+		{"insert into Customer k, v", syncql.NewErrExpected(db.GetContext(), 21, "(")},
+		{"insert into Customer (k, 5) values (\"a\", 1)", syncql.NewErrExpectedIdentifier(db.GetContext(), 25, "5")},
+		{"insert into Customer (k, v", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 26)},
+		{"insert into Customer (k, v) (\"a\", 1)", syncql.NewErrExpected(db.GetContext(), 28, "values")},
+		{"insert into Customer (k, v) values \"a\"", syncql.NewErrExpected(db.GetContext(), 35, "(")},
+		{"insert into Customer (k, v) values (\"a\", 1", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 42)},
+		{"insert into Customer (k, v) values (\"a\", 1) where k = 1", syncql.NewErrUnexpected(db.GetContext(), 44, "where")},
 
 		// Select
 		{"select foo.", syncql.NewErrExpectedIdentifier(db.GetContext(), 11, "")},
@@ -4658,6 +5004,31 @@ func TestQueryParserErrors(t *testing.T) {
 		{"select foo from Customer where (A=123 or B=456) and C=789)))))", syncql.NewErrUnexpected(db.GetContext(), 57, ")")},
 		{"select foo from Customer where", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 30)},
 		{"select foo from Customer where ", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 31)},
+		{"select foo from Customer order", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 30)},
+		{"select foo from Customer order v.A", syncql.NewErrExpected(db.GetContext(), 31, "by")},
+		{"select foo from Customer order by", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 33)},
+		{"select foo from Customer order by v.A,", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 38)},
+		{"select foo from Customer order by v.A descending", syncql.NewErrUnexpected(db.GetContext(), 38, "descending")},
+		{"select foo from Customer group", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 30)},
+		{"select foo from Customer group v.A", syncql.NewErrExpected(db.GetContext(), 31, "by")},
+		{"select foo from Customer group by", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 33)},
+		{"select foo from Customer group by v.A having", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 44)},
+		{"select foo from Customer group by v.A having Count(k) > 1 group by v.B", syncql.NewErrUnexpected(db.GetContext(), 58, "group")},
+		{"select foo from Customer order by v.A group by v.A", syncql.NewErrUnexpected(db.GetContext(), 38, "group")},
+		{"select v from Customer c", syncql.NewErrUnexpected(db.GetContext(), 23, "c")},
+		{"select a.v from Customer a join", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 31)},
+		{"select a.v from Customer a join Invoice", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 39)},
+		{"select a.v from Customer a join Invoice b where a.k = b.k", syncql.NewErrExpected(db.GetContext(), 42, "on")},
+		{"select a.v from Customer a join Invoice b on", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 44)},
+		{"select a.v from Customer a left Invoice b on a.k = b.k", syncql.NewErrExpected(db.GetContext(), 32, "join")},
+		{"select a.v from Customer a inner outer join Invoice b on a.k = b.k", syncql.NewErrExpected(db.GetContext(), 33, "join")},
+		{"select a.v from Customer a join 42 on a.k = b.k", syncql.NewErrExpectedIdentifier(db.GetContext(), 32, "42")},
+		{"select v from Customer a join Invoice b on a.k = b.k", syncql.NewErrFieldNotQualified(db.GetContext(), 7)},
+		{"select a.v from Customer a join Invoice b on a.k = c.k", syncql.NewErrFieldNotQualified(db.GetContext(), 51)},
+		{"select a.v from Customer a join Invoice b on a.k = b.k where Len(v.A) > 0", syncql.NewErrFieldNotQualified(db.GetContext(), 65)},
+		{"select a.v from Customer a join Invoice b on a.k = b.k order by a.v.Map[k]", syncql.NewErrFieldNotQualified(db.GetContext(), 72)},
+		{"select a.v from Customer a join Invoice a on a.k = a.k", syncql.NewErrDuplicateTableName(db.GetContext(), 32, "a")},
+		{"select Customer.v from Customer join Customer on Customer.k = Customer.k", syncql.NewErrDuplicateTableName(db.GetContext(), 37, "Customer")},
 		{"select foo from Customer where )", syncql.NewErrExpectedOperand(db.GetContext(), 31, ")")},
 		{"select foo from Customer where )A=123 or B=456) and C=789", syncql.NewErrExpectedOperand(db.GetContext(), 31, ")")},
 		{"select foo from Customer where ()A=123 or B=456) and C=789", syncql.NewErrExpectedOperand(db.GetContext(), 32, ")")},
@@ -4665,8 +5036,8 @@ func TestQueryParserErrors(t *testing.T) {
 		{"select foo bar from Customer", syncql.NewErrExpectedFrom(db.GetContext(), 11, "bar")},
 		{"select foo from Customer Invoice", syncql.NewErrUnexpected(db.GetContext(), 25, "Invoice")},
 		{"select (foo) from (Customer)", syncql.NewErrExpectedIdentifier(db.GetContext(), 7, "(")},
-		{"select foo, bar from Customer where a = (b)", syncql.NewErrExpectedOperand(db.GetContext(), 40, "(")},
-		{"select foo, bar from Customer where a = b and (c) = d", syncql.NewErrExpectedOperator(db.GetContext(), 48, ")")},
+		{"select foo, bar from Customer where a = (b c)", syncql.NewErrExpected(db.GetContext(), 43, ")")},
+		{"select foo, bar from Customer where a = b and (c) d", syncql.NewErrExpectedOperator(db.GetContext(), 50, "d")},
 		{"select foo, bar from Customer where a = b and c =", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 49)},
 		{"select foo, bar from Customer where a = ", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 40)},
 		{"select foo, bar from Customer where a", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 37)},
@@ -4674,10 +5045,10 @@ func TestQueryParserErrors(t *testing.T) {
 		{"select a from", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 13)},
 		{"select a from b where c = d and e =", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 35)},
 		{"select a from b where c = d and f", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 33)},
-		{"select a from b where c = d and f *", syncql.NewErrExpectedOperator(db.GetContext(), 34, "*")},
+		{"select a from b where c = d and f *", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 35)},
 		{"select a from b where c <", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 25)},
-		{"select a from b where c not", syncql.NewErrExpected(db.GetContext(), 27, "'equal' or 'like'")},
-		{"select a from b where c not 8", syncql.NewErrExpected(db.GetContext(), 28, "'equal' or 'like'")},
+		{"select a from b where c not", syncql.NewErrExpected(db.GetContext(), 27, "'equal', 'like', 'in' or 'between'")},
+		{"select a from b where c not 8", syncql.NewErrExpected(db.GetContext(), 28, "'equal', 'like', 'in' or 'between'")},
 		{"select x from y where a and b = c", syncql.NewErrExpectedOperator(db.GetContext(), 24, "and")},
 		{"select v from Customer limit 100 offset a", syncql.NewErrExpected(db.GetContext(), 40, "positive integer literal")},
 		{"select v from Customer limit -100 offset 5", syncql.NewErrExpected(db.GetContext(), 29, "positive integer literal")},
@@ -4716,7 +5087,18 @@ func TestQueryParserErrors(t *testing.T) {
 		{"select v[abc from Customers", syncql.NewErrExpected(db.GetContext(), 13, "]")},
 		{"select v[* from Customers", syncql.NewErrExpectedOperand(db.GetContext(), 9, "*")},
 		{"select v from 123", syncql.NewErrExpectedIdentifier(db.GetContext(), 14, "123")},
-		{"select v from Customers where (a = b *", syncql.NewErrExpected(db.GetContext(), 37, ")")},
+		{"select v from Customers where (a = b c", syncql.NewErrExpected(db.GetContext(), 37, ")")},
+		{"select k from Customers where v.A in", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 36)},
+		{"select k from Customers where v.A in ()", syncql.NewErrExpectedOperand(db.GetContext(), 38, ")")},
+		{"select k from Customers where v.A in (1, 2", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 42)},
+		{"select k from Customers where v.A in 1", syncql.NewErrExpected(db.GetContext(), 37, "(")},
+		{"select k from Customers where v.A between 1", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 43)},
+		{"select k from Customers where v.A between 1 or 2", syncql.NewErrExpected(db.GetContext(), 44, "and")},
+		{"select k from Customers where v.A between 1 and", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 47)},
+		{"select k from Customers where v.A + = 1", syncql.NewErrExpectedOperand(db.GetContext(), 36, "=")},
+		{"select k from Customers where v.A * (1 + 2 = 3", syncql.NewErrExpected(db.GetContext(), 43, ")")},
+		{"select k from Customers where (v.A + 1) = 2)", syncql.NewErrUnexpected(db.GetContext(), 43, ")")},
+		{"select k from Customers where -", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 31)},
 		// Delete
 		{"delete.", syncql.NewErrExpectedFrom(db.GetContext(), 6, ".")},
 		{"delete. from a", syncql.NewErrExpectedFrom(db.GetContext(), 6, ".")},
@@ -4734,8 +5116,8 @@ func TestQueryParserErrors(t *testing.T) {
 		{"delete bar from Customer", syncql.NewErrExpectedFrom(db.GetContext(), 7, "bar")},
 		{"delete from Customer Invoice", syncql.NewErrUnexpected(db.GetContext(), 21, "Invoice")},
 		{"delete from (Customer)", syncql.NewErrExpectedIdentifier(db.GetContext(), 12, "(")},
-		{"delete from Customer where a = (b)", syncql.NewErrExpectedOperand(db.GetContext(), 31, "(")},
-		{"delete from Customer where a = b and (c) = d", syncql.NewErrExpectedOperator(db.GetContext(), 39, ")")},
+		{"delete from Customer where a = (b c)", syncql.NewErrExpected(db.GetContext(), 34, ")")},
+		{"delete from Customer where a = b and (c) d", syncql.NewErrExpectedOperator(db.GetContext(), 41, "d")},
 		{"delete from Customer where a = b and c =", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 40)},
 		{"delete from Customer where a = ", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 31)},
 		{"delete from Customer where a", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 28)},
@@ -4743,10 +5125,10 @@ func TestQueryParserErrors(t *testing.T) {
 		{"delete from", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 11)},
 		{"delete from b where c = d and e =", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 33)},
 		{"delete from b where c = d and f", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 31)},
-		{"delete from b where c = d and f *", syncql.NewErrExpectedOperator(db.GetContext(), 32, "*")},
+		{"delete from b where c = d and f *", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 33)},
 		{"delete from b where c <", syncql.NewErrUnexpectedEndOfStatement(db.GetContext(), 23)},
-		{"delete from b where c not", syncql.NewErrExpected(db.GetContext(), 25, "'equal' or 'like'")},
-		{"delete from b where c not 8", syncql.NewErrExpected(db.GetContext(), 26, "'equal' or 'like'")},
+		{"delete from b where c not", syncql.NewErrExpected(db.GetContext(), 25, "'equal', 'like', 'in' or 'between'")},
+		{"delete from b where c not 8", syncql.NewErrExpected(db.GetContext(), 26, "'equal', 'like', 'in' or 'between'")},
 		{"delete from y where a and b = c", syncql.NewErrExpectedOperator(db.GetContext(), 22, "and")},
 		{"delete from Customer limit a", syncql.NewErrExpected(db.GetContext(), 27, "positive integer literal")},
 		{"delete from Customer limit -100", syncql.NewErrExpected(db.GetContext(), 27, "positive integer literal")},
@@ -4779,7 +5161,7 @@ func TestQueryParserErrors(t *testing.T) {
 		{"delete from Customers where v[abc = 1", syncql.NewErrExpected(db.GetContext(), 34, "]")},
 		{"delete from Customers where v[*", syncql.NewErrExpectedOperand(db.GetContext(), 30, "*")},
 		{"delete from 123", syncql.NewErrExpectedIdentifier(db.GetContext(), 12, "123")},
-		{"delete from Customers where (a = b *", syncql.NewErrExpected(db.GetContext(), 35, ")")},
+		{"delete from Customers where (a = b c", syncql.NewErrExpected(db.GetContext(), 35, ")")},
 	}
 
 	for _, test := range basic {
@@ -4809,6 +5191,58 @@ func TestToString(t *testing.T) {
 			"select k from Customers limit 100 offset 200",
 			"Off(0): Off(0):SELECT Columns( Off(7): Off(7): Off(7):k) Off(9):FROM Off(14):Customers  Off(24):LIMIT  Off(30): 100  Off(34):OFFSET  Off(41): 200",
 		},
+		{
+			"select k from Customers where v.A > 0 order by v.A desc, Len(v.B) limit 10",
+			"Off(0): Off(0):SELECT Columns( Off(7): Off(7): Off(7):k) Off(9):FROM Off(14):Customers  Off(24):WHERE (Off(30):Off(30):(field) Off(30): Off(30):v. Off(32):A Off(34):> Off(36):(int)0)  Off(38):ORDER BY Off(47):Off(47):(field) Off(47): Off(47):v. Off(49):A DESC,Off(57):Off(57):(function)Off(57):Len(Off(61):(field) Off(61): Off(61):v. Off(63):B) ASC  Off(66):LIMIT  Off(72): 10",
+		},
+		{
+			"select k from Customers where v like \"x^%\" escape '^' order by k",
+			"Off(0): Off(0):SELECT Columns( Off(7): Off(7): Off(7):k) Off(9):FROM Off(14):Customers  Off(24):WHERE (Off(30):Off(30):(field) Off(30): Off(30):v Off(32):LIKE Off(37):(string)x^%)  Off(54):ORDER BY Off(63):Off(63):(field) Off(63): Off(63):k ASC  Off(43):ESCAPE  Off(50): ^",
+		},
+		{
+			"select v.A, Count(k) from Customers group by v.A, Len(v.B) having Sum(v.C) > 10 order by v.A",
+			"Off(0): Off(0):SELECT Columns( Off(7): Off(7): Off(7):v. Off(9):A, Off(12):Off(12):Count(Off(18):(field) Off(18): Off(18):k)) Off(21):FROM Off(26):Customers  Off(36):GROUP BY Off(45):(field) Off(45): Off(45):v. Off(47):A,Off(50):(function)Off(50):Len(Off(54):(field) Off(54): Off(54):v. Off(56):B)  Off(59):HAVING (Off(66):Off(66):(function)Off(66):Sum(Off(70):(field) Off(70): Off(70):v. Off(72):C) Off(75):> Off(77):(int)10)  Off(80):ORDER BY Off(89):Off(89):(field) Off(89): Off(89):v. Off(91):A ASC",
+		},
+		{
+			"explain select k from Customers limit 10",
+			"Off(0):EXPLAIN Off(8): Off(8):SELECT Columns( Off(15): Off(15): Off(15):k) Off(17):FROM Off(22):Customers  Off(32):LIMIT  Off(38): 10",
+		},
+		{
+			"select distinct v.A from Customers",
+			"Off(0): Off(0):SELECT DISTINCT Columns( Off(16): Off(16): Off(16):v. Off(18):A) Off(20):FROM Off(25):Customers",
+		},
+		{
+			"select k from Customers limit 2 union all select distinct v.A from Invoices order by v.A union select k from Items limit 5 offset 2",
+			"Off(0):(Off(0): Off(0):SELECT Columns( Off(7): Off(7): Off(7):k) Off(9):FROM Off(14):Customers  Off(24):LIMIT  Off(30): 2) UNION ALL (Off(42): Off(42):SELECT DISTINCT Columns( Off(58): Off(58): Off(58):v. Off(60):A) Off(62):FROM Off(67):Invoices  Off(76):ORDER BY Off(85):Off(85):(field) Off(85): Off(85):v. Off(87):A ASC) UNION (Off(95): Off(95):SELECT Columns( Off(102): Off(102): Off(102):k) Off(104):FROM Off(109):Items)  Off(115):LIMIT  Off(121): 5  Off(123):OFFSET  Off(130): 2",
+		},
+		{
+			"explain select k from Customers union select k from Invoices",
+			"Off(0):EXPLAIN Off(8):(Off(8): Off(8):SELECT Columns( Off(15): Off(15): Off(15):k) Off(17):FROM Off(22):Customers) UNION (Off(38): Off(38):SELECT Columns( Off(45): Off(45): Off(45):k) Off(47):FROM Off(52):Invoices)",
+		},
+		{
+			"explain delete from Customers where k = \"001\"",
+			"Off(0):EXPLAIN Off(8):DELETE Off(15):FROM Off(20):Customers  Off(30):WHERE (Off(36):Off(36):(field) Off(36): Off(36):k Off(38):= Off(40):(string)001)",
+		},
+		{
+			"explain update Customers set v.A = 10 where k = \"001\"",
+			"Off(0):EXPLAIN Off(8):UPDATE Off(15):Customers Off(25):SET Off(29): Off(29): Off(29):v. Off(31):A = Off(35):(int)10  Off(38):WHERE (Off(44):Off(44):(field) Off(44): Off(44):k Off(46):= Off(48):(string)001)",
+		},
+		{
+			"explain insert into Customers (k, v) values (\"001\", 10)",
+			"Off(0):EXPLAIN Off(8):INSERT INTO Off(20):Customers ( Off(31): Off(31):k, Off(34): Off(34):v) VALUES Off(44):(Off(45):(string)001,Off(52):(int)10)",
+		},
+		{
+			"update Customers set v.A = 10, v.B[2].C = ? where k = \"001\" limit 5",
+			"Off(0):UPDATE Off(7):Customers Off(17):SET Off(21): Off(21): Off(21):v. Off(23):A = Off(27):(int)10,Off(31): Off(31): Off(31):v. Off(33):B[Off(35):(int)2]. Off(38):C = Off(42):?  Off(44):WHERE (Off(50):Off(50):(field) Off(50): Off(50):k Off(52):= Off(54):(string)001)  Off(60):LIMIT  Off(66): 5",
+		},
+		{
+			"insert into Customers (v, k) values (?, \"001\"), (10, \"002\")",
+			"Off(0):INSERT INTO Off(12):Customers ( Off(23): Off(23):v, Off(26): Off(26):k) VALUES Off(36):(Off(37):?,Off(40):(string)001),Off(48):(Off(49):(int)10,Off(53):(string)002)",
+		},
+		{
+			"select c.k, i.k from Customers c join Invoices i on i.v.CustID = c.k left outer join Items it on it.k = i.v.Item",
+			"Off(0): Off(0):SELECT Columns( Off(7): Off(7): Off(7):c. Off(9):k, Off(12): Off(12): Off(12):i. Off(14):k) Off(16):FROM Off(21):Customers c Off(33):INNER JOIN Off(38):Invoices i ON (Off(52):Off(52):(field) Off(52): Off(52):i. Off(54):v. Off(56):CustID Off(63):= Off(65):(field) Off(65): Off(65):c. Off(67):k) Off(69):LEFT JOIN Off(85):Items it ON (Off(97):Off(97):(field) Off(97): Off(97):it. Off(100):k Off(102):= Off(104):(field) Off(104): Off(104):i. Off(106):v. Off(108):Item)",
+		},
 		{
 			"select k from Customers where v.A = 10 and v.B <> 20",
 			"Off(0): Off(0):SELECT Columns( Off(7): Off(7): Off(7):k) Off(9):FROM Off(14):Customers  Off(24):WHERE (Off(30):Off(30):(expr)(Off(30):Off(30):(field) Off(30): Off(30):v. Off(32):A Off(34):= Off(36):(int)10) Off(39):AND Off(43):(expr)(Off(43):Off(43):(field) Off(43): Off(43):v. Off(45):B Off(47):<> Off(50):(int)20))",
@@ -4833,6 +5267,22 @@ func TestToString(t *testing.T) {
 			"select v.Foo as Foo from Customers where v.A not like \"foo%\" or v.B = ?",
 			"Off(0): Off(0):SELECT Columns( Off(7): Off(7): Off(7):v. Off(9):Foo Off(13): Off(16):Foo) Off(20):FROM Off(25):Customers  Off(35):WHERE (Off(41):Off(41):(expr)(Off(41):Off(41):(field) Off(41): Off(41):v. Off(43):A Off(45):NOT LIKE Off(54):(string)foo%) Off(61):OR Off(64):(expr)(Off(64):Off(64):(field) Off(64): Off(64):v. Off(66):B Off(68):= Off(70):?))",
 		},
+		{
+			"select k from Customers where v.Status in (\"a\", \"b\") and v.Age between 18 and 65",
+			"Off(0): Off(0):SELECT Columns( Off(7): Off(7): Off(7):k) Off(9):FROM Off(14):Customers  Off(24):WHERE (Off(30):Off(30):(expr)(Off(30):Off(30):(field) Off(30): Off(30):v. Off(32):Status Off(39):IN Off(42):(list)(Off(43):(string)a,Off(48):(string)b)) Off(53):AND Off(57):(expr)(Off(57):Off(57):(field) Off(57): Off(57):v. Off(59):Age Off(63):BETWEEN Off(71):(list)(Off(71):(int)18,Off(78):(int)65)))",
+		},
+		{
+			"select k from Customers where v.Price * v.Qty > 100",
+			"Off(0): Off(0):SELECT Columns( Off(7): Off(7): Off(7):k) Off(9):FROM Off(14):Customers  Off(24):WHERE (Off(30):Off(30):(arithmetic)(Off(30):Off(30):(field) Off(30): Off(30):v. Off(32):Price Off(38):* Off(40):(field) Off(40): Off(40):v. Off(42):Qty) Off(46):> Off(48):(int)100)",
+		},
+		{
+			"select k from Customers where v.A - v.B - 1 = v.C / 2 + 3",
+			"Off(0): Off(0):SELECT Columns( Off(7): Off(7): Off(7):k) Off(9):FROM Off(14):Customers  Off(24):WHERE (Off(30):Off(30):(arithmetic)(Off(30):Off(30):(arithmetic)(Off(30):Off(30):(field) Off(30): Off(30):v. Off(32):A Off(34):- Off(36):(field) Off(36): Off(36):v. Off(38):B) Off(40):- Off(42):(int)1) Off(44):= Off(46):(arithmetic)(Off(46):Off(46):(arithmetic)(Off(46):Off(46):(field) Off(46): Off(46):v. Off(48):C Off(50):/ Off(52):(int)2) Off(54):+ Off(56):(int)3))",
+		},
+		{
+			"select k from Customers where (v.A + 1) * 2 <> -v.B % 3 or k not in (\"1\") or v.C not between 1.5 and ?",
+			"Off(0): Off(0):SELECT Columns( Off(7): Off(7): Off(7):k) Off(9):FROM Off(14):Customers  Off(24):WHERE (Off(30):Off(30):(expr)(Off(30):Off(30):(expr)(Off(30):Off(31):(arithmetic)(Off(31):Off(31):(arithmetic)(Off(31):Off(31):(field) Off(31): Off(31):v. Off(33):A Off(35):+ Off(37):(int)1) Off(40):* Off(42):(int)2) Off(44):<> Off(47):(arithmetic)(Off(47):Off(47):(arithmetic)(Off(47):Off(47):(int)0 Off(47):- Off(48):(field) Off(48): Off(48):v. Off(50):B) Off(52):% Off(54):(int)3)) Off(56):OR Off(59):(expr)(Off(59):Off(59):(field) Off(59): Off(59):k Off(61):NOT IN Off(68):(list)(Off(69):(string)1))) Off(74):OR Off(77):(expr)(Off(77):Off(77):(field) Off(77): Off(77):v. Off(79):C Off(81):NOT BETWEEN Off(93):(list)(Off(93):(float)1.5,Off(101):?)))",
+		},
 	}
 	for _, test := range basic {
 		st, err := query_parser.Parse(&db, test.query)
@@ -5187,6 +5637,97 @@ func TestCopyAndSubstituteSelect(t *testing.T) {
 				Node: query_parser.Node{Off: 0},
 			},
 		},
+		{
+			"select v from Customer where v.Value * ? in (?, 3)",
+			[]*vdl.Value{vdl.ValueOf(2), vdl.ValueOf(4)},
+			query_parser.SelectStatement{
+				Select: &query_parser.SelectClause{
+					Selectors: []query_parser.Selector{
+						query_parser.Selector{
+							Type: query_parser.TypSelField,
+							Field: &query_parser.Field{
+								Segments: []query_parser.Segment{
+									query_parser.Segment{
+										Value: "v",
+										Node:  query_parser.Node{Off: 7},
+									},
+								},
+								Node: query_parser.Node{Off: 7},
+							},
+							Node: query_parser.Node{Off: 7},
+						},
+					},
+					Node: query_parser.Node{Off: 0},
+				},
+				From: &query_parser.FromClause{
+					Table: query_parser.TableEntry{
+						Name: "Customer",
+						Node: query_parser.Node{Off: 14},
+					},
+					Node: query_parser.Node{Off: 9},
+				},
+				Where: &query_parser.WhereClause{
+					Expr: &query_parser.Expression{
+						Operand1: &query_parser.Operand{
+							Type: query_parser.TypArithmetic,
+							Expr: &query_parser.Expression{
+								Operand1: &query_parser.Operand{
+									Type: query_parser.TypField,
+									Column: &query_parser.Field{
+										Segments: []query_parser.Segment{
+											query_parser.Segment{
+												Value: "v",
+												Node:  query_parser.Node{Off: 29},
+											},
+											query_parser.Segment{
+												Value: "Value",
+												Node:  query_parser.Node{Off: 31},
+											},
+										},
+										Node: query_parser.Node{Off: 29},
+									},
+									Node: query_parser.Node{Off: 29},
+								},
+								Operator: &query_parser.BinaryOperator{
+									Type: query_parser.Times,
+									Node: query_parser.Node{Off: 37},
+								},
+								Operand2: &query_parser.Operand{
+									Type: query_parser.TypInt,
+									Int:  2,
+									Node: query_parser.Node{Off: 39},
+								},
+								Node: query_parser.Node{Off: 29},
+							},
+							Node: query_parser.Node{Off: 29},
+						},
+						Operator: &query_parser.BinaryOperator{
+							Type: query_parser.In,
+							Node: query_parser.Node{Off: 41},
+						},
+						Operand2: &query_parser.Operand{
+							Type: query_parser.TypList,
+							List: []*query_parser.Operand{
+								&query_parser.Operand{
+									Type: query_parser.TypInt,
+									Int:  4,
+									Node: query_parser.Node{Off: 45},
+								},
+								&query_parser.Operand{
+									Type: query_parser.TypInt,
+									Int:  3,
+									Node: query_parser.Node{Off: 48},
+								},
+							},
+							Node: query_parser.Node{Off: 44},
+						},
+						Node: query_parser.Node{Off: 29},
+					},
+					Node: query_parser.Node{Off: 23},
+				},
+				Node: query_parser.Node{Off: 0},
+			},
+		},
 	}
 	for _, test := range basic {
 		st, err := query_parser.Parse(&db, test.query)
@@ -5487,6 +6028,32 @@ func TestCopyAndSubstituteDelete(t *testing.T) {
 	}
 }
 
+// Parameters are substituted in the order the selects appear in the union.
+func TestCopyAndSubstituteUnion(t *testing.T) {
+	basic := []copyAndSubstituteUnionTest{
+		{
+			"select v from Customers where v.A = ? union all select v from Invoices where v.B = ? limit 3",
+			[]*vdl.Value{vdl.ValueOf(10), vdl.ValueOf("x")},
+			"Off(0):(Off(0): Off(0):SELECT Columns( Off(7): Off(7): Off(7):v) Off(9):FROM Off(14):Customers  Off(24):WHERE (Off(30):Off(30):(field) Off(30): Off(30):v. Off(32):A Off(34):= Off(36):(int)10)) UNION ALL (Off(48): Off(48):SELECT Columns( Off(55): Off(55): Off(55):v) Off(57):FROM Off(62):Invoices  Off(71):WHERE (Off(77):Off(77):(field) Off(77): Off(77):v. Off(79):B Off(81):= Off(83):(string)x))  Off(85):LIMIT  Off(91): 3",
+		},
+	}
+	for _, test := range basic {
+		st, err := query_parser.Parse(&db, test.query)
+		if err != nil {
+			t.Errorf("query: %s; unexpected parse error: got %v, want nil", test.query, err)
+			continue
+		}
+		st2, err := (*st).CopyAndSubstitute(&db, test.subValues)
+		if err != nil {
+			t.Errorf("query: %s; unexpected error on st.CopyAndSubstitute: got %v, want nil", test.query, err)
+			continue
+		}
+		if s := st2.String(); s != test.s {
+			t.Errorf("query: %s; got %s, want %s", test.query, s, test.s)
+		}
+	}
+}
+
 func TestCopyAndSubstituteError(t *testing.T) {
 	basic := []copyAndSubstituteErrorTest{
 		{
@@ -5504,6 +6071,71 @@ func TestCopyAndSubstituteError(t *testing.T) {
 			[]*vdl.Value{vdl.ValueOf(10)},
 			syncql.NewErrNotEnoughParamValuesSpecified(db.GetContext(), 48),
 		},
+		{
+			"select v from Customers where v.A = ? order by v.B, ?",
+			[]*vdl.Value{vdl.ValueOf(10)},
+			syncql.NewErrNotEnoughParamValuesSpecified(db.GetContext(), 52),
+		},
+		{
+			"select v from Customers where v.A in (?, ?) and v.B between ? and ?",
+			[]*vdl.Value{vdl.ValueOf(10), vdl.ValueOf(20), vdl.ValueOf(30)},
+			syncql.NewErrNotEnoughParamValuesSpecified(db.GetContext(), 66),
+		},
+		{
+			"select Count(k) from Customers where v.A = ? group by v.B having Count(k) > ? and Max(v.C) < ?",
+			[]*vdl.Value{vdl.ValueOf(10), vdl.ValueOf(2)},
+			syncql.NewErrNotEnoughParamValuesSpecified(db.GetContext(), 93),
+		},
+		{
+			"explain delete from Customers where k = ? or k = ?",
+			[]*vdl.Value{vdl.ValueOf("001")},
+			syncql.NewErrNotEnoughParamValuesSpecified(db.GetContext(), 49),
+		},
+		{
+			"select v from Customers where v.A = ? union all select v from Invoices where v.B = ?",
+			[]*vdl.Value{vdl.ValueOf(10)},
+			syncql.NewErrNotEnoughParamValuesSpecified(db.GetContext(), 83),
+		},
+		{
+			"select v from Customers where v.A = ? union all select v from Invoices where v.B = ?",
+			[]*vdl.Value{vdl.ValueOf(10), vdl.ValueOf(10), vdl.ValueOf(10)},
+			syncql.NewErrTooManyParamValuesSpecified(db.GetContext(), 71),
+		},
+		{
+			"select a.v from Customers a join Invoices b on b.k = ? where a.v.A = ?",
+			[]*vdl.Value{vdl.ValueOf("001")},
+			syncql.NewErrNotEnoughParamValuesSpecified(db.GetContext(), 69),
+		},
+		{
+			"select a.v from Customers a join Invoices b on b.k = ?",
+			[]*vdl.Value{vdl.ValueOf("001"), vdl.ValueOf(10)},
+			syncql.NewErrTooManyParamValuesSpecified(db.GetContext(), 0),
+		},
+		{
+			"select v from Customers order by v.B",
+			[]*vdl.Value{vdl.ValueOf(10)},
+			syncql.NewErrTooManyParamValuesSpecified(db.GetContext(), 0),
+		},
+		{
+			"update Customers set v.A = ? where v.B = ?",
+			[]*vdl.Value{vdl.ValueOf(10)},
+			syncql.NewErrNotEnoughParamValuesSpecified(db.GetContext(), 41),
+		},
+		{
+			"update Customers set v.A = ? where v.B = 10",
+			[]*vdl.Value{vdl.ValueOf(10), vdl.ValueOf(20)},
+			syncql.NewErrTooManyParamValuesSpecified(db.GetContext(), 29),
+		},
+		{
+			"insert into Customers (k, v) values (?, ?), (?, 10)",
+			[]*vdl.Value{vdl.ValueOf("001"), vdl.ValueOf(10)},
+			syncql.NewErrNotEnoughParamValuesSpecified(db.GetContext(), 45),
+		},
+		{
+			"insert into Customers (k, v) values (?, 10)",
+			[]*vdl.Value{vdl.ValueOf("001"), vdl.ValueOf(10)},
+			syncql.NewErrTooManyParamValuesSpecified(db.GetContext(), 0),
+		},
 		{
 			"delete from Customers",
 			[]*vdl.Value{vdl.ValueOf(10)},
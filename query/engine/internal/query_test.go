@@ -8,6 +8,8 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
@@ -122,6 +124,18 @@ func (t *table) Delete(k string) (bool, error) {
 	return false, nil
 }
 
+func (t *table) Put(k string, v *vom.RawBytes) error {
+	i := sort.Search(len(t.rows), func(i int) bool { return t.rows[i].key >= k })
+	if i < len(t.rows) && t.rows[i].key == k {
+		t.rows[i].value = v
+		return nil
+	}
+	t.rows = append(t.rows, kv{})
+	copy(t.rows[i+1:], t.rows[i:])
+	t.rows[i] = kv{k, v}
+	return nil
+}
+
 func (db mockDB) GetContext() *context.T {
 	return db.ctx
 }
@@ -480,6 +494,15 @@ type execDeleteTest struct {
 	selResults [][]*vom.RawBytes
 }
 
+type execWriteTest struct {
+	query      string
+	headers    []string
+	results    [][]*vom.RawBytes
+	selQuery   string
+	selHeaders []string
+	selResults [][]*vom.RawBytes
+}
+
 type preExecFunctionTest struct {
 	query   string
 	headers []string
@@ -2288,133 +2311,191 @@ func TestSelect(t *testing.T) {
 	}
 }
 
-func TestDelete(t *testing.T) {
-	basic := []execDeleteTest{
+func TestInBetweenArithmetic(t *testing.T) {
+	initTables()
+	basic := []execSelectTest{
 		{
-			"delete from Customer where k like \"001%\"",
-			[]string{"Count"},
-			[][]*vom.RawBytes{{vom.RawBytesOf(4)}},
-			"select k from Customer",
+			"select k from Numbers where v.I64 * 2 > 200",
 			[]string{"k"},
 			[][]*vom.RawBytes{
-				{vom.RawBytesOf("002")},
-				{vom.RawBytesOf("002001")},
-				{vom.RawBytesOf("002002")},
-				{vom.RawBytesOf("002003")},
-				{vom.RawBytesOf("002004")},
+				{vom.RawBytesOf("001")},
 				{vom.RawBytesOf("003")},
 			},
 		},
 		{
-			"delete from Customer where k = \"001\"",
-			[]string{"Count"},
-			[][]*vom.RawBytes{{vom.RawBytesOf(1)}},
-			"select k from Customer",
+			"select k from Numbers where v.I64 in (88, 210)",
 			[]string{"k"},
 			[][]*vom.RawBytes{
-				{vom.RawBytesOf("001001")},
-				{vom.RawBytesOf("001002")},
-				{vom.RawBytesOf("001003")},
 				{vom.RawBytesOf("002")},
-				{vom.RawBytesOf("002001")},
-				{vom.RawBytesOf("002002")},
-				{vom.RawBytesOf("002003")},
-				{vom.RawBytesOf("002004")},
 				{vom.RawBytesOf("003")},
 			},
 		},
 		{
-			"delete from Customer where Type(v) like \"%Customer\" and v.Name like \"%Masterson\"",
-			[]string{"Count"},
-			[][]*vom.RawBytes{{vom.RawBytesOf(1)}},
-			"select k from Customer where Type(v) like \"%Customer\"",
+			"select k from Numbers where v.I64 not in (88, 210.0)",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001")},
+			},
+		},
+		{
+			"select k from Numbers where v.F64 between 1.5 and 3",
 			[]string{"k"},
 			[][]*vom.RawBytes{
 				{vom.RawBytesOf("001")},
+				{vom.RawBytesOf("002")},
+			},
+		},
+		{
+			"select k from Numbers where v.I64 between v.I16 - 100 and v.I32",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("002")},
 				{vom.RawBytesOf("003")},
 			},
 		},
 		{
-			"delete from Customer where Type(v) like \"%Invoice\" and v.CustId = 1 limit 2",
-			[]string{"Count"},
-			[][]*vom.RawBytes{{vom.RawBytesOf(2)}},
-			"select k from Customer where Type(v) like \"%Invoice\"",
+			// uint64 - int64 is computed as a big int.
+			"select k from Numbers where v.Ui64 - v.I64 not between 0 and 1000",
 			[]string{"k"},
 			[][]*vom.RawBytes{
-				{vom.RawBytesOf("001003")},
-				{vom.RawBytesOf("002001")},
-				{vom.RawBytesOf("002002")},
-				{vom.RawBytesOf("002003")},
-				{vom.RawBytesOf("002004")},
+				{vom.RawBytesOf("001")},
+				{vom.RawBytesOf("002")},
+			},
+		},
+		{
+			// Unary minus; % takes the sign of the dividend.
+			"select k from Numbers where -v.I16 % 7 = -6",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001")},
+			},
+		},
+		{
+			// Division by zero resolves to nil (i.e., the comparison is false).
+			"select k from Numbers where v.B / 0 = 0 or (v.I32 + v.F32) / 2 < 100",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("002")},
 			},
 		},
+		{
+			"select k, v.Key from BigTable where k between \"110\" and \"112\"",
+			[]string{"k", "v.Key"},
+			[][]*vom.RawBytes{svPair("110"), svPair("111"), svPair("112")},
+		},
+		{
+			"select k, v.Key from BigTable where k in (\"300\", \"100\", \"x\")",
+			[]string{"k", "v.Key"},
+			[][]*vom.RawBytes{svPair("100"), svPair("300")},
+		},
+		{
+			"select k, v.Key from BigTable where k not between \"101\" and \"299\"",
+			[]string{"k", "v.Key"},
+			[][]*vom.RawBytes{svPair("100"), svPair("300")},
+		},
 	}
 
 	for _, test := range basic {
-		initTables()
-		// Delete
-		headers, rs, err := internal.Exec(db, test.delQuery)
+		headers, rs, err := internal.Exec(db, test.query)
 		if err != nil {
-			t.Errorf("delQuery: %s; got %v, want nil", test.delQuery, err)
+			t.Errorf("query: %s; got %v, want nil", test.query, err)
 		} else {
 			// Collect results.
-			r := [][]*vom.RawBytes{}
+			rbs := [][]*vom.RawBytes{}
 			for rs.Advance() {
-				r = append(r, rs.Result())
-			}
-			if !reflect.DeepEqual(test.delResults, r) {
-				t.Errorf("delQuery: %s; got %v, want %v", test.delQuery, r, test.delResults)
-			}
-			if !reflect.DeepEqual(test.delHeaders, headers) {
-				t.Errorf("delQuery: %s; got %v, want %v", test.delQuery, headers, test.delHeaders)
+				rbs = append(rbs, rs.Result())
 			}
-		}
-		// Select
-		headers, rs, err = internal.Exec(db, test.selQuery)
-		if err != nil {
-			t.Errorf("selQuery: %s; got %v, want nil", test.selQuery, err)
-		} else {
-			// Collect results.
-			r := [][]*vom.RawBytes{}
-			for rs.Advance() {
-				r = append(r, rs.Result())
+			if err := rs.Err(); err != nil {
+				t.Errorf("query: %s; got %v, want nil", test.query, err)
 			}
-			if !reflect.DeepEqual(test.selResults, r) {
-				t.Errorf("selQuery: %s; got %v, want %v", test.selQuery, r, test.selResults)
+			if got, want := vdl.ValueOf(rbs), vdl.ValueOf(test.r); !vdl.EqualValue(got, want) {
+				t.Errorf("query: %s; got %v, want %v", test.query, got, want)
 			}
-			if !reflect.DeepEqual(test.selHeaders, headers) {
-				t.Errorf("selQuery: %s; got %v, want %v", test.selQuery, headers, test.selHeaders)
+			if !reflect.DeepEqual(test.headers, headers) {
+				t.Errorf("query: %s; got %#v, want %#v", test.query, headers, test.headers)
 			}
 		}
 	}
 }
 
-func svPair(s string) []*vom.RawBytes {
-	v := vom.RawBytesOf(s)
-	return []*vom.RawBytes{v, v}
-}
+func TestOrderBy(t *testing.T) {
+	initTables()
+	// Sort in runs of 16 rows so that queries over BigTable are sorted
+	// externally.
+	defer internal.SetSortRunSize(internal.SetSortRunSize(16))
 
-// Genearate k,v pairs for start to finish (*INCLUSIVE*)
-func svPairs(start, finish int64) [][]*vom.RawBytes {
-	retVal := [][]*vom.RawBytes{}
-	for i := start; i <= finish; i++ {
-		v := vom.RawBytesOf(fmt.Sprintf("%d", i))
-		retVal = append(retVal, []*vom.RawBytes{v, v})
+	bigTableDesc := [][]*vom.RawBytes{}
+	for i := 149; i >= 100; i-- {
+		bigTableDesc = append(bigTableDesc, []*vom.RawBytes{vom.RawBytesOf(fmt.Sprintf("%d", i))})
+	}
+	bigTableTail := [][]*vom.RawBytes{}
+	for i := 280; i < 301; i++ {
+		bigTableTail = append(bigTableTail, []*vom.RawBytes{vom.RawBytesOf(fmt.Sprintf("%d", i))})
 	}
-	return retVal
-}
 
-// Use Now to verify it is "pre" executed such that all the rows
-// have the same time.
-func TestPreExecFunctions(t *testing.T) {
-	initTables()
-	basic := []preExecFunctionTest{
+	basic := []execSelectTest{
 		{
-			"select Now() from Customer",
-			[]string{
-				"Now",
+			"select k from Numbers order by v.I64 desc",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("003")},
+				{vom.RawBytesOf("001")},
+				{vom.RawBytesOf("002")},
+			},
+		},
+		{
+			"select k, v.B from Numbers order by v.B",
+			[]string{"k", "v.B"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("002"), vom.RawBytesOf(byte(9))},
+				{vom.RawBytesOf("001"), vom.RawBytesOf(byte(12))},
+				{vom.RawBytesOf("003"), vom.RawBytesOf(byte(210))},
+			},
+		},
+		{
+			// Multiple sort keys; the first is equal for all rows.
+			"select k from Numbers order by Type(v), v.I16 desc",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001")},
+				{vom.RawBytesOf("003")},
+				{vom.RawBytesOf("002")},
+			},
+		},
+		{
+			// Limit and offset apply to the sorted rows.
+			"select k from Numbers order by v.F32 desc limit 2 offset 1",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001")},
+				{vom.RawBytesOf("002")},
+			},
+		},
+		{
+			"select k from Numbers where v.I64 > 100 order by k desc",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("003")},
+				{vom.RawBytesOf("001")},
 			},
 		},
+		{
+			// Sorted in multiple runs, which are merged.
+			"select k from BigTable where k < \"150\" order by v.Key desc",
+			[]string{"k"},
+			bigTableDesc,
+		},
+		{
+			// The sort is stable, rows with equal keys remain in key order.
+			"select k from BigTable where k >= \"280\" order by Len(k)",
+			[]string{"k"},
+			bigTableTail,
+		},
+		{
+			"select k from BigTable where k >= \"280\" order by Len(k) desc limit 3 offset 18",
+			[]string{"k"},
+			bigTableTail[18:],
+		},
 	}
 
 	for _, test := range basic {
@@ -2422,88 +2503,1070 @@ func TestPreExecFunctions(t *testing.T) {
 		if err != nil {
 			t.Errorf("query: %s; got %v, want nil", test.query, err)
 		} else {
-			// Check that all results are identical.
 			// Collect results.
-			var last []*vom.RawBytes
+			rbs := [][]*vom.RawBytes{}
 			for rs.Advance() {
-				result := rs.Result()
-				if last != nil && !reflect.DeepEqual(last, result) {
-					t.Errorf("query: %s; got %v, want %v", test.query, result, last)
-				}
-				last = result
+				rbs = append(rbs, rs.Result())
+			}
+			if err := rs.Err(); err != nil {
+				t.Errorf("query: %s; got %v, want nil", test.query, err)
+			}
+			if got, want := vdl.ValueOf(rbs), vdl.ValueOf(test.r); !vdl.EqualValue(got, want) {
+				t.Errorf("query: %s; got %v, want %v", test.query, got, want)
 			}
 			if !reflect.DeepEqual(test.headers, headers) {
-				t.Errorf("query: %s; got %v, want %v", test.query, headers, test.headers)
+				t.Errorf("query: %s; got %#v, want %#v", test.query, headers, test.headers)
 			}
 		}
 	}
 }
 
-func appendZeroByte(start string) string {
-	limit := []byte(start)
-	limit = append(limit, 0)
-	return string(limit)
-
-}
-
-func plusOne(start string) string {
-	limit := []byte(start)
-	for len(limit) > 0 {
-		if limit[len(limit)-1] == 255 {
-			limit = limit[:len(limit)-1] // chop off trailing \x00
-		} else {
-			limit[len(limit)-1] += 1 // add 1
-			break                    // no carry
-		}
-	}
-	return string(limit)
-}
-
-func TestKeyRanges(t *testing.T) {
+func TestGroupBy(t *testing.T) {
 	initTables()
-	basic := []keyRangesTest{
+	basic := []execSelectTest{
 		{
-			// Need all keys
-			"select k, v from Customer",
-			&ds.IndexRanges{
-				FieldName:  "k",
-				Kind:       vdl.String,
-				NilAllowed: true,
-				StringRanges: &ds.StringFieldRanges{
-					ds.StringFieldRange{Start: "", Limit: ""},
-				},
+			// Without a group by clause, all rows are in a single group.
+			"select Count(k), Sum(v.I64), Min(v.B), Max(v.F64), Avg(v.I16) from Numbers",
+			[]string{"Count", "Sum", "Min", "Max", "Avg"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf(int64(3)), vom.RawBytesOf(int64(426)), vom.RawBytesOf(int64(9)), vom.RawBytesOf(float64(210)), vom.RawBytesOf(float64(3365))},
 			},
-			nil,
 		},
 		{
-			// Keys 001 or 003
-			"   select  k,  v from Customer where k = \"001\" or k = \"003\"",
-			&ds.IndexRanges{
-				FieldName:  "k",
-				Kind:       vdl.String,
-				NilAllowed: false,
-				StringRanges: &ds.StringFieldRanges{
-					ds.StringFieldRange{Start: "001", Limit: appendZeroByte("001")},
-					ds.StringFieldRange{Start: "003", Limit: appendZeroByte("003")},
-				},
+			// Even if there are no rows.
+			"select Count(k), Sum(v.I64), Max(k) from Numbers where v.I64 > 1000",
+			[]string{"Count", "Sum", "Max"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf(int64(0)), vom.RawBytesOf(nil), vom.RawBytesOf(nil)},
 			},
-			nil,
 		},
 		{
-			// Keys 001 and 003 (resulting in no keys)
-			"   select  k,  v from Customer where k = \"001\" and k = \"003\"",
-			&ds.IndexRanges{
-				FieldName:    "k",
-				Kind:         vdl.String,
-				NilAllowed:   false,
-				StringRanges: &ds.StringFieldRanges{},
+			"select k, Count(k) from Numbers where v.I64 > 1000 group by k",
+			[]string{"k", "Count"},
+			[][]*vom.RawBytes{},
+		},
+		{
+			"select CountDistinct(Len(k)) from BigTable",
+			[]string{"CountDistinct"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf(int64(1))},
 			},
-			nil,
 		},
 		{
-			// Need all keys
-			"select  k,  v from Customer where k like \"%\" or k like \"001%\" or k like \"002%\"",
-			&ds.IndexRanges{
+			// Groups are returned in the order of their keys.
+			"select Mod(Atof(k), 2), Count(k), Min(k), Max(k) from BigTable group by Mod(Atof(k), 2)",
+			[]string{"Mod", "Count", "Min", "Max"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf(float64(0)), vom.RawBytesOf(int64(101)), vom.RawBytesOf("100"), vom.RawBytesOf("300")},
+				{vom.RawBytesOf(float64(1)), vom.RawBytesOf(int64(100)), vom.RawBytesOf("101"), vom.RawBytesOf("299")},
+			},
+		},
+		{
+			"select Mod(Atof(k), 4), Count(k) from BigTable group by Mod(Atof(k), 4) having Count(k) < 51 order by Count(k), Mod(Atof(k), 4) desc limit 2 offset 1",
+			[]string{"Mod", "Count"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf(float64(2)), vom.RawBytesOf(int64(50))},
+				{vom.RawBytesOf(float64(1)), vom.RawBytesOf(int64(50))},
+			},
+		},
+	}
+
+	for _, test := range basic {
+		headers, rs, err := internal.Exec(db, test.query)
+		if err != nil {
+			t.Errorf("query: %s; got %v, want nil", test.query, err)
+		} else {
+			// Collect results.
+			rbs := [][]*vom.RawBytes{}
+			for rs.Advance() {
+				rbs = append(rbs, rs.Result())
+			}
+			if err := rs.Err(); err != nil {
+				t.Errorf("query: %s; got %v, want nil", test.query, err)
+			}
+			if got, want := vdl.ValueOf(rbs), vdl.ValueOf(test.r); !vdl.EqualValue(got, want) {
+				t.Errorf("query: %s; got %v, want %v", test.query, got, want)
+			}
+			if !reflect.DeepEqual(test.headers, headers) {
+				t.Errorf("query: %s; got %#v, want %#v", test.query, headers, test.headers)
+			}
+		}
+	}
+}
+
+// Errors computing aggregates are returned by the result stream.
+func TestGroupByErrors(t *testing.T) {
+	initTables()
+	basic := []execSelectErrorTest{
+		{
+			"select Sum(k) from Numbers",
+			syncql.NewErrAggregateArgNotNumeric(db.GetContext(), 11, "Sum"),
+		},
+		{
+			"select v.B, Avg(v.B), Avg(k) from Numbers group by v.B",
+			syncql.NewErrAggregateArgNotNumeric(db.GetContext(), 26, "Avg"),
+		},
+	}
+
+	for _, test := range basic {
+		_, rs, err := internal.Exec(db, test.query)
+		if err != nil {
+			t.Errorf("query: %s; got %v, want nil", test.query, err)
+			continue
+		}
+		for rs.Advance() {
+			t.Errorf("query: %s; unexpected result: %v", test.query, rs.Result())
+		}
+		// Test both that the IDs compare and the text compares (since the offset needs to match).
+		if err := rs.Err(); verror.ErrorID(err) != verror.ErrorID(test.err) || err.Error() != test.err.Error() {
+			t.Errorf("query: %s; got %v, want %v", test.query, err, test.err)
+		}
+	}
+}
+
+// The results of the aggregates of one execution of a prepared statement must
+// not leak into another execution.
+func TestGroupByPreparedConcurrently(t *testing.T) {
+	initTables()
+	query := "select Count(k), Max(k) from BigTable where k < ? having Count(k) > 0 order by Count(k)"
+	ps, err := internal.Create(db).PrepareStatement(query)
+	if err != nil {
+		t.Fatalf("query: %s; got %v, want nil", query, err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			bound := fmt.Sprint(101 + 10*n)
+			for j := 0; j < 10; j++ {
+				_, rs, err := ps.Exec(vom.RawBytesOf(bound))
+				if err != nil {
+					t.Errorf("query: %s, param: %s; got %v, want nil", query, bound, err)
+					return
+				}
+				for rs.Advance() {
+					var count int64
+					var max string
+					if err := rs.Result()[0].ToValue(&count); err != nil {
+						t.Errorf("query: %s, param: %s; got %v, want nil", query, bound, err)
+					}
+					if err := rs.Result()[1].ToValue(&max); err != nil {
+						t.Errorf("query: %s, param: %s; got %v, want nil", query, bound, err)
+					}
+					if got, want := count, int64(1+10*n); got != want {
+						t.Errorf("query: %s, param: %s; got count %v, want %v", query, bound, got, want)
+					}
+					if got, want := max, fmt.Sprint(100+10*n); got != want {
+						t.Errorf("query: %s, param: %s; got max %v, want %v", query, bound, got, want)
+					}
+				}
+				if err := rs.Err(); err != nil {
+					t.Errorf("query: %s, param: %s; got %v, want nil", query, bound, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestJoin(t *testing.T) {
+	initTables()
+	basic := []execSelectTest{
+		{
+			// The row of BigTable is looked up by key.
+			"select n.k, n.v.I64, b.k from Numbers n join BigTable b on b.k = Str(n.v.I64)",
+			[]string{"n.k", "n.v.I64", "b.k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001"), vom.RawBytesOf(int64(128)), vom.RawBytesOf("128")},
+				{vom.RawBytesOf("003"), vom.RawBytesOf(int64(210)), vom.RawBytesOf("210")},
+			},
+		},
+		{
+			"select n.k, b.k from Numbers n inner join BigTable b on b.v.Key > Str(n.v.I64) and b.k < \"131\"",
+			[]string{"n.k", "b.k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001"), vom.RawBytesOf("129")},
+				{vom.RawBytesOf("001"), vom.RawBytesOf("130")},
+			},
+		},
+		{
+			// Rows of Numbers without a matching row of BigTable are joined with nil.
+			"select n.k, b.k from Numbers n left join BigTable b on b.k = Str(n.v.I64)",
+			[]string{"n.k", "b.k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001"), vom.RawBytesOf("128")},
+				{vom.RawBytesOf("002"), vom.RawBytesOf(nil)},
+				{vom.RawBytesOf("003"), vom.RawBytesOf("210")},
+			},
+		},
+		{
+			"select n.k from Numbers n left outer join BigTable b on b.k = Str(n.v.I64) where b.k is nil",
+			[]string{"n.k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("002")},
+			},
+		},
+		{
+			"select a.k, b.k from Numbers a join Numbers b on b.v.I64 < a.v.I64 where a.k <> \"002\" order by a.k desc, b.k",
+			[]string{"a.k", "b.k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("003"), vom.RawBytesOf("001")},
+				{vom.RawBytesOf("003"), vom.RawBytesOf("002")},
+				{vom.RawBytesOf("001"), vom.RawBytesOf("002")},
+			},
+		},
+		{
+			"select n.k, Count(b.k) from Numbers n left join BigTable b on b.k > Str(n.v.I64) and b.k < \"135\" group by n.k order by Count(b.k) desc",
+			[]string{"n.k", "Count"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001"), vom.RawBytesOf(int64(6))},
+				{vom.RawBytesOf("002"), vom.RawBytesOf(int64(0))},
+				{vom.RawBytesOf("003"), vom.RawBytesOf(int64(0))},
+			},
+		},
+	}
+
+	for _, test := range basic {
+		headers, rs, err := internal.Exec(db, test.query)
+		if err != nil {
+			t.Errorf("query: %s; got %v, want nil", test.query, err)
+		} else {
+			// Collect results.
+			rbs := [][]*vom.RawBytes{}
+			for rs.Advance() {
+				rbs = append(rbs, rs.Result())
+			}
+			if err := rs.Err(); err != nil {
+				t.Errorf("query: %s; got %v, want nil", test.query, err)
+			}
+			if got, want := vdl.ValueOf(rbs), vdl.ValueOf(test.r); !vdl.EqualValue(got, want) {
+				t.Errorf("query: %s; got %v, want %v", test.query, got, want)
+			}
+			if !reflect.DeepEqual(test.headers, headers) {
+				t.Errorf("query: %s; got %#v, want %#v", test.query, headers, test.headers)
+			}
+		}
+	}
+}
+
+func TestDistinctUnion(t *testing.T) {
+	initTables()
+	basic := []execSelectTest{
+		{
+			"select distinct Len(k) from BigTable",
+			[]string{"Len"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf(int64(3))},
+			},
+		},
+		{
+			// Duplicates are eliminated before the limit and offset clauses
+			// are applied.
+			"select distinct Mod(Atof(k), 3) from BigTable limit 2 offset 1",
+			[]string{"Mod"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf(float64(2))},
+				{vom.RawBytesOf(float64(0))},
+			},
+		},
+		{
+			"select distinct Mod(Atof(k), 3) from BigTable order by Mod(Atof(k), 3) desc",
+			[]string{"Mod"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf(float64(2))},
+				{vom.RawBytesOf(float64(1))},
+				{vom.RawBytesOf(float64(0))},
+			},
+		},
+		{
+			"select distinct Count(k) from BigTable group by Mod(Atof(k), 4)",
+			[]string{"Count"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf(int64(51))},
+				{vom.RawBytesOf(int64(50))},
+			},
+		},
+		{
+			"select k from Numbers union select k from BigTable where k < \"102\"",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001")},
+				{vom.RawBytesOf("002")},
+				{vom.RawBytesOf("003")},
+				{vom.RawBytesOf("100")},
+				{vom.RawBytesOf("101")},
+			},
+		},
+		{
+			"select Len(k) from Numbers union select Len(k) from BigTable",
+			[]string{"Len"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf(int64(3))},
+			},
+		},
+		{
+			"select Len(k) from Numbers where k = \"001\" union all select Len(k) from BigTable where k < \"102\"",
+			[]string{"Len"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf(int64(3))},
+				{vom.RawBytesOf(int64(3))},
+				{vom.RawBytesOf(int64(3))},
+			},
+		},
+		{
+			// The union eliminates the duplicates of the preceding union all.
+			"select k from Numbers where k = \"001\" union all select k from Numbers where k = \"001\" union select k from Numbers",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001")},
+				{vom.RawBytesOf("002")},
+				{vom.RawBytesOf("003")},
+			},
+		},
+		{
+			// The results of the select following union all are appended as is.
+			"select k from Numbers union select k from Numbers where k < \"003\" union all select k from Numbers where k = \"001\"",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001")},
+				{vom.RawBytesOf("002")},
+				{vom.RawBytesOf("003")},
+				{vom.RawBytesOf("001")},
+			},
+		},
+		{
+			// The limit and offset clauses following the last select apply
+			// to the union.
+			"select k from Numbers union all select k from BigTable limit 3 offset 2",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("003")},
+				{vom.RawBytesOf("100")},
+				{vom.RawBytesOf("101")},
+			},
+		},
+		{
+			// Those of the other selects apply to the select.
+			"select k from BigTable limit 2 union all select k from Numbers limit 3",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("100")},
+				{vom.RawBytesOf("101")},
+				{vom.RawBytesOf("001")},
+			},
+		},
+		{
+			"select k from Numbers union select k from Numbers offset 5",
+			[]string{"k"},
+			[][]*vom.RawBytes{},
+		},
+		{
+			// The column headings are those of the first select.
+			"select k as Key, v.I64 from Numbers where k = \"001\" union select k, v.I16 from Numbers where k = \"002\"",
+			[]string{"Key", "v.I64"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001"), vom.RawBytesOf(int64(128))},
+				{vom.RawBytesOf("002"), vom.RawBytesOf(int16(9))},
+			},
+		},
+	}
+
+	for _, test := range basic {
+		headers, rs, err := internal.Exec(db, test.query)
+		if err != nil {
+			t.Errorf("query: %s; got %v, want nil", test.query, err)
+		} else {
+			// Collect results.
+			rbs := [][]*vom.RawBytes{}
+			for rs.Advance() {
+				rbs = append(rbs, rs.Result())
+			}
+			if err := rs.Err(); err != nil {
+				t.Errorf("query: %s; got %v, want nil", test.query, err)
+			}
+			if got, want := vdl.ValueOf(rbs), vdl.ValueOf(test.r); !vdl.EqualValue(got, want) {
+				t.Errorf("query: %s; got %v, want %v", test.query, got, want)
+			}
+			if !reflect.DeepEqual(test.headers, headers) {
+				t.Errorf("query: %s; got %#v, want %#v", test.query, headers, test.headers)
+			}
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	initTables()
+	basic := []execSelectTest{
+		{
+			"explain select k, v.I64 from Numbers where k >= \"002\" and v.I64 > 10 limit 1 offset 1",
+			[]string{"Table", "Property", "Value"},
+			[][]*vom.RawBytes{
+				planRow("Numbers", "Access", "key range scan"),
+				planRow("Numbers", "IndexRanges", "k: [\"002\", <end>)"),
+				planRow("", "KeyOnlyPredicate", "k >= \"002\""),
+				planRow("", "ValuePredicate", "v.I64 > 10"),
+				planRow("", "WhereEvaluation", "key; the value is fetched only if the key does not determine the result"),
+				planRow("", "Projection", "k"),
+				planRow("", "Projection", "v.I64"),
+				planRow("", "Limit", "1"),
+				planRow("", "Offset", "1"),
+				planRow("", "LimitOffset", "applied while scanning; the scan stops once the limit is reached"),
+				planRow("", "EstimatedCost", "100"),
+			},
+		},
+		{
+			// The key alone determines the rows, so the scan reads at most 5.
+			"explain select k from BigTable where k = \"150\" or k like \"20%\" limit 5",
+			[]string{"Table", "Property", "Value"},
+			[][]*vom.RawBytes{
+				planRow("BigTable", "Access", "key range scan"),
+				planRow("BigTable", "IndexRanges", "k: [\"150\", \"150\\x00\"), [\"20\", \"21\")"),
+				planRow("", "KeyOnlyPredicate", "k = \"150\""),
+				planRow("", "KeyOnlyPredicate", "k like \"20%\""),
+				planRow("", "WhereEvaluation", "key only"),
+				planRow("", "Projection", "k"),
+				planRow("", "Limit", "5"),
+				planRow("", "LimitOffset", "applied while scanning; the scan stops once the limit is reached"),
+				planRow("", "EstimatedCost", "5"),
+			},
+		},
+		{
+			"explain select k from Numbers where k = \"001\" and k = \"002\"",
+			[]string{"Table", "Property", "Value"},
+			[][]*vom.RawBytes{
+				planRow("Numbers", "Access", "none (no row can satisfy the where clause)"),
+				planRow("Numbers", "IndexRanges", "k: none"),
+				planRow("", "KeyOnlyPredicate", "k = \"001\""),
+				planRow("", "KeyOnlyPredicate", "k = \"002\""),
+				planRow("", "WhereEvaluation", "key only"),
+				planRow("", "Projection", "k"),
+				planRow("", "EstimatedCost", "0"),
+			},
+		},
+		{
+			"explain select v.I64, Count(k) as Cnt from Numbers group by v.I64 having Count(k) > 1 order by v.I64 desc limit 2",
+			[]string{"Table", "Property", "Value"},
+			[][]*vom.RawBytes{
+				planRow("Numbers", "Access", "table scan"),
+				planRow("Numbers", "IndexRanges", "k: [\"\", <end>), nil"),
+				planRow("", "WhereEvaluation", "none"),
+				planRow("", "Projection", "v.I64"),
+				planRow("", "Projection", "Count(k) as Cnt"),
+				planRow("", "GroupBy", "v.I64"),
+				planRow("", "Having", "Count(k) > 1"),
+				planRow("", "OrderBy", "v.I64 desc"),
+				planRow("", "Limit", "2"),
+				planRow("", "LimitOffset", "applied to the groups after all rows are grouped"),
+				planRow("", "EstimatedCost", "10000"),
+			},
+		},
+		{
+			// BigTable is read once per row of Numbers.
+			"explain select n.k, b.k from Numbers n left join BigTable b on b.k = Str(n.v.I64) where n.k <> \"002\"",
+			[]string{"Table", "Property", "Value"},
+			[][]*vom.RawBytes{
+				planRow("n", "Access", "key range scan"),
+				planRow("n", "IndexRanges", "k: [\"\", \"002\"), [\"002\\x00\", <end>)"),
+				planRow("b", "Join", "left join on b.k = Str(n.v.I64)"),
+				planRow("b", "Access", "key lookup: k = Str(n.v.I64)"),
+				planRow("", "ValuePredicate", "n.k <> \"002\""),
+				planRow("", "WhereEvaluation", "value"),
+				planRow("", "Projection", "n.k"),
+				planRow("", "Projection", "b.k"),
+				planRow("", "EstimatedCost", "400"),
+			},
+		},
+		{
+			"explain select k from BigTable where k between \"110\" and \"119\" and v.Key not in (\"111\", \"112\")",
+			[]string{"Table", "Property", "Value"},
+			[][]*vom.RawBytes{
+				planRow("BigTable", "Access", "key range scan"),
+				planRow("BigTable", "IndexRanges", "k: [\"110\", \"119\\x00\")"),
+				planRow("", "KeyOnlyPredicate", "k between \"110\" and \"119\""),
+				planRow("", "ValuePredicate", "v.Key not in (\"111\", \"112\")"),
+				planRow("", "WhereEvaluation", "key; the value is fetched only if the key does not determine the result"),
+				planRow("", "Projection", "k"),
+				planRow("", "EstimatedCost", "100"),
+			},
+		},
+		{
+			"explain select k from Numbers where v.I64 * 2 > 100 + v.I16",
+			[]string{"Table", "Property", "Value"},
+			[][]*vom.RawBytes{
+				planRow("Numbers", "Access", "table scan"),
+				planRow("Numbers", "IndexRanges", "k: [\"\", <end>), nil"),
+				planRow("", "ValuePredicate", "(v.I64 * 2) > (100 + v.I16)"),
+				planRow("", "WhereEvaluation", "value"),
+				planRow("", "Projection", "k"),
+				planRow("", "EstimatedCost", "10000"),
+			},
+		},
+		{
+			// The scan can't stop once the limit is reached as duplicates
+			// are not counted.
+			"explain select distinct k from BigTable where k like \"20%\" limit 5",
+			[]string{"Table", "Property", "Value"},
+			[][]*vom.RawBytes{
+				planRow("BigTable", "Access", "key range scan"),
+				planRow("BigTable", "IndexRanges", "k: [\"20\", \"21\")"),
+				planRow("", "KeyOnlyPredicate", "k like \"20%\""),
+				planRow("", "WhereEvaluation", "key only"),
+				planRow("", "Projection", "k"),
+				planRow("", "Distinct", "duplicate rows are eliminated"),
+				planRow("", "Limit", "5"),
+				planRow("", "LimitOffset", "applied while scanning; the scan stops once the limit is reached"),
+				planRow("", "EstimatedCost", "100"),
+			},
+		},
+		{
+			"explain select k from Numbers where k = \"001\" union all select k from BigTable limit 3",
+			[]string{"Table", "Property", "Value"},
+			[][]*vom.RawBytes{
+				planRow("Numbers", "Access", "key lookup"),
+				planRow("Numbers", "IndexRanges", "k: [\"001\", \"001\\x00\")"),
+				planRow("", "KeyOnlyPredicate", "k = \"001\""),
+				planRow("", "WhereEvaluation", "key only"),
+				planRow("", "Projection", "k"),
+				planRow("", "EstimatedCost", "1"),
+				planRow("", "Union", "union all"),
+				planRow("BigTable", "Access", "table scan"),
+				planRow("BigTable", "IndexRanges", "k: [\"\", <end>), nil"),
+				planRow("", "WhereEvaluation", "none"),
+				planRow("", "Projection", "k"),
+				planRow("", "EstimatedCost", "10000"),
+				planRow("", "Limit", "3"),
+				planRow("", "LimitOffset", "applied to the results of the union"),
+				planRow("", "EstimatedCost", "10001"),
+			},
+		},
+		{
+			// Nothing is deleted.
+			"explain delete from BigTable where k > \"250\" limit 3",
+			[]string{"Table", "Property", "Value"},
+			[][]*vom.RawBytes{
+				planRow("BigTable", "Access", "key range scan"),
+				planRow("BigTable", "IndexRanges", "k: [\"250\\x00\", <end>)"),
+				planRow("", "KeyOnlyPredicate", "k > \"250\""),
+				planRow("", "WhereEvaluation", "key only"),
+				planRow("", "Limit", "3"),
+				planRow("", "LimitOffset", "applied while scanning; the scan stops once the limit is reached"),
+				planRow("", "EstimatedCost", "3"),
+			},
+		},
+		{
+			"explain update BigTable set v.Key = \"x\" where k >= \"290\" limit 3",
+			[]string{"Table", "Property", "Value"},
+			[][]*vom.RawBytes{
+				planRow("BigTable", "Access", "key range scan"),
+				planRow("BigTable", "IndexRanges", "k: [\"290\", <end>)"),
+				planRow("", "KeyOnlyPredicate", "k >= \"290\""),
+				planRow("", "WhereEvaluation", "key only"),
+				planRow("", "Limit", "3"),
+				planRow("", "LimitOffset", "applied while scanning; the scan stops once the limit is reached"),
+				planRow("", "Assignment", "v.Key = \"x\""),
+				planRow("", "Write", "after all rows to be updated are read"),
+				planRow("", "EstimatedCost", "3"),
+			},
+		},
+		{
+			"explain insert into BigTable (k, v) values (\"400\", 1), (\"401\", Lowercase(\"Y\"))",
+			[]string{"Table", "Property", "Value"},
+			[][]*vom.RawBytes{
+				planRow("BigTable", "Access", "key lookup"),
+				planRow("BigTable", "Row", "k = \"400\", v = 1"),
+				planRow("BigTable", "Row", "k = \"401\", v = \"y\""),
+				planRow("BigTable", "ValueType", "type of the value of an existing row, if any"),
+				planRow("", "Write", "after the keys of all rows are found not to exist"),
+				planRow("", "EstimatedCost", "2"),
+			},
+		},
+	}
+
+	for _, test := range basic {
+		headers, rs, err := internal.Exec(db, test.query)
+		if err != nil {
+			t.Errorf("query: %s; got %v, want nil", test.query, err)
+		} else {
+			// Collect results.
+			rbs := [][]*vom.RawBytes{}
+			for rs.Advance() {
+				rbs = append(rbs, rs.Result())
+			}
+			if err := rs.Err(); err != nil {
+				t.Errorf("query: %s; got %v, want nil", test.query, err)
+			}
+			if got, want := vdl.ValueOf(rbs), vdl.ValueOf(test.r); !vdl.EqualValue(got, want) {
+				t.Errorf("query: %s; got %v, want %v", test.query, got, want)
+			}
+			if !reflect.DeepEqual(test.headers, headers) {
+				t.Errorf("query: %s; got %#v, want %#v", test.query, headers, test.headers)
+			}
+		}
+	}
+	if got, want := len(bigTable.rows), 201; got != want {
+		t.Errorf("explain delete or insert wrote rows: got %d rows, want %d", got, want)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	basic := []execDeleteTest{
+		{
+			"delete from Customer where k like \"001%\"",
+			[]string{"Count"},
+			[][]*vom.RawBytes{{vom.RawBytesOf(4)}},
+			"select k from Customer",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("002")},
+				{vom.RawBytesOf("002001")},
+				{vom.RawBytesOf("002002")},
+				{vom.RawBytesOf("002003")},
+				{vom.RawBytesOf("002004")},
+				{vom.RawBytesOf("003")},
+			},
+		},
+		{
+			"delete from Customer where k = \"001\"",
+			[]string{"Count"},
+			[][]*vom.RawBytes{{vom.RawBytesOf(1)}},
+			"select k from Customer",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001001")},
+				{vom.RawBytesOf("001002")},
+				{vom.RawBytesOf("001003")},
+				{vom.RawBytesOf("002")},
+				{vom.RawBytesOf("002001")},
+				{vom.RawBytesOf("002002")},
+				{vom.RawBytesOf("002003")},
+				{vom.RawBytesOf("002004")},
+				{vom.RawBytesOf("003")},
+			},
+		},
+		{
+			"delete from Customer where Type(v) like \"%Customer\" and v.Name like \"%Masterson\"",
+			[]string{"Count"},
+			[][]*vom.RawBytes{{vom.RawBytesOf(1)}},
+			"select k from Customer where Type(v) like \"%Customer\"",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001")},
+				{vom.RawBytesOf("003")},
+			},
+		},
+		{
+			"delete from Customer where Type(v) like \"%Invoice\" and v.CustId = 1 limit 2",
+			[]string{"Count"},
+			[][]*vom.RawBytes{{vom.RawBytesOf(2)}},
+			"select k from Customer where Type(v) like \"%Invoice\"",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001003")},
+				{vom.RawBytesOf("002001")},
+				{vom.RawBytesOf("002002")},
+				{vom.RawBytesOf("002003")},
+				{vom.RawBytesOf("002004")},
+			},
+		},
+	}
+
+	for _, test := range basic {
+		initTables()
+		// Delete
+		headers, rs, err := internal.Exec(db, test.delQuery)
+		if err != nil {
+			t.Errorf("delQuery: %s; got %v, want nil", test.delQuery, err)
+		} else {
+			// Collect results.
+			r := [][]*vom.RawBytes{}
+			for rs.Advance() {
+				r = append(r, rs.Result())
+			}
+			if !reflect.DeepEqual(test.delResults, r) {
+				t.Errorf("delQuery: %s; got %v, want %v", test.delQuery, r, test.delResults)
+			}
+			if !reflect.DeepEqual(test.delHeaders, headers) {
+				t.Errorf("delQuery: %s; got %v, want %v", test.delQuery, headers, test.delHeaders)
+			}
+		}
+		// Select
+		headers, rs, err = internal.Exec(db, test.selQuery)
+		if err != nil {
+			t.Errorf("selQuery: %s; got %v, want nil", test.selQuery, err)
+		} else {
+			// Collect results.
+			r := [][]*vom.RawBytes{}
+			for rs.Advance() {
+				r = append(r, rs.Result())
+			}
+			if !reflect.DeepEqual(test.selResults, r) {
+				t.Errorf("selQuery: %s; got %v, want %v", test.selQuery, r, test.selResults)
+			}
+			if !reflect.DeepEqual(test.selHeaders, headers) {
+				t.Errorf("selQuery: %s; got %v, want %v", test.selQuery, headers, test.selHeaders)
+			}
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	basic := []execWriteTest{
+		{
+			// Operands are resolved with the row before it is updated.
+			"update Numbers set v.I64 = 5, v.F64 = v.I64 where k = \"001\"",
+			[]string{"Count"},
+			[][]*vom.RawBytes{{vom.RawBytesOf(1)}},
+			"select k, v.I64, v.F64 from Numbers",
+			[]string{"k", "v.I64", "v.F64"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001"), vom.RawBytesOf(int64(5)), vom.RawBytesOf(float64(128))},
+				{vom.RawBytesOf("002"), vom.RawBytesOf(int64(88)), vom.RawBytesOf(float64(1.73205080757))},
+				{vom.RawBytesOf("003"), vom.RawBytesOf(int64(210)), vom.RawBytesOf(float64(210.0))},
+			},
+		},
+		{
+			"update Numbers set v.B = 7 where v.I64 > 100",
+			[]string{"Count"},
+			[][]*vom.RawBytes{{vom.RawBytesOf(2)}},
+			"select k, v.B from Numbers",
+			[]string{"k", "v.B"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001"), vom.RawBytesOf(byte(7))},
+				{vom.RawBytesOf("002"), vom.RawBytesOf(byte(9))},
+				{vom.RawBytesOf("003"), vom.RawBytesOf(byte(7))},
+			},
+		},
+		{
+			"update BigTable set v.Key = \"x\" where k >= \"290\" limit 3",
+			[]string{"Count"},
+			[][]*vom.RawBytes{{vom.RawBytesOf(3)}},
+			"select k from BigTable where v.Key = \"x\"",
+			[]string{"k"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("290")},
+				{vom.RawBytesOf("291")},
+				{vom.RawBytesOf("292")},
+			},
+		},
+		{
+			// Rows without the field are not updated.
+			"update Numbers set v.NoSuchField = 1",
+			[]string{"Count"},
+			[][]*vom.RawBytes{{vom.RawBytesOf(0)}},
+			"select k, v.I64 from Numbers where k = \"002\"",
+			[]string{"k", "v.I64"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("002"), vom.RawBytesOf(int64(88))},
+			},
+		},
+	}
+
+	for _, test := range basic {
+		initTables()
+		execWriteAndSelect(t, db, test)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	basic := []execWriteTest{
+		{
+			// Values are written as they are to tables whose rows have
+			// values of different types.
+			"insert into BigTable (k, v) values (\"400\", \"x\"), (\"401\", Lowercase(\"Y\"))",
+			[]string{"Count"},
+			[][]*vom.RawBytes{{vom.RawBytesOf(2)}},
+			"select k, v from BigTable where k > \"300\"",
+			[]string{"k", "v"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("400"), vom.RawBytesOf("x")},
+				{vom.RawBytesOf("401"), vom.RawBytesOf("y")},
+			},
+		},
+		{
+			// Values are converted to the type of the values of the table.
+			"insert into Numbers (v, k) values (42.0, StrCat(\"0\", \"00\"))",
+			[]string{"Count"},
+			[][]*vom.RawBytes{{vom.RawBytesOf(1)}},
+			"select k, v from Numbers where k = \"000\"",
+			[]string{"k", "v"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("000"), vom.RawBytesOf(int64(42))},
+			},
+		},
+	}
+
+	for _, test := range basic {
+		initTables()
+		execWriteAndSelect(t, newTypedDB(), test)
+	}
+
+	initTables()
+	query := "insert into Numbers (k, v) values (\"000\", 1), (\"004\", 42.5)"
+	want := syncql.NewErrAssignmentTypeError(db.GetContext(), 54, "v", errors.New("vdl: conversion from float64 into int64 loses precision: 42.5"))
+	if _, _, err := internal.Exec(newTypedDB(), query); verror.ErrorID(err) != verror.ErrorID(want) || err.Error() != want.Error() {
+		t.Errorf("query: %s; got %v, want %v", query, err, want)
+	}
+	if got, want := len(numTable.rows), 3; got != want {
+		t.Errorf("query: %s; got %d rows, want %d", query, got, want)
+	}
+}
+
+// typedTable is a table that reports the type of its values.
+type typedTable struct {
+	*table
+	valueType *vdl.Type
+}
+
+func (t typedTable) ValueType() *vdl.Type {
+	return t.valueType
+}
+
+// typedDB is a database in which BigTable has values of any type and
+// Numbers has int64 values.
+type typedDB struct {
+	mockDB
+}
+
+func newTypedDB() typedDB {
+	return typedDB{db}
+}
+
+func (db typedDB) GetTable(table string, writeAccessReq bool) (ds.Table, error) {
+	switch table {
+	case bigTable.name:
+		return typedTable{&bigTable, vdl.AnyType}, nil
+	case numTable.name:
+		return typedTable{&numTable, vdl.Int64Type}, nil
+	}
+	return db.mockDB.GetTable(table, writeAccessReq)
+}
+
+// racingTable is a table in which a row with key k is written concurrently
+// with an insert statement, after the statement checked that k does not exist.
+type racingTable struct {
+	*table
+	k string
+}
+
+func (t racingTable) PutIfAbsent(k string, v *vom.RawBytes) (bool, error) {
+	if k == t.k {
+		return false, nil
+	}
+	return true, t.Put(k, v)
+}
+
+type racingDB struct {
+	mockDB
+	table racingTable
+}
+
+func (db racingDB) GetTable(table string, writeAccessReq bool) (ds.Table, error) {
+	if table == db.table.name {
+		return db.table, nil
+	}
+	return db.mockDB.GetTable(table, writeAccessReq)
+}
+
+// Rows are inserted with PutIfAbsent if the table implements it.
+func TestInsertIfAbsent(t *testing.T) {
+	initTables()
+	rdb := racingDB{db, racingTable{&bigTable, "401"}}
+	query := "insert into BigTable (k, v) values (\"400\", ?), (\"401\", ?)"
+	ps, err := internal.Create(rdb).PrepareStatement(query)
+	if err != nil {
+		t.Fatalf("query: %s; got %v, want nil", query, err)
+	}
+	if _, _, err := ps.Exec(vom.RawBytesOf(td.BigData{Key: "400"}), vom.RawBytesOf(td.BigData{Key: "401"})); verror.ErrorID(err) != syncql.ErrKeyExists.ID || err.Error() != syncql.NewErrKeyExists(db.GetContext(), 48, "401").Error() {
+		t.Errorf("query: %s; got %v, want %v", query, err, syncql.NewErrKeyExists(db.GetContext(), 48, "401"))
+	}
+	if got, want := len(bigTable.rows), 202; got != want {
+		t.Errorf("query: %s; got %d rows, want %d", query, got, want)
+	}
+}
+
+// execWriteAndSelect executes the update or insert statement of the test
+// with db and then its select statement to check the rows written.
+func execWriteAndSelect(t *testing.T, db ds.Database, test execWriteTest) {
+	headers, rs, err := internal.Exec(db, test.query)
+	if err != nil {
+		t.Errorf("query: %s; got %v, want nil", test.query, err)
+		return
+	}
+	r := [][]*vom.RawBytes{}
+	for rs.Advance() {
+		r = append(r, rs.Result())
+	}
+	if !reflect.DeepEqual(test.results, r) {
+		t.Errorf("query: %s; got %v, want %v", test.query, r, test.results)
+	}
+	if !reflect.DeepEqual(test.headers, headers) {
+		t.Errorf("query: %s; got %v, want %v", test.query, headers, test.headers)
+	}
+	headers, rs, err = internal.Exec(db, test.selQuery)
+	if err != nil {
+		t.Errorf("selQuery: %s; got %v, want nil", test.selQuery, err)
+		return
+	}
+	rbs := [][]*vom.RawBytes{}
+	for rs.Advance() {
+		rbs = append(rbs, rs.Result())
+	}
+	if got, want := vdl.ValueOf(rbs), vdl.ValueOf(test.selResults); !vdl.EqualValue(got, want) {
+		t.Errorf("selQuery: %s; got %v, want %v", test.selQuery, got, want)
+	}
+	if !reflect.DeepEqual(test.selHeaders, headers) {
+		t.Errorf("selQuery: %s; got %v, want %v", test.selQuery, headers, test.selHeaders)
+	}
+}
+
+func planRow(table, property, value string) []*vom.RawBytes {
+	return []*vom.RawBytes{vom.RawBytesOf(table), vom.RawBytesOf(property), vom.RawBytesOf(value)}
+}
+
+func svPair(s string) []*vom.RawBytes {
+	v := vom.RawBytesOf(s)
+	return []*vom.RawBytes{v, v}
+}
+
+// Genearate k,v pairs for start to finish (*INCLUSIVE*)
+func svPairs(start, finish int64) [][]*vom.RawBytes {
+	retVal := [][]*vom.RawBytes{}
+	for i := start; i <= finish; i++ {
+		v := vom.RawBytesOf(fmt.Sprintf("%d", i))
+		retVal = append(retVal, []*vom.RawBytes{v, v})
+	}
+	return retVal
+}
+
+// Use Now to verify it is "pre" executed such that all the rows
+// have the same time.
+func TestPreExecFunctions(t *testing.T) {
+	initTables()
+	basic := []preExecFunctionTest{
+		{
+			"select Now() from Customer",
+			[]string{
+				"Now",
+			},
+		},
+	}
+
+	for _, test := range basic {
+		headers, rs, err := internal.Exec(db, test.query)
+		if err != nil {
+			t.Errorf("query: %s; got %v, want nil", test.query, err)
+		} else {
+			// Check that all results are identical.
+			// Collect results.
+			var last []*vom.RawBytes
+			for rs.Advance() {
+				result := rs.Result()
+				if last != nil && !reflect.DeepEqual(last, result) {
+					t.Errorf("query: %s; got %v, want %v", test.query, result, last)
+				}
+				last = result
+			}
+			if !reflect.DeepEqual(test.headers, headers) {
+				t.Errorf("query: %s; got %v, want %v", test.query, headers, test.headers)
+			}
+		}
+	}
+}
+
+func appendZeroByte(start string) string {
+	limit := []byte(start)
+	limit = append(limit, 0)
+	return string(limit)
+
+}
+
+func plusOne(start string) string {
+	limit := []byte(start)
+	for len(limit) > 0 {
+		if limit[len(limit)-1] == 255 {
+			limit = limit[:len(limit)-1] // chop off trailing \x00
+		} else {
+			limit[len(limit)-1] += 1 // add 1
+			break                    // no carry
+		}
+	}
+	return string(limit)
+}
+
+func TestKeyRanges(t *testing.T) {
+	initTables()
+	basic := []keyRangesTest{
+		{
+			// Need all keys
+			"select k, v from Customer",
+			&ds.IndexRanges{
+				FieldName:  "k",
+				Kind:       vdl.String,
+				NilAllowed: true,
+				StringRanges: &ds.StringFieldRanges{
+					ds.StringFieldRange{Start: "", Limit: ""},
+				},
+			},
+			nil,
+		},
+		{
+			// Keys 001 or 003
+			"   select  k,  v from Customer where k = \"001\" or k = \"003\"",
+			&ds.IndexRanges{
+				FieldName:  "k",
+				Kind:       vdl.String,
+				NilAllowed: false,
+				StringRanges: &ds.StringFieldRanges{
+					ds.StringFieldRange{Start: "001", Limit: appendZeroByte("001")},
+					ds.StringFieldRange{Start: "003", Limit: appendZeroByte("003")},
+				},
+			},
+			nil,
+		},
+		{
+			// Keys 001 or 003
+			"select k, v from Customer where k in (\"003\", \"001\")",
+			&ds.IndexRanges{
+				FieldName:  "k",
+				Kind:       vdl.String,
+				NilAllowed: false,
+				StringRanges: &ds.StringFieldRanges{
+					ds.StringFieldRange{Start: "001", Limit: appendZeroByte("001")},
+					ds.StringFieldRange{Start: "003", Limit: appendZeroByte("003")},
+				},
+			},
+			nil,
+		},
+		{
+			// Keys 001 through 003 (inclusive), except 002
+			"select k, v from Customer where k between \"001\" and \"003\" and k <> \"002\"",
+			&ds.IndexRanges{
+				FieldName:  "k",
+				Kind:       vdl.String,
+				NilAllowed: false,
+				StringRanges: &ds.StringFieldRanges{
+					ds.StringFieldRange{Start: "001", Limit: "002"},
+					ds.StringFieldRange{Start: appendZeroByte("002"), Limit: appendZeroByte("003")},
+				},
+			},
+			nil,
+		},
+		{
+			// Keys 001 and 003 (resulting in no keys)
+			"   select  k,  v from Customer where k = \"001\" and k = \"003\"",
+			&ds.IndexRanges{
+				FieldName:    "k",
+				Kind:         vdl.String,
+				NilAllowed:   false,
+				StringRanges: &ds.StringFieldRanges{},
+			},
+			nil,
+		},
+		{
+			// Need all keys
+			"select  k,  v from Customer where k like \"%\" or k like \"001%\" or k like \"002%\"",
+			&ds.IndexRanges{
 				FieldName:  "k",
 				Kind:       vdl.String,
 				NilAllowed: false,
@@ -2842,6 +3905,24 @@ func TestEvalWhereUsingOnlyKey(t *testing.T) {
 			"wxyz",
 			internal.FETCH_VALUE,
 		},
+		{
+			// Row will be selected using only the key.
+			"select k, v from Customer where k in (\"abc\", \"abd\")",
+			"abd",
+			internal.INCLUDE,
+		},
+		{
+			// Row will be rejected using only the key.
+			"select k, v from Customer where k between \"abc\" and \"abd\"",
+			"abe",
+			internal.EXCLUDE,
+		},
+		{
+			// Need value to determine if row should be selected.
+			"select k, v from Customer where k in (\"abc\", v.Name)",
+			"abd",
+			internal.FETCH_VALUE,
+		},
 		{
 			// Although value is in where clause, it is not needed to reject row.
 			"select k, v from Customer where k = \"abcd\" and v.zip = \"94303\"",
@@ -3198,6 +4279,23 @@ func TestExecSelectSingleRow(t *testing.T) {
 				vom.RawBytesOf("94303"),
 			},
 		},
+		{
+			"select RegexpExtract(v.Address.Zip, \"^([0-9]{3})\"), RegexpReplace(v.Name, \"([a-zA-Z]+) ([a-zA-Z]+)\", \"$2, $1\"), Coalesce(v.Nickname, v.Name), IfNull(v.Credit.Report.ExperianReport.Rating, \"none\"), Contains(Split(v.Address.Street, \" \"), \"Main\"), Index(Split(v.Name, \" \"), 1) from Customer where RegexpMatch(v.Name, \"^John\") = true and Coalesce(v.Nickname, v.Id) = 1",
+			custTable.rows[0].key, custTable.rows[0].value,
+			[]*vom.RawBytes{
+				vom.RawBytesOf("943"),
+				vom.RawBytesOf("Smith, John"),
+				vom.RawBytesOf("John Smith"),
+				vom.RawBytesOf("none"),
+				vom.RawBytesOf(true),
+				vom.RawBytesOf("Smith"),
+			},
+		},
+		{
+			"select k from Customer where RegexpMatch(v.Name, \"^Bob\") = true or Index(Split(v.Name, \" \"), 2) is not nil",
+			custTable.rows[0].key, custTable.rows[0].value,
+			[]*vom.RawBytes{},
+		},
 	}
 
 	for _, test := range basic {
@@ -3247,6 +4345,18 @@ func TestExecErrors(t *testing.T) {
 			"select k, v.Key from BigTable where 110 <= k and 205 >= k",
 			syncql.NewErrKeyExpressionLiteral(db.GetContext(), 36),
 		},
+		{
+			"select k, v.Key from BigTable where k between \"110\" and 205",
+			syncql.NewErrKeyExpressionLiteral(db.GetContext(), 56),
+		},
+		{
+			"select k from Numbers where v.I64 * \"2\" > 100",
+			syncql.NewErrInvalidArithmeticOperand(db.GetContext(), 36),
+		},
+		{
+			"select k from Numbers where v.I64 > 10 % 0",
+			syncql.NewErrArithmeticError(db.GetContext(), 39, errors.New("Division by zero.")),
+		},
 		{
 			"select k, v.Key from BigTable where Type(k) = \"BigData\"",
 			syncql.NewErrArgMustBeField(db.GetContext(), 41),
@@ -3351,6 +4461,32 @@ func TestExecErrors(t *testing.T) {
 			"select v from Customer where k like \"abc %\" escape ' '",
 			syncql.NewErrInvalidEscapeChar(db.GetContext(), 51, string(' ')),
 		},
+		{
+			"update Numbers set v.B = 300",
+			syncql.NewErrAssignmentTypeError(db.GetContext(), 25, "v.B", errors.New("vdl: conversion from int64 into uint8 loses precision: 300")),
+		},
+		{
+			"insert into BigTable (k, v) values (\"100\", 1)",
+			syncql.NewErrKeyExists(db.GetContext(), 36, "100"),
+		},
+		{
+			"insert into BigTable (k, v) values (\"400\", 1), (\"400\", 2)",
+			syncql.NewErrKeyExists(db.GetContext(), 48, "400"),
+		},
+		{
+			// The rows of Numbers have values of type td.Numbers.
+			"insert into Numbers (k, v) values (\"000\", 42)",
+			syncql.NewErrAssignmentTypeError(db.GetContext(), 42, "v", errors.New("vdl: pipe incompatible decode from int64 into v.io/v23/query/engine/internal/testdata.Numbers struct{B byte;Ui16 uint16;Ui32 uint32;Ui64 uint64;I16 int16;I32 int32;I64 int64;F32 float32;F64 float64}")),
+		},
+		{
+			"select Sum(\"1.5\") from Numbers",
+			syncql.NewErrAggregateArgNotNumeric(db.GetContext(), 11, "Sum"),
+		},
+		{
+			// The results of a union cannot be ordered.
+			"select k from Numbers union select k from BigTable order by k limit 1",
+			syncql.NewErrUnionOrderBy(db.GetContext(), 51),
+		},
 	}
 
 	for _, test := range basic {
@@ -5,6 +5,8 @@
 package internal
 
 import (
+	"bytes"
+
 	ds "v.io/v23/query/engine/datasource"
 	"v.io/v23/query/engine/internal/query_parser"
 	"v.io/v23/query/syncql"
@@ -16,11 +18,14 @@ import (
 type selectResultStreamImpl struct {
 	db              ds.Database
 	selectStatement *query_parser.SelectStatement
-	resultCount     int64 // results served so far (needed for limit clause)
-	skippedCount    int64 // skipped so far (needed for offset clause)
+	resultCount     int64          // results served so far (needed for limit clause)
+	skippedCount    int64          // skipped so far (needed for offset clause)
+	distinct        distinctFilter // projections seen so far (nil unless select distinct)
 	keyValueStream  ds.KeyValueStream
+	sorted          bool // keyValueStream is a sortingStream
 	k               string
 	v               *vom.RawBytes
+	projection      []*vom.RawBytes // projection of k, v if already composed
 	err             error
 }
 
@@ -31,24 +36,27 @@ func (rs *selectResultStreamImpl) Advance() bool {
 	}
 	for rs.keyValueStream.Advance() {
 		k, v := rs.keyValueStream.KeyValue()
-		// EvalWhereUsingOnlyKey
-		// INCLUDE: the row should be included in the results
-		// EXCLUDE: the row should NOT be included
-		// FETCH_VALUE: the value and/or type of the value are required to make determination.
-		rv := EvalWhereUsingOnlyKey(rs.db, rs.selectStatement.Where, k)
-		var match bool
-		switch rv {
-		case INCLUDE:
-			match = true
-		case EXCLUDE:
-			match = false
-		case FETCH_VALUE:
-			match = Eval(rs.db, k, vdl.ValueOf(v), rs.selectStatement.Where.Expr)
-		}
-		if match {
+		// Rows served by a sorting stream already satisfy the where clause.
+		if rs.sorted || evalWhere(rs.db, rs.selectStatement.Where, k, v) {
+			// Duplicates are eliminated before the offset and limit clauses
+			// are applied.
+			var projection []*vom.RawBytes
+			if rs.distinct != nil {
+				projection = ComposeProjection(rs.db, k, vdl.ValueOf(v), rs.selectStatement.Select)
+				isNew, err := rs.distinct.add(projection)
+				if err != nil {
+					rs.keyValueStream.Cancel()
+					rs.err = syncql.NewErrKeyValueStreamError(rs.db.GetContext(), rs.selectStatement.Off, err)
+					return false
+				}
+				if !isNew {
+					continue
+				}
+			}
 			if rs.selectStatement.ResultsOffset == nil || rs.selectStatement.ResultsOffset.ResultsOffset.Value <= rs.skippedCount {
 				rs.k = k
 				rs.v = v
+				rs.projection = projection
 				rs.resultCount++
 				return true
 			} else {
@@ -63,6 +71,9 @@ func (rs *selectResultStreamImpl) Advance() bool {
 }
 
 func (rs *selectResultStreamImpl) Result() []*vom.RawBytes {
+	if rs.projection != nil {
+		return rs.projection
+	}
 	return ComposeProjection(rs.db, rs.k, vdl.ValueOf(rs.v), rs.selectStatement.Select)
 }
 
@@ -74,31 +85,162 @@ func (rs *selectResultStreamImpl) Cancel() {
 	rs.keyValueStream.Cancel()
 }
 
-// Delete result stream
-type deleteResultStreamImpl struct {
+// distinctFilter holds the projections seen so far, keyed by their vom
+// encoding.
+type distinctFilter map[string]bool
+
+// add returns true if projection has not been seen before.
+func (d distinctFilter) add(projection []*vom.RawBytes) (bool, error) {
+	var buf bytes.Buffer
+	enc := vom.NewEncoder(&buf)
+	for _, rb := range projection {
+		if err := enc.Encode(rb); err != nil {
+			return false, err
+		}
+	}
+	key := buf.String()
+	if d[key] {
+		return false, nil
+	}
+	d[key] = true
+	return true, nil
+}
+
+// Union result stream: the results of each select of the union in turn.
+// The union eliminates duplicates from the results of the selects up to the
+// last one preceded by union (rather than union all); the results of any
+// selects after it are appended as is.
+type unionResultStream struct {
 	db              ds.Database
-	deleteStatement *query_parser.DeleteStatement
-	deleteCursor    int64 // zero or one
-	deleteCount     int64
+	unionStatement  *query_parser.UnionStatement
+	streams         []syncql.ResultStream // result streams of the selects
+	cur             int                   // index of the current stream
+	distinctThrough int                   // index of the last stream whose duplicates are eliminated (-1 if none)
+	distinct        distinctFilter
+	resultCount     int64 // results served so far (needed for limit clause)
+	skippedCount    int64 // skipped so far (needed for offset clause)
+	result          []*vom.RawBytes
 	err             error
 }
 
-func (rs *deleteResultStreamImpl) Advance() bool {
-	if rs.deleteCursor == 0 {
-		rs.deleteCursor++
+func newUnionResultStream(db ds.Database, st *query_parser.UnionStatement) *unionResultStream {
+	rs := &unionResultStream{
+		db:              db,
+		unionStatement:  st,
+		distinctThrough: -1,
+		distinct:        distinctFilter{},
+	}
+	for i, all := range st.All {
+		if !all {
+			rs.distinctThrough = i + 1
+		}
+	}
+	return rs
+}
+
+func (rs *unionResultStream) Advance() bool {
+	st := rs.unionStatement
+	if st.Limit != nil && rs.resultCount >= st.Limit.Limit.Value {
+		rs.Cancel()
+		return false
+	}
+	for ; rs.cur < len(rs.streams); rs.cur++ {
+		stream := rs.streams[rs.cur]
+		for stream.Advance() {
+			result := stream.Result()
+			if rs.cur <= rs.distinctThrough {
+				isNew, err := rs.distinct.add(result)
+				if err != nil {
+					rs.Cancel()
+					rs.err = syncql.NewErrKeyValueStreamError(rs.db.GetContext(), st.Off, err)
+					return false
+				}
+				if !isNew {
+					continue
+				}
+			}
+			if st.ResultsOffset == nil || st.ResultsOffset.ResultsOffset.Value <= rs.skippedCount {
+				rs.result = result
+				rs.resultCount++
+				return true
+			}
+			rs.skippedCount++
+		}
+		if err := stream.Err(); err != nil {
+			rs.Cancel()
+			rs.err = err
+			return false
+		}
+	}
+	return false
+}
+
+func (rs *unionResultStream) Result() []*vom.RawBytes {
+	return rs.result
+}
+
+func (rs *unionResultStream) Err() error {
+	return rs.err
+}
+
+func (rs *unionResultStream) Cancel() {
+	for _, stream := range rs.streams[rs.cur:] {
+		stream.Cancel()
+	}
+	rs.cur = len(rs.streams)
+}
+
+// Count result stream: the single row of the result of a delete, update or
+// insert statement (i.e., the number of rows deleted, updated or inserted).
+type countResultStreamImpl struct {
+	db     ds.Database
+	cursor int64 // zero or one
+	count  int64
+	err    error
+}
+
+func (rs *countResultStreamImpl) Advance() bool {
+	if rs.cursor == 0 {
+		rs.cursor++
 		return true
 	}
 	return false
 }
 
-func (rs *deleteResultStreamImpl) Result() []*vom.RawBytes {
-	return []*vom.RawBytes{vom.RawBytesOf(rs.deleteCount)}
+func (rs *countResultStreamImpl) Result() []*vom.RawBytes {
+	return []*vom.RawBytes{vom.RawBytesOf(rs.count)}
 }
 
-func (rs *deleteResultStreamImpl) Err() error {
+func (rs *countResultStreamImpl) Err() error {
 	return rs.err
 }
 
-func (rs *deleteResultStreamImpl) Cancel() {
-	rs.deleteCursor++
+func (rs *countResultStreamImpl) Cancel() {
+	rs.cursor++
+}
+
+// Explain result stream
+type explainResultStreamImpl struct {
+	rows   [][]*vom.RawBytes
+	cursor int // index of the current row + 1
+}
+
+func (rs *explainResultStreamImpl) Advance() bool {
+	if rs.cursor < len(rs.rows) {
+		rs.cursor++
+		return true
+	}
+	return false
+}
+
+func (rs *explainResultStreamImpl) Result() []*vom.RawBytes {
+	return rs.rows[rs.cursor-1]
+}
+
+func (rs *explainResultStreamImpl) Err() error {
+	return nil
+}
+
+func (rs *explainResultStreamImpl) Cancel() {
+	rs.cursor = len(rs.rows)
 }
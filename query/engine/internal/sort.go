@@ -0,0 +1,300 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	"bufio"
+	"container/heap"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	ds "v.io/v23/query/engine/datasource"
+	"v.io/v23/query/engine/internal/conversions"
+	"v.io/v23/query/engine/internal/query_parser"
+	"v.io/v23/vdl"
+	"v.io/v23/vom"
+)
+
+// sortRunSize is the maximum number of rows that are sorted in memory to
+// satisfy an order by clause.  If more rows are selected, they are sorted in
+// runs of sortRunSize rows, each of which is written to a temporary file, and
+// the runs are then merged.
+var sortRunSize = 10000
+
+// For testing purposes, set the maximum number of rows sorted in memory and
+// return the previous maximum.
+func SetSortRunSize(n int) int {
+	old := sortRunSize
+	sortRunSize = n
+	return old
+}
+
+type sortRow struct {
+	k    string
+	v    *vom.RawBytes
+	keys []*query_parser.Operand // resolved sort keys, nil if unresolved
+}
+
+// sortingStream is a ds.KeyValueStream over the rows of a source stream
+// that satisfy the where clause of a select statement, in the order
+// specified by the statement's order by clause.  The source stream is
+// consumed on the first call to Advance.
+type sortingStream struct {
+	db      ds.Database
+	st      *query_parser.SelectStatement
+	source  ds.KeyValueStream
+	started bool
+	rows    []sortRow  // rows sorted in memory (if no runs were written)
+	runs    []*sortRun // runs written to temporary files
+	merge   runHeap    // runs with remaining rows, during the merge
+	cur     sortRow
+	err     error
+}
+
+func newSortingStream(db ds.Database, st *query_parser.SelectStatement, source ds.KeyValueStream) *sortingStream {
+	return &sortingStream{db: db, st: st, source: source}
+}
+
+func (s *sortingStream) Advance() bool {
+	if !s.started {
+		s.started = true
+		if s.err = s.sort(); s.err != nil {
+			s.close()
+			return false
+		}
+	}
+	if s.runs == nil {
+		if len(s.rows) == 0 {
+			return false
+		}
+		s.cur, s.rows = s.rows[0], s.rows[1:]
+		return true
+	}
+	if len(s.merge) == 0 {
+		s.close()
+		return false
+	}
+	run := s.merge[0]
+	s.cur = run.cur
+	ok, err := run.next(s.db)
+	switch {
+	case err != nil:
+		s.err = err
+		s.close()
+		return false
+	case ok:
+		heap.Fix(&s.merge, 0)
+	default:
+		heap.Pop(&s.merge)
+	}
+	return true
+}
+
+func (s *sortingStream) KeyValue() (string, *vom.RawBytes) {
+	return s.cur.k, s.cur.v
+}
+
+func (s *sortingStream) Err() error {
+	return s.err
+}
+
+func (s *sortingStream) Cancel() {
+	s.source.Cancel()
+	s.rows = nil
+	s.close()
+}
+
+// sort reads the rows that satisfy the where clause from the source stream
+// and sorts them, in memory if there are at most sortRunSize of them, else
+// in runs which are then prepared for merging.
+func (s *sortingStream) sort() error {
+	for s.source.Advance() {
+		k, v := s.source.KeyValue()
+		if !evalWhere(s.db, s.st.Where, k, v) {
+			continue
+		}
+		s.rows = append(s.rows, sortRow{k, v, resolveSortKeys(s.db, k, vdl.ValueOf(v), s.st.OrderBy)})
+		if len(s.rows) >= sortRunSize {
+			if err := s.writeRun(); err != nil {
+				s.source.Cancel()
+				return err
+			}
+		}
+	}
+	if err := s.source.Err(); err != nil {
+		return err
+	}
+	if s.runs == nil {
+		sortRows(s.rows, s.st.OrderBy)
+		return nil
+	}
+	if len(s.rows) > 0 {
+		if err := s.writeRun(); err != nil {
+			return err
+		}
+	}
+	for i, run := range s.runs {
+		if err := run.startReading(); err != nil {
+			return err
+		}
+		ok, err := run.next(s.db)
+		if err != nil {
+			return err
+		}
+		if ok {
+			run.index = i
+			s.merge = append(s.merge, run)
+		}
+	}
+	heap.Init(&s.merge)
+	return nil
+}
+
+// writeRun sorts the rows in memory and writes them to a new run.
+func (s *sortingStream) writeRun() error {
+	sortRows(s.rows, s.st.OrderBy)
+	f, err := ioutil.TempFile("", "syncql_sort")
+	if err != nil {
+		return err
+	}
+	run := &sortRun{f: f, orderBy: s.st.OrderBy}
+	s.runs = append(s.runs, run)
+	w := bufio.NewWriter(f)
+	enc := vom.NewEncoder(w)
+	for _, row := range s.rows {
+		if err := enc.Encode(row.k); err != nil {
+			return err
+		}
+		if err := enc.Encode(row.v); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	s.rows = s.rows[:0]
+	return nil
+}
+
+// close removes the temporary files of all runs.
+func (s *sortingStream) close() {
+	for _, run := range s.runs {
+		run.close()
+	}
+	s.runs, s.merge = nil, nil
+}
+
+// sortRun is a sorted sequence of rows, stored in a temporary file.
+type sortRun struct {
+	f       *os.File
+	orderBy *query_parser.OrderByClause
+	dec     *vom.Decoder
+	cur     sortRow // the next row to be merged
+	index   int     // the position of the run, used to keep the merge stable
+}
+
+func (r *sortRun) startReading() error {
+	if _, err := r.f.Seek(0, 0); err != nil {
+		return err
+	}
+	r.dec = vom.NewDecoder(bufio.NewReader(r.f))
+	return nil
+}
+
+// next reads the next row of the run into r.cur, returning false if there
+// are no more rows.
+func (r *sortRun) next(db ds.Database) (bool, error) {
+	var k string
+	if err := r.dec.Decode(&k); err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, err
+	}
+	var v vom.RawBytes
+	if err := r.dec.Decode(&v); err != nil {
+		return false, err
+	}
+	// The sort keys are not stored in the run; they are resolved again.
+	r.cur = sortRow{k, &v, resolveSortKeys(db, k, vdl.ValueOf(&v), r.orderBy)}
+	return true, nil
+}
+
+func (r *sortRun) close() {
+	name := r.f.Name()
+	r.f.Close()
+	os.Remove(name)
+}
+
+// runHeap implements heap.Interface to merge runs, ordered by their next
+// row.
+type runHeap []*sortRun
+
+func (h runHeap) Len() int { return len(h) }
+
+func (h runHeap) Less(i, j int) bool {
+	if c := compareSortKeys(h[i].cur.keys, h[j].cur.keys, h[i].orderBy); c != 0 {
+		return c < 0
+	}
+	return h[i].index < h[j].index
+}
+
+func (h runHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*sortRun)) }
+
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	run := old[n-1]
+	*h = old[:n-1]
+	return run
+}
+
+// resolveSortKeys returns the values of the keys of the order by clause for
+// the row with key k and value v.  Keys that cannot be resolved are nil.
+func resolveSortKeys(db ds.Database, k string, v *vdl.Value, orderBy *query_parser.OrderByClause) []*query_parser.Operand {
+	keys := make([]*query_parser.Operand, len(orderBy.Keys))
+	for i, key := range orderBy.Keys {
+		keys[i] = resolveOperand(db, k, v, key.Operand)
+	}
+	return keys
+}
+
+// sortRows sorts rows as specified by the order by clause.  The sort is
+// stable, so rows with equal sort keys remain in key order.
+func sortRows(rows []sortRow, orderBy *query_parser.OrderByClause) {
+	sort.Stable(rowSorter{rows, orderBy})
+}
+
+type rowSorter struct {
+	rows    []sortRow
+	orderBy *query_parser.OrderByClause
+}
+
+func (s rowSorter) Len() int { return len(s.rows) }
+
+func (s rowSorter) Less(i, j int) bool {
+	return compareSortKeys(s.rows[i].keys, s.rows[j].keys, s.orderBy) < 0
+}
+
+func (s rowSorter) Swap(i, j int) { s.rows[i], s.rows[j] = s.rows[j], s.rows[i] }
+
+// compareSortKeys compares the resolved sort keys of two rows, returning
+// -1, 0 or 1 if the first row sorts before, with or after the second.
+func compareSortKeys(lhs, rhs []*query_parser.Operand, orderBy *query_parser.OrderByClause) int {
+	for i, key := range orderBy.Keys {
+		c := conversions.CompareOperands(lhs[i], rhs[i])
+		if key.Direction == query_parser.Descending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
@@ -0,0 +1,269 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	ds "v.io/v23/query/engine/datasource"
+	"v.io/v23/query/engine/internal/query_checker"
+	"v.io/v23/query/engine/internal/query_parser"
+	"v.io/v23/query/syncql"
+	"v.io/v23/vdl"
+	"v.io/v23/vom"
+)
+
+// updatedRow is the new value of a row to be written by an update statement.
+type updatedRow struct {
+	k string
+	v *vdl.Value
+}
+
+// execUpdate executes the update statement and returns the number of rows
+// updated.  The new values of all rows are computed before any is written,
+// so that an assignment that fails (e.g., because the operand cannot be
+// converted to the type of the field) leaves the table unchanged.
+func execUpdate(db ds.Database, st *query_parser.UpdateStatement) (int64, error) {
+	indexes, err := getIndexRanges(db, st.Table.Name, st.Table.Off, "", st.Table.DBTable.GetIndexFields(), st.Where)
+	if err != nil {
+		return 0, err
+	}
+
+	keyValueStream, err := st.Table.DBTable.Scan(indexes...)
+	if err != nil {
+		return 0, syncql.NewErrScanError(db.GetContext(), st.Off, err)
+	}
+
+	var rows []updatedRow
+	for keyValueStream.Advance() {
+		if st.Limit != nil && int64(len(rows)) >= st.Limit.Limit.Value {
+			defer keyValueStream.Cancel()
+			break
+		}
+		k, v := keyValueStream.KeyValue()
+		if !evalWhere(db, st.Where, k, v) {
+			continue
+		}
+		row, err := applyAssignments(db, k, vdl.ValueOf(v), st.Set)
+		if err != nil {
+			keyValueStream.Cancel()
+			return 0, err
+		}
+		if row != nil {
+			rows = append(rows, updatedRow{k, row})
+		}
+	}
+	if err := keyValueStream.Err(); err != nil {
+		return 0, syncql.NewErrKeyValueStreamError(db.GetContext(), st.Off, err)
+	}
+
+	updateCount := int64(0)
+	for _, row := range rows {
+		// May not have write permission to update this k/v pair.
+		// Continue, but don't increment update count.
+		if err := st.Table.DBTable.Put(row.k, vom.RawBytesOf(row.v)); err == nil {
+			updateCount++
+		}
+	}
+	return updateCount, nil
+}
+
+// applyAssignments returns a copy of v, the value of the row with key k, with
+// the assignments of the set clause applied.  Operands are resolved with the
+// row before any assignment is applied.  If the value has no field to assign
+// to, nil is returned (i.e., the row is not updated).
+func applyAssignments(db ds.Database, k string, v *vdl.Value, s *query_parser.SetClause) (*vdl.Value, error) {
+	newValue := vdl.CopyValue(v)
+	for _, a := range s.Assignments {
+		target := assignmentTarget(db, k, v, newValue, a.Field)
+		if target == nil {
+			return nil, nil
+		}
+		var operandValue interface{}
+		if o := resolveOperand(db, k, v, a.Operand); o != nil {
+			operandValue = valueFromResolvedOperand(o)
+		}
+		value := vdl.ZeroValue(target.Type())
+		if err := vdl.Convert(value, operandValue); err != nil {
+			return nil, syncql.NewErrAssignmentTypeError(db.GetContext(), a.Operand.Off, formatField(a.Field), err)
+		}
+		target.Assign(value)
+	}
+	return newValue, nil
+}
+
+// assignmentTarget returns the field f of value (which is the value of the
+// row being updated, or a copy of it) that an assignment replaces, or nil if
+// there is no such field.  As with ResolveField, Any and Optional values are
+// dereferenced and the keys of segments are resolved with the row (k and v).
+// Only existing elements of lists, arrays and maps can be assigned to.
+func assignmentTarget(db ds.Database, k string, v, value *vdl.Value, f *query_parser.Field) *vdl.Value {
+	object := value
+	for i, segment := range f.Path() {
+		if i > 0 {
+			// object must be a struct in order to look for the next segment.
+			if object = autoDereference(object); object.Kind() != vdl.Struct {
+				return nil
+			}
+			if object = object.StructFieldByName(segment.Value); object == nil {
+				return nil
+			}
+		}
+		for _, key := range segment.Keys {
+			o := resolveOperand(db, k, v, key)
+			if o == nil {
+				return nil
+			}
+			proposedKey := valueFromResolvedOperand(o)
+			if proposedKey == nil {
+				return nil
+			}
+			switch object = autoDereference(object); object.Kind() {
+			case vdl.Array, vdl.List:
+				index32 := vdl.IntValue(vdl.Int32Type, 0)
+				if err := vdl.Convert(index32, proposedKey); err != nil {
+					return nil
+				}
+				index := int(index32.Int())
+				if index < 0 || index >= object.Len() {
+					return nil
+				}
+				object = object.Index(index)
+			case vdl.Map:
+				keyVal := vdl.ZeroValue(object.Type().Key())
+				if err := vdl.Convert(keyVal, proposedKey); err != nil {
+					return nil
+				}
+				if object = object.MapIndex(keyVal); object == nil {
+					return nil
+				}
+			default:
+				return nil
+			}
+		}
+	}
+	return object
+}
+
+// execInsert executes the insert statement and returns the number of rows
+// inserted.  All keys are checked to be strings that are not in the table,
+// and all values to be convertible to the value type of the table (see
+// insertValueType), before any row is written.  The key check is repeated
+// atomically with the write of each row if the table is a ds.InsertTable;
+// otherwise a row written concurrently with the same key may be replaced.
+func execInsert(db ds.Database, st *query_parser.InsertStatement) (int64, error) {
+	keyColumn := 0
+	if st.Columns[0].Segments[0].Value == "v" {
+		keyColumn = 1
+	}
+
+	type insertedRow struct {
+		off    int64
+		keyOff int64
+		k      string
+		v      *vdl.Value
+	}
+	var rows []insertedRow
+	keys := map[string]bool{}
+	for _, r := range st.Rows {
+		var row insertedRow
+		row.off = r.Off
+		row.keyOff = r.Values[keyColumn].Off
+		// The values contain no fields; as such, they are resolved without a row.
+		key := resolveOperand(db, "", vdl.ValueOf(nil), r.Values[keyColumn])
+		if key == nil || key.Type != query_parser.TypStr {
+			return 0, syncql.NewErrInsertKeyNotString(db.GetContext(), r.Values[keyColumn].Off)
+		}
+		row.k = key.Str
+		if keys[row.k] {
+			return 0, syncql.NewErrKeyExists(db.GetContext(), r.Values[keyColumn].Off, row.k)
+		}
+		keys[row.k] = true
+		if exists, err := keyExists(st.Table.DBTable, row.k); err != nil {
+			return 0, syncql.NewErrScanError(db.GetContext(), st.Off, err)
+		} else if exists {
+			return 0, syncql.NewErrKeyExists(db.GetContext(), r.Values[keyColumn].Off, row.k)
+		}
+		rows = append(rows, row)
+	}
+
+	// As with the assignments of update statements, values are converted to
+	// the type of the values they are stored with.
+	valueType, err := insertValueType(st.Table.DBTable)
+	if err != nil {
+		return 0, syncql.NewErrScanError(db.GetContext(), st.Off, err)
+	}
+	for i, r := range st.Rows {
+		var value interface{}
+		if o := resolveOperand(db, "", vdl.ValueOf(nil), r.Values[1-keyColumn]); o != nil {
+			value = valueFromResolvedOperand(o)
+		}
+		if valueType == nil {
+			rows[i].v = vdl.ValueOf(value)
+			continue
+		}
+		rows[i].v = vdl.ZeroValue(valueType)
+		if err := vdl.Convert(rows[i].v, value); err != nil {
+			return 0, syncql.NewErrAssignmentTypeError(db.GetContext(), r.Values[1-keyColumn].Off, "v", err)
+		}
+	}
+
+	insertTable, atomic := st.Table.DBTable.(ds.InsertTable)
+	insertCount := int64(0)
+	for _, row := range rows {
+		inserted := true
+		var err error
+		if atomic {
+			inserted, err = insertTable.PutIfAbsent(row.k, vom.RawBytesOf(row.v))
+		} else {
+			err = st.Table.DBTable.Put(row.k, vom.RawBytesOf(row.v))
+		}
+		if err != nil {
+			return insertCount, syncql.NewErrWriteError(db.GetContext(), row.off, err)
+		}
+		if !inserted {
+			return insertCount, syncql.NewErrKeyExists(db.GetContext(), row.keyOff, row.k)
+		}
+		insertCount++
+	}
+	return insertCount, nil
+}
+
+// insertValueType returns the type that the values of the rows inserted into
+// t are converted to: the type reported by t if it is a ds.TypedTable, else
+// the type of the value of the first row of t.  Nil is returned if t reports
+// vdl.AnyType or, not being a ds.TypedTable, is empty; in that case values
+// are written as they are.
+func insertValueType(t ds.Table) (*vdl.Type, error) {
+	if tt, ok := t.(ds.TypedTable); ok {
+		if vt := tt.ValueType(); vt != vdl.AnyType {
+			return vt, nil
+		}
+		return nil, nil
+	}
+	keyRanges := ds.StringFieldRanges{query_checker.StringFieldRangeAll}
+	keyValueStream, err := t.Scan(ds.IndexRanges{FieldName: "k", Kind: vdl.String, StringRanges: &keyRanges})
+	if err != nil {
+		return nil, err
+	}
+	if keyValueStream.Advance() {
+		_, v := keyValueStream.KeyValue()
+		keyValueStream.Cancel()
+		return v.Type, nil
+	}
+	return nil, keyValueStream.Err()
+}
+
+// keyExists returns true if the table contains a row with key k.
+func keyExists(t ds.Table, k string) (bool, error) {
+	keyRanges := ds.StringFieldRanges{ds.StringFieldRange{Start: k, Limit: k + "\x00"}}
+	keyValueStream, err := t.Scan(ds.IndexRanges{FieldName: "k", Kind: vdl.String, StringRanges: &keyRanges})
+	if err != nil {
+		return false, err
+	}
+	if keyValueStream.Advance() {
+		keyValueStream.Cancel()
+		return true, nil
+	}
+	return false, keyValueStream.Err()
+}
@@ -8,7 +8,9 @@
 package public
 
 import (
+	"v.io/v23/context"
 	"v.io/v23/query/syncql"
+	"v.io/v23/vdl"
 	"v.io/v23/vom"
 )
 
@@ -28,6 +30,66 @@ type QueryEngine interface {
 	// Get an existing PreparedStatement from the int64 returned from calling
 	// PreparedStatement.ToHandle.
 	GetPreparedStatement(handle int64) (PreparedStatement, error)
+
+	// RegisterFunction registers a user-defined function which can then be
+	// called (by name) in the queries executed by this QueryEngine.  The name
+	// must be an identifier and must not match (ignoring case) the name of a
+	// built-in function or of a function already registered with this
+	// QueryEngine, else the syncql.InvalidUserFunction error is returned.
+	RegisterFunction(name string, f Function) error
+}
+
+// ValueType is the type of an argument or of the return value of a
+// user-defined function.  Values are converted to the type (e.g., the
+// string "12" is converted to the int64 12) before being passed to, or
+// after being returned from, the function.  If a value cannot be converted,
+// the corresponding syncql conversion error is returned.
+type ValueType int
+
+const (
+	TypeAny   ValueType = iota // No conversion.
+	TypeBool                   // bool
+	TypeFloat                  // float64
+	TypeInt                    // int64
+	TypeStr                    // string
+	TypeTime                   // time.Time
+	TypeUint                   // uint64
+)
+
+// Function describes a user-defined function.
+// e.g., a function which returns the number of elements of a list field
+// plus one:
+//
+//	qe.RegisterFunction("LenPlusOne", public.Function{
+//	        ArgTypes:   []public.ValueType{public.TypeAny},
+//	        ReturnType: public.TypeInt,
+//	        Impl: func(ctx *context.T, k string, v *vdl.Value, args []*vdl.Value) (*vdl.Value, error) {
+//	                return vdl.ValueOf(int64(args[0].Len() + 1)), nil
+//	        },
+//	})
+type Function struct {
+	// ArgTypes are the types of the (required) arguments.  If the number
+	// of arguments in a call doesn't match, syncql.FunctionArgCount (or, if
+	// VarArgs is true, syncql.FunctionAtLeastArgCount) is returned.
+	ArgTypes []ValueType
+	// VarArgs is true if the function takes any number of additional args
+	// (of type VarArgsType).
+	VarArgs     bool
+	VarArgsType ValueType
+	// ReturnType is the type to which the result of Impl is converted.
+	ReturnType ValueType
+	// CheckArgs, if not nil, is called when the query is checked (i.e.,
+	// before any row is read).  Literal args are passed (converted to
+	// their ArgTypes); args that are not known until the query is executed
+	// (e.g., fields of v) are passed as nil.  An error returned by CheckArgs
+	// is reported as syncql.UserFunctionError.
+	CheckArgs func(ctx *context.T, args []*vdl.Value) error
+	// Impl is called for each row with the row's key and value.  (For a
+	// select statement with joins, k is the key of the first table's row
+	// and v holds the key and value of each table's row.)  As with the
+	// built-in functions, if Impl returns an error, the result of the
+	// function call is nil.
+	Impl func(ctx *context.T, k string, v *vdl.Value, args []*vdl.Value) (*vdl.Value, error)
 }
 
 type PreparedStatement interface {
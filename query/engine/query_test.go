@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 
@@ -173,6 +174,18 @@ func (t *table) Delete(k string) (bool, error) {
 	return false, nil
 }
 
+func (t *table) Put(k string, v *vom.RawBytes) error {
+	i := sort.Search(len(t.rows), func(i int) bool { return t.rows[i].key >= k })
+	if i < len(t.rows) && t.rows[i].key == k {
+		t.rows[i].value = v
+		return nil
+	}
+	t.rows = append(t.rows, kv{})
+	copy(t.rows[i+1:], t.rows[i:])
+	t.rows[i] = kv{k, v}
+	return nil
+}
+
 func (db mockDB) GetContext() *context.T {
 	return db.ctx
 }
@@ -3278,3 +3291,174 @@ func TestErrorOnPrepareExec(t *testing.T) {
 		}
 	}
 }
+
+func TestUserFunctions(t *testing.T) {
+	initTables()
+	qe := engine.Create(db)
+
+	// KeyPlus(i) returns the key followed by a dash and i.
+	if err := qe.RegisterFunction("KeyPlus", public.Function{
+		ArgTypes:   []public.ValueType{public.TypeInt},
+		ReturnType: public.TypeStr,
+		Impl: func(ctx *context.T, k string, v *vdl.Value, args []*vdl.Value) (*vdl.Value, error) {
+			return vdl.ValueOf(fmt.Sprintf("%s-%d", k, args[0].Int())), nil
+		},
+	}); err != nil {
+		t.Fatalf("RegisterFunction(KeyPlus): %v", err)
+	}
+	// Scaled(i) returns v.I64 * i.
+	if err := qe.RegisterFunction("Scaled", public.Function{
+		ArgTypes:   []public.ValueType{public.TypeInt},
+		ReturnType: public.TypeInt,
+		Impl: func(ctx *context.T, k string, v *vdl.Value, args []*vdl.Value) (*vdl.Value, error) {
+			return vdl.ValueOf(v.StructFieldByName("I64").Int() * args[0].Int()), nil
+		},
+	}); err != nil {
+		t.Fatalf("RegisterFunction(Scaled): %v", err)
+	}
+	// Choose(i, args...) returns the ith (zero based) of args.
+	if err := qe.RegisterFunction("Choose", public.Function{
+		ArgTypes:    []public.ValueType{public.TypeInt},
+		VarArgs:     true,
+		VarArgsType: public.TypeAny,
+		ReturnType:  public.TypeAny,
+		CheckArgs: func(ctx *context.T, args []*vdl.Value) error {
+			if args[0] != nil && args[0].Int() < 0 {
+				return errors.New("index must not be negative")
+			}
+			return nil
+		},
+		Impl: func(ctx *context.T, k string, v *vdl.Value, args []*vdl.Value) (*vdl.Value, error) {
+			i := args[0].Int() + 1
+			if i >= int64(len(args)) {
+				return nil, errors.New("index out of range")
+			}
+			return args[i], nil
+		},
+	}); err != nil {
+		t.Fatalf("RegisterFunction(Choose): %v", err)
+	}
+
+	impl := func(ctx *context.T, k string, v *vdl.Value, args []*vdl.Value) (*vdl.Value, error) {
+		return nil, nil
+	}
+	registerErrors := []struct {
+		name string
+		f    public.Function
+		err  error
+	}{
+		{
+			"len",
+			public.Function{Impl: impl},
+			syncql.NewErrInvalidUserFunction(db.GetContext(), "len", errors.New("conflicts with built-in function Len")),
+		},
+		{
+			"keyPlus",
+			public.Function{Impl: impl},
+			syncql.NewErrInvalidUserFunction(db.GetContext(), "keyPlus", errors.New("conflicts with function KeyPlus")),
+		},
+		{
+			"1Bad",
+			public.Function{Impl: impl},
+			syncql.NewErrInvalidUserFunction(db.GetContext(), "1Bad", errors.New("name is not an identifier")),
+		},
+		{
+			"NoImpl",
+			public.Function{},
+			syncql.NewErrInvalidUserFunction(db.GetContext(), "NoImpl", errors.New("Impl is nil")),
+		},
+		{
+			"BadType",
+			public.Function{ArgTypes: []public.ValueType{public.ValueType(99)}, Impl: impl},
+			syncql.NewErrInvalidUserFunction(db.GetContext(), "BadType", errors.New("unknown type 99")),
+		},
+	}
+	for _, test := range registerErrors {
+		err := qe.RegisterFunction(test.name, test.f)
+		if verror.ErrorID(err) != verror.ErrorID(test.err) || err.Error() != test.err.Error() {
+			t.Errorf("RegisterFunction(%s): got %v, want %v", test.name, err, test.err)
+		}
+	}
+
+	basic := []execSelectTest{
+		{
+			"select k, KeyPlus(7) from Numbers",
+			[]string{"k", "KeyPlus"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("001"), vom.RawBytesOf("001-7")},
+				{vom.RawBytesOf("002"), vom.RawBytesOf("002-7")},
+				{vom.RawBytesOf("003"), vom.RawBytesOf("003-7")},
+			},
+		},
+		{
+			"select k, Scaled(v.B) from Numbers where Scaled(2) > 300",
+			[]string{"k", "Scaled"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf("003"), vom.RawBytesOf(int64(44100))},
+			},
+		},
+		{
+			// Out of range, Choose returns an error; so the result is nil.
+			"select Choose(1, v.B, v.I64, \"x\"), Choose(2, v.B, v.I64, \"x\"), Choose(3, v.B) from Numbers where k = \"001\"",
+			[]string{"Choose", "Choose", "Choose"},
+			[][]*vom.RawBytes{
+				{vom.RawBytesOf(int64(128)), vom.RawBytesOf("x"), vom.RawBytesOf(nil)},
+			},
+		},
+	}
+	for _, test := range basic {
+		headers, rs, err := qe.Exec(test.query)
+		if err != nil {
+			t.Errorf("query: %s; got %v, want nil", test.query, err)
+		} else {
+			// Collect results.
+			r := [][]*vom.RawBytes{}
+			for rs.Advance() {
+				r = append(r, rs.Result())
+			}
+			if got, want := vdl.ValueOf(r), vdl.ValueOf(test.r); !vdl.EqualValue(got, want) {
+				t.Errorf("query: %s; got %v, want %v", test.query, r, test.r)
+			}
+			if !reflect.DeepEqual(test.headers, headers) {
+				t.Errorf("query: %s; got %v, want %v", test.query, headers, test.headers)
+			}
+		}
+	}
+
+	execErrors := []execSelectErrorTest{
+		{
+			"select Choose(-1, v.B) from Numbers",
+			syncql.NewErrUserFunctionError(db.GetContext(), 7, "Choose", errors.New("index must not be negative")),
+		},
+		{
+			"select KeyPlus(\"abc\") from Numbers",
+			syncql.NewErrIntConversionError(db.GetContext(), 15, errors.New("Cannot convert operand to int64.")),
+		},
+		{
+			"select KeyPlus() from Numbers",
+			syncql.NewErrFunctionArgCount(db.GetContext(), 7, "KeyPlus", 1, 0),
+		},
+		{
+			"select Choose() from Numbers",
+			syncql.NewErrFunctionAtLeastArgCount(db.GetContext(), 7, "Choose", 1, 0),
+		},
+		{
+			"select keyplus(1) from Numbers",
+			syncql.NewErrDidYouMeanFunction(db.GetContext(), 7, "KeyPlus"),
+		},
+	}
+	for _, test := range execErrors {
+		_, _, err := qe.Exec(test.query)
+		// Test both that the IDs compare and the text compares (since the offset needs to match).
+		if verror.ErrorID(err) != verror.ErrorID(test.err) || err.Error() != test.err.Error() {
+			t.Errorf("query: %s; got %v, want %v", test.query, err, test.err)
+		}
+	}
+
+	// User-defined functions are registered per QueryEngine.
+	query := "select KeyPlus(1) from Numbers"
+	wantErr := syncql.NewErrFunctionNotFound(db.GetContext(), 7, "KeyPlus")
+	if _, _, err := engine.Create(db).Exec(query); verror.ErrorID(err) != verror.ErrorID(wantErr) || err.Error() != wantErr.Error() {
+		t.Errorf("query: %s; got %v, want %v", query, err, wantErr)
+	}
+}
@@ -74,6 +74,32 @@ var (
 	ErrInvalidIndexField               = verror.Register("v.io/v23/query/syncql.InvalidIndexField", verror.NoRetry, "{1:}{2:} [{3}]Invalid index field {4} returned by table {5}.")
 	ErrNotWritable                     = verror.Register("v.io/v23/query/syncql.NotWritable", verror.NoRetry, "{1:}{2:} [0]Can't write to table {3} (not supported on batch/connection).")
 	ErrOperationNotSupported           = verror.Register("v.io/v23/query/syncql.OperationNotSupported", verror.NoRetry, "{1:}{2:} [0]{3} not supported.")
+	ErrInvalidOrderByKey               = verror.Register("v.io/v23/query/syncql.InvalidOrderByKey", verror.NoRetry, "{1:}{2:} [{3}]Order by key must be 'k', 'v[{.<ident>}...]' or a function.")
+	ErrInvalidGroupByKey               = verror.Register("v.io/v23/query/syncql.InvalidGroupByKey", verror.NoRetry, "{1:}{2:} [{3}]Group by key must be 'k', 'v[{.<ident>}...]' or a function.")
+	ErrAggregateNotAllowed             = verror.Register("v.io/v23/query/syncql.AggregateNotAllowed", verror.NoRetry, "{1:}{2:} [{3}]Aggregate function {4} cannot be used in where or group by clauses, or as an argument of an aggregate function.")
+	ErrFieldNotGrouped                 = verror.Register("v.io/v23/query/syncql.FieldNotGrouped", verror.NoRetry, "{1:}{2:} [{3}]Field must be a group by key or the argument of an aggregate function.")
+	ErrDuplicateTableName              = verror.Register("v.io/v23/query/syncql.DuplicateTableName", verror.NoRetry, "{1:}{2:} [{3}]Table name or alias {4} appears more than once in the from clause.")
+	ErrFieldNotQualified               = verror.Register("v.io/v23/query/syncql.FieldNotQualified", verror.NoRetry, "{1:}{2:} [{3}]Fields of select statements with joins must begin with a table name or alias.")
+	ErrInvalidSetField                 = verror.Register("v.io/v23/query/syncql.InvalidSetField", verror.NoRetry, "{1:}{2:} [{3}]Set field must be 'v[{.<ident>}...]'.")
+	ErrInvalidInsertColumns            = verror.Register("v.io/v23/query/syncql.InvalidInsertColumns", verror.NoRetry, "{1:}{2:} [{3}]Insert columns must be exactly k and v.")
+	ErrInsertValueCount                = verror.Register("v.io/v23/query/syncql.InsertValueCount", verror.NoRetry, "{1:}{2:} [{3}]Expected {4} values, found {5}.")
+	ErrInvalidInsertValue              = verror.Register("v.io/v23/query/syncql.InvalidInsertValue", verror.NoRetry, "{1:}{2:} [{3}]Insert values must be literals, parameters or functions of them.")
+	ErrInsertKeyNotString              = verror.Register("v.io/v23/query/syncql.InsertKeyNotString", verror.NoRetry, "{1:}{2:} [{3}]Inserted key must be a string.")
+	ErrKeyExists                       = verror.Register("v.io/v23/query/syncql.KeyExists", verror.NoRetry, "{1:}{2:} [{3}]Key {4} already exists.")
+	ErrAssignmentTypeError             = verror.Register("v.io/v23/query/syncql.AssignmentTypeError", verror.NoRetry, "{1:}{2:} [{3}]Cannot assign to {4}: {5}.")
+	ErrWriteError                      = verror.Register("v.io/v23/query/syncql.WriteError", verror.NoRetry, "{1:}{2:} [{3}]Write error: {4}.")
+	ErrInvalidArithmeticOperand        = verror.Register("v.io/v23/query/syncql.InvalidArithmeticOperand", verror.NoRetry, "{1:}{2:} [{3}]Arithmetic operands must be numeric.")
+	ErrArithmeticError                 = verror.Register("v.io/v23/query/syncql.ArithmeticError", verror.NoRetry, "{1:}{2:} [{3}]Arithmetic error: {4}.")
+	ErrUserFunctionError               = verror.Register("v.io/v23/query/syncql.UserFunctionError", verror.NoRetry, "{1:}{2:} [{3}]Function '{4}' failed: {5}.")
+	ErrInvalidUserFunction             = verror.Register("v.io/v23/query/syncql.InvalidUserFunction", verror.NoRetry, "{1:}{2:} Invalid user-defined function '{3}': {4}.")
+	ErrInvalidRegularExpression        = verror.Register("v.io/v23/query/syncql.InvalidRegularExpression", verror.NoRetry, "{1:}{2:} [{3}]Invalid regular expression: {4}.")
+	ErrFunctionInvalidArg              = verror.Register("v.io/v23/query/syncql.FunctionInvalidArg", verror.NoRetry, "{1:}{2:} [{3}]Function '{4}()' expects {5}.")
+	ErrDurationConversionError         = verror.Register("v.io/v23/query/syncql.DurationConversionError", verror.NoRetry, "{1:}{2:} [{3}]Can't convert to duration: {4}.")
+	ErrInvalidTimeUnit                 = verror.Register("v.io/v23/query/syncql.InvalidTimeUnit", verror.NoRetry, "{1:}{2:} [{3}]Time unit must be 'hour', 'day', 'week' or 'month', found '{4}'.")
+	ErrUnionColumnCount                = verror.Register("v.io/v23/query/syncql.UnionColumnCount", verror.NoRetry, "{1:}{2:} [{3}]Select statements of a union must have the same number of columns, expected {4}, found {5}.")
+	ErrAggregateArgNotNumeric          = verror.Register("v.io/v23/query/syncql.AggregateArgNotNumeric", verror.NoRetry, "{1:}{2:} [{3}]Argument of aggregate function {4} must be numeric.")
+	ErrInvalidJsonPath                 = verror.Register("v.io/v23/query/syncql.InvalidJsonPath", verror.NoRetry, "{1:}{2:} [{3}]Invalid JSON path: {4}.")
+	ErrUnionOrderBy                    = verror.Register("v.io/v23/query/syncql.UnionOrderBy", verror.NoRetry, "{1:}{2:} [{3}]Order by is not allowed in the select statements of a union.")
 )
 
 // NewErrBadFieldInWhere returns an error with the ErrBadFieldInWhere ID.
@@ -351,6 +377,136 @@ func NewErrOperationNotSupported(ctx *context.T, operation string) error {
 	return verror.New(ErrOperationNotSupported, ctx, operation)
 }
 
+// NewErrInvalidOrderByKey returns an error with the ErrInvalidOrderByKey ID.
+func NewErrInvalidOrderByKey(ctx *context.T, off int64) error {
+	return verror.New(ErrInvalidOrderByKey, ctx, off)
+}
+
+// NewErrInvalidGroupByKey returns an error with the ErrInvalidGroupByKey ID.
+func NewErrInvalidGroupByKey(ctx *context.T, off int64) error {
+	return verror.New(ErrInvalidGroupByKey, ctx, off)
+}
+
+// NewErrAggregateNotAllowed returns an error with the ErrAggregateNotAllowed ID.
+func NewErrAggregateNotAllowed(ctx *context.T, off int64, name string) error {
+	return verror.New(ErrAggregateNotAllowed, ctx, off, name)
+}
+
+// NewErrFieldNotGrouped returns an error with the ErrFieldNotGrouped ID.
+func NewErrFieldNotGrouped(ctx *context.T, off int64) error {
+	return verror.New(ErrFieldNotGrouped, ctx, off)
+}
+
+// NewErrDuplicateTableName returns an error with the ErrDuplicateTableName ID.
+func NewErrDuplicateTableName(ctx *context.T, off int64, name string) error {
+	return verror.New(ErrDuplicateTableName, ctx, off, name)
+}
+
+// NewErrFieldNotQualified returns an error with the ErrFieldNotQualified ID.
+func NewErrFieldNotQualified(ctx *context.T, off int64) error {
+	return verror.New(ErrFieldNotQualified, ctx, off)
+}
+
+// NewErrInvalidSetField returns an error with the ErrInvalidSetField ID.
+func NewErrInvalidSetField(ctx *context.T, off int64) error {
+	return verror.New(ErrInvalidSetField, ctx, off)
+}
+
+// NewErrInvalidInsertColumns returns an error with the ErrInvalidInsertColumns ID.
+func NewErrInvalidInsertColumns(ctx *context.T, off int64) error {
+	return verror.New(ErrInvalidInsertColumns, ctx, off)
+}
+
+// NewErrInsertValueCount returns an error with the ErrInsertValueCount ID.
+func NewErrInsertValueCount(ctx *context.T, off int64, expected int64, found int64) error {
+	return verror.New(ErrInsertValueCount, ctx, off, expected, found)
+}
+
+// NewErrInvalidInsertValue returns an error with the ErrInvalidInsertValue ID.
+func NewErrInvalidInsertValue(ctx *context.T, off int64) error {
+	return verror.New(ErrInvalidInsertValue, ctx, off)
+}
+
+// NewErrInsertKeyNotString returns an error with the ErrInsertKeyNotString ID.
+func NewErrInsertKeyNotString(ctx *context.T, off int64) error {
+	return verror.New(ErrInsertKeyNotString, ctx, off)
+}
+
+// NewErrKeyExists returns an error with the ErrKeyExists ID.
+func NewErrKeyExists(ctx *context.T, off int64, key string) error {
+	return verror.New(ErrKeyExists, ctx, off, key)
+}
+
+// NewErrAssignmentTypeError returns an error with the ErrAssignmentTypeError ID.
+func NewErrAssignmentTypeError(ctx *context.T, off int64, field string, err error) error {
+	return verror.New(ErrAssignmentTypeError, ctx, off, field, err)
+}
+
+// NewErrWriteError returns an error with the ErrWriteError ID.
+func NewErrWriteError(ctx *context.T, off int64, err error) error {
+	return verror.New(ErrWriteError, ctx, off, err)
+}
+
+// NewErrInvalidArithmeticOperand returns an error with the ErrInvalidArithmeticOperand ID.
+func NewErrInvalidArithmeticOperand(ctx *context.T, off int64) error {
+	return verror.New(ErrInvalidArithmeticOperand, ctx, off)
+}
+
+// NewErrArithmeticError returns an error with the ErrArithmeticError ID.
+func NewErrArithmeticError(ctx *context.T, off int64, err error) error {
+	return verror.New(ErrArithmeticError, ctx, off, err)
+}
+
+// NewErrUserFunctionError returns an error with the ErrUserFunctionError ID.
+func NewErrUserFunctionError(ctx *context.T, off int64, name string, err error) error {
+	return verror.New(ErrUserFunctionError, ctx, off, name, err)
+}
+
+// NewErrInvalidUserFunction returns an error with the ErrInvalidUserFunction ID.
+func NewErrInvalidUserFunction(ctx *context.T, name string, err error) error {
+	return verror.New(ErrInvalidUserFunction, ctx, name, err)
+}
+
+// NewErrInvalidRegularExpression returns an error with the ErrInvalidRegularExpression ID.
+func NewErrInvalidRegularExpression(ctx *context.T, off int64, err error) error {
+	return verror.New(ErrInvalidRegularExpression, ctx, off, err)
+}
+
+// NewErrFunctionInvalidArg returns an error with the ErrFunctionInvalidArg ID.
+func NewErrFunctionInvalidArg(ctx *context.T, off int64, name string, expected string) error {
+	return verror.New(ErrFunctionInvalidArg, ctx, off, name, expected)
+}
+
+// NewErrDurationConversionError returns an error with the ErrDurationConversionError ID.
+func NewErrDurationConversionError(ctx *context.T, off int64, err error) error {
+	return verror.New(ErrDurationConversionError, ctx, off, err)
+}
+
+// NewErrInvalidTimeUnit returns an error with the ErrInvalidTimeUnit ID.
+func NewErrInvalidTimeUnit(ctx *context.T, off int64, unit string) error {
+	return verror.New(ErrInvalidTimeUnit, ctx, off, unit)
+}
+
+// NewErrUnionColumnCount returns an error with the ErrUnionColumnCount ID.
+func NewErrUnionColumnCount(ctx *context.T, off int64, expected int64, found int64) error {
+	return verror.New(ErrUnionColumnCount, ctx, off, expected, found)
+}
+
+// NewErrAggregateArgNotNumeric returns an error with the ErrAggregateArgNotNumeric ID.
+func NewErrAggregateArgNotNumeric(ctx *context.T, off int64, name string) error {
+	return verror.New(ErrAggregateArgNotNumeric, ctx, off, name)
+}
+
+// NewErrInvalidJsonPath returns an error with the ErrInvalidJsonPath ID.
+func NewErrInvalidJsonPath(ctx *context.T, off int64, path string) error {
+	return verror.New(ErrInvalidJsonPath, ctx, off, path)
+}
+
+// NewErrUnionOrderBy returns an error with the ErrUnionOrderBy ID.
+func NewErrUnionOrderBy(ctx *context.T, off int64) error {
+	return verror.New(ErrUnionOrderBy, ctx, off)
+}
+
 var __VDLInitCalled bool
 
 // __VDLInit performs vdl initialization.  It is safe to call multiple times.
@@ -428,6 +584,32 @@ func __VDLInit() struct{} {
 	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrInvalidIndexField.ID), "{1:}{2:} [{3}]Invalid index field {4} returned by table {5}.")
 	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrNotWritable.ID), "{1:}{2:} [0]Can't write to table {3} (not supported on batch/connection).")
 	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrOperationNotSupported.ID), "{1:}{2:} [0]{3} not supported.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrInvalidOrderByKey.ID), "{1:}{2:} [{3}]Order by key must be 'k', 'v[{.<ident>}...]' or a function.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrInvalidGroupByKey.ID), "{1:}{2:} [{3}]Group by key must be 'k', 'v[{.<ident>}...]' or a function.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrAggregateNotAllowed.ID), "{1:}{2:} [{3}]Aggregate function {4} cannot be used in where or group by clauses, or as an argument of an aggregate function.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrFieldNotGrouped.ID), "{1:}{2:} [{3}]Field must be a group by key or the argument of an aggregate function.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrDuplicateTableName.ID), "{1:}{2:} [{3}]Table name or alias {4} appears more than once in the from clause.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrFieldNotQualified.ID), "{1:}{2:} [{3}]Fields of select statements with joins must begin with a table name or alias.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrInvalidSetField.ID), "{1:}{2:} [{3}]Set field must be 'v[{.<ident>}...]'.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrInvalidInsertColumns.ID), "{1:}{2:} [{3}]Insert columns must be exactly k and v.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrInsertValueCount.ID), "{1:}{2:} [{3}]Expected {4} values, found {5}.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrInvalidInsertValue.ID), "{1:}{2:} [{3}]Insert values must be literals, parameters or functions of them.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrInsertKeyNotString.ID), "{1:}{2:} [{3}]Inserted key must be a string.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrKeyExists.ID), "{1:}{2:} [{3}]Key {4} already exists.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrAssignmentTypeError.ID), "{1:}{2:} [{3}]Cannot assign to {4}: {5}.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrWriteError.ID), "{1:}{2:} [{3}]Write error: {4}.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrInvalidArithmeticOperand.ID), "{1:}{2:} [{3}]Arithmetic operands must be numeric.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrArithmeticError.ID), "{1:}{2:} [{3}]Arithmetic error: {4}.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrUserFunctionError.ID), "{1:}{2:} [{3}]Function '{4}' failed: {5}.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrInvalidUserFunction.ID), "{1:}{2:} Invalid user-defined function '{3}': {4}.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrInvalidRegularExpression.ID), "{1:}{2:} [{3}]Invalid regular expression: {4}.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrFunctionInvalidArg.ID), "{1:}{2:} [{3}]Function '{4}()' expects {5}.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrDurationConversionError.ID), "{1:}{2:} [{3}]Can't convert to duration: {4}.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrInvalidTimeUnit.ID), "{1:}{2:} [{3}]Time unit must be 'hour', 'day', 'week' or 'month', found '{4}'.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrUnionColumnCount.ID), "{1:}{2:} [{3}]Select statements of a union must have the same number of columns, expected {4}, found {5}.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrAggregateArgNotNumeric.ID), "{1:}{2:} [{3}]Argument of aggregate function {4} must be numeric.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrInvalidJsonPath.ID), "{1:}{2:} [{3}]Invalid JSON path: {4}.")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrUnionOrderBy.ID), "{1:}{2:} [{3}]Order by is not allowed in the select statements of a union.")
 
 	return struct{}{}
 }
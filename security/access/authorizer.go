@@ -36,6 +36,10 @@ var (
 // an error. However, if multiple tags become a common occurrence, then this
 // behavior may change.
 //
+// If a tag hierarchy is provided as a TagImplications option, the
+// AccessLists of the tags that imply the tag on the method also grant access.
+// An error is returned if the hierarchy is cyclic.
+//
 // If the Permissions provided is nil, then a nil authorizer is returned.
 //
 // Sample usage:
@@ -81,17 +85,27 @@ var (
 // A peer presenting the blessing "alice:colleague:carol" will get access only
 // to the "Set" and "SetIndex" methods. A peer presenting "alice:family:mom"
 // will get access to all methods.
-func PermissionsAuthorizer(perms Permissions, tagType *vdl.Type) (security.Authorizer, error) {
+func PermissionsAuthorizer(perms Permissions, tagType *vdl.Type, opts ...AuthorizerOpt) (security.Authorizer, error) {
 	if tagType.Kind() != vdl.String {
 		return nil, errTagType(tagType)
 	}
-	return &authorizer{perms, tagType}, nil
+	hierarchy, err := NewTagHierarchy(tagType, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &authorizer{perms, tagType, hierarchy}, nil
+}
+
+// AuthorizerOpt is the interface for all options of the authorizers in this
+// package, and of Simulate.
+type AuthorizerOpt interface {
+	AccessAuthorizerOpt()
 }
 
 // TypicalTagTypePermissionsAuthorizer is like PermissionsAuthorizer, but
 // assumes TypicalTagType and thus avoids returning an error.
 func TypicalTagTypePermissionsAuthorizer(perms Permissions) security.Authorizer {
-	return &authorizer{perms, TypicalTagType()}
+	return &authorizer{perms, TypicalTagType(), TagHierarchy{}}
 }
 
 // PermissionsAuthorizerFromFile applies the same authorization policy as
@@ -100,14 +114,19 @@ func TypicalTagTypePermissionsAuthorizer(perms Permissions) security.Authorizer
 //
 // Changes to the file are monitored and affect subsequent calls to Authorize.
 // Currently, this is achieved by re-reading the file on every call to
-// Authorize.
+// Authorize. Servers that authorize many requests should use
+// NewWatchingAuthorizer instead, which caches the Permissions.
 // TODO(ashankar,ataly): Use inotify or a similar mechanism to watch for
 // changes.
-func PermissionsAuthorizerFromFile(filename string, tagType *vdl.Type) (security.Authorizer, error) {
+func PermissionsAuthorizerFromFile(filename string, tagType *vdl.Type, opts ...AuthorizerOpt) (security.Authorizer, error) {
 	if tagType.Kind() != vdl.String {
 		return nil, errTagType(tagType)
 	}
-	return &fileAuthorizer{filename, tagType}, nil
+	hierarchy, err := NewTagHierarchy(tagType, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &fileAuthorizer{filename, tagType, hierarchy}, nil
 }
 
 func errTagType(tt *vdl.Type) error {
@@ -115,8 +134,9 @@ func errTagType(tt *vdl.Type) error {
 }
 
 type authorizer struct {
-	perms   Permissions
-	tagType *vdl.Type
+	perms     Permissions
+	tagType   *vdl.Type
+	hierarchy TagHierarchy
 }
 
 func (a *authorizer) Authorize(ctx *context.T, call security.Call) error {
@@ -128,7 +148,7 @@ func (a *authorizer) Authorize(ctx *context.T, call security.Call) error {
 				return verror.New(errMultipleMethodTags, ctx, call.Suffix(), call.Method(), a.tagType, call.MethodTags())
 			}
 			hastag = true
-			if acl, exists := a.perms[tag.RawString()]; !exists || !acl.Includes(blessings...) {
+			if !a.perms.includes(a.hierarchy, tag.RawString(), blessings) {
 				return NewErrNoPermissions(ctx, blessings, invalid, tag.RawString())
 			}
 		}
@@ -140,8 +160,9 @@ func (a *authorizer) Authorize(ctx *context.T, call security.Call) error {
 }
 
 type fileAuthorizer struct {
-	filename string
-	tagType  *vdl.Type
+	filename  string
+	tagType   *vdl.Type
+	hierarchy TagHierarchy
 }
 
 func (a *fileAuthorizer) Authorize(ctx *context.T, call security.Call) error {
@@ -150,7 +171,7 @@ func (a *fileAuthorizer) Authorize(ctx *context.T, call security.Call) error {
 		// TODO(ashankar): Information leak?
 		return verror.New(errCantReadPermissionsFromFile, ctx, err)
 	}
-	return (&authorizer{perms, a.tagType}).Authorize(ctx, call)
+	return (&authorizer{perms, a.tagType, a.hierarchy}).Authorize(ctx, call)
 }
 
 func loadPermissionsFromFile(filename string) (Permissions, error) {
@@ -0,0 +1,219 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package access
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"v.io/v23/security"
+	"v.io/v23/verror"
+)
+
+var errMergeConflict = verror.Register(pkgPath+".errMergeConflict", verror.NoRetry, "{1:}{2:}merged AccessList for tag {3} is invalid{:_}")
+
+// AccessListDiff describes the changes made to an AccessList.
+type AccessListDiff struct {
+	AddedIn, RemovedIn       []security.BlessingPattern
+	AddedNotIn, RemovedNotIn []string
+}
+
+// IsEmpty returns true iff d describes no changes.
+func (d AccessListDiff) IsEmpty() bool {
+	return len(d.AddedIn)+len(d.RemovedIn)+len(d.AddedNotIn)+len(d.RemovedNotIn) == 0
+}
+
+// PermissionsDiff describes the changes made to a Permissions object, keyed
+// by tag. Tags whose AccessList did not change are not present.
+type PermissionsDiff map[string]AccessListDiff
+
+// Diff returns the changes that turn the Permissions from into to.
+//
+// A tag that is present in only one of the two Permissions is treated as if
+// it had an empty AccessList in the other.
+func Diff(from, to Permissions) PermissionsDiff {
+	ret := make(PermissionsDiff)
+	for _, tag := range unionOfTags(from, to) {
+		var d AccessListDiff
+		d.AddedIn, d.RemovedIn = diffPatterns(from[tag].In, to[tag].In)
+		d.AddedNotIn, d.RemovedNotIn = diffStrings(from[tag].NotIn, to[tag].NotIn)
+		if !d.IsEmpty() {
+			ret[tag] = d
+		}
+	}
+	return ret
+}
+
+// Apply applies the changes described by d to perms, returning perms.
+//
+// Adding an entry that is already present or removing one that is not are
+// no-ops, and tags whose AccessList becomes empty are removed.
+func (d PermissionsDiff) Apply(perms Permissions) Permissions {
+	for tag, ad := range d {
+		for _, p := range ad.AddedIn {
+			perms.Add(p, tag)
+		}
+		for _, b := range ad.AddedNotIn {
+			perms.Blacklist(b, tag)
+		}
+		for _, p := range ad.RemovedIn {
+			acl := perms[tag]
+			acl.In = removePattern(acl.In, p)
+			perms[tag] = acl
+		}
+		for _, b := range ad.RemovedNotIn {
+			acl := perms[tag]
+			acl.NotIn = removeString(acl.NotIn, b)
+			perms[tag] = acl
+		}
+		if acl := perms[tag]; len(acl.In) == 0 && len(acl.NotIn) == 0 {
+			delete(perms, tag)
+		}
+	}
+	return perms
+}
+
+// String returns a human-readable representation of d, with one line per
+// added ("+") or removed ("-") entry. For example:
+//   Read: +In dev:alice
+//   Read: -NotIn dev:alice:phone
+func (d PermissionsDiff) String() string {
+	tags := make([]string, 0, len(d))
+	for tag := range d {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	var buf bytes.Buffer
+	for _, tag := range tags {
+		ad := d[tag]
+		for _, p := range ad.AddedIn {
+			fmt.Fprintf(&buf, "%s: +In %s\n", tag, p)
+		}
+		for _, p := range ad.RemovedIn {
+			fmt.Fprintf(&buf, "%s: -In %s\n", tag, p)
+		}
+		for _, b := range ad.AddedNotIn {
+			fmt.Fprintf(&buf, "%s: +NotIn %s\n", tag, b)
+		}
+		for _, b := range ad.RemovedNotIn {
+			fmt.Fprintf(&buf, "%s: -NotIn %s\n", tag, b)
+		}
+	}
+	return buf.String()
+}
+
+// VersionedPermissions is a Permissions object along with the version string
+// returned by GetPermissions (see v.io/v23/services/permissions) for it.
+type VersionedPermissions struct {
+	Permissions Permissions
+	Version     string
+}
+
+// Merge performs a three-way merge of concurrent edits to a Permissions
+// object.
+//
+// base is the Permissions and version that were read by GetPermissions
+// before being edited locally into ours (whose Version is ignored), and
+// theirs is the Permissions and version currently stored, as returned by a
+// later call to GetPermissions (typically after SetPermissions failed
+// because of a version mismatch). The returned Version is that of theirs, so
+// that the returned Permissions can be passed to SetPermissions.
+//
+// If theirs has the same version as base, ours is returned. Otherwise the
+// changes made to each AccessList in ours (relative to base) are applied to
+// theirs. An error is returned if the result contains an AccessList that
+// would be rejected by Enforceable because it combines the pattern "..." with
+// other patterns.
+func Merge(base, ours, theirs VersionedPermissions) (VersionedPermissions, error) {
+	if theirs.Version == base.Version {
+		return VersionedPermissions{ours.Permissions.Copy().Normalize(), theirs.Version}, nil
+	}
+	merged := Diff(base.Permissions, ours.Permissions).Apply(theirs.Permissions.Copy())
+	for tag, acl := range merged {
+		for _, p := range acl.In {
+			if p == security.AllPrincipals && !acl.isOpen() {
+				return VersionedPermissions{}, verror.New(errMergeConflict, nil, tag, NewErrInvalidOpenAccessList(nil))
+			}
+		}
+	}
+	return VersionedPermissions{merged.Normalize(), theirs.Version}, nil
+}
+
+func unionOfTags(perms ...Permissions) []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, m := range perms {
+		for tag := range m {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func diffPatterns(from, to []security.BlessingPattern) (added, removed []security.BlessingPattern) {
+	a, r := diffStrings(patternStrings(from), patternStrings(to))
+	for _, p := range a {
+		added = append(added, security.BlessingPattern(p))
+	}
+	for _, p := range r {
+		removed = append(removed, security.BlessingPattern(p))
+	}
+	return added, removed
+}
+
+func diffStrings(from, to []string) (added, removed []string) {
+	inFrom := make(map[string]bool)
+	for _, s := range from {
+		inFrom[s] = true
+	}
+	inTo := make(map[string]bool)
+	for _, s := range to {
+		inTo[s] = true
+	}
+	for _, s := range removeDuplicateStrings(to) {
+		if !inFrom[s] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range removeDuplicateStrings(from) {
+		if !inTo[s] {
+			removed = append(removed, s)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+func patternStrings(l []security.BlessingPattern) []string {
+	ret := make([]string, len(l))
+	for i, p := range l {
+		ret[i] = string(p)
+	}
+	return ret
+}
+
+func removePattern(l []security.BlessingPattern, p security.BlessingPattern) (ret []security.BlessingPattern) {
+	for _, item := range l {
+		if item != p {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+func removeString(l []string, s string) (ret []string) {
+	for _, item := range l {
+		if item != s {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,111 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package access_test
+
+import (
+	"reflect"
+	"testing"
+
+	"v.io/v23/security"
+	"v.io/v23/security/access"
+)
+
+func TestDiff(t *testing.T) {
+	type bp []security.BlessingPattern // shorthand
+	from := access.Permissions{
+		"Read":  {In: bp{"dev:alice", "dev:bob"}, NotIn: []string{"dev:alice:phone"}},
+		"Write": {In: bp{"dev:alice"}},
+		"Debug": {In: bp{"dev:carol"}},
+	}
+	to := access.Permissions{
+		"Read":  {In: bp{"dev:bob", "dev:carol", "dev:alice:laptop"}, NotIn: []string{"dev:bob:tv"}},
+		"Write": {In: bp{"dev:alice"}},
+		"Admin": {In: bp{"dev:alice:$"}},
+	}
+	got := access.Diff(from, to)
+	want := access.PermissionsDiff{
+		"Read": {
+			AddedIn:      bp{"dev:alice:laptop", "dev:carol"},
+			RemovedIn:    bp{"dev:alice"},
+			AddedNotIn:   []string{"dev:bob:tv"},
+			RemovedNotIn: []string{"dev:alice:phone"},
+		},
+		"Admin": {AddedIn: bp{"dev:alice:$"}},
+		"Debug": {RemovedIn: bp{"dev:carol"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+	wantString := `Admin: +In dev:alice:$
+Debug: -In dev:carol
+Read: +In dev:alice:laptop
+Read: +In dev:carol
+Read: -In dev:alice
+Read: +NotIn dev:bob:tv
+Read: -NotIn dev:alice:phone
+`
+	if got := got.String(); got != wantString {
+		t.Errorf("Got:\n%s\nWant:\n%s", got, wantString)
+	}
+	if got, want := got.Apply(from.Copy()), to.Copy().Normalize(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+	if d := access.Diff(to, to.Copy()); len(d) != 0 {
+		t.Errorf("Got %v, want an empty diff", d)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	type bp []security.BlessingPattern // shorthand
+	base := access.VersionedPermissions{
+		Permissions: access.Permissions{
+			"Read":  {In: bp{"dev:alice", "dev:bob"}},
+			"Write": {In: bp{"dev:alice"}},
+		},
+		Version: "1",
+	}
+	// We add carol to Read and remove Write.
+	ours := access.VersionedPermissions{
+		Permissions: access.Permissions{
+			"Read": {In: bp{"dev:alice", "dev:bob", "dev:carol"}},
+		},
+	}
+	// If nobody else changed the permissions, ours wins.
+	got, err := access.Merge(base, ours, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (access.VersionedPermissions{ours.Permissions, "1"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+	// Concurrently, they removed bob from Read and blacklisted alice's
+	// phone for Write.
+	theirs := access.VersionedPermissions{
+		Permissions: access.Permissions{
+			"Read":  {In: bp{"dev:alice"}},
+			"Write": {In: bp{"dev:alice"}, NotIn: []string{"dev:alice:phone"}},
+		},
+		Version: "2",
+	}
+	got, err = access.Merge(base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := access.VersionedPermissions{
+		Permissions: access.Permissions{
+			"Read":  {In: bp{"dev:alice", "dev:carol"}},
+			"Write": {NotIn: []string{"dev:alice:phone"}},
+		},
+		Version: "2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+	// Merges that result in an invalid open AccessList are rejected.
+	theirs.Permissions = access.Permissions{"Read": {In: bp{"..."}}, "Write": {In: bp{"dev:alice"}}}
+	if got, err := access.Merge(base, ours, theirs); err == nil {
+		t.Errorf("Got %v, want error", got)
+	}
+}
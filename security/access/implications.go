@@ -0,0 +1,125 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package access
+
+import (
+	"sort"
+
+	"v.io/v23/vdl"
+	"v.io/v23/verror"
+)
+
+var errTagImplicationCycle = verror.Register(pkgPath+".errTagImplicationCycle", verror.NoRetry, "{1:}{2:}tag {3} of type {4} implies itself{:_}")
+
+// TagImplications describes a hierarchy of tags, mapping each tag to the
+// tags it directly implies. For example, with:
+//   TagImplications{"Admin": {"Write"}, "Write": {"Read"}}
+// the AccessList for Admin grants access to methods tagged Admin, Write or
+// Read, and the AccessList for Write grants access to methods tagged Write or
+// Read.
+//
+// A hierarchy is in effect only for the authorizers it is passed to as an
+// AuthorizerOpt. The stored Permissions are not affected.
+type TagImplications map[string][]string
+
+// AccessAuthorizerOpt makes TagImplications an AuthorizerOpt.
+func (TagImplications) AccessAuthorizerOpt() {}
+
+// TypicalTagImplications returns the hierarchy Admin ⊃ Write ⊃ Read for the
+// tags of TypicalTagType.
+func TypicalTagImplications() TagImplications {
+	return TagImplications{
+		string(Admin): {string(Write)},
+		string(Write): {string(Read)},
+	}
+}
+
+// ImplyingTags returns the tags whose AccessLists grant access to methods
+// with the provided tag as per ti: the tag itself, followed by the tags that
+// transitively imply it in sorted order.
+func (ti TagImplications) ImplyingTags(tag string) []string {
+	var implying []string
+	for t := range ti {
+		if t != tag && ti.implies(t, tag) {
+			implying = append(implying, t)
+		}
+	}
+	sort.Strings(implying)
+	return append([]string{tag}, implying...)
+}
+
+// implies returns true iff tag transitively implies implied.
+func (ti TagImplications) implies(tag, implied string) bool {
+	visited := make(map[string]bool)
+	stack := append([]string(nil), ti[tag]...)
+	for len(stack) > 0 {
+		t := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if t == implied {
+			return true
+		}
+		if visited[t] {
+			continue
+		}
+		visited[t] = true
+		stack = append(stack, ti[t]...)
+	}
+	return false
+}
+
+// TagHierarchy is the precomputed form of the TagImplications passed to an
+// authorizer, mapping each tag to the tags whose AccessLists grant access to
+// methods with it. The zero value is the hierarchy in which tags imply only
+// themselves.
+type TagHierarchy struct {
+	implying map[string][]string
+}
+
+// NewTagHierarchy returns the hierarchy described by the last TagImplications
+// in opts, if any, for tags of tagType. An error is returned if the
+// implications are cyclic.
+//
+// Authorizers outside this package that support TagImplications use
+// NewTagHierarchy so that they validate and evaluate them like
+// PermissionsAuthorizer does.
+func NewTagHierarchy(tagType *vdl.Type, opts ...AuthorizerOpt) (TagHierarchy, error) {
+	var implications TagImplications
+	for _, opt := range opts {
+		if ti, ok := opt.(TagImplications); ok {
+			implications = ti
+		}
+	}
+	h := TagHierarchy{implying: make(map[string][]string)}
+	for tag, implied := range implications {
+		if implications.implies(tag, tag) {
+			return TagHierarchy{}, verror.New(errTagImplicationCycle, nil, tag, tagType)
+		}
+		for _, t := range implied {
+			if _, done := h.implying[t]; !done {
+				h.implying[t] = implications.ImplyingTags(t)
+			}
+		}
+	}
+	return h, nil
+}
+
+// ImplyingTags is like TagImplications.ImplyingTags.
+func (h TagHierarchy) ImplyingTags(tag string) []string {
+	if tags, ok := h.implying[tag]; ok {
+		return tags
+	}
+	return []string{tag}
+}
+
+// includes returns true iff the AccessList in m of tag, or of any tag that
+// implies it as per h, includes blessings.
+func (m Permissions) includes(h TagHierarchy, tag string, blessings []string) bool {
+	for _, t := range h.ImplyingTags(tag) {
+		if acl, exists := m[t]; exists && acl.Includes(blessings...) {
+			return true
+		}
+	}
+	return false
+}
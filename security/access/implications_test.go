@@ -0,0 +1,148 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package access_test
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"v.io/v23/security"
+	"v.io/v23/security/access"
+	"v.io/v23/vdl"
+	"v.io/v23/vdlroot/signature"
+)
+
+func TestTagImplications(t *testing.T) {
+	tt := access.TypicalTagType()
+	cyclic := access.TagImplications{"Admin": {"Write"}, "Write": {"Read", "Admin"}}
+	if _, err := access.PermissionsAuthorizer(nil, tt, cyclic); err == nil {
+		t.Errorf("Expected error for cyclic implications")
+	}
+	if _, err := access.PermissionsAuthorizer(nil, vdl.Int32Type, access.TypicalTagImplications()); err == nil {
+		t.Errorf("Expected error since tag type is not a string")
+	}
+	for tag, want := range map[access.Tag][]string{
+		access.Read:    {"Read", "Admin", "Write"},
+		access.Write:   {"Write", "Admin"},
+		access.Admin:   {"Admin"},
+		access.Resolve: {"Resolve"},
+	} {
+		if got := access.TypicalTagImplications().ImplyingTags(string(tag)); !reflect.DeepEqual(got, want) {
+			t.Errorf("ImplyingTags(%v): got %v, want %v", tag, got, want)
+		}
+	}
+	if got, want := access.TagImplications(nil).ImplyingTags("Read"), []string{"Read"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+	if _, err := access.NewTagHierarchy(tt, cyclic); err == nil {
+		t.Errorf("Expected error for cyclic implications")
+	}
+	h, err := access.NewTagHierarchy(tt, access.TypicalTagImplications())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := h.ImplyingTags(string(access.Read)), []string{"Read", "Admin", "Write"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+	if got, want := (access.TagHierarchy{}).ImplyingTags("Read"), []string{"Read"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestPermissionsAuthorizerWithTagImplications(t *testing.T) {
+	file, err := ioutil.TempFile("", "TestPermissionsAuthorizerWithTagImplications")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := file.Name()
+	defer os.Remove(filename)
+	perms := access.Permissions{
+		"Admin": {In: []security.BlessingPattern{"admin"}},
+		"Write": {In: []security.BlessingPattern{"writer"}},
+		"Read":  {In: []security.BlessingPattern{"reader"}, NotIn: []string{"writer:bad"}},
+	}
+	if err := access.WritePermissions(file, perms); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	var (
+		tt             = access.TypicalTagType()
+		implications   = access.TypicalTagImplications()
+		newAuthorizers = func(opts ...access.AuthorizerOpt) []security.Authorizer {
+			authorizer, err := access.PermissionsAuthorizer(perms, tt, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fileAuthorizer, err := access.PermissionsAuthorizerFromFile(filename, tt, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return []security.Authorizer{authorizer, fileAuthorizer}
+		}
+		pserver = newPrincipal(t)
+		pclient = newPrincipal(t)
+		tag     = func(t access.Tag) []*vdl.Value { return []*vdl.Value{vdl.ValueOf(t)} }
+		// Allowed methods, per client, once implications are in effect.
+		tests = []struct {
+			client string
+			read   bool
+			write  bool
+			admin  bool
+		}{
+			{"admin", true, true, true},
+			{"writer", true, true, false},
+			{"writer:bad", true, true, false}, // NotIn of Read does not apply to Write
+			{"reader", true, false, false},
+			{"other", false, false, false},
+		}
+	)
+	check := func(implications bool, authorizers []security.Authorizer) {
+		for _, test := range tests {
+			client, err := pclient.BlessSelf(test.client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, method := range []struct {
+				tag     access.Tag
+				allowed bool
+			}{
+				{access.Read, test.read && (implications || test.client == "reader")},
+				{access.Write, test.write && (implications || strings.HasPrefix(test.client, "writer"))},
+				{access.Admin, test.admin},
+			} {
+				params := &security.CallParams{
+					LocalPrincipal:  pserver,
+					RemoteBlessings: client,
+					Method:          "Method",
+					MethodTags:      tag(method.tag),
+				}
+				for _, authorizer := range authorizers {
+					if err := authorize(authorizer, params); (err == nil) != method.allowed {
+						t.Errorf("implications=%v, %v calling %v method: got %v, want allowed=%v", implications, test.client, method.tag, err, method.allowed)
+					}
+				}
+			}
+		}
+	}
+	check(false, newAuthorizers())
+	check(true, newAuthorizers(implications))
+
+	// The simulator honors implications too.
+	sig := []signature.Interface{{Methods: []signature.Method{{Name: "Get", Tags: tag(access.Read)}}}}
+	m, err := access.Simulate(perms, tt, sig, []string{"writer:bad", "other"}, implications)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Decisions[0][0].String(), "allowed: writer:bad matches pattern writer for tag Write"; got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+	if got, want := m.Decisions[1][0].String(), "denied: other matches no pattern for tag Read; other matches no pattern for tag Admin; other matches no pattern for tag Write"; got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+}
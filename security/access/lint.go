@@ -0,0 +1,176 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package access
+
+import (
+	"fmt"
+	"strings"
+
+	"v.io/v23/context"
+	"v.io/v23/security"
+	"v.io/v23/vdl"
+)
+
+// LintKind identifies the kind of problem reported by Lint.
+type LintKind int
+
+const (
+	// LintOpenAccessList is reported for AccessLists that grant access to
+	// everyone, i.e., that contain the pattern "..." in their In list.
+	LintOpenAccessList LintKind = iota
+	// LintUnreachableNotIn is reported for NotIn entries that do not match
+	// any blessing name matched by a pattern in the In list, and thus have
+	// no effect. This includes all NotIn entries of AccessLists whose In
+	// list contains "...", which matches even when every blessing name has
+	// been excluded.
+	LintUnreachableNotIn
+	// LintUnrecognizedPattern is reported for patterns in the In list that
+	// are rejected by AccessList.Enforceable, i.e., patterns that are invalid
+	// or not matched by blessing names recognized by any root of the
+	// principal.
+	LintUnrecognizedPattern
+	// LintUnknownTag is reported for tags that are not values of the tag
+	// type.
+	LintUnknownTag
+)
+
+func (k LintKind) String() string {
+	switch k {
+	case LintOpenAccessList:
+		return "OpenAccessList"
+	case LintUnreachableNotIn:
+		return "UnreachableNotIn"
+	case LintUnrecognizedPattern:
+		return "UnrecognizedPattern"
+	case LintUnknownTag:
+		return "UnknownTag"
+	}
+	return fmt.Sprintf("LintKind(%d)", int(k))
+}
+
+// LintIssue describes a potential problem with a Permissions object.
+type LintIssue struct {
+	Kind LintKind
+	// Tag is the tag whose AccessList has the problem.
+	Tag string
+	// Entry is the pattern or blessing name that has the problem, empty for
+	// problems that concern the AccessList as a whole.
+	Entry string
+	// Message describes the problem.
+	Message string
+}
+
+func (i LintIssue) String() string {
+	if i.Entry == "" {
+		return fmt.Sprintf("%s: %s: %s", i.Tag, i.Kind, i.Message)
+	}
+	return fmt.Sprintf("%s: %s %s: %s", i.Tag, i.Kind, i.Entry, i.Message)
+}
+
+// Lint returns the potential problems found in perms, ordered by tag.
+//
+// Patterns are checked against the roots of the principal p using
+// AccessList.Enforceable, and skipped if p is nil. Tags are checked against
+// the values of tagType, and skipped if tagType is nil or its values cannot
+// be enumerated. The values of TypicalTagType are AllTypicalTags, and those
+// of an enum type are its labels; the values of other types (e.g., of other
+// string types) cannot be enumerated.
+func Lint(ctx *context.T, perms Permissions, p security.Principal, tagType *vdl.Type) []LintIssue {
+	tags := tagValues(tagType)
+	known := make(map[string]bool)
+	for _, tag := range tags {
+		known[tag] = true
+	}
+	var issues []LintIssue
+	for _, tag := range unionOfTags(perms) {
+		acl := perms[tag]
+		if tags != nil && !known[tag] {
+			issues = append(issues, LintIssue{LintUnknownTag, tag, "", "tag is not a value of the tag type"})
+		}
+		var closed AccessList
+		for _, pattern := range acl.In {
+			if pattern != security.AllPrincipals {
+				closed.In = append(closed.In, pattern)
+			}
+		}
+		open := len(closed.In) < len(acl.In)
+		if open {
+			msg := "grants access to everyone"
+			if !acl.isOpen() {
+				var others []string
+				if len(closed.In) > 0 {
+					others = append(others, "other patterns")
+				}
+				if len(acl.NotIn) > 0 {
+					others = append(others, "NotIn entries")
+				}
+				if len(others) == 0 {
+					// ... appears more than once.
+					others = append(others, "itself")
+				}
+				msg = fmt.Sprintf("grants access to everyone, and combines ... with %s, which Enforceable rejects", strings.Join(others, " and "))
+			}
+			issues = append(issues, LintIssue{LintOpenAccessList, tag, "", msg})
+		}
+		for _, b := range acl.NotIn {
+			switch {
+			case open:
+				issues = append(issues, LintIssue{LintUnreachableNotIn, tag, b, "has no effect as ... in the In list matches all blessing names"})
+			case !notInReachable(b, acl.In):
+				issues = append(issues, LintIssue{LintUnreachableNotIn, tag, b, "not matched by any pattern in the In list"})
+			}
+		}
+		if p == nil {
+			continue
+		}
+		for _, pattern := range IsUnenforceablePatterns(closed.Enforceable(ctx, p)) {
+			msg := "not recognized by any root"
+			if !pattern.IsValid() {
+				msg = "invalid pattern"
+			}
+			issues = append(issues, LintIssue{LintUnrecognizedPattern, tag, string(pattern), msg})
+		}
+	}
+	return issues
+}
+
+// tagValues returns the values of tagType as strings, or nil if they cannot
+// be enumerated.
+func tagValues(tagType *vdl.Type) []string {
+	switch {
+	case tagType == nil:
+		return nil
+	case tagType == TypicalTagType():
+		return TagStrings(AllTypicalTags()...)
+	case tagType.Kind() == vdl.Enum:
+		labels := make([]string, tagType.NumEnumLabel())
+		for i := range labels {
+			labels[i] = tagType.EnumLabel(i)
+		}
+		return labels
+	}
+	return nil
+}
+
+// notInReachable returns true iff some blessing name that is matched by the
+// NotIn entry b (i.e., b or one of its delegates) is matched by one of the
+// patterns in in, which must not contain "...".
+func notInReachable(b string, in []security.BlessingPattern) bool {
+	for _, pattern := range in {
+		name := string(pattern)
+		if strings.HasSuffix(name, security.ChainSeparator+string(security.NoExtension)) {
+			// Only matched by name itself.
+			name = strings.TrimSuffix(name, security.ChainSeparator+string(security.NoExtension))
+			if security.BlessingPattern(b).MatchedBy(name) {
+				return true
+			}
+			continue
+		}
+		if security.BlessingPattern(b).MatchedBy(name) || pattern.MatchedBy(b) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package access_test
+
+import (
+	"reflect"
+	"testing"
+
+	"v.io/v23/context"
+	"v.io/v23/security"
+	"v.io/v23/security/access"
+	"v.io/v23/vdl"
+)
+
+func TestLint(t *testing.T) {
+	ctx, cancel := context.RootContext()
+	defer cancel()
+	p := newPrincipal(t)
+	key, err := p.PublicKey().MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Roots().Add(key, "dev"); err != nil {
+		t.Fatal(err)
+	}
+	type bp []security.BlessingPattern // shorthand
+	perms := access.Permissions{
+		"Read":    {In: bp{"..."}},
+		"Write":   {In: bp{"dev:alice", "corp:bob"}, NotIn: []string{"dev:alice:phone", "dev:carol", "dev"}},
+		"Admin":   {In: bp{"dev:alice:$", "dev::x"}, NotIn: []string{"dev:alice:phone"}},
+		"Resolve": {In: bp{"...", "dev"}},
+		"Mystery": {In: bp{"dev"}},
+		"Debug":   {In: bp{"..."}, NotIn: []string{"dev:carol"}},
+	}
+	var got []string
+	for _, issue := range access.Lint(ctx, perms, p, access.TypicalTagType()) {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		"Admin: UnreachableNotIn dev:alice:phone: not matched by any pattern in the In list",
+		"Admin: UnrecognizedPattern dev::x: invalid pattern",
+		"Debug: OpenAccessList: grants access to everyone, and combines ... with NotIn entries, which Enforceable rejects",
+		"Debug: UnreachableNotIn dev:carol: has no effect as ... in the In list matches all blessing names",
+		"Mystery: UnknownTag: tag is not a value of the tag type",
+		"Read: OpenAccessList: grants access to everyone",
+		"Resolve: OpenAccessList: grants access to everyone, and combines ... with other patterns, which Enforceable rejects",
+		"Write: UnreachableNotIn dev:carol: not matched by any pattern in the In list",
+		"Write: UnrecognizedPattern corp:bob: not recognized by any root",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+	// The values of enum tag types are their labels; those of other string
+	// types cannot be enumerated, so the tags are not checked.
+	colors := access.Permissions{"Red": {In: bp{"dev"}}, "Blue": {In: bp{"dev"}}}
+	if got := access.Lint(ctx, colors, nil, vdl.NamedType("Color", vdl.EnumType("Red", "Green"))); len(got) != 1 || got[0].Kind != access.LintUnknownTag || got[0].Tag != "Blue" {
+		t.Errorf("Got %v, want a single UnknownTag issue for Blue", got)
+	}
+	if got := access.Lint(ctx, colors, nil, vdl.NamedType("Color", vdl.StringType)); len(got) != 0 {
+		t.Errorf("Got %v, want no issues", got)
+	}
+	// Without a principal or tag type, only the AccessLists themselves are
+	// checked.
+	if got := access.Lint(ctx, access.Permissions{"Mystery": {In: bp{"corp:bob"}, NotIn: []string{"dev"}}}, nil, nil); len(got) != 1 || got[0].Kind != access.LintUnreachableNotIn {
+		t.Errorf("Got %v, want a single UnreachableNotIn issue", got)
+	}
+}
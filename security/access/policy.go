@@ -0,0 +1,266 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package access
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"v.io/v23/security"
+	"v.io/v23/verror"
+)
+
+var (
+	errPolicySyntax         = verror.Register(pkgPath+".errPolicySyntax", verror.NoRetry, "{1:}{2:}policy line {3}: {4}{:_}")
+	errPolicyInvalidPattern = verror.Register(pkgPath+".errPolicyInvalidPattern", verror.NoRetry, "{1:}{2:}policy line {3}: invalid blessing pattern {4}{:_}")
+	errPolicyInvalidExcept  = verror.Register(pkgPath+".errPolicyInvalidExcept", verror.NoRetry, "{1:}{2:}policy line {3}: invalid blessing name {4} in except clause{:_}")
+	errPolicyConflict       = verror.Register(pkgPath+".errPolicyConflict", verror.NoRetry, "{1:}{2:}policy line {3}: {4} excludes {5}, which is allowed {6} access on line {7}{:_}")
+	errPolicyPartConflict   = verror.Register(pkgPath+".errPolicyPartConflict", verror.NoRetry, "{1:}{2:}policy line {3}: {4} excludes some of the blessings matched by {5}, which is allowed {6} access on line {7}{:_}")
+	errPolicyOpenConflict   = verror.Register(pkgPath+".errPolicyOpenConflict", verror.NoRetry, "{1:}{2:}policy line {3}: granting {4} access to everyone ({5}) cannot be combined with other patterns or exceptions for {4}{:_}")
+	errPolicyInexpressible  = verror.Register(pkgPath+".errPolicyInexpressible", verror.NoRetry, "{1:}{2:}permissions cannot be expressed as a policy{:_}")
+)
+
+// CompilePolicy compiles a policy, written in a small declarative language,
+// into Permissions.
+//
+// A policy consists of rules, one per line. Empty lines and text following
+// a '#' are ignored. Each rule has the form:
+//   allow <tags> to <patterns> [except <blessings>]
+// where <tags> is a comma-separated list of tags, <patterns> a comma-separated
+// list of blessing patterns and <blessings> a comma-separated list of
+// blessing names. The rule adds the patterns to the In list and the blessing
+// names to the NotIn list of the AccessList for each of the tags. For
+// example:
+//   allow Read,Write to dev:alice:* except dev:alice:phone
+//   allow Admin to dev:alice:$
+// grants Read and Write access to dev:alice and all its delegates, except to
+// dev:alice:phone (and its delegates), and Admin access to dev:alice only.
+//
+// Patterns use the syntax of security.BlessingPattern, with the addition that
+// a trailing ":*" may be used to stress that a pattern matches all delegates
+// ("dev:alice:*" is the same as "dev:alice"). Every pattern must be valid
+// (see security.BlessingPattern.IsValid).
+//
+// Since the NotIn list of an AccessList applies to all patterns in its In
+// list, an exclusion must only affect the patterns of its own rule. As such,
+// rules conflict if a blessing name excluded by one rule excludes any of the
+// blessings matched by a pattern that is allowed for the same tag by another
+// rule (e.g., "allow Read to org:contractors" and "allow Read to org:staff
+// except org:contractors:bob"), if it excludes all of the blessings matched
+// by a pattern of its own rule, or if everyone ("...") is allowed a tag along
+// with other patterns or exclusions for the same tag. Such policies are
+// rejected.
+func CompilePolicy(policy string) (Permissions, error) {
+	type origin struct {
+		pattern security.BlessingPattern
+		line    int
+	}
+	var (
+		perms    = make(Permissions)
+		allowed  = make(map[string][]origin)
+		excluded = make(map[string][]origin)
+	)
+	for idx, line := range strings.Split(policy, "\n") {
+		lineno := idx + 1
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		tags, patterns, except, err := parsePolicyRule(lineno, line)
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tags {
+			for _, p := range patterns {
+				allowed[tag] = append(allowed[tag], origin{p, lineno})
+			}
+			for _, b := range except {
+				excluded[tag] = append(excluded[tag], origin{security.BlessingPattern(b), lineno})
+			}
+			perms[tag] = AccessList{}
+		}
+	}
+	for tag := range perms {
+		var acl AccessList
+		for _, a := range allowed[tag] {
+			if a.pattern == security.AllPrincipals && (len(allowed[tag]) > 1 || len(excluded[tag]) > 0) {
+				return nil, verror.New(errPolicyOpenConflict, nil, a.line, tag, security.AllPrincipals)
+			}
+			name := strings.TrimSuffix(string(a.pattern), security.ChainSeparator+string(security.NoExtension))
+			exact := name != string(a.pattern)
+			for _, e := range excluded[tag] {
+				if name == string(e.pattern) || strings.HasPrefix(name, string(e.pattern)+security.ChainSeparator) {
+					return nil, verror.New(errPolicyConflict, nil, e.line, e.pattern, a.pattern, tag, a.line)
+				}
+				// Otherwise, the exclusion affects the pattern iff it
+				// excludes delegates of the blessings matched by it.
+				if !exact && e.line != a.line && strings.HasPrefix(string(e.pattern), name+security.ChainSeparator) {
+					return nil, verror.New(errPolicyPartConflict, nil, e.line, e.pattern, a.pattern, tag, a.line)
+				}
+			}
+			acl.In = append(acl.In, a.pattern)
+		}
+		for _, e := range excluded[tag] {
+			acl.NotIn = append(acl.NotIn, string(e.pattern))
+		}
+		perms[tag] = acl
+	}
+	return perms.Normalize(), nil
+}
+
+// parsePolicyRule parses a single rule of a policy.
+func parsePolicyRule(lineno int, line string) (tags []string, patterns []security.BlessingPattern, except []string, err error) {
+	fields := strings.Fields(line)
+	if fields[0] != "allow" {
+		return nil, nil, nil, verror.New(errPolicySyntax, nil, lineno, fmt.Sprintf("expected allow, found %q", fields[0]))
+	}
+	to, exc := -1, len(fields)
+	for i, f := range fields {
+		switch {
+		case f == "to" && to < 0:
+			to = i
+		case f == "except" && to >= 0 && exc == len(fields):
+			exc = i
+		}
+	}
+	if to < 0 {
+		return nil, nil, nil, verror.New(errPolicySyntax, nil, lineno, "missing to")
+	}
+	list := func(what string, fields []string) ([]string, error) {
+		items := strings.Split(strings.Join(fields, ""), ",")
+		for _, item := range items {
+			if item == "" {
+				return nil, verror.New(errPolicySyntax, nil, lineno, "missing "+what)
+			}
+		}
+		return items, nil
+	}
+	if tags, err = list("tag", fields[1:to]); err != nil {
+		return nil, nil, nil, err
+	}
+	for _, tag := range tags {
+		if strings.IndexFunc(tag, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' }) >= 0 {
+			return nil, nil, nil, verror.New(errPolicySyntax, nil, lineno, fmt.Sprintf("invalid tag %q", tag))
+		}
+	}
+	items, err := list("pattern", fields[to+1:exc])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, item := range items {
+		p := security.BlessingPattern(strings.TrimSuffix(item, security.ChainSeparator+"*"))
+		if !p.IsValid() {
+			return nil, nil, nil, verror.New(errPolicyInvalidPattern, nil, lineno, item)
+		}
+		patterns = append(patterns, p)
+	}
+	if exc == len(fields) {
+		return tags, patterns, nil, nil
+	}
+	if items, err = list("blessing", fields[exc+1:]); err != nil {
+		return nil, nil, nil, err
+	}
+	for _, item := range items {
+		b := strings.TrimSuffix(item, security.ChainSeparator+"*")
+		if p := security.BlessingPattern(b); !p.IsValid() || p == security.AllPrincipals || p == security.NoExtension || strings.HasSuffix(b, security.ChainSeparator+string(security.NoExtension)) {
+			return nil, nil, nil, verror.New(errPolicyInvalidExcept, nil, lineno, item)
+		}
+		except = append(except, b)
+	}
+	return tags, patterns, except, nil
+}
+
+// DecompilePolicy returns the text of a policy that CompilePolicy compiles
+// into Permissions equivalent to perms, i.e., that grant access to the same
+// blessings for each tag.
+//
+// Tags with identical AccessLists are combined into a single rule. Patterns
+// whose blessings are all excluded by the NotIn list, and the patterns that
+// accompany everyone ("..."), are omitted, as are AccessLists that do not
+// grant access to anyone. An error is returned if perms cannot be expressed
+// as a policy, e.g., if a tag or a blessing name cannot be written in the
+// policy language or if an AccessList grants access to everyone except some
+// blessings.
+func DecompilePolicy(perms Permissions) (string, error) {
+	var (
+		byACL      = make(map[string][]string)
+		acls       = make(map[string]AccessList)
+		simplified = make(Permissions)
+	)
+	for tag, acl := range perms.Copy().Normalize() {
+		if acl.In = policyPatterns(acl); len(acl.In) == 0 {
+			continue
+		}
+		simplified[tag] = acl
+		var key bytes.Buffer
+		for _, p := range acl.In {
+			fmt.Fprintf(&key, "%s,", p)
+		}
+		key.WriteString(" except ")
+		for _, b := range acl.NotIn {
+			fmt.Fprintf(&key, "%s,", b)
+		}
+		byACL[key.String()] = append(byACL[key.String()], tag)
+		acls[key.String()] = acl
+	}
+	var rules []string
+	for key, tags := range byACL {
+		sort.Strings(tags)
+		acl := acls[key]
+		patterns := make([]string, len(acl.In))
+		for i, p := range acl.In {
+			patterns[i] = string(p)
+		}
+		rule := fmt.Sprintf("allow %s to %s", strings.Join(tags, ","), strings.Join(patterns, ", "))
+		if len(acl.NotIn) > 0 {
+			rule += " except " + strings.Join(acl.NotIn, ", ")
+		}
+		rules = append(rules, rule+"\n")
+	}
+	sort.Strings(rules)
+	policy := strings.Join(rules, "")
+	// Each tag has a single rule, so no exclusion affects the patterns of
+	// another rule. Any remaining conflict or syntax error, or a difference
+	// in the compiled policy (e.g., due to a blessing name containing a
+	// comma), is due to perms not being expressible.
+	compiled, err := CompilePolicy(policy)
+	if err != nil {
+		return "", verror.New(errPolicyInexpressible, nil, err)
+	}
+	if !reflect.DeepEqual(compiled, simplified) {
+		return "", verror.New(errPolicyInexpressible, nil)
+	}
+	return policy, nil
+}
+
+// policyPatterns returns the patterns of the In list of acl that grant
+// access to some blessing, i.e., those whose blessings are not all excluded
+// by the NotIn list, or only everyone ("...") if it is one of them.
+func policyPatterns(acl AccessList) []security.BlessingPattern {
+	var patterns []security.BlessingPattern
+	for _, p := range acl.In {
+		if p == security.AllPrincipals {
+			return []security.BlessingPattern{p}
+		}
+		name := strings.TrimSuffix(string(p), security.ChainSeparator+string(security.NoExtension))
+		excluded := false
+		for _, b := range acl.NotIn {
+			if name == b || strings.HasPrefix(name, b+security.ChainSeparator) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
@@ -0,0 +1,133 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package access
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"v.io/v23/security"
+)
+
+func TestCompilePolicy(t *testing.T) {
+	policy := `
+# Alice's devices.
+allow Read,Write to dev:alice:* except dev:alice:phone
+allow Admin to dev:alice:$   # but not her delegates
+
+allow Read to dev:bob, dev:carol except dev:carol:tablet
+allow Debug to ...
+`
+	got, err := CompilePolicy(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Permissions{
+		"Read": {
+			In:    []security.BlessingPattern{"dev:alice", "dev:bob", "dev:carol"},
+			NotIn: []string{"dev:alice:phone", "dev:carol:tablet"},
+		},
+		"Write": {
+			In:    []security.BlessingPattern{"dev:alice"},
+			NotIn: []string{"dev:alice:phone"},
+		},
+		"Admin": {In: []security.BlessingPattern{"dev:alice:$"}},
+		"Debug": {In: []security.BlessingPattern{security.AllPrincipals}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+	if !got["Write"].Includes("dev:alice:laptop") || got["Write"].Includes("dev:alice:phone:app") || got["Admin"].Includes("dev:alice:laptop") {
+		t.Errorf("Unexpected access granted by %v", got)
+	}
+
+	// Decompiling and recompiling yields the same permissions.
+	text, err := DecompilePolicy(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := CompilePolicy(text); err != nil || !reflect.DeepEqual(again, want) {
+		t.Errorf("CompilePolicy(%q): got (%v, %v), want %v", text, again, err, want)
+	}
+	wantText := strings.Join([]string{
+		"allow Admin to dev:alice:$",
+		"allow Debug to ...",
+		"allow Read to dev:alice, dev:bob, dev:carol except dev:alice:phone, dev:carol:tablet",
+		"allow Write to dev:alice except dev:alice:phone",
+		"",
+	}, "\n")
+	if text != wantText {
+		t.Errorf("Got:\n%s\nWant:\n%s", text, wantText)
+	}
+	// Tags with the same AccessList are combined, and empty AccessLists omitted.
+	text, err = DecompilePolicy(Permissions{
+		"Read":    {In: []security.BlessingPattern{"dev:alice"}},
+		"Write":   {In: []security.BlessingPattern{"dev:alice"}},
+		"Resolve": {NotIn: []string{"dev:bob"}},
+	})
+	if want := "allow Read,Write to dev:alice\n"; err != nil || text != want {
+		t.Errorf("Got (%q, %v), want %q", text, err, want)
+	}
+	// Patterns that grant access to no one, or are redundant with everyone,
+	// are omitted.
+	text, err = DecompilePolicy(Permissions{
+		"Read":  {In: []security.BlessingPattern{"dev:alice", "dev:bob:phone"}, NotIn: []string{"dev:bob"}},
+		"Write": {In: []security.BlessingPattern{security.AllPrincipals, "dev:alice"}},
+		"Admin": {In: []security.BlessingPattern{"dev:bob:$"}, NotIn: []string{"dev:bob"}},
+	})
+	if want := "allow Read to dev:alice except dev:bob\nallow Write to ...\n"; err != nil || text != want {
+		t.Errorf("Got (%q, %v), want %q", text, err, want)
+	}
+	// Permissions that cannot be expressed as a policy.
+	for _, perms := range []Permissions{
+		{"Read": {In: []security.BlessingPattern{security.AllPrincipals}, NotIn: []string{"dev:bob"}}},
+		{"Re-ad": {In: []security.BlessingPattern{"dev:alice"}}},
+		{"Read": {In: []security.BlessingPattern{"dev:alice"}, NotIn: []string{"dev:alice:phone,tablet"}}},
+	} {
+		if text, err := DecompilePolicy(perms); err == nil {
+			t.Errorf("DecompilePolicy(%v): got %q, want error", perms, text)
+		}
+	}
+}
+
+func TestCompilePolicyErrors(t *testing.T) {
+	tests := []struct {
+		policy, errstr string
+	}{
+		{"deny Read to dev", "policy line 1: expected allow"},
+		{"\nallow Read dev", "policy line 2: missing to"},
+		{"allow to dev", "missing tag"},
+		{"allow Read, to dev", "missing tag"},
+		{"allow Re-ad to dev", `invalid tag "Re-ad"`},
+		{"allow Read to", "missing pattern"},
+		{"allow Read to dev except", "missing blessing"},
+		{"allow Read to dev::alice", "invalid blessing pattern dev::alice"},
+		{"allow Read to dev:$:alice", "invalid blessing pattern"},
+		{"allow Read to dev except dev:alice:$", "invalid blessing name dev:alice:$ in except clause"},
+		{"allow Read to dev except ...", "invalid blessing name ... in except clause"},
+		{"allow Read to dev:alice except dev", "policy line 1: dev excludes dev:alice, which is allowed Read access on line 1"},
+		{"allow Read to dev:alice:$\n\nallow Read,Write to dev:bob except dev:alice", "policy line 3: dev:alice excludes dev:alice:$, which is allowed Read access on line 1"},
+		{"allow Read to org:contractors\nallow Read to org:staff except org:contractors:bob", "policy line 2: org:contractors:bob excludes some of the blessings matched by org:contractors, which is allowed Read access on line 1"},
+		{"allow Read to dev:alice\nallow Read to dev:alice except dev:alice:phone", "policy line 2: dev:alice:phone excludes some of the blessings matched by dev:alice, which is allowed Read access on line 1"},
+		{"allow Read to ...\nallow Read to dev", "granting Read access to everyone"},
+		{"allow Read to ... except dev", "granting Read access to everyone"},
+	}
+	for _, test := range tests {
+		perms, err := CompilePolicy(test.policy)
+		if err == nil || !strings.Contains(err.Error(), test.errstr) {
+			t.Errorf("CompilePolicy(%q): got (%v, %v), want error containing %q", test.policy, perms, err, test.errstr)
+		}
+	}
+	// Exclusions for one tag do not conflict with patterns for another.
+	if _, err := CompilePolicy("allow Read to dev\nallow Write to dev:alice except dev:alice:phone\nallow Debug to ..."); err != nil {
+		t.Error(err)
+	}
+	// Nor do exclusions of delegates of blessings that another rule allows
+	// without their delegates.
+	if _, err := CompilePolicy("allow Read to dev:alice:$\nallow Read to dev:bob except dev:alice:phone"); err != nil {
+		t.Error(err)
+	}
+}
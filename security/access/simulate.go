@@ -0,0 +1,163 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package access
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"v.io/v23/security"
+	"v.io/v23/vdl"
+	"v.io/v23/vdlroot/signature"
+)
+
+// SimulatedMethod describes a method whose access is simulated by Simulate.
+type SimulatedMethod struct {
+	// Interface and Name identify the method.
+	Interface, Name string
+	// Tag is the single tag of the tag type attached to the method, empty if
+	// the method does not have exactly one such tag, in which case
+	// PermissionsAuthorizer denies all access to it.
+	Tag string
+	// TagErr describes why Tag is empty.
+	TagErr string
+}
+
+func (m SimulatedMethod) String() string {
+	if m.Interface == "" {
+		return m.Name
+	}
+	return m.Interface + "." + m.Name
+}
+
+// AccessDecision is the decision, and the reason for it, of whether a
+// principal presenting a single blessing name can call a method.
+type AccessDecision struct {
+	Allowed bool
+	Reason  string
+}
+
+func (d AccessDecision) String() string {
+	if d.Allowed {
+		return "allowed: " + d.Reason
+	}
+	return "denied: " + d.Reason
+}
+
+// AccessMatrix describes who can call what, and why.
+type AccessMatrix struct {
+	Blessings []string
+	Methods   []SimulatedMethod
+	// Decisions[i][j] is the decision for a principal presenting
+	// Blessings[i] calling Methods[j].
+	Decisions [][]AccessDecision
+}
+
+// Simulate computes the access matrix of a PermissionsAuthorizer for perms
+// and tagType, for principals presenting each one of blessings, calling each
+// method described by sig.
+//
+// Decisions are made using the same logic as PermissionsAuthorizer (i.e.,
+// AccessList.Includes, which prunes blessings matched by the NotIn list
+// before matching them against the In list), treating each blessing name as
+// if it were the only (valid) blessing name presented by a principal. The opts
+// are interpreted as by PermissionsAuthorizer.
+func Simulate(perms Permissions, tagType *vdl.Type, sig []signature.Interface, blessings []string, opts ...AuthorizerOpt) (AccessMatrix, error) {
+	if tagType.Kind() != vdl.String {
+		return AccessMatrix{}, errTagType(tagType)
+	}
+	hierarchy, err := NewTagHierarchy(tagType, opts...)
+	if err != nil {
+		return AccessMatrix{}, err
+	}
+	m := AccessMatrix{Blessings: blessings}
+	for _, iface := range sig {
+		for _, method := range iface.Methods {
+			sm := SimulatedMethod{Interface: iface.Name, Name: method.Name}
+			var tags []string
+			for _, tag := range method.Tags {
+				if tag.Type() == tagType {
+					tags = append(tags, tag.RawString())
+				}
+			}
+			switch len(tags) {
+			case 0:
+				sm.TagErr = fmt.Sprintf("method has no tags of type %v", tagType)
+			case 1:
+				sm.Tag = tags[0]
+			default:
+				sm.TagErr = fmt.Sprintf("method has multiple tags of type %v (%v)", tagType, tags)
+			}
+			m.Methods = append(m.Methods, sm)
+		}
+	}
+	m.Decisions = make([][]AccessDecision, len(blessings))
+	for i, b := range blessings {
+		m.Decisions[i] = make([]AccessDecision, len(m.Methods))
+		for j, method := range m.Methods {
+			m.Decisions[i][j] = decideAccess(perms, hierarchy, method, b)
+		}
+	}
+	return m, nil
+}
+
+// String returns a table with a row per blessing name and a column per
+// method, marking the methods that can be called with "yes".
+func (m AccessMatrix) String() string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprint(w, "BLESSING")
+	for _, method := range m.Methods {
+		fmt.Fprintf(w, "\t%v", method)
+	}
+	fmt.Fprintln(w)
+	for i, b := range m.Blessings {
+		fmt.Fprint(w, b)
+		for _, d := range m.Decisions[i] {
+			if d.Allowed {
+				fmt.Fprint(w, "\tyes")
+			} else {
+				fmt.Fprint(w, "\tno")
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	return buf.String()
+}
+
+func decideAccess(perms Permissions, hierarchy TagHierarchy, method SimulatedMethod, blessing string) AccessDecision {
+	if method.Tag == "" {
+		return AccessDecision{false, method.TagErr}
+	}
+	var reasons []string
+	for _, tag := range hierarchy.ImplyingTags(method.Tag) {
+		acl, exists := perms[tag]
+		if !exists {
+			reasons = append(reasons, fmt.Sprintf("no AccessList for tag %v", tag))
+			continue
+		}
+		if acl.Includes(blessing) {
+			for _, p := range acl.In {
+				if p.MatchedBy(blessing) {
+					return AccessDecision{true, fmt.Sprintf("%v matches pattern %v for tag %v", blessing, p, tag)}
+				}
+			}
+		}
+		reason := fmt.Sprintf("%v matches no pattern for tag %v", blessing, tag)
+		if len(acl.pruneBlacklisted([]string{blessing})) == 0 {
+			for _, b := range acl.NotIn {
+				if security.BlessingPattern(b).MatchedBy(blessing) {
+					reason = fmt.Sprintf("%v is excluded by NotIn entry %v for tag %v", blessing, b, tag)
+					break
+				}
+			}
+		}
+		reasons = append(reasons, reason)
+	}
+	return AccessDecision{false, strings.Join(reasons, "; ")}
+}
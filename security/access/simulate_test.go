@@ -0,0 +1,105 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package access_test
+
+import (
+	"strings"
+	"testing"
+
+	"v.io/v23/security"
+	"v.io/v23/security/access"
+	"v.io/v23/vdl"
+	"v.io/v23/vdlroot/signature"
+)
+
+func TestSimulate(t *testing.T) {
+	type bp []security.BlessingPattern // shorthand
+	var (
+		perms = access.Permissions{
+			"Read":  {In: bp{"dev:alice", "dev:bob"}, NotIn: []string{"dev:alice:phone"}},
+			"Write": {In: bp{"dev:alice:$"}},
+		}
+		tag = func(t access.Tag) *vdl.Value { return vdl.ValueOf(t) }
+		sig = []signature.Interface{{
+			Name: "Store",
+			Methods: []signature.Method{
+				{Name: "Admin", Tags: []*vdl.Value{tag(access.Admin)}},
+				{Name: "Both", Tags: []*vdl.Value{tag(access.Read), tag(access.Write)}},
+				{Name: "Get", Tags: []*vdl.Value{tag(access.Read)}},
+				{Name: "Put", Tags: []*vdl.Value{tag(access.Write), vdl.StringValue(nil, "other")}},
+				{Name: "Untagged"},
+			},
+		}}
+		blessings = []string{"dev:alice", "dev:alice:phone", "dev:bob:tv", "dev:carol"}
+	)
+	m, err := access.Simulate(perms, access.TypicalTagType(), sig, blessings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{
+			"denied: no AccessList for tag Admin",
+			"denied: method has multiple tags",
+			"allowed: dev:alice matches pattern dev:alice for tag Read",
+			"allowed: dev:alice matches pattern dev:alice:$ for tag Write",
+			"denied: method has no tags",
+		},
+		{
+			"denied: no AccessList for tag Admin",
+			"denied: method has multiple tags",
+			"denied: dev:alice:phone is excluded by NotIn entry dev:alice:phone for tag Read",
+			"denied: dev:alice:phone matches no pattern for tag Write",
+			"denied: method has no tags",
+		},
+		{
+			"denied: no AccessList for tag Admin",
+			"denied: method has multiple tags",
+			"allowed: dev:bob:tv matches pattern dev:bob for tag Read",
+			"denied: dev:bob:tv matches no pattern for tag Write",
+			"denied: method has no tags",
+		},
+		{
+			"denied: no AccessList for tag Admin",
+			"denied: method has multiple tags",
+			"denied: dev:carol matches no pattern for tag Read",
+			"denied: dev:carol matches no pattern for tag Write",
+			"denied: method has no tags",
+		},
+	}
+	pserver, pclient := newPrincipal(t), newPrincipal(t)
+	for i, row := range m.Decisions {
+		client, err := pclient.BlessSelf(blessings[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, d := range row {
+			if got := d.String(); !strings.HasPrefix(got, want[i][j]) {
+				t.Errorf("%v calling %v: got %q, want %q", blessings[i], m.Methods[j], got, want[i][j])
+			}
+			// Decisions match those of the authorizer.
+			params := &security.CallParams{
+				LocalPrincipal:  pserver,
+				RemoteBlessings: client,
+				Method:          m.Methods[j].Name,
+				MethodTags:      sig[0].Methods[j].Tags,
+			}
+			if err := authorize(access.TypicalTagTypePermissionsAuthorizer(perms), params); (err == nil) != d.Allowed {
+				t.Errorf("%v calling %v: authorizer returned %v, simulator %v", blessings[i], m.Methods[j], err, d)
+			}
+		}
+	}
+	wantTable := `BLESSING         Store.Admin  Store.Both  Store.Get  Store.Put  Store.Untagged
+dev:alice        no           no          yes        yes        no
+dev:alice:phone  no           no          no         no         no
+dev:bob:tv       no           no          yes        no         no
+dev:carol        no           no          no         no         no
+`
+	if got := m.String(); got != wantTable {
+		t.Errorf("Got:\n%s\nWant:\n%s", got, wantTable)
+	}
+	if _, err := access.Simulate(perms, vdl.Int32Type, sig, blessings); err == nil {
+		t.Errorf("Expected error since tag type is not a string")
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package access
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/security"
+	"v.io/v23/vdl"
+	"v.io/v23/verror"
+)
+
+var (
+	errInvalidPermissionsPattern = verror.Register(pkgPath+".errInvalidPermissionsPattern", verror.NoRetry, "{1:}{2:}AccessList for tag {3} contains the invalid pattern {4}{:_}")
+	errBadWatchInterval          = verror.Register(pkgPath+".errBadWatchInterval", verror.NoRetry, "{1:}{2:}watch interval must be positive, not {3}{:_}")
+)
+
+// reloadEventBuffer is the capacity of the channel returned by
+// WatchingAuthorizer.Events.
+const reloadEventBuffer = 16
+
+// ReloadEvent describes an attempt by a WatchingAuthorizer to reload the
+// Permissions from its file.
+type ReloadEvent struct {
+	// Time is the time of the attempt.
+	Time time.Time
+	// Permissions are the Permissions in use after the attempt: the newly
+	// loaded ones if Err is nil, the last good ones otherwise.
+	Permissions Permissions
+	// Err is the error that caused the new contents of the file to be
+	// rejected, or nil if they were loaded.
+	Err error
+}
+
+// WatchingAuthorizer applies the same authorization policy as
+// PermissionsAuthorizer, with the Permissions sourced from a file that is
+// watched for changes.
+//
+// Unlike the Authorizer returned by PermissionsAuthorizerFromFile, which
+// re-reads the file on every call to Authorize, a WatchingAuthorizer caches
+// the parsed Permissions and reloads them only when the modification time or
+// size of the file changes. New contents are validated before use: if they
+// cannot be parsed or contain invalid blessing patterns, they are rejected
+// and the last good Permissions remain in use.
+type WatchingAuthorizer struct {
+	filename  string
+	tagType   *vdl.Type
+	hierarchy TagHierarchy
+	events    chan ReloadEvent
+
+	// reloadMu serializes calls to reload, and mu guards the fields below it.
+	reloadMu sync.Mutex
+	mu       sync.RWMutex
+	perms    Permissions
+	modTime  time.Time
+	size     int64
+	lastErr  error
+}
+
+// NewWatchingAuthorizer returns a WatchingAuthorizer for the Permissions in
+// the file named filename, checking for changes every interval until ctx is
+// canceled.
+//
+// The opts are interpreted as by PermissionsAuthorizer. An error is returned
+// if the file cannot be loaded initially.
+func NewWatchingAuthorizer(ctx *context.T, filename string, tagType *vdl.Type, interval time.Duration, opts ...AuthorizerOpt) (*WatchingAuthorizer, error) {
+	if tagType.Kind() != vdl.String {
+		return nil, errTagType(tagType)
+	}
+	if interval <= 0 {
+		return nil, verror.New(errBadWatchInterval, ctx, interval)
+	}
+	hierarchy, err := NewTagHierarchy(tagType, opts...)
+	if err != nil {
+		return nil, err
+	}
+	a := &WatchingAuthorizer{
+		filename:  filename,
+		tagType:   tagType,
+		hierarchy: hierarchy,
+		events:    make(chan ReloadEvent, reloadEventBuffer),
+	}
+	if err := a.reload(true, false); err != nil {
+		return nil, err
+	}
+	go a.watch(ctx, interval)
+	return a, nil
+}
+
+// Authorize implements security.Authorizer using the most recently loaded
+// Permissions.
+func (a *WatchingAuthorizer) Authorize(ctx *context.T, call security.Call) error {
+	a.mu.RLock()
+	perms := a.perms
+	a.mu.RUnlock()
+	return (&authorizer{perms, a.tagType, a.hierarchy}).Authorize(ctx, call)
+}
+
+// Permissions returns a copy of the Permissions currently in use.
+func (a *WatchingAuthorizer) Permissions() Permissions {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.perms.Copy()
+}
+
+// LastError returns the error from the most recent attempt to reload the
+// Permissions, or nil if it succeeded.
+func (a *WatchingAuthorizer) LastError() error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.lastErr
+}
+
+// Events returns a channel on which an event is sent for every attempt to
+// reload the Permissions after the file changed. Events are dropped if the
+// channel's buffer is full.
+func (a *WatchingAuthorizer) Events() <-chan ReloadEvent {
+	return a.events
+}
+
+// Reload reloads the Permissions from the file, even if it has not changed,
+// and returns the error that caused the new contents to be rejected, if any.
+func (a *WatchingAuthorizer) Reload() error {
+	return a.reload(true, true)
+}
+
+func (a *WatchingAuthorizer) watch(ctx *context.T, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.reload(false, true)
+		}
+	}
+}
+
+// reload loads the Permissions from the file if force is true or the file
+// changed since the last attempt, sending a ReloadEvent if notify is true.
+//
+// The file is read and validated without holding a.mu, which is locked only
+// to install the outcome, so that Authorize is not blocked by the I/O.
+func (a *WatchingAuthorizer) reload(force, notify bool) error {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+	// modTime, size and lastErr are only modified with reloadMu held, so they
+	// can be read here without a.mu.
+	modTime, size := a.modTime, a.size
+	var perms Permissions
+	info, err := os.Stat(a.filename)
+	if err == nil {
+		if !force && info.ModTime().Equal(modTime) && info.Size() == size {
+			return a.lastErr
+		}
+		modTime, size = info.ModTime(), info.Size()
+		if perms, err = loadPermissionsFromFile(a.filename); err == nil {
+			err = validatePermissions(perms)
+		}
+	} else if !force && modTime.IsZero() && a.lastErr != nil {
+		// The missing file has already been reported.
+		return a.lastErr
+	} else {
+		modTime, size = time.Time{}, 0
+	}
+	if err != nil {
+		err = verror.New(errCantReadPermissionsFromFile, nil, err)
+	}
+	a.mu.Lock()
+	if err == nil {
+		a.perms = perms
+	}
+	a.modTime, a.size, a.lastErr = modTime, size, err
+	current := a.perms
+	a.mu.Unlock()
+	if notify {
+		select {
+		case a.events <- ReloadEvent{time.Now(), current.Copy(), err}:
+		default:
+		}
+	}
+	return err
+}
+
+// validatePermissions returns an error if any of the patterns in perms is
+// invalid.
+func validatePermissions(perms Permissions) error {
+	for tag, acl := range perms {
+		for _, p := range acl.In {
+			if !p.IsValid() {
+				return verror.New(errInvalidPermissionsPattern, nil, tag, p)
+			}
+		}
+		for _, b := range acl.NotIn {
+			if p := security.BlessingPattern(b); !p.IsValid() {
+				return verror.New(errInvalidPermissionsPattern, nil, tag, p)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package access_test
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/security"
+	"v.io/v23/security/access"
+	"v.io/v23/vdl"
+)
+
+func TestWatchingAuthorizer(t *testing.T) {
+	ctx, cancel := context.RootContext()
+	defer cancel()
+	dir, err := ioutil.TempDir("", "TestWatchingAuthorizer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := dir + "/perms.json"
+	write := func(contents string) {
+		// Write to a temporary file and rename it, as an administrator
+		// would, so that the watcher never sees partial contents.
+		if err := ioutil.WriteFile(filename+".tmp", []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(filename+".tmp", filename); err != nil {
+			t.Fatal(err)
+		}
+	}
+	nextEvent := func(a *access.WatchingAuthorizer) access.ReloadEvent {
+		select {
+		case ev := <-a.Events():
+			return ev
+		case <-time.After(10 * time.Second):
+			t.Fatal("Timed out waiting for a reload event")
+		}
+		return access.ReloadEvent{}
+	}
+
+	if _, err := access.NewWatchingAuthorizer(ctx, filename, access.TypicalTagType(), time.Millisecond); err == nil {
+		t.Errorf("Expected error for a missing file")
+	}
+	write(`{"Read": {"In": ["alice"]}}`)
+	if _, err := access.NewWatchingAuthorizer(ctx, filename, vdl.Int32Type, time.Millisecond); err == nil {
+		t.Errorf("Expected error since tag type is not a string")
+	}
+	if _, err := access.NewWatchingAuthorizer(ctx, filename, access.TypicalTagType(), 0); err == nil {
+		t.Errorf("Expected error for a zero interval")
+	}
+	a, err := access.NewWatchingAuthorizer(ctx, filename, access.TypicalTagType(), time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		pserver = newPrincipal(t)
+		pclient = newPrincipal(t)
+		bob, _  = pclient.BlessSelf("bob")
+		params  = &security.CallParams{
+			LocalPrincipal:  pserver,
+			RemoteBlessings: bob,
+			Method:          "Get",
+			MethodTags:      []*vdl.Value{vdl.ValueOf(access.Read)},
+		}
+		withBob = access.Permissions{"Read": {In: []security.BlessingPattern{"alice", "bob"}}}
+	)
+	if err := authorize(a, params); err == nil {
+		t.Errorf("Expected bob to be denied access")
+	}
+	// Changes to the file are picked up.
+	write(`{"Read": {"In": ["alice", "bob"]}}`)
+	if ev := nextEvent(a); ev.Err != nil || !reflect.DeepEqual(ev.Permissions, withBob) {
+		t.Errorf("Got event %+v, want %v", ev, withBob)
+	}
+	if err := authorize(a, params); err != nil {
+		t.Error(err)
+	}
+	// Invalid contents are rejected, keeping the last good Permissions.
+	for _, contents := range []string{`{"Read": {"In": ["alice", "bob::x"]}}`, `{"Read": `} {
+		write(contents)
+		if ev := nextEvent(a); ev.Err == nil || !reflect.DeepEqual(ev.Permissions, withBob) {
+			t.Errorf("%s: got event %+v, want an error and %v", contents, ev, withBob)
+		}
+		if a.LastError() == nil {
+			t.Errorf("%s: expected LastError to be set", contents)
+		}
+		if err := authorize(a, params); err != nil {
+			t.Error(err)
+		}
+	}
+	// As is a missing file.
+	if err := os.Remove(filename); err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextEvent(a); ev.Err == nil {
+		t.Errorf("Got event %+v, want an error", ev)
+	}
+	if got := a.Permissions(); !reflect.DeepEqual(got, withBob) {
+		t.Errorf("Got %v, want %v", got, withBob)
+	}
+	write(`{"Read": {"In": ["alice"]}}`)
+	if ev := nextEvent(a); ev.Err != nil || a.LastError() != nil {
+		t.Errorf("Got event %+v and last error %v, want no errors", ev, a.LastError())
+	}
+	if err := authorize(a, params); err == nil {
+		t.Errorf("Expected bob to be denied access")
+	}
+	// Reloads can also be forced.
+	if err := a.Reload(); err != nil {
+		t.Error(err)
+	}
+	if ev := nextEvent(a); ev.Err != nil {
+		t.Error(ev.Err)
+	}
+}
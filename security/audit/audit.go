@@ -0,0 +1,112 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package audit provides a security.Authorizer that records every
+// authorization decision made by another Authorizer.
+//
+// Sample usage:
+//   sink, err := audit.NewFileSink("/var/log/myservice/authz", 64<<20, 10)
+//   if err != nil {
+//     return err
+//   }
+//   authorizer := audit.Authorizer(access.TypicalTagTypePermissionsAuthorizer(perms), sink)
+//
+// The records written by a FileSink can be read back with ReadRecords.
+package audit
+
+import (
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/security"
+	"v.io/v23/verror"
+)
+
+const pkgPath = "v.io/v23/security/audit"
+
+var (
+	errCantRecordDecision = verror.Register(pkgPath+".errCantRecordDecision", verror.NoRetry, "{1:}{2:}failed to record authorization decision for {3}.{4}{:_}")
+)
+
+// Record describes a single authorization decision.
+type Record struct {
+	// Timestamp is the time at which the authorization was checked
+	// (security.Call.Timestamp).
+	Timestamp time.Time
+	// Method and Suffix identify the invocation being authorized.
+	Method string
+	Suffix string
+	// MethodTags are the string representations of the tags of the method.
+	MethodTags []string
+	// LocalBlessings are the blessing names presented by the local end.
+	LocalBlessings []string
+	// RemoteBlessings are the validated blessing names presented by the
+	// remote end (see security.RemoteBlessingNames).
+	RemoteBlessings []string
+	// Rejected are the blessings presented by the remote end that failed
+	// validation, including those in CaveatFailures.
+	Rejected []security.RejectedBlessing
+	// CaveatFailures are the blessings in Rejected that failed validation
+	// because one of their caveats was not satisfied.
+	CaveatFailures []security.RejectedBlessing
+	// Authorized is true iff the call was authorized.
+	Authorized bool
+	// Err is the error returned by the Authorizer, nil if Authorized.
+	Err error
+}
+
+// Sink is the interface for storing Records.
+//
+// It is safe to invoke methods on a Sink concurrently.
+type Sink interface {
+	// Put stores r.
+	Put(r Record) error
+}
+
+// Authorizer returns a security.Authorizer that delegates authorization
+// decisions to authorizer and records each decision in sink.
+//
+// If a decision cannot be recorded, the call is not authorized, so that no
+// call is ever authorized without leaving a record.
+func Authorizer(authorizer security.Authorizer, sink Sink) security.Authorizer {
+	return &auditor{authorizer, sink}
+}
+
+type auditor struct {
+	authorizer security.Authorizer
+	sink       Sink
+}
+
+func (a *auditor) Authorize(ctx *context.T, call security.Call) error {
+	err := a.authorizer.Authorize(ctx, call)
+	if perr := a.sink.Put(newRecord(ctx, call, err)); perr != nil {
+		return verror.New(errCantRecordDecision, ctx, call.Suffix(), call.Method(), perr)
+	}
+	return err
+}
+
+func newRecord(ctx *context.T, call security.Call, err error) Record {
+	remote, rejected := security.RemoteBlessingNames(ctx, call)
+	r := Record{
+		Timestamp:       call.Timestamp(),
+		Method:          call.Method(),
+		Suffix:          call.Suffix(),
+		RemoteBlessings: remote,
+		Rejected:        rejected,
+		Authorized:      err == nil,
+		Err:             err,
+	}
+	if call.LocalPrincipal() != nil {
+		r.LocalBlessings = security.LocalBlessingNames(ctx, call)
+	}
+	for _, tag := range call.MethodTags() {
+		r.MethodTags = append(r.MethodTags, tag.String())
+	}
+	for _, rej := range rejected {
+		if verror.ErrorID(rej.Err) == security.ErrCaveatValidation.ID {
+			r.CaveatFailures = append(r.CaveatFailures, rej)
+		}
+	}
+	return r
+}
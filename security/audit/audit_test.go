@@ -0,0 +1,242 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package audit_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/security"
+	"v.io/v23/security/audit"
+	"v.io/v23/vdl"
+)
+
+type testTag string
+
+type failingSink struct{}
+
+func (failingSink) Put(audit.Record) error { return errors.New("disk full") }
+
+func TestAuthorizer(t *testing.T) {
+	var (
+		pserver = newPrincipal(t)
+		pclient = newPrincipal(t)
+
+		expired, _ = security.NewExpiryCaveat(time.Now().Add(-time.Minute))
+		server, _  = pserver.BlessSelf("server")
+		alice, _   = pserver.Bless(pclient.PublicKey(), server, "alice", security.UnconstrainedUse())
+		bob, _     = pserver.Bless(pclient.PublicKey(), server, "bob", expired)
+		both, _    = security.UnionOfBlessings(alice, bob)
+
+		sink        = &audit.MemorySink{}
+		ctx, cancel = context.RootContext()
+		now         = time.Now()
+		call        = func(method string, remote security.Blessings) security.Call {
+			return security.NewCall(&security.CallParams{
+				Timestamp:       now,
+				Method:          method,
+				MethodTags:      []*vdl.Value{vdl.ValueOf(testTag("R"))},
+				Suffix:          "a/b",
+				LocalPrincipal:  pserver,
+				LocalBlessings:  server,
+				RemoteBlessings: remote,
+			})
+		}
+	)
+	defer cancel()
+	allowed := audit.Authorizer(security.AllowEveryone(), sink)
+	if err := allowed.Authorize(ctx, call("Get", both)); err != nil {
+		t.Fatal(err)
+	}
+	denied := audit.Authorizer(security.DefaultAuthorizer(), sink)
+	if err := denied.Authorize(ctx, call("Put", bob)); err == nil {
+		t.Fatal("Expected error")
+	}
+	records := sink.Records()
+	if got, want := len(records), 2; got != want {
+		t.Fatalf("Got %d records, want %d", got, want)
+	}
+	r := records[0]
+	if got, want := r, (audit.Record{
+		Timestamp:       now,
+		Method:          "Get",
+		Suffix:          "a/b",
+		MethodTags:      []string{`v.io/v23/security/audit_test.testTag string("R")`},
+		LocalBlessings:  []string{"server"},
+		RemoteBlessings: []string{"server:alice"},
+		Rejected:        r.Rejected,
+		CaveatFailures:  r.CaveatFailures,
+		Authorized:      true,
+	}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %#v, want %#v", got, want)
+	}
+	if len(r.Rejected) != 1 || r.Rejected[0].Blessing != "server:bob" || !reflect.DeepEqual(r.Rejected, r.CaveatFailures) {
+		t.Errorf("Got rejected %v and caveat failures %v, want the expired server:bob blessing in both", r.Rejected, r.CaveatFailures)
+	}
+	r = records[1]
+	if r.Authorized || r.Err == nil || r.Method != "Put" || len(r.RemoteBlessings) != 0 || len(r.CaveatFailures) != 1 {
+		t.Errorf("Unexpected record for denied call: %#v", r)
+	}
+	// Calls must not be authorized if they cannot be audited.
+	if err := audit.Authorizer(security.AllowEveryone(), failingSink{}).Authorize(ctx, call("Get", alice)); err == nil {
+		t.Errorf("Expected error when the decision could not be recorded")
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log")
+	if _, err := audit.NewFileSink(path, 0, 1); err == nil {
+		t.Errorf("Expected error for invalid rotation parameters")
+	}
+	sink, err := audit.NewFileSink(path, 512, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 100
+	for i := 0; i < n; i++ {
+		if err := sink.Put(audit.Record{Method: fmt.Sprintf("Method%d", i), Authorized: i%2 == 0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Put(audit.Record{}); err == nil {
+		t.Errorf("Put should fail on a closed sink")
+	}
+	// Only the current file and two rotated files should be retained, with the
+	// most recent records in path.
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Got %v, expected %v.3 to not exist", err, path)
+	}
+	var methods []string
+	for _, name := range []string{path + ".2", path + ".1", path} {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := audit.ReadRecords(f, func(r audit.Record) error {
+			methods = append(methods, r.Method)
+			return nil
+		}); err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		f.Close()
+	}
+	if len(methods) == 0 || len(methods) >= n {
+		t.Fatalf("Got %d records, want between 1 and %d", len(methods), n)
+	}
+	for i, m := range methods {
+		if want := fmt.Sprintf("Method%d", n-len(methods)+i); m != want {
+			t.Errorf("Got record %q, want %q", m, want)
+		}
+	}
+	// An existing file is rotated when a new sink is created.
+	if sink, err = audit.NewFileSink(path, 512, 2); err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 0 {
+		t.Errorf("Got (%v, %v), want an empty file", fi, err)
+	}
+}
+
+func TestFileSinkRotationFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log")
+	sink, err := audit.NewFileSink(path, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	// A non-empty directory at path.1 makes the rotation fail.
+	if err := os.MkdirAll(filepath.Join(path+".1", "x"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	// The record is written even though the rotation it triggers fails.
+	if err := sink.Put(audit.Record{Method: "Method0"}); err != nil {
+		t.Errorf("Put failed: %v", err)
+	}
+	if sink.Err() == nil {
+		t.Errorf("Err should report the failed rotation")
+	}
+	// The rotation is retried, and fails, before writing the next record.
+	if err := sink.Put(audit.Record{Method: "Method1"}); err == nil {
+		t.Errorf("Put should fail while the rotation fails")
+	}
+	var methods []string
+	readRecords := func(name string) {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := audit.ReadRecords(f, func(r audit.Record) error {
+			methods = append(methods, r.Method)
+			return nil
+		}); err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+	}
+	readRecords(path)
+	if got, want := methods, []string{"Method0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+	// Once the rotation succeeds, so does Put.
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Put(audit.Record{Method: "Method2"}); err != nil {
+		t.Errorf("Put failed: %v", err)
+	}
+	if err := sink.Err(); err != nil {
+		t.Errorf("Err: got %v, want nil", err)
+	}
+	methods = nil
+	readRecords(path + ".1")
+	if got, want := methods, []string{"Method2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func newPrincipal(t *testing.T) security.Principal {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := security.CreatePrincipal(security.NewInMemoryECDSASigner(key), nil, trustAllRoots{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+type trustAllRoots struct{}
+
+func (trustAllRoots) Add([]byte, security.BlessingPattern) error { return nil }
+func (trustAllRoots) Recognized([]byte, string) error            { return nil }
+func (trustAllRoots) Dump() map[security.BlessingPattern][]security.PublicKey {
+	return nil
+}
+func (trustAllRoots) DebugString() string { return "trustAllRoots" }
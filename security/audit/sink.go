@@ -0,0 +1,221 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"v.io/v23/verror"
+	"v.io/v23/vom"
+)
+
+var (
+	errBadRotationParams = verror.Register(pkgPath+".errBadRotationParams", verror.NoRetry, "{1:}{2:}invalid rotation parameters maxBytes={3}, maxFiles={4}{:_}")
+	errSinkClosed        = verror.Register(pkgPath+".errSinkClosed", verror.NoRetry, "{1:}{2:}sink is closed{:_}")
+)
+
+// MemorySink is a Sink that retains all Records in memory.
+// It is primarily intended for tests.
+type MemorySink struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+// Put implements Sink.Put.
+func (s *MemorySink) Put(r Record) error {
+	s.mu.Lock()
+	s.records = append(s.records, r)
+	s.mu.Unlock()
+	return nil
+}
+
+// Records returns a copy of the Records stored so far, in the order in which
+// they were stored.
+func (s *MemorySink) Records() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ret := make([]Record, len(s.records))
+	copy(ret, s.records)
+	return ret
+}
+
+// Reset discards all Records stored so far.
+func (s *MemorySink) Reset() {
+	s.mu.Lock()
+	s.records = nil
+	s.mu.Unlock()
+}
+
+// FileSink is a Sink that appends vom-encoded Records to a file.
+//
+// Once the file grows beyond a configured size, it is rotated: the file named
+// path is renamed to path.1, path.1 to path.2 and so on, discarding the oldest
+// file, and a new file is started at path. Each file is a self-contained vom
+// stream that can be read by ReadRecords.
+//
+// If a rotation fails, the Put that triggered it still succeeds, as its
+// Record has already been written, and the rotation is retried by the next
+// Put. Err reports the failure until then.
+type FileSink struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+
+	mu        sync.Mutex
+	closed    bool
+	file      *os.File // nil if the last rotation failed
+	counter   *countingWriter
+	enc       *vom.Encoder
+	rotateErr error // error of the last rotation
+}
+
+// NewFileSink returns a FileSink that writes Records to the file named path,
+// rotating it once it exceeds maxBytes and retaining at most maxFiles rotated
+// files in addition to the one currently being written.
+//
+// If the file already exists, it is rotated before writing any Records.
+func NewFileSink(path string, maxBytes int64, maxFiles int) (*FileSink, error) {
+	if maxBytes <= 0 || maxFiles < 0 {
+		return nil, verror.New(errBadRotationParams, nil, maxBytes, maxFiles)
+	}
+	s := &FileSink{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+	if _, err := os.Stat(path); err == nil {
+		if err := s.rotateLocked(); err != nil {
+			return nil, err
+		}
+	} else if err := s.openLocked(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Put implements Sink.Put.
+func (s *FileSink) Put(r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return verror.New(errSinkClosed, nil)
+	}
+	if s.file == nil {
+		// The last rotation failed, retry it before writing r.
+		if s.rotateErr = s.rotateLocked(); s.rotateErr != nil {
+			return s.rotateErr
+		}
+	}
+	if err := s.enc.Encode(r); err != nil {
+		return err
+	}
+	if s.counter.n >= s.maxBytes {
+		// r has been written, so a failure to rotate is not returned.
+		s.rotateErr = s.rotateLocked()
+	}
+	return nil
+}
+
+// Err returns the error of the last rotation of the file, or nil if it
+// succeeded.
+func (s *FileSink) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rotateErr
+}
+
+// Close flushes and closes the file being written to. Subsequent calls to Put
+// will fail.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.file == nil {
+		return nil
+	}
+	err := s.closeLocked()
+	s.file = nil
+	return err
+}
+
+func (s *FileSink) openLocked() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	s.file = f
+	s.counter = &countingWriter{w: f}
+	s.enc = vom.NewEncoder(s.counter)
+	return nil
+}
+
+func (s *FileSink) closeLocked() error {
+	if err := s.file.Sync(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+func (s *FileSink) rotateLocked() error {
+	if s.file != nil {
+		err := s.closeLocked()
+		s.file = nil
+		if err != nil {
+			return err
+		}
+	}
+	if s.maxFiles == 0 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return s.openLocked()
+	}
+	for i := s.maxFiles - 1; i > 0; i-- {
+		if err := os.Rename(rotatedName(s.path, i), rotatedName(s.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(s.path, rotatedName(s.path, 1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return s.openLocked()
+}
+
+func rotatedName(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// ReadRecords reads the vom-encoded Records written by a FileSink from r,
+// invoking fn on each of them in order until r is exhausted or fn returns an
+// error.
+func ReadRecords(r io.Reader, fn func(Record) error) error {
+	dec := vom.NewDecoder(r)
+	for {
+		var rec Record
+		if err := dec.Decode(&rec); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+}
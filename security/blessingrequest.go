@@ -0,0 +1,134 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"bytes"
+
+	"v.io/v23/context"
+	"v.io/v23/verror"
+)
+
+var (
+	errBadBlessingRequest     = verror.Register(pkgPath+".errBadBlessingRequest", verror.NoRetry, "{1:}{2:}invalid blessing request for extension {3}{:_}")
+	errNoGrantCaveats         = verror.Register(pkgPath+".errNoGrantCaveats", verror.NoRetry, "{1:}{2:}blessing policy chose no caveats for extension {3}; use UnconstrainedUse to grant an unconstrained blessing{:_}")
+	errGrantedKeyMismatch     = verror.Register(pkgPath+".errGrantedKeyMismatch", verror.NoRetry, "{1:}{2:}granted blessings {3} are bound to {4}, not {5}{:_}")
+	errGrantedNameMismatch    = verror.Register(pkgPath+".errGrantedNameMismatch", verror.NoRetry, "{1:}{2:}granted blessing {3} does not have the requested extension {4}{:_}")
+	errNoGrantedBlessings     = verror.Register(pkgPath+".errNoGrantedBlessings", verror.NoRetry, "{1:}{2:}no blessings were granted for extension {3}{:_}")
+	errBlessingPolicyRejected = verror.Register(pkgPath+".errBlessingPolicyRejected", verror.NoRetry, "{1:}{2:}blessing policy rejected the request for extension {3}{:_}")
+)
+
+// BlessingPolicy is used by GrantBlessing to decide whether to grant the
+// blessing requested by a principal with public key requester and, if so,
+// the caveats to attach to it.
+//
+// call is the call on which the request was received; typically, policies
+// decide based on the blessings presented by the requester
+// (RemoteBlessingNames). A policy returning an error rejects the request.
+type BlessingPolicy func(ctx *context.T, call Call, requester PublicKey, extension string) ([]Caveat, error)
+
+// NewBlessingRequest returns a BlessingRequest for p to be blessed with the
+// provided extension, signed by p.
+//
+// This package does not define how the request reaches the granter:
+// applications send it, typically as an argument of an RPC method of their
+// own that returns the granted blessings, and pass the response to
+// AcceptBlessing. For example:
+//   req, err := security.NewBlessingRequest(p, "phone")
+//   ...
+//   // Send req to the granter and receive the granted blessings.
+//   ...
+//   err = security.AcceptBlessing(p, req, granted, security.AllPrincipals)
+func NewBlessingRequest(p Principal, extension string) (BlessingRequest, error) {
+	key, err := p.PublicKey().MarshalBinary()
+	if err != nil {
+		return BlessingRequest{}, err
+	}
+	req := BlessingRequest{PublicKey: key, Extension: extension}
+	if req.Signature, err = p.Sign(req.message()); err != nil {
+		return BlessingRequest{}, err
+	}
+	return req, nil
+}
+
+// Verify returns the public key of the requester if r was signed by the
+// private counterpart of that key, and an error otherwise.
+func (r BlessingRequest) Verify() (PublicKey, error) {
+	key, err := UnmarshalPublicKey(r.PublicKey)
+	if err != nil {
+		return nil, verror.New(errBadBlessingRequest, nil, r.Extension, err)
+	}
+	if !bytes.Equal(r.Signature.Purpose, signPurpose) || !r.Signature.Verify(key, r.message()) {
+		return nil, verror.New(errBadBlessingRequest, nil, r.Extension)
+	}
+	return key, nil
+}
+
+// GrantBlessing handles req, received on call, by blessing the requester
+// with the extension it asked for under the caveats chosen by policy. The
+// granted blessings extend with, which must belong to the local principal of
+// call.
+//
+// Typically, GrantBlessing is called by the granter in the implementation
+// of an application-defined RPC method that receives the request, e.g.:
+//   func (s *service) Bless(ctx *context.T, call rpc.ServerCall, req security.BlessingRequest) (security.Blessings, error) {
+//     return security.GrantBlessing(ctx, call.Security(), req, s.blessings, s.policy)
+//   }
+func GrantBlessing(ctx *context.T, call Call, req BlessingRequest, with Blessings, policy BlessingPolicy) (Blessings, error) {
+	key, err := req.Verify()
+	if err != nil {
+		return Blessings{}, err
+	}
+	caveats, err := policy(ctx, call, key, req.Extension)
+	if err != nil {
+		return Blessings{}, verror.New(errBlessingPolicyRejected, ctx, req.Extension, err)
+	}
+	if len(caveats) == 0 {
+		return Blessings{}, verror.New(errNoGrantCaveats, ctx, req.Extension)
+	}
+	return call.LocalPrincipal().Bless(key, with, req.Extension, caveats[0], caveats[1:]...)
+}
+
+// AcceptBlessing checks that the granted blessings are the ones that p asked
+// for with req, and adds them to p: the roots of granted are marked as
+// recognized (see AddToRoots) and granted are set in the BlessingStore of p
+// for peers matching the provided pattern, as well as the default blessings
+// if the store has none.
+func AcceptBlessing(p Principal, req BlessingRequest, granted Blessings, peers BlessingPattern) error {
+	if granted.IsZero() {
+		return verror.New(errNoGrantedBlessings, nil, req.Extension)
+	}
+	key, err := p.PublicKey().MarshalBinary()
+	if err != nil {
+		return err
+	}
+	for _, chain := range granted.chains {
+		if !bytes.Equal(chain[len(chain)-1].PublicKey, key) {
+			return verror.New(errGrantedKeyMismatch, nil, granted, granted.PublicKey(), p.PublicKey())
+		}
+		if chain[len(chain)-1].Extension != req.Extension {
+			return verror.New(errGrantedNameMismatch, nil, claimedName(chain), req.Extension)
+		}
+	}
+	if err := AddToRoots(p, granted); err != nil {
+		return err
+	}
+	if _, err := p.BlessingStore().Set(granted, peers); err != nil {
+		return err
+	}
+	if def, _ := p.BlessingStore().Default(); def.IsZero() {
+		return p.BlessingStore().SetDefault(granted)
+	}
+	return nil
+}
+
+// message returns the bytes signed by the requester of r.
+func (r BlessingRequest) message() []byte {
+	var fields []byte
+	for _, data := range [][]byte{[]byte("BlessingRequest"), r.PublicKey, []byte(r.Extension)} {
+		fields = append(fields, SHA256Hash.sum(data)...)
+	}
+	return fields
+}
@@ -0,0 +1,106 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"v.io/v23/context"
+)
+
+func TestBlessingRequest(t *testing.T) {
+	var (
+		powner  = newPrincipalWithStore(t)
+		pdevice = newPrincipalWithStore(t)
+		pother  = newPrincipal(t)
+		owner   = blessSelf(t, powner, "alice")
+		device  = blessSelf(t, pdevice, "device")
+		now     = time.Now()
+		// The owner only blesses devices that present a "device" blessing,
+		// and only for a day.
+		policy = func(ctx *context.T, call Call, requester PublicKey, extension string) ([]Caveat, error) {
+			names, _ := RemoteBlessingNames(ctx, call)
+			if len(names) != 1 || names[0] != "device" {
+				return nil, fmt.Errorf("requester %v is not a device", names)
+			}
+			cav, err := NewExpiryCaveat(now.Add(24 * time.Hour))
+			return []Caveat{cav}, err
+		}
+		call = func(remote Blessings) Call {
+			return NewCall(&CallParams{LocalPrincipal: powner, RemoteBlessings: remote})
+		}
+	)
+	addToRoots(t, powner, device)
+	ctx, cancel := context.RootContext()
+	defer cancel()
+
+	req, err := NewBlessingRequest(pdevice, "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The request survives transmission.
+	var decoded BlessingRequest
+	if err := roundTrip(req, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GrantBlessing(ctx, call(blessSelf(t, pother, "other")), decoded, owner, policy); err == nil {
+		t.Errorf("Expected the policy to reject the request")
+	}
+	none := func(*context.T, Call, PublicKey, string) ([]Caveat, error) { return nil, nil }
+	if _, err := GrantBlessing(ctx, call(device), decoded, owner, none); err == nil {
+		t.Errorf("Expected error when the policy chooses no caveats")
+	}
+	// Tampered requests are rejected.
+	tampered := decoded
+	tampered.Extension = "admin"
+	if _, err := GrantBlessing(ctx, call(device), tampered, owner, policy); err == nil {
+		t.Errorf("Expected error for a tampered request")
+	}
+	tampered = decoded
+	if tampered.PublicKey, err = pother.PublicKey().MarshalBinary(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GrantBlessing(ctx, call(device), tampered, owner, policy); err == nil {
+		t.Errorf("Expected error for a request for another key")
+	}
+
+	granted, err := GrantBlessing(ctx, call(device), decoded, owner, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Blessings for another key or extension are not accepted.
+	otherReq, err := NewBlessingRequest(pdevice, "tablet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := AcceptBlessing(pdevice, otherReq, granted, AllPrincipals); err == nil {
+		t.Errorf("Expected error accepting blessings with the wrong extension")
+	}
+	if err := AcceptBlessing(pother, req, granted, AllPrincipals); err == nil {
+		t.Errorf("Expected error accepting blessings for another key")
+	}
+	if err := AcceptBlessing(pdevice, req, Blessings{}, AllPrincipals); err == nil {
+		t.Errorf("Expected error accepting no blessings")
+	}
+	if err := AcceptBlessing(pdevice, req, granted, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	// The device now recognizes the owner's blessings and presents the
+	// granted ones to the owner, and by default.
+	if err := checkBlessings(granted, CallParams{LocalPrincipal: pdevice, Timestamp: now}, "alice:phone"); err != nil {
+		t.Error(err)
+	}
+	if err := checkBlessings(granted, CallParams{LocalPrincipal: pdevice, Timestamp: now.Add(48 * time.Hour)}); err != nil {
+		t.Error(err)
+	}
+	if got := pdevice.BlessingStore().ForPeer("alice:server"); !granted.Equivalent(got) {
+		t.Errorf("Got %v for alice:server, want %v", got, granted)
+	}
+	if got, _ := pdevice.BlessingStore().Default(); !granted.Equivalent(got) {
+		t.Errorf("Got default %v, want %v", got, granted)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"fmt"
 	"reflect"
+	"runtime"
 	"sort"
 	"strings"
 	"sync"
@@ -197,19 +198,41 @@ func chainCaveats(chain []Certificate) []Caveat {
 	return cavs
 }
 
+// defaultCaveatValidation validates the chains of caveats independently of
+// each other, in parallel if more than one CPU may be used, consulting
+// caveatValidationCache for the caveats whose validation is cacheable.
 func defaultCaveatValidation(ctx *context.T, call Call, chains [][]Caveat) []error {
 	results := make([]error, len(chains))
-	for i, chain := range chains {
-		for _, cav := range chain {
-			if err := cav.Validate(ctx, call); err != nil {
-				results[i] = err
-				break
-			}
+	if len(chains) < 2 || runtime.GOMAXPROCS(0) == 1 {
+		for i, chain := range chains {
+			results[i] = validateCaveatChain(ctx, call, chain)
 		}
-	}
+		return results
+	}
+	var wg sync.WaitGroup
+	wg.Add(len(chains) - 1)
+	for i := 1; i < len(chains); i++ {
+		go func(i int) {
+			results[i] = validateCaveatChain(ctx, call, chains[i])
+			wg.Done()
+		}(i)
+	}
+	results[0] = validateCaveatChain(ctx, call, chains[0])
+	wg.Wait()
 	return results
 }
 
+// validateCaveatChain returns the error from validating the first caveat in
+// chain that fails to validate, or nil if all of them validate.
+func validateCaveatChain(ctx *context.T, call Call, chain []Caveat) error {
+	for i := range chain {
+		if err := caveatValidationCache.validate(ctx, call, &chain[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // TODO(ashankar): Get rid of this function? It allows users to mess
 // with the integrity of 'b'.
 func MarshalBlessings(b Blessings) WireBlessings {
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"reflect"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -36,6 +37,7 @@ var (
 	errCantUnmarshalDischargeKey      = verror.Register(pkgPath+".errCantUnmarshalDischargeKey", verror.NoRetry, "{1:}{2:}invalid {3}: failed to unmarshal discharger's public key: {4}{:_}")
 	errInapproriateDischargeSignature = verror.Register(pkgPath+".errInapproriateDischargeSignature", verror.NoRetry, "{1:}{2:}signature on discharge for caveat {3} was not intended for discharges(purpose={4}){:_}")
 	errBadDischargeSignature          = verror.Register(pkgPath+".errBadDischargeSignature", verror.NoRetry, "{1:}{2:}signature verification on discharge for caveat {3} failed{:_}")
+	errBadTimeOfDayWindow             = verror.Register(pkgPath+".errBadTimeOfDayWindow", verror.NoRetry, "{1:}{2:}invalid time of day window({3}): {4}{:_}")
 
 	dischargeSignatureCache = &sigCache{m: make(map[[sha256.Size]byte]bool)}
 )
@@ -224,6 +226,45 @@ func NewExpiryCaveat(t time.Time) (Caveat, error) {
 	return NewCaveat(ExpiryCaveat, t)
 }
 
+// NewNotBeforeCaveat returns a Caveat that validates iff the current time is
+// not before t.
+func NewNotBeforeCaveat(t time.Time) (Caveat, error) {
+	return NewCaveat(NotBeforeCaveat, t)
+}
+
+// NewTimeOfDayCaveat returns a Caveat that validates iff the current time, as
+// observed in loc, is at least start and less than end past midnight on one of
+// the provided days of the week. If no days are provided, the window recurs
+// every day. If end is not after start, the window extends past midnight into
+// the following day.
+//
+// For example, a caveat restricting use to business hours in New York is:
+//   loc, _ := time.LoadLocation("America/New_York")
+//   NewTimeOfDayCaveat(loc, 9*time.Hour, 18*time.Hour, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday)
+//
+// loc must be a location that can be loaded by name (see time.LoadLocation)
+// on the validating end, so neither time.Local nor a location created with
+// time.FixedZone is permitted.
+func NewTimeOfDayCaveat(loc *time.Location, start, end time.Duration, days ...time.Weekday) (Caveat, error) {
+	if loc == nil || loc == time.Local {
+		return Caveat{}, verror.New(errBadTimeOfDayWindow, nil, loc, "location must be a named time zone")
+	}
+	if _, err := time.LoadLocation(loc.String()); err != nil {
+		return Caveat{}, verror.New(errBadTimeOfDayWindow, nil, loc, err)
+	}
+	w := TimeOfDayWindow{Start: start, End: end}
+	if loc != time.UTC {
+		w.Location = loc.String()
+	}
+	for _, d := range days {
+		w.Weekdays = append(w.Weekdays, int8(d))
+	}
+	if err := w.validate(); err != nil {
+		return Caveat{}, err
+	}
+	return NewCaveat(TimeOfDayCaveat, w)
+}
+
 // NewMethodCaveat returns a Caveat that validates iff the method being invoked by
 // the peer is listed in an argument to this function.
 func NewMethodCaveat(method string, additionalMethods ...string) (Caveat, error) {
@@ -259,6 +300,76 @@ func NewPublicKeyCaveat(discharger PublicKey, location string, requirements Thir
 	return c, nil
 }
 
+func (w TimeOfDayWindow) validate() error {
+	const day = 24 * time.Hour
+	if w.Start < 0 || w.Start >= day || w.End < 0 || w.End > day {
+		return verror.New(errBadTimeOfDayWindow, nil, w, "start and end must be within a day")
+	}
+	if w.Start == w.End {
+		return verror.New(errBadTimeOfDayWindow, nil, w, "empty window")
+	}
+	for _, d := range w.Weekdays {
+		if d < int8(time.Sunday) || d > int8(time.Saturday) {
+			return verror.New(errBadTimeOfDayWindow, nil, w, fmt.Sprintf("invalid weekday %d", d))
+		}
+	}
+	return nil
+}
+
+// contains returns true iff t falls within the window.
+func (w TimeOfDayWindow) contains(t time.Time) (bool, error) {
+	if err := w.validate(); err != nil {
+		return false, err
+	}
+	loc, err := time.LoadLocation(w.Location)
+	if err != nil {
+		return false, verror.New(errBadTimeOfDayWindow, nil, w, err)
+	}
+	t = t.In(loc)
+	// Use the wall clock offset from midnight, so that the window is unaffected
+	// by daylight savings transitions.
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	day := t.Weekday()
+	switch {
+	case w.Start < w.End:
+		return offset >= w.Start && offset < w.End && w.opensOn(day), nil
+	case offset >= w.Start:
+		return w.opensOn(day), nil
+	case offset < w.End:
+		// The window opened on the previous day.
+		return w.opensOn((day + 6) % 7), nil
+	}
+	return false, nil
+}
+
+func (w TimeOfDayWindow) opensOn(day time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, d := range w.Weekdays {
+		if time.Weekday(d) == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (w TimeOfDayWindow) String() string {
+	days := "daily"
+	if len(w.Weekdays) > 0 {
+		names := make([]string, len(w.Weekdays))
+		for i, d := range w.Weekdays {
+			names[i] = time.Weekday(d).String()[:3]
+		}
+		days = strings.Join(names, ",")
+	}
+	loc := w.Location
+	if loc == "" {
+		loc = "UTC"
+	}
+	return fmt.Sprintf("%v-%v %s %s", w.Start, w.End, days, loc)
+}
+
 func (c *publicKeyThirdPartyCaveatParam) ID() string {
 	key, err := c.discharger(nil)
 	if err != nil {
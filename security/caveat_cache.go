@@ -0,0 +1,162 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/uniqueid"
+	"v.io/v23/vom"
+)
+
+const (
+	caveatCacheMaxSize = 1 << 10 // 32 bytes * 1K = 32KB + map overhead in Go
+
+	// caveatCacheTimeBucket is the granularity at which the results of
+	// validating time-dependent caveats are cached.
+	caveatCacheTimeBucket = time.Minute
+)
+
+// Cache of previously successful caveat validations, used to avoid decoding
+// the parameters of and invoking the validation function for the same caveats
+// on every call.
+var caveatValidationCache = &caveatCache{
+	cacheable: make(map[uniqueid.Id]cacheableCaveat),
+	m:         make(map[[sha256.Size]byte]bool),
+}
+
+// cacheableCaveat describes how the outcome of validating caveats of a type
+// depends on the call, for the types whose validation is cacheable.
+type cacheableCaveat struct {
+	// key returns the properties of call that validation depends on. Caveats
+	// that validate under a call are assumed to validate under any call with
+	// the same key.
+	key func(call Call) []byte
+	// storable, if not nil, returns false if a successful validation of a
+	// caveat with the provided parameters under call must not be reused for
+	// other calls with the same key.
+	storable func(call Call, paramvom []byte) bool
+}
+
+// caveatCache is a concurrent access friendly set of caveats (identified by
+// their digest and the properties of the call that their validation depends
+// on) that were validated successfully.
+//
+// Failed validations are never cached, as the returned errors depend on the
+// context of the call.
+type caveatCache struct {
+	disabled bool // Only here for microbenchmarks
+	sync.RWMutex
+	cacheable map[uniqueid.Id]cacheableCaveat
+	m         map[[sha256.Size]byte]bool
+}
+
+// markCacheable declares that the validation of caveats with the provided id
+// is cacheable as described by c.
+func (s *caveatCache) markCacheable(id uniqueid.Id, c cacheableCaveat) {
+	s.Lock()
+	s.cacheable[id] = c
+	s.Unlock()
+}
+
+func (s *caveatCache) disable() {
+	s.Lock()
+	s.disabled = true
+	s.m = make(map[[sha256.Size]byte]bool)
+	s.Unlock()
+}
+
+func (s *caveatCache) enable() {
+	s.Lock()
+	s.disabled = false
+	s.Unlock()
+}
+
+// validate is equivalent to cav.Validate(ctx, call), consulting and updating
+// the cache if cav is cacheable.
+func (s *caveatCache) validate(ctx *context.T, call Call, cav *Caveat) error {
+	s.RLock()
+	c, ok := s.cacheable[cav.Id]
+	s.RUnlock()
+	if !ok {
+		return cav.Validate(ctx, call)
+	}
+	key := sha256.Sum256(append(cav.digest(SHA256Hash), c.key(call)...))
+	s.RLock()
+	hit := s.m[key]
+	s.RUnlock()
+	if hit {
+		return nil
+	}
+	if err := cav.Validate(ctx, call); err != nil {
+		return err
+	}
+	if c.storable == nil || c.storable(call, cav.ParamVom) {
+		s.cache(key)
+	}
+	return nil
+}
+
+func (s *caveatCache) cache(key [sha256.Size]byte) {
+	s.Lock()
+	if s.disabled {
+		s.Unlock()
+		return
+	}
+	s.m[key] = true
+	// Might have gone over our size limit for the cache, remove entries.
+	// This may evict the entry that was just inserted, live with that.
+	if len(s.m) > caveatCacheMaxSize {
+		n := len(s.m) - caveatCacheMaxSize
+		m := 0
+		// Map iteration is in random key order, so this evicts random entries.
+		for key := range s.m {
+			delete(s.m, key)
+			m++
+			if m >= n {
+				break
+			}
+		}
+	}
+	s.Unlock()
+}
+
+// timeBucket returns the start of the interval of length
+// caveatCacheTimeBucket that contains t.
+func timeBucket(t time.Time) time.Time {
+	return t.Truncate(caveatCacheTimeBucket)
+}
+
+func init() {
+	// Const caveats do not depend on the call.
+	caveatValidationCache.markCacheable(ConstCaveat.Id, cacheableCaveat{
+		key: func(Call) []byte { return nil },
+	})
+	// Method caveats depend only on the method.
+	caveatValidationCache.markCacheable(MethodCaveat.Id, cacheableCaveat{
+		key: func(call Call) []byte { return []byte(call.Method()) },
+	})
+	// Expiry caveats depend on the time of the call, which is bucketed.
+	// A successful validation is only cached if the caveat has not expired
+	// by the end of the bucket, so that it holds for all calls in the bucket.
+	caveatValidationCache.markCacheable(ExpiryCaveat.Id, cacheableCaveat{
+		key: func(call Call) []byte {
+			var b [8]byte
+			binary.BigEndian.PutUint64(b[:], uint64(timeBucket(call.Timestamp()).Unix()))
+			return b[:]
+		},
+		storable: func(call Call, paramvom []byte) bool {
+			var expiry time.Time
+			if err := vom.Decode(paramvom, &expiry); err != nil {
+				return false
+			}
+			return !expiry.Before(timeBucket(call.Timestamp()).Add(caveatCacheTimeBucket))
+		},
+	})
+}
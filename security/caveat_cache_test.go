@@ -0,0 +1,148 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/uniqueid"
+	"v.io/v23/vdl"
+	"v.io/v23/verror"
+)
+
+func TestCaveatCacheKeyedByCallProperties(t *testing.T) {
+	uid, err := uniqueid.Random()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		cd = CaveatDescriptor{
+			Id:        uid,
+			ParamType: vdl.TypeOf(string("")),
+		}
+		nvalidations int32
+		ctx, cancel  = context.RootContext()
+	)
+	defer cancel()
+	RegisterCaveatValidator(cd, func(ctx *context.T, call Call, method string) error {
+		atomic.AddInt32(&nvalidations, 1)
+		if call.Method() != method {
+			return fmt.Errorf("method %v is not %v", call.Method(), method)
+		}
+		return nil
+	})
+	caveatValidationCache.markCacheable(cd.Id, cacheableCaveat{
+		key: func(call Call) []byte { return []byte(call.Method()) },
+	})
+	var (
+		cav = newCaveat(NewCaveat(cd, "Foo"))
+		foo = NewCall(&CallParams{Method: "Foo"})
+		bar = NewCall(&CallParams{Method: "Bar"})
+	)
+	tests := []struct {
+		call  Call
+		ok    bool
+		count int32 // expected value of nvalidations after the validation
+	}{
+		{foo, true, 1},
+		{foo, true, 1},  // cached
+		{bar, false, 2}, // different method, not cached
+		{bar, false, 3}, // failures are not cached
+		{foo, true, 3},  // still cached
+	}
+	for i, test := range tests {
+		err := caveatValidationCache.validate(ctx, test.call, &cav)
+		if got := err == nil; got != test.ok {
+			t.Errorf("#%d: got error %v, want success=%v", i, err, test.ok)
+		}
+		if got := atomic.LoadInt32(&nvalidations); got != test.count {
+			t.Errorf("#%d: got %d validations, want %d", i, got, test.count)
+		}
+	}
+	// With the cache disabled, every validation invokes the validator.
+	caveatValidationCache.disable()
+	defer caveatValidationCache.enable()
+	for i := 0; i < 2; i++ {
+		if err := caveatValidationCache.validate(ctx, foo, &cav); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := atomic.LoadInt32(&nvalidations), int32(5); got != want {
+		t.Errorf("got %d validations, want %d", got, want)
+	}
+}
+
+func TestCaveatCacheExpiryBuckets(t *testing.T) {
+	ctx, cancel := context.RootContext()
+	defer cancel()
+	var (
+		start = timeBucket(time.Now())
+		// Expires in the middle of the bucket that starts at start.
+		soon = newCaveat(NewExpiryCaveat(start.Add(caveatCacheTimeBucket / 2)))
+		// Expires well after the bucket that starts at start.
+		later = newCaveat(NewExpiryCaveat(start.Add(10 * caveatCacheTimeBucket)))
+		at    = func(d time.Duration) Call { return NewCall(&CallParams{Timestamp: start.Add(d)}) }
+	)
+	tests := []struct {
+		cav  Caveat
+		call Call
+		ok   bool
+	}{
+		{soon, at(0), true},
+		// Same bucket, but after the expiry: the earlier success must not
+		// have been cached.
+		{soon, at(3 * caveatCacheTimeBucket / 4), false},
+		{later, at(0), true},
+		{later, at(3 * caveatCacheTimeBucket / 4), true},
+		{later, at(10*caveatCacheTimeBucket + time.Second), false},
+	}
+	for i, test := range tests {
+		err := caveatValidationCache.validate(ctx, test.call, &test.cav)
+		if got := err == nil; got != test.ok {
+			t.Errorf("#%d: got error %v, want success=%v", i, err, test.ok)
+		}
+		if err != nil && verror.ErrorID(err) != ErrCaveatValidation.ID {
+			t.Errorf("#%d: got error %v, want errorid=%v", i, err, ErrCaveatValidation.ID)
+		}
+	}
+}
+
+func TestDefaultCaveatValidationParallel(t *testing.T) {
+	// Chains are only validated in parallel if more than one CPU may be used.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	ctx, cancel := context.RootContext()
+	defer cancel()
+	var (
+		call   = NewCall(&CallParams{Method: "Foo"})
+		now    = time.Now()
+		chains = [][]Caveat{
+			{newCaveat(NewMethodCaveat("Foo")), newCaveat(NewExpiryCaveat(now.Add(time.Hour)))},
+			{newCaveat(NewMethodCaveat("Bar"))},
+			nil,
+			{newCaveat(NewExpiryCaveat(now.Add(-time.Hour))), newCaveat(NewMethodCaveat("Foo"))},
+			{UnconstrainedUse(), newSuffixCaveat("")},
+		}
+		want = []bool{true, false, true, false, true}
+	)
+	for iter := 0; iter < 3; iter++ { // Repeat to exercise the cache.
+		results := defaultCaveatValidation(ctx, call, chains)
+		if got, want := len(results), len(chains); got != want {
+			t.Fatalf("got %d results, want %d", got, want)
+		}
+		for i, err := range results {
+			if got := err == nil; got != want[i] {
+				t.Errorf("iteration %d, chain #%d: got error %v, want success=%v", iter, i, err, want[i])
+			}
+		}
+	}
+	if got := defaultCaveatValidation(ctx, call, nil); len(got) != 0 {
+		t.Errorf("got %v, want no results", got)
+	}
+}
@@ -37,6 +37,9 @@ func TestStandardCaveatFactories(t *testing.T) {
 			// ExpiryCaveat
 			{C(NewExpiryCaveat(now.Add(time.Second))), true},
 			{C(NewExpiryCaveat(now.Add(-1 * time.Second))), false},
+			// NotBeforeCaveat
+			{C(NewNotBeforeCaveat(now.Add(-1 * time.Second))), true},
+			{C(NewNotBeforeCaveat(now.Add(time.Second))), false},
 			// MethodCaveat
 			{C(NewMethodCaveat("Foo")), true},
 			{C(NewMethodCaveat("Bar")), false},
@@ -64,6 +67,74 @@ func TestStandardCaveatFactories(t *testing.T) {
 	}
 }
 
+func TestTimeOfDayCaveat(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+		// Friday 2015-07-03 in New York.
+		friday      = func(hour, min int) time.Time { return time.Date(2015, 7, 3, hour, min, 0, 0, ny) }
+		saturday    = func(hour, min int) time.Time { return time.Date(2015, 7, 4, hour, min, 0, 0, ny) }
+		business    = newCaveat(NewTimeOfDayCaveat(ny, 9*time.Hour, 18*time.Hour, weekdays...))
+		night       = newCaveat(NewTimeOfDayCaveat(ny, 22*time.Hour, 6*time.Hour, time.Friday))
+		daily       = newCaveat(NewTimeOfDayCaveat(time.UTC, 0, 12*time.Hour))
+		ctx, cancel = context.RootContext()
+		tests       = []struct {
+			cav Caveat
+			now time.Time
+			ok  bool
+		}{
+			{business, friday(9, 0), true},
+			{business, friday(17, 59), true},
+			{business, friday(8, 59), false},
+			{business, friday(18, 0), false},
+			{business, saturday(12, 0), false},
+			// Time zones of the caller do not matter.
+			{business, friday(12, 0).UTC(), true},
+			{business, friday(6, 0).UTC(), false},
+			// Windows that extend past midnight belong to the day they opened.
+			{night, friday(23, 0), true},
+			{night, saturday(5, 59), true},
+			{night, saturday(6, 0), false},
+			{night, saturday(23, 0), false},
+			{night, friday(5, 0), false},
+			{daily, saturday(7, 59), true},
+			{daily, saturday(8, 0), false},
+		}
+	)
+	defer cancel()
+	for idx, test := range tests {
+		call := NewCall(&CallParams{Timestamp: test.now})
+		err := test.cav.Validate(ctx, call)
+		if test.ok && err != nil {
+			t.Errorf("#%d: %v.Validate(%v) failed validation: %v", idx, test.cav, test.now, err)
+		} else if !test.ok && verror.ErrorID(err) != ErrCaveatValidation.ID {
+			t.Errorf("#%d: %v.Validate(%v) returned error='%v' (errorid=%v), want errorid=%v", idx, test.cav, test.now, err, verror.ErrorID(err), ErrCaveatValidation.ID)
+		}
+	}
+	// Invalid windows should be rejected.
+	invalid := []struct {
+		loc        *time.Location
+		start, end time.Duration
+		days       []time.Weekday
+	}{
+		{nil, 0, time.Hour, nil},
+		{time.Local, 0, time.Hour, nil},
+		{time.FixedZone("UTC+3", 3*60*60), 0, time.Hour, nil},
+		{ny, time.Hour, time.Hour, nil},
+		{ny, -time.Hour, time.Hour, nil},
+		{ny, time.Hour, 25 * time.Hour, nil},
+		{ny, 0, time.Hour, []time.Weekday{7}},
+	}
+	for idx, test := range invalid {
+		if _, err := NewTimeOfDayCaveat(test.loc, test.start, test.end, test.days...); err == nil {
+			t.Errorf("#%d: NewTimeOfDayCaveat(%v, %v, %v, %v) should have failed", idx, test.loc, test.start, test.end, test.days)
+		}
+	}
+}
+
 func TestPublicKeyThirdPartyCaveat(t *testing.T) {
 	var (
 		now              = time.Now()
@@ -0,0 +1,79 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"testing"
+	"time"
+
+	"v.io/v23/context"
+)
+
+// benchmarkCaveatValidation benchmarks the validation of nchains chains of
+// caveats typical of blessings presented on calls (a method caveat and an
+// expiry caveat on each chain) using validate.
+func benchmarkCaveatValidation(b *testing.B, nchains int, validate func(*context.T, Call, [][]Caveat) []error) {
+	ctx, cancel := context.RootContext()
+	defer cancel()
+	var (
+		call   = NewCall(&CallParams{Method: "Foo"})
+		expiry = time.Now().Add(time.Hour)
+		chains = make([][]Caveat, nchains)
+	)
+	for i := range chains {
+		chains[i] = []Caveat{
+			newCaveat(NewMethodCaveat("Foo", "Bar")),
+			newCaveat(NewExpiryCaveat(expiry.Add(time.Duration(i) * time.Second))),
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, err := range validate(ctx, call, chains) {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// serialCaveatValidation validates the chains one after the other, as
+// defaultCaveatValidation does when a single CPU may be used.
+func serialCaveatValidation(ctx *context.T, call Call, chains [][]Caveat) []error {
+	results := make([]error, len(chains))
+	for i, chain := range chains {
+		results[i] = validateCaveatChain(ctx, call, chain)
+	}
+	return results
+}
+
+func BenchmarkCaveatValidation_1Chain(b *testing.B) {
+	benchmarkCaveatValidation(b, 1, defaultCaveatValidation)
+}
+
+func BenchmarkCaveatValidation_1Chain_NoCaching(b *testing.B) {
+	caveatValidationCache.disable()
+	defer caveatValidationCache.enable()
+	benchmarkCaveatValidation(b, 1, defaultCaveatValidation)
+}
+
+func BenchmarkCaveatValidation_8Chains(b *testing.B) {
+	benchmarkCaveatValidation(b, 8, defaultCaveatValidation)
+}
+
+func BenchmarkCaveatValidation_8Chains_NoCaching(b *testing.B) {
+	caveatValidationCache.disable()
+	defer caveatValidationCache.enable()
+	benchmarkCaveatValidation(b, 8, defaultCaveatValidation)
+}
+
+func BenchmarkCaveatValidation_8Chains_Serial(b *testing.B) {
+	benchmarkCaveatValidation(b, 8, serialCaveatValidation)
+}
+
+func BenchmarkCaveatValidation_8Chains_Serial_NoCaching(b *testing.B) {
+	caveatValidationCache.disable()
+	defer caveatValidationCache.enable()
+	benchmarkCaveatValidation(b, 8, serialCaveatValidation)
+}
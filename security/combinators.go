@@ -0,0 +1,188 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"fmt"
+	"sort"
+
+	"v.io/v23/context"
+	"v.io/v23/vdl"
+	"v.io/v23/verror"
+)
+
+var (
+	errAllOfDenied         = verror.Register(pkgPath+".errAllOfDenied", verror.NoRetry, "{1:}{2:}authorizer {3} of {4} denied access{:_}")
+	errAnyOfDenied         = verror.Register(pkgPath+".errAnyOfDenied", verror.NoRetry, "{1:}{2:}none of the {3} authorizers granted access{:_}")
+	errNotDenied           = verror.Register(pkgPath+".errNotDenied", verror.NoRetry, "{1:}{2:}negated authorizer granted access{:_}")
+	errNoMethodAuthorizer  = verror.Register(pkgPath+".errNoMethodAuthorizer", verror.NoRetry, "{1:}{2:}no authorizer for method {3}{:_}")
+	errNoTagAuthorizer     = verror.Register(pkgPath+".errNoTagAuthorizer", verror.NoRetry, "{1:}{2:}no authorizer for tags {3} of type {4} on method {5}{:_}")
+	errTagAuthorizerDenied = verror.Register(pkgPath+".errTagAuthorizerDenied", verror.NoRetry, "{1:}{2:}authorizer for tag {3} denied access to method {4}{:_}")
+	errTagTypeNotString    = verror.Register(pkgPath+".errTagTypeNotString", verror.NoRetry, "{1:}{2:}tag type({3}) must be backed by a string not {4}{:_}")
+)
+
+// AllOf returns an Authorizer that authorizes a call iff every one of the
+// provided authorizers authorizes it. The authorizers are consulted in order
+// and the first denial is returned, identifying the authorizer that failed.
+//
+// AllOf with no authorizers authorizes every call.
+func AllOf(authorizers ...Authorizer) Authorizer {
+	return allOf(authorizers)
+}
+
+type allOf []Authorizer
+
+func (a allOf) Authorize(ctx *context.T, call Call) error {
+	for i, auth := range a {
+		if err := auth.Authorize(ctx, call); err != nil {
+			return verror.New(errAllOfDenied, ctx, i, len(a), verror.SubErrs{{
+				Name:    fmt.Sprintf("authorizer=%d", i),
+				Err:     err,
+				Options: verror.Print,
+			}})
+		}
+	}
+	return nil
+}
+
+// AnyOf returns an Authorizer that authorizes a call iff at least one of the
+// provided authorizers authorizes it. The authorizers are consulted in order
+// until one of them grants access. If none does, the returned error includes
+// the reason each of them denied access.
+//
+// AnyOf with no authorizers authorizes no calls.
+func AnyOf(authorizers ...Authorizer) Authorizer {
+	return anyOf(authorizers)
+}
+
+type anyOf []Authorizer
+
+func (a anyOf) Authorize(ctx *context.T, call Call) error {
+	var errs verror.SubErrs
+	for i, auth := range a {
+		err := auth.Authorize(ctx, call)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, verror.SubErr{
+			Name:    fmt.Sprintf("authorizer=%d", i),
+			Err:     err,
+			Options: verror.Print,
+		})
+	}
+	return verror.New(errAnyOfDenied, ctx, len(a), errs)
+}
+
+// Not returns an Authorizer that authorizes a call iff the provided authorizer
+// does not.
+//
+// Care must be taken when using Not, since the provided authorizer may deny
+// access for reasons other than the one being negated (for example, an
+// unreadable Permissions file).
+func Not(authorizer Authorizer) Authorizer {
+	return not{authorizer}
+}
+
+type not struct{ a Authorizer }
+
+func (n not) Authorize(ctx *context.T, call Call) error {
+	if err := n.a.Authorize(ctx, call); err != nil {
+		return nil
+	}
+	return verror.New(errNotDenied, ctx)
+}
+
+// MethodAuthorizer returns an Authorizer that delegates the authorization
+// decision to the authorizer associated with the method being invoked
+// (call.Method()) in byMethod.
+//
+// Calls to methods not present in byMethod are authorized by otherwise. If
+// otherwise is nil, such calls are denied.
+func MethodAuthorizer(byMethod map[string]Authorizer, otherwise Authorizer) Authorizer {
+	m := make(map[string]Authorizer, len(byMethod))
+	for method, auth := range byMethod {
+		m[method] = auth
+	}
+	return &methodAuthorizer{m, otherwise}
+}
+
+type methodAuthorizer struct {
+	byMethod  map[string]Authorizer
+	otherwise Authorizer
+}
+
+func (a *methodAuthorizer) Authorize(ctx *context.T, call Call) error {
+	method := call.Method()
+	auth, exists := a.byMethod[method]
+	if !exists {
+		auth = a.otherwise
+	}
+	if auth == nil {
+		return verror.New(errNoMethodAuthorizer, ctx, method)
+	}
+	return auth.Authorize(ctx, call)
+}
+
+// TagAuthorizer returns an Authorizer that delegates the authorization
+// decision to the authorizers associated with the tags of tagType on the
+// method being invoked (call.MethodTags()). byTag is keyed by the string value
+// of a tag, so tagType.Kind must be vdl.String.
+//
+// If the method has multiple tags of tagType, the authorizers of all of them
+// must authorize the call. otherwise is used in place of the authorizer for
+// tags that are not present in byTag, and to authorize calls to methods that
+// have no tags of tagType. If otherwise is nil, such calls are denied.
+func TagAuthorizer(tagType *vdl.Type, byTag map[string]Authorizer, otherwise Authorizer) (Authorizer, error) {
+	if tagType == nil {
+		return nil, verror.New(errTagTypeNotString, nil, tagType, "nil")
+	}
+	if tagType.Kind() != vdl.String {
+		return nil, verror.New(errTagTypeNotString, nil, tagType, tagType.Kind())
+	}
+	m := make(map[string]Authorizer, len(byTag))
+	for tag, auth := range byTag {
+		m[tag] = auth
+	}
+	return &tagAuthorizer{tagType, m, otherwise}, nil
+}
+
+type tagAuthorizer struct {
+	tagType   *vdl.Type
+	byTag     map[string]Authorizer
+	otherwise Authorizer
+}
+
+func (a *tagAuthorizer) Authorize(ctx *context.T, call Call) error {
+	var tags []string
+	for _, tag := range call.MethodTags() {
+		if tag.Type() == a.tagType {
+			tags = append(tags, tag.RawString())
+		}
+	}
+	if len(tags) == 0 {
+		if a.otherwise == nil {
+			return verror.New(errNoTagAuthorizer, ctx, tags, a.tagType, call.Method())
+		}
+		return a.otherwise.Authorize(ctx, call)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		auth, exists := a.byTag[tag]
+		if !exists {
+			auth = a.otherwise
+		}
+		if auth == nil {
+			return verror.New(errNoTagAuthorizer, ctx, []string{tag}, a.tagType, call.Method())
+		}
+		if err := auth.Authorize(ctx, call); err != nil {
+			return verror.New(errTagAuthorizerDenied, ctx, tag, call.Method(), verror.SubErrs{{
+				Name:    "tag=" + tag,
+				Err:     err,
+				Options: verror.Print,
+			}})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"fmt"
+	"testing"
+
+	"v.io/v23/context"
+	"v.io/v23/vdl"
+	"v.io/v23/verror"
+)
+
+type testTag string
+
+type denyAuthorizer string
+
+func (d denyAuthorizer) Authorize(*context.T, Call) error {
+	return fmt.Errorf("%s says no", string(d))
+}
+
+func TestAuthorizerCombinators(t *testing.T) {
+	var (
+		allow = AllowEveryone()
+		deny  = denyAuthorizer("deny")
+		tests = []struct {
+			authorizer Authorizer
+			ok         bool
+			errmsg     string
+		}{
+			{AllOf(), true, ""},
+			{AllOf(allow, allow), true, ""},
+			{AllOf(allow, deny, allow), false, "authorizer 1 of 3 denied access: [authorizer=1: deny says no]"},
+			{AnyOf(), false, "none of the 0 authorizers"},
+			{AnyOf(deny, allow), true, ""},
+			{AnyOf(deny, denyAuthorizer("other")), false, "[authorizer=0: deny says no], [authorizer=1: other says no]"},
+			{Not(deny), true, ""},
+			{Not(allow), false, "negated authorizer granted access"},
+			{AnyOf(AllOf(allow, deny), Not(deny)), true, ""},
+			{AllOf(AnyOf(deny, allow), Not(allow)), false, "authorizer 1 of 2 denied access"},
+		}
+		ctx, cancel = context.RootContext()
+		call        = NewCall(&CallParams{Method: "Foo"})
+	)
+	defer cancel()
+	for idx, test := range tests {
+		err := test.authorizer.Authorize(ctx, call)
+		if test.ok {
+			if err != nil {
+				t.Errorf("#%d: unexpected error: %v", idx, err)
+			}
+			continue
+		}
+		if merr := matchesError(err, test.errmsg); merr != nil {
+			t.Errorf("#%d: %v", idx, merr)
+		}
+	}
+}
+
+func TestMethodAuthorizer(t *testing.T) {
+	var (
+		byMethod = map[string]Authorizer{
+			"Get": AllowEveryone(),
+			"Put": denyAuthorizer("put"),
+		}
+		ctx, cancel = context.RootContext()
+		tests       = []struct {
+			authorizer Authorizer
+			method     string
+			errmsg     string
+		}{
+			{MethodAuthorizer(byMethod, nil), "Get", ""},
+			{MethodAuthorizer(byMethod, nil), "Put", "put says no"},
+			{MethodAuthorizer(byMethod, nil), "Delete", "no authorizer for method Delete"},
+			{MethodAuthorizer(byMethod, AllowEveryone()), "Delete", ""},
+			{MethodAuthorizer(byMethod, AllowEveryone()), "Put", "put says no"},
+		}
+	)
+	defer cancel()
+	for idx, test := range tests {
+		err := test.authorizer.Authorize(ctx, NewCall(&CallParams{Method: test.method}))
+		if merr := matchesError(err, test.errmsg); merr != nil {
+			t.Errorf("#%d (%s): %v", idx, test.method, merr)
+		}
+	}
+}
+
+func TestTagAuthorizer(t *testing.T) {
+	var (
+		tagType = vdl.TypeOf(testTag(""))
+		byTag   = map[string]Authorizer{
+			"R": AllowEveryone(),
+			"W": denyAuthorizer("writer"),
+		}
+		tags = func(tags ...interface{}) []*vdl.Value {
+			var ret []*vdl.Value
+			for _, t := range tags {
+				ret = append(ret, vdl.ValueOf(t))
+			}
+			return ret
+		}
+		ctx, cancel = context.RootContext()
+		tests       = []struct {
+			otherwise Authorizer
+			tags      []*vdl.Value
+			errmsg    string
+		}{
+			{nil, tags(testTag("R")), ""},
+			{nil, tags(testTag("W")), "authorizer for tag W denied access to method Foo: [tag=W: writer says no]"},
+			{nil, tags(testTag("R"), testTag("W")), "tag=W: writer says no"},
+			{nil, tags(testTag("X")), "no authorizer for tags [X]"},
+			{nil, tags(), "no authorizer for tags"},
+			// Tags of other types are ignored.
+			{nil, tags("R"), "no authorizer for tags"},
+			{nil, tags("W", testTag("R")), ""},
+			{AllowEveryone(), tags(testTag("X")), ""},
+			{AllowEveryone(), tags(), ""},
+			{denyAuthorizer("otherwise"), tags(testTag("R"), testTag("X")), "tag=X: otherwise says no"},
+		}
+	)
+	defer cancel()
+	for _, badType := range []*vdl.Type{vdl.Int32Type, nil} {
+		if _, err := TagAuthorizer(badType, byTag, nil); verror.ErrorID(err) != errTagTypeNotString.ID {
+			t.Errorf("TagAuthorizer(%v): got error %v, want %v", badType, err, errTagTypeNotString.ID)
+		}
+	}
+	for idx, test := range tests {
+		auth, err := TagAuthorizer(tagType, byTag, test.otherwise)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = auth.Authorize(ctx, NewCall(&CallParams{Method: "Foo", MethodTags: test.tags}))
+		if merr := matchesError(err, test.errmsg); merr != nil {
+			t.Errorf("#%d (%v): %v", idx, test.tags, merr)
+		}
+	}
+}
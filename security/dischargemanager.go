@@ -0,0 +1,242 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/verror"
+)
+
+var (
+	errNotThirdPartyCaveat    = verror.Register(pkgPath+".errNotThirdPartyCaveat", verror.NoRetry, "{1:}{2:}{3} is not a third-party caveat{:_}")
+	errDischargeForWrongID    = verror.Register(pkgPath+".errDischargeForWrongID", verror.NoRetry, "{1:}{2:}fetched discharge is for third-party caveat {3}, not {4}{:_}")
+	errDischargeRefreshFailed = verror.Register(pkgPath+".errDischargeRefreshFailed", verror.NoRetry, "{1:}{2:}failed to refresh {3} of {4} discharges{:_}")
+)
+
+// dischargeRetryInterval is the time after which the DischargeManager retries
+// to fetch a discharge that it failed to fetch.
+const dischargeRetryInterval = 30 * time.Second
+
+// DischargeFetcher is the interface for obtaining discharges for third-party
+// caveats, typically by contacting the discharging service at the caveat's
+// Location.
+type DischargeFetcher interface {
+	// FetchDischarge returns a discharge for caveat, intended to be used for
+	// a call described by impetus.
+	FetchDischarge(ctx *context.T, caveat Caveat, impetus DischargeImpetus) (Discharge, error)
+}
+
+// DischargeManagerStats counts the operations performed by a DischargeManager.
+type DischargeManagerStats struct {
+	// Hits is the number of discharges requested via Discharge that were
+	// served from the BlessingStore.
+	Hits uint64
+	// Misses is the number of discharges requested via Discharge that had to
+	// be fetched.
+	Misses uint64
+	// Fetches is the number of discharges fetched, whether to serve a miss or
+	// to refresh a discharge.
+	Fetches uint64
+	// FetchFailures is the number of those fetches that failed.
+	FetchFailures uint64
+}
+
+// DischargeManager obtains discharges for the third-party caveats on the
+// blessings of a principal before they are needed, and keeps them fresh.
+//
+// Discharges are cached in the BlessingStore of the principal. A discharge is
+// refreshed once three quarters of the time between it being cached and it
+// expiring have passed, so that the discharges in the store are always usable
+// and RPCs do not stall waiting for a discharge to be fetched.
+//
+// Only third-party caveats that do not require any information about the call
+// (see ThirdPartyRequirements) are fetched ahead of time, since the discharges
+// for other caveats depend on the call they are used for.
+//
+// It is safe to invoke methods on a DischargeManager concurrently.
+type DischargeManager struct {
+	hits, misses, fetches, failures uint64 // accessed atomically, kept first for alignment
+
+	principal Principal
+	fetcher   DischargeFetcher
+}
+
+// NewDischargeManager returns a DischargeManager that fetches discharges for
+// the third-party caveats on the blessings of p using fetcher.
+func NewDischargeManager(p Principal, fetcher DischargeFetcher) *DischargeManager {
+	return &DischargeManager{principal: p, fetcher: fetcher}
+}
+
+// Stats returns a snapshot of the counters of m.
+func (m *DischargeManager) Stats() DischargeManagerStats {
+	return DischargeManagerStats{
+		Hits:          atomic.LoadUint64(&m.hits),
+		Misses:        atomic.LoadUint64(&m.misses),
+		Fetches:       atomic.LoadUint64(&m.fetches),
+		FetchFailures: atomic.LoadUint64(&m.failures),
+	}
+}
+
+// Discharge returns a discharge for caveat to be used for the call described
+// by impetus. An unexpired discharge cached in the BlessingStore is returned
+// if there is one, otherwise a discharge is fetched and cached.
+func (m *DischargeManager) Discharge(ctx *context.T, caveat Caveat, impetus DischargeImpetus) (Discharge, error) {
+	if caveat.ThirdPartyDetails() == nil {
+		return Discharge{}, verror.New(errNotThirdPartyCaveat, ctx, caveat)
+	}
+	if d, _ := m.principal.BlessingStore().Discharge(caveat, impetus); !d.VDLIsZero() {
+		if exp := d.Expiry(); exp.IsZero() || time.Now().Before(exp) {
+			atomic.AddUint64(&m.hits, 1)
+			return d, nil
+		}
+	}
+	atomic.AddUint64(&m.misses, 1)
+	return m.fetch(ctx, caveat, impetus)
+}
+
+// Refresh fetches discharges for the third-party caveats on the blessings in
+// the BlessingStore of the principal (both the default blessings and those
+// for specific peers) for which the store has no discharge, or a discharge
+// that is due to be refreshed.
+//
+// It returns the time at which Refresh should be called next, which is the
+// zero value if none of the discharges expire. If some discharges could not
+// be fetched, the returned error has one verror.SubErr per failure and the
+// fetch will be retried the next time Refresh is called.
+func (m *DischargeManager) Refresh(ctx *context.T) (time.Time, error) {
+	var (
+		caveats = m.caveats()
+		now     = time.Now()
+		next    time.Time
+		errs    []verror.SubErr
+		update  = func(t time.Time) {
+			if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+				next = t
+			}
+		}
+	)
+	for _, cav := range caveats {
+		if d, cached := m.principal.BlessingStore().Discharge(cav, DischargeImpetus{}); !d.VDLIsZero() {
+			if t := refreshTime(d, cached, now); t.IsZero() || now.Before(t) {
+				update(t)
+				continue
+			}
+		}
+		d, err := m.fetch(ctx, cav, DischargeImpetus{})
+		if err != nil {
+			errs = append(errs, verror.SubErr{
+				Name:    fmt.Sprintf("caveat=%v", cav.ThirdPartyDetails().ID()),
+				Err:     err,
+				Options: verror.Print,
+			})
+			update(now.Add(dischargeRetryInterval))
+			continue
+		}
+		// A discharge that is already due (e.g., because it expired before it
+		// was received, or the clocks of the discharger and this process
+		// disagree) is not fetched again before the retry interval.
+		t := refreshTime(d, now, now)
+		if !t.IsZero() && !t.After(now) {
+			t = now.Add(dischargeRetryInterval)
+		}
+		update(t)
+	}
+	if len(errs) > 0 {
+		return next, verror.New(errDischargeRefreshFailed, ctx, len(errs), len(caveats), verror.SubErrs(errs))
+	}
+	return next, nil
+}
+
+// Run calls Refresh whenever discharges are due to be refreshed or the
+// default blessings of the principal change, until ctx is canceled.
+// Errors from Refresh are logged to ctx.
+//
+// Changes to the blessings for specific peers are only noticed on the next
+// refresh.
+func (m *DischargeManager) Run(ctx *context.T) {
+	for {
+		_, changed := m.principal.BlessingStore().Default()
+		next, err := m.Refresh(ctx)
+		if err != nil {
+			ctx.Errorf("%v", err)
+		}
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(next.Sub(time.Now()))
+			expired = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-changed:
+		case <-expired:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+func (m *DischargeManager) fetch(ctx *context.T, caveat Caveat, impetus DischargeImpetus) (Discharge, error) {
+	atomic.AddUint64(&m.fetches, 1)
+	d, err := m.fetcher.FetchDischarge(ctx, caveat, impetus)
+	if err == nil && d.ID() != caveat.ThirdPartyDetails().ID() {
+		err = verror.New(errDischargeForWrongID, ctx, d.ID(), caveat.ThirdPartyDetails().ID())
+	}
+	if err != nil {
+		atomic.AddUint64(&m.failures, 1)
+		return Discharge{}, err
+	}
+	m.principal.BlessingStore().CacheDischarge(d, caveat, impetus)
+	return d, nil
+}
+
+// caveats returns the third-party caveats that can be discharged ahead of
+// time on all blessings in the store.
+func (m *DischargeManager) caveats() []Caveat {
+	var (
+		store   = m.principal.BlessingStore()
+		def, _  = store.Default()
+		all     = []Blessings{def}
+		seen    = make(map[string]bool)
+		caveats []Caveat
+	)
+	for _, b := range store.PeerBlessings() {
+		all = append(all, b)
+	}
+	for _, b := range all {
+		for _, cav := range b.ThirdPartyCaveats() {
+			tp := cav.ThirdPartyDetails()
+			if seen[tp.ID()] || tp.Requirements() != (ThirdPartyRequirements{}) {
+				continue
+			}
+			seen[tp.ID()] = true
+			caveats = append(caveats, cav)
+		}
+	}
+	return caveats
+}
+
+// refreshTime returns the time at which d, cached at cacheTime, should be
+// refreshed, or the zero value if d never needs to be refreshed. If the time
+// at which d was cached is unknown, now is used instead.
+func refreshTime(d Discharge, cacheTime, now time.Time) time.Time {
+	exp := d.Expiry()
+	if exp.IsZero() {
+		return time.Time{}
+	}
+	if cacheTime.IsZero() || cacheTime.After(exp) {
+		cacheTime = now
+	}
+	return exp.Add(-exp.Sub(cacheTime) / 4)
+}
@@ -0,0 +1,204 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"v.io/v23/context"
+)
+
+// mintingFetcher mints discharges valid for lifetime using a discharger.
+type mintingFetcher struct {
+	discharger Principal
+	lifetime   time.Duration
+
+	mu      sync.Mutex
+	fail    bool
+	fetched chan string
+}
+
+func (f *mintingFetcher) FetchDischarge(ctx *context.T, cav Caveat, _ DischargeImpetus) (Discharge, error) {
+	f.mu.Lock()
+	fail := f.fail
+	f.mu.Unlock()
+	if f.fetched != nil {
+		f.fetched <- cav.ThirdPartyDetails().ID()
+	}
+	if fail {
+		return Discharge{}, errors.New("discharger unavailable")
+	}
+	expiry, err := NewExpiryCaveat(time.Now().Add(f.lifetime))
+	if err != nil {
+		return Discharge{}, err
+	}
+	return f.discharger.MintDischarge(cav, expiry)
+}
+
+func (f *mintingFetcher) setFail(fail bool) {
+	f.mu.Lock()
+	f.fail = fail
+	f.mu.Unlock()
+}
+
+func TestDischargeManager(t *testing.T) {
+	var (
+		p          = newPrincipalWithStore(t)
+		discharger = newPrincipal(t)
+		newTPC     = func(req ThirdPartyRequirements) Caveat {
+			return newCaveat(NewPublicKeyCaveat(discharger.PublicKey(), "discharger", req, UnconstrainedUse()))
+		}
+		tpc1 = newTPC(ThirdPartyRequirements{})
+		tpc2 = newTPC(ThirdPartyRequirements{})
+		tpc3 = newTPC(ThirdPartyRequirements{ReportMethod: true})
+
+		fetcher     = &mintingFetcher{discharger: discharger, lifetime: time.Hour}
+		m           = NewDischargeManager(p, fetcher)
+		ctx, cancel = context.RootContext()
+	)
+	defer cancel()
+	if err := p.BlessingStore().SetDefault(blessSelf(t, p, "default", tpc1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.BlessingStore().Set(blessSelf(t, p, "peer", tpc2, tpc3), "server"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Refresh fetches discharges for the caveats not requiring information
+	// about the call.
+	next, err := m.Refresh(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Now().Add(45 * time.Minute); next.After(want) || next.Before(want.Add(-time.Minute)) {
+		t.Errorf("Got next refresh at %v, want around %v", next, want)
+	}
+	if got, want := m.Stats(), (DischargeManagerStats{Fetches: 2}); got != want {
+		t.Errorf("Got %+v, want %+v", got, want)
+	}
+	for _, cav := range []Caveat{tpc1, tpc2} {
+		if d, _ := p.BlessingStore().Discharge(cav, DischargeImpetus{}); d.ID() != cav.ThirdPartyDetails().ID() {
+			t.Errorf("No discharge cached for %v", cav)
+		}
+	}
+	// Nothing to do until the discharges are due.
+	if _, err := m.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Stats().Fetches, uint64(2); got != want {
+		t.Errorf("Got %d fetches, want %d", got, want)
+	}
+
+	// Discharge serves cached discharges and fetches missing ones.
+	if _, err := m.Discharge(ctx, tpc1, DischargeImpetus{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Discharge(ctx, tpc3, DischargeImpetus{Method: "Foo"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Discharge(ctx, UnconstrainedUse(), DischargeImpetus{}); err == nil {
+		t.Errorf("Expected error for a first-party caveat")
+	}
+	if got, want := m.Stats(), (DischargeManagerStats{Hits: 1, Misses: 1, Fetches: 3}); got != want {
+		t.Errorf("Got %+v, want %+v", got, want)
+	}
+
+	// Failures are reported and retried.
+	fetcher.setFail(true)
+	tpc4 := newTPC(ThirdPartyRequirements{})
+	if err := p.BlessingStore().SetDefault(blessSelf(t, p, "default", tpc4)); err != nil {
+		t.Fatal(err)
+	}
+	next, err = m.Refresh(ctx)
+	if merr := matchesError(err, "failed to refresh 1 of 2 discharges"); merr != nil {
+		t.Error(merr)
+	}
+	if want := time.Now().Add(dischargeRetryInterval); next.After(want) {
+		t.Errorf("Got next refresh at %v, want no later than %v", next, want)
+	}
+	if got, want := m.Stats(), (DischargeManagerStats{Hits: 1, Misses: 1, Fetches: 4, FetchFailures: 1}); got != want {
+		t.Errorf("Got %+v, want %+v", got, want)
+	}
+	fetcher.setFail(false)
+	if _, err := m.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := p.BlessingStore().Discharge(tpc4, DischargeImpetus{}); d.ID() != tpc4.ThirdPartyDetails().ID() {
+		t.Errorf("No discharge cached for %v", tpc4)
+	}
+}
+
+func TestDischargeManagerExpiredDischarge(t *testing.T) {
+	var (
+		p          = newPrincipalWithStore(t)
+		discharger = newPrincipal(t)
+		tpc        = newCaveat(NewPublicKeyCaveat(discharger.PublicKey(), "discharger", ThirdPartyRequirements{}, UnconstrainedUse()))
+		// The discharger returns discharges that have already expired.
+		fetcher     = &mintingFetcher{discharger: discharger, lifetime: -time.Minute}
+		m           = NewDischargeManager(p, fetcher)
+		ctx, cancel = context.RootContext()
+	)
+	defer cancel()
+	if err := p.BlessingStore().SetDefault(blessSelf(t, p, "default", tpc)); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	next, err := m.Refresh(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := start.Add(dischargeRetryInterval); next.Before(want) {
+		t.Errorf("Got next refresh at %v, want no earlier than %v", next, want)
+	}
+	if got, want := m.Stats().Fetches, uint64(1); got != want {
+		t.Errorf("Got %d fetches, want %d", got, want)
+	}
+}
+
+func TestDischargeManagerRun(t *testing.T) {
+	var (
+		p          = newPrincipalWithStore(t)
+		discharger = newPrincipal(t)
+		tpc        = newCaveat(NewPublicKeyCaveat(discharger.PublicKey(), "discharger", ThirdPartyRequirements{}, UnconstrainedUse()))
+		fetcher    = &mintingFetcher{
+			discharger: discharger,
+			lifetime:   100 * time.Millisecond,
+			fetched:    make(chan string),
+		}
+		ctx, cancel = context.RootContext()
+		done        = make(chan struct{})
+	)
+	go func() {
+		NewDischargeManager(p, fetcher).Run(ctx)
+		close(done)
+	}()
+	// The discharge is fetched as soon as the default blessings change, and
+	// then repeatedly before it expires.
+	if err := p.BlessingStore().SetDefault(blessSelf(t, p, "default", tpc)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case id := <-fetcher.fetched:
+			if id != tpc.ThirdPartyDetails().ID() {
+				t.Errorf("Fetched discharge for %v, want %v", id, tpc.ThirdPartyDetails().ID())
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Timed out waiting for fetch #%d", i)
+		}
+	}
+	cancel()
+	for {
+		select {
+		case <-fetcher.fetched:
+			continue
+		case <-done:
+		}
+		break
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"bytes"
+	"fmt"
+
+	"v.io/v23/context"
+	"v.io/v23/verror"
+)
+
+// ChainExplanation describes the validation of a single certificate chain
+// of a Blessings object, as performed by RemoteBlessingNames.
+type ChainExplanation struct {
+	// Name is the blessing name claimed by the certificate chain.
+	Name string
+	// RootKey is the public key of the root certificate of the chain.
+	RootKey PublicKey
+	// RootErr is nil iff RootKey is recognized as an authority on Name
+	// by the roots of the local principal.
+	RootErr error
+	// Caveats describes the validation of each caveat on the chain, in the
+	// order in which the caveats appear in the chain.
+	Caveats []CaveatExplanation
+	// Err is nil iff the chain is valid, i.e., iff Name would be returned by
+	// RemoteBlessingNames. Otherwise, it is the error that RemoteBlessingNames
+	// would report in the RejectedBlessing for Name.
+	Err error
+}
+
+// CaveatExplanation describes the validation of a single caveat.
+type CaveatExplanation struct {
+	// Certificate is the index in the chain of the certificate the caveat
+	// is on, and Extension is the extension of that certificate.
+	Certificate int
+	Extension   string
+	// Caveat is the caveat itself.
+	Caveat Caveat
+	// ThirdParty is non-nil iff Caveat is a third-party caveat.
+	ThirdParty ThirdPartyCaveat
+	// HasDischarge is true iff ThirdParty is non-nil and a discharge for it
+	// was found in Call.RemoteDischarges, in which case Discharge is set to
+	// that discharge.
+	HasDischarge bool
+	Discharge    Discharge
+	// Err is nil iff the caveat was successfully validated.
+	Err error
+}
+
+// Explain describes, for each certificate chain in blessings, whether its
+// root is recognized, the result of validating each of its caveats and
+// whether discharges are available for its third-party caveats.
+//
+// The blessings are examined as if they had been presented by the remote end
+// of call, so the explanation for a chain is consistent with the names and
+// RejectedBlessings returned by RemoteBlessingNames. Unlike
+// RemoteBlessingNames, Explain validates every caveat on every chain, even
+// those on chains whose root is not recognized or that have already failed
+// validation, so as to report all failures.
+//
+// Explain is intended for debugging: the caveat validation it performs does
+// not use any caveat validation function installed by the runtime.
+func Explain(ctx *context.T, call Call, blessings Blessings) []ChainExplanation {
+	if blessings.IsZero() || blessings.isNamelessBlessing() {
+		return nil
+	}
+	var rootsErr error
+	if p := call.LocalPrincipal(); p == nil || p.Roots() == nil {
+		rootsErr = verror.New(errMisconfiguredRoots, ctx)
+	}
+	ret := make([]ChainExplanation, len(blessings.chains))
+	for i, chain := range blessings.chains {
+		e := &ret[i]
+		e.Name = claimedName(chain)
+		e.RootKey, e.RootErr = UnmarshalPublicKey(chain[0].PublicKey)
+		if e.RootErr == nil {
+			e.RootErr = rootsErr
+		}
+		if e.RootErr == nil {
+			e.RootErr = call.LocalPrincipal().Roots().Recognized(chain[0].PublicKey, e.Name)
+		}
+		e.Err = e.RootErr
+		for ci, cert := range chain {
+			for _, cav := range cert.Caveats {
+				ce := CaveatExplanation{
+					Certificate: ci,
+					Extension:   cert.Extension,
+					Caveat:      cav,
+					ThirdParty:  cav.ThirdPartyDetails(),
+					Err:         cav.Validate(ctx, call),
+				}
+				if ce.ThirdParty != nil {
+					ce.Discharge, ce.HasDischarge = call.RemoteDischarges()[ce.ThirdParty.ID()]
+				}
+				if e.Err == nil {
+					e.Err = ce.Err
+				}
+				e.Caveats = append(e.Caveats, ce)
+			}
+		}
+	}
+	return ret
+}
+
+// String returns a multi-line, human-readable description of e.
+func (e ChainExplanation) String() string {
+	var buf bytes.Buffer
+	if e.Err == nil {
+		fmt.Fprintf(&buf, "%s: valid\n", e.Name)
+	} else {
+		fmt.Fprintf(&buf, "%s: rejected: %v\n", e.Name, e.Err)
+	}
+	if e.RootErr == nil {
+		fmt.Fprintf(&buf, "  root %v: recognized\n", e.RootKey)
+	} else {
+		fmt.Fprintf(&buf, "  root %v: %v\n", e.RootKey, e.RootErr)
+	}
+	for _, c := range e.Caveats {
+		fmt.Fprintf(&buf, "  caveat on %q: %v\n", c.Extension, c.Caveat)
+		if c.ThirdParty != nil {
+			if c.HasDischarge {
+				fmt.Fprintf(&buf, "    discharge for %v: found (expires %v)\n", c.ThirdParty.ID(), c.Discharge.Expiry())
+			} else {
+				fmt.Fprintf(&buf, "    discharge for %v: missing (location %q)\n", c.ThirdParty.ID(), c.ThirdParty.Location())
+			}
+		}
+		if c.Err == nil {
+			fmt.Fprintf(&buf, "    ok\n")
+		} else {
+			fmt.Fprintf(&buf, "    failed: %v\n", c.Err)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,120 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"v.io/v23/context"
+)
+
+func TestExplain(t *testing.T) {
+	var (
+		pserver    = newPrincipal(t)
+		pclient    = newPrincipal(t)
+		pdischarge = newPrincipal(t)
+		pother     = newPrincipal(t)
+
+		tpc = newCaveat(NewPublicKeyCaveat(pdischarge.PublicKey(), "discharger", ThirdPartyRequirements{}, UnconstrainedUse()))
+
+		alice = blessSelf(t, pclient, "alice", newCaveat(NewMethodCaveat("Foo")))
+		carol = blessSelf(t, pclient, "carol", tpc)
+
+		ctx, cancel = context.RootContext()
+	)
+	defer cancel()
+	bob, err := pother.Bless(pclient.PublicKey(), blessSelf(t, pother, "other"), "bob", newCaveat(NewExpiryCaveat(time.Now().Add(-time.Hour))), newCaveat(NewMethodCaveat("Bar")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addToRoots(t, pserver, alice)
+	addToRoots(t, pserver, carol)
+	discharge, err := pdischarge.MintDischarge(tpc, UnconstrainedUse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	union, err := UnionOfBlessings(alice, bob, carol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newCall := func(discharges ...Discharge) Call {
+		params := &CallParams{
+			Method:           "Foo",
+			LocalPrincipal:   pserver,
+			RemoteBlessings:  union,
+			RemoteDischarges: make(map[string]Discharge),
+		}
+		for _, d := range discharges {
+			params.RemoteDischarges[d.ID()] = d
+		}
+		return NewCall(params)
+	}
+
+	explain := func(call Call) map[string]ChainExplanation {
+		ret := make(map[string]ChainExplanation)
+		for _, e := range Explain(ctx, call, union) {
+			ret[e.Name] = e
+		}
+		if got, want := len(ret), 3; got != want {
+			t.Fatalf("Got %d explanations, want %d", got, want)
+		}
+		return ret
+	}
+
+	call := newCall()
+	explained := explain(call)
+	// The explanation of each chain must agree with RemoteBlessingNames.
+	valid, rejected := RemoteBlessingNames(ctx, call)
+	if !reflect.DeepEqual(valid, []string{"alice"}) || len(rejected) != 2 {
+		t.Fatalf("Got (%v, %v), want alice to be the only valid blessing", valid, rejected)
+	}
+	for _, r := range rejected {
+		if e := explained[r.Blessing]; e.Err == nil || e.Err.Error() != r.Err.Error() {
+			t.Errorf("%v: got error %v, want %v", r.Blessing, e.Err, r.Err)
+		}
+	}
+	// alice: recognized root and the method caveat is satisfied.
+	if e := explained["alice"]; e.Err != nil || e.RootErr != nil || len(e.Caveats) != 1 || e.Caveats[0].Err != nil || e.Caveats[0].ThirdParty != nil {
+		t.Errorf("Unexpected explanation for alice: %v", e)
+	}
+	// other:bob: unrecognized root, and both caveats are still validated.
+	if e := explained["other:bob"]; e.RootErr == nil || len(e.Caveats) != 2 || e.Caveats[0].Err == nil || e.Caveats[1].Err == nil {
+		t.Errorf("Unexpected explanation for other:bob: %v", e)
+	}
+	if got, want := explained["other:bob"].RootKey.String(), pother.PublicKey().String(); got != want {
+		t.Errorf("Got root %v, want %v", got, want)
+	}
+	// carol: missing discharge.
+	if e := explained["carol"]; e.RootErr != nil || len(e.Caveats) != 1 || e.Caveats[0].ThirdParty == nil || e.Caveats[0].HasDischarge || e.Caveats[0].Err == nil {
+		t.Errorf("Unexpected explanation for carol: %v", e)
+	}
+	if s := explained["carol"].String(); !strings.Contains(s, "missing") || !strings.Contains(s, "rejected") {
+		t.Errorf("Unexpected description for carol: %s", s)
+	}
+
+	// With the discharge, carol is valid.
+	call = newCall(discharge)
+	explained = explain(call)
+	if e := explained["carol"]; e.Err != nil || !e.Caveats[0].HasDischarge || e.Caveats[0].Discharge.ID() != discharge.ID() {
+		t.Errorf("Unexpected explanation for carol: %v", e)
+	}
+	if s := explained["carol"].String(); !strings.Contains(s, "carol: valid") || !strings.Contains(s, "found") {
+		t.Errorf("Unexpected description for carol: %s", s)
+	}
+	if valid, _ := RemoteBlessingNames(ctx, call); !reflect.DeepEqual(valid, []string{"alice", "carol"}) {
+		t.Errorf("Got %v, want [alice carol]", valid)
+	}
+
+	// Without a local principal, no root is recognized.
+	if e := Explain(ctx, NewCall(&CallParams{Method: "Foo"}), alice); len(e) != 1 || e[0].RootErr == nil || e[0].Err == nil {
+		t.Errorf("Unexpected explanation %v", e)
+	}
+	if explained := Explain(ctx, call, Blessings{}); explained != nil {
+		t.Errorf("Got %v, want nil for empty blessings", explained)
+	}
+}
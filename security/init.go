@@ -37,6 +37,26 @@ func init() {
 		return nil
 	})
 
+	RegisterCaveatValidator(NotBeforeCaveat, func(ctx *context.T, call Call, notBefore time.Time) error {
+		now := call.Timestamp()
+		if now.Before(notBefore) {
+			return NewErrNotBeforeCaveatValidation(ctx, now, notBefore)
+		}
+		return nil
+	})
+
+	RegisterCaveatValidator(TimeOfDayCaveat, func(ctx *context.T, call Call, window TimeOfDayWindow) error {
+		now := call.Timestamp()
+		ok, err := window.contains(now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return NewErrTimeOfDayCaveatValidation(ctx, now, window)
+		}
+		return nil
+	})
+
 	RegisterCaveatValidator(MethodCaveat, func(ctx *context.T, call Call, methods []string) error {
 		for _, m := range methods {
 			if call.Method() == m {
@@ -0,0 +1,231 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"v.io/v23/verror"
+	"v.io/v23/vom"
+)
+
+// BlessingsArmorType is the PEM block type used by ArmorBlessings.
+const BlessingsArmorType = "VANADIUM BLESSINGS"
+
+var (
+	errNoArmoredBlessings  = verror.Register(pkgPath+".errNoArmoredBlessings", verror.NoRetry, "{1:}{2:}no armored blessings found{:_}")
+	errBadBlessingsArmor   = verror.Register(pkgPath+".errBadBlessingsArmor", verror.NoRetry, "{1:}{2:}armored block is of type {3}, not {4}{:_}")
+	errCantDecodeBlessings = verror.Register(pkgPath+".errCantDecodeBlessings", verror.NoRetry, "{1:}{2:}failed to decode armored blessings{:_}")
+)
+
+// ChainInfo describes a single certificate chain of a Blessings object.
+type ChainInfo struct {
+	// Name is the blessing name claimed by the chain.
+	Name string
+	// Certificates describes the certificates in the chain, starting with
+	// the root.
+	Certificates []CertificateInfo
+	// Expiry is the earliest expiry among the caveats of the chain, or the
+	// zero value if the chain does not expire.
+	Expiry time.Time
+}
+
+// CertificateInfo describes a single Certificate.
+type CertificateInfo struct {
+	// Extension is the blessing extension of the certificate.
+	Extension string
+	// PublicKey is the public key the certificate is bound to, nil if the key
+	// cannot be parsed.
+	PublicKey PublicKey
+	// Caveats describes the caveats of the certificate.
+	Caveats []CaveatInfo
+	// SignatureAlgorithm is the algorithm used to sign the certificate, for
+	// example "ECDSA-SHA256".
+	SignatureAlgorithm string
+}
+
+// CaveatInfo describes a single Caveat in human-readable form.
+type CaveatInfo struct {
+	// Caveat is the caveat being described.
+	Caveat Caveat
+	// Kind names the CaveatDescriptor of the caveat, for example "Expiry",
+	// or is the string representation of the caveat's Id for caveats not
+	// defined in this package.
+	Kind string
+	// Description describes the restriction imposed by the caveat, obtained
+	// by decoding its parameter using the ParamType of its CaveatDescriptor.
+	Description string
+}
+
+// Inspect returns a description of each certificate chain in b.
+func Inspect(b Blessings) []ChainInfo {
+	ret := make([]ChainInfo, len(b.chains))
+	for i, chain := range b.chains {
+		info := &ret[i]
+		info.Name = claimedName(chain)
+		for _, cert := range chain {
+			cinfo := CertificateInfo{
+				Extension:          cert.Extension,
+				SignatureAlgorithm: "ECDSA-" + string(cert.Signature.Hash),
+			}
+			cinfo.PublicKey, _ = UnmarshalPublicKey(cert.PublicKey)
+			for _, cav := range cert.Caveats {
+				cinfo.Caveats = append(cinfo.Caveats, InspectCaveat(cav))
+				if t := expiryTime(cav); !t.IsZero() && (info.Expiry.IsZero() || t.Before(info.Expiry)) {
+					info.Expiry = t
+				}
+			}
+			info.Certificates = append(info.Certificates, cinfo)
+		}
+	}
+	return ret
+}
+
+// InspectCaveat returns a human-readable description of cav.
+func InspectCaveat(cav Caveat) CaveatInfo {
+	info := CaveatInfo{Caveat: cav, Kind: cav.Id.String()}
+	var param interface{}
+	if entry, ok := registry.lookup(cav.Id); ok {
+		ptr := reflect.New(entry.paramType)
+		if err := vom.Decode(cav.ParamVom, ptr.Interface()); err != nil {
+			info.Description = fmt.Sprintf("undecodable parameter: %v", err)
+			return info
+		}
+		param = ptr.Elem().Interface()
+	} else if err := vom.Decode(cav.ParamVom, &param); err != nil {
+		info.Description = fmt.Sprintf("unregistered caveat with %d bytes of parameter", len(cav.ParamVom))
+		return info
+	}
+	switch cav.Id {
+	case ConstCaveat.Id:
+		info.Kind = "Const"
+		if v, _ := param.(bool); v {
+			info.Description = "unconstrained use"
+		} else {
+			info.Description = "never valid"
+		}
+	case ExpiryCaveat.Id:
+		info.Kind = "Expiry"
+		info.Description = fmt.Sprintf("valid until %v", param)
+	case NotBeforeCaveat.Id:
+		info.Kind = "NotBefore"
+		info.Description = fmt.Sprintf("valid from %v", param)
+	case MethodCaveat.Id:
+		info.Kind = "Method"
+		info.Description = fmt.Sprintf("valid for methods %v", param)
+	case PeerBlessingsCaveat.Id:
+		info.Kind = "PeerBlessings"
+		info.Description = fmt.Sprintf("valid with peers matching %v", param)
+	case TimeOfDayCaveat.Id:
+		info.Kind = "TimeOfDay"
+		info.Description = fmt.Sprintf("valid during %v", param)
+	case PublicKeyThirdPartyCaveat.Id:
+		info.Kind = "ThirdParty"
+		tp := param.(publicKeyThirdPartyCaveatParam)
+		var restrictions []string
+		for _, c := range tp.Caveats {
+			restrictions = append(restrictions, InspectCaveat(c).Description)
+		}
+		info.Description = fmt.Sprintf("requires discharge %v from %q", tp.ID(), tp.Location())
+		if len(restrictions) > 0 {
+			info.Description += fmt.Sprintf(" (dischargeable if %s)", strings.Join(restrictions, "; "))
+		}
+	default:
+		info.Description = fmt.Sprintf("%T=%v", param, param)
+	}
+	return info
+}
+
+// PrintBlessings writes a tree-style description of b to w, with one
+// subtree per certificate chain of b. For example:
+//   Blessings bound to 42:b8:...:75
+//   └─ root:alice (expires 2016-01-01 00:00:00 +0000 UTC)
+//      ├─ root [key 2c:6e:...:91, ECDSA-SHA256]
+//      │  └─ Const: unconstrained use
+//      └─ alice [key 42:b8:...:75, ECDSA-SHA256]
+//         └─ Expiry: valid until 2016-01-01 00:00:00 +0000 UTC
+func PrintBlessings(w io.Writer, b Blessings) error {
+	var buf bytes.Buffer
+	if b.IsZero() {
+		buf.WriteString("No blessings\n")
+	} else {
+		fmt.Fprintf(&buf, "Blessings bound to %v\n", b.PublicKey())
+	}
+	chains := Inspect(b)
+	for i, chain := range chains {
+		prefix := treeBranch(&buf, "", i == len(chains)-1)
+		fmt.Fprintf(&buf, "%s", chain.Name)
+		if !chain.Expiry.IsZero() {
+			fmt.Fprintf(&buf, " (expires %v)", chain.Expiry)
+		}
+		buf.WriteString("\n")
+		for j, cert := range chain.Certificates {
+			certPrefix := treeBranch(&buf, prefix, j == len(chain.Certificates)-1)
+			fmt.Fprintf(&buf, "%s [key %v, %s]\n", cert.Extension, cert.PublicKey, cert.SignatureAlgorithm)
+			for k, cav := range cert.Caveats {
+				treeBranch(&buf, certPrefix, k == len(cert.Caveats)-1)
+				fmt.Fprintf(&buf, "%s: %s\n", cav.Kind, cav.Description)
+			}
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// treeBranch writes the branch leading to a node of a tree printed by
+// PrintBlessings, and returns the prefix to be used by the children of the
+// node.
+func treeBranch(buf *bytes.Buffer, prefix string, last bool) string {
+	buf.WriteString(prefix)
+	if last {
+		buf.WriteString("└─ ")
+		return prefix + "   "
+	}
+	buf.WriteString("├─ ")
+	return prefix + "│  "
+}
+
+// ArmorBlessings returns a PEM-encoded representation of the WireBlessings
+// for b, suitable for pasting into text. The encoding is lossless:
+// UnarmorBlessings returns blessings equivalent to b.
+//
+// The names of b are included as a header of the PEM block for the benefit
+// of human readers, but are ignored by UnarmorBlessings.
+func ArmorBlessings(b Blessings) (string, error) {
+	data, err := vom.Encode(MarshalBlessings(b))
+	if err != nil {
+		return "", err
+	}
+	block := &pem.Block{
+		Type:    BlessingsArmorType,
+		Headers: map[string]string{"Names": b.String()},
+		Bytes:   data,
+	}
+	return string(pem.EncodeToMemory(block)), nil
+}
+
+// UnarmorBlessings decodes the first block of armored blessings, as produced
+// by ArmorBlessings, found in text. Text before and after the block is
+// ignored.
+func UnarmorBlessings(text string) (Blessings, error) {
+	block, _ := pem.Decode([]byte(text))
+	if block == nil {
+		return Blessings{}, verror.New(errNoArmoredBlessings, nil)
+	}
+	if block.Type != BlessingsArmorType {
+		return Blessings{}, verror.New(errBadBlessingsArmor, nil, block.Type, BlessingsArmorType)
+	}
+	var b Blessings
+	if err := vom.Decode(block.Bytes, &b); err != nil {
+		return Blessings{}, verror.New(errCantDecodeBlessings, nil, err)
+	}
+	return b, nil
+}
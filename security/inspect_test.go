@@ -0,0 +1,135 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"bytes"
+	"encoding/pem"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInspect(t *testing.T) {
+	var (
+		proot      = newPrincipal(t)
+		palice     = newPrincipal(t)
+		discharger = newPrincipal(t)
+		expiry     = time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+		root       = blessSelf(t, proot, "root")
+		tpc        = newCaveat(NewPublicKeyCaveat(discharger.PublicKey(), "discharger", ThirdPartyRequirements{}, newCaveat(NewMethodCaveat("Get"))))
+	)
+	alice, err := proot.Bless(palice.PublicKey(), root, "alice", newCaveat(NewExpiryCaveat(expiry)), newCaveat(NewMethodCaveat("Get", "Put")), tpc, newSuffixCaveat("a/b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chains := Inspect(alice)
+	if len(chains) != 1 {
+		t.Fatalf("Got %d chains, want 1", len(chains))
+	}
+	chain := chains[0]
+	if chain.Name != "root:alice" || !chain.Expiry.Equal(expiry) || len(chain.Certificates) != 2 {
+		t.Fatalf("Unexpected chain %+v", chain)
+	}
+	if got, want := chain.Certificates[0].PublicKey.String(), proot.PublicKey().String(); got != want {
+		t.Errorf("Got root key %v, want %v", got, want)
+	}
+	cert := chain.Certificates[1]
+	if cert.Extension != "alice" || cert.SignatureAlgorithm != "ECDSA-SHA256" || cert.PublicKey.String() != palice.PublicKey().String() {
+		t.Errorf("Unexpected certificate %+v", cert)
+	}
+	var kinds, descriptions []string
+	for _, cav := range cert.Caveats {
+		kinds = append(kinds, cav.Kind)
+		descriptions = append(descriptions, cav.Description)
+	}
+	// Bless places its first caveat last.
+	if want := []string{"Method", "ThirdParty", suffixCaveat.Id.String(), "Expiry"}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("Got kinds %v, want %v", kinds, want)
+	}
+	for i, want := range []string{
+		"valid for methods [Put Get]",
+		`requires discharge ` + tpc.ThirdPartyDetails().ID() + ` from "discharger" (dischargeable if valid for methods [Get])`,
+		"string=a/b",
+		"valid until 2030-01-02 03:04:05 +0000 UTC",
+	} {
+		if descriptions[i] != want {
+			t.Errorf("Got description %q, want %q", descriptions[i], want)
+		}
+	}
+	if got, want := InspectCaveat(UnconstrainedUse()).Description, "unconstrained use"; got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := PrintBlessings(&buf, alice); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"Blessings bound to " + palice.PublicKey().String(),
+		"└─ root:alice (expires 2030-01-02 03:04:05 +0000 UTC)",
+		"   ├─ root [key " + proot.PublicKey().String() + ", ECDSA-SHA256]",
+		"   └─ alice [key " + palice.PublicKey().String() + ", ECDSA-SHA256]",
+		"      ├─ Method: valid for methods [Put Get]",
+		"      ├─ ThirdParty: " + descriptions[1],
+		"      ├─ " + suffixCaveat.Id.String() + ": string=a/b",
+		"      └─ Expiry: valid until 2030-01-02 03:04:05 +0000 UTC",
+		"",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Errorf("Got:\n%s\nWant:\n%s", got, want)
+	}
+}
+
+func TestArmorBlessings(t *testing.T) {
+	var (
+		p      = newPrincipal(t)
+		alice  = blessSelf(t, p, "alice", newCaveat(NewMethodCaveat("Get")))
+		bob    = blessSelf(t, p, "bob")
+		union  = mustUnion(t, alice, bob)
+		tested = []Blessings{alice, union, {}}
+	)
+	for _, b := range tested {
+		armored, err := ArmorBlessings(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(armored, "-----BEGIN VANADIUM BLESSINGS-----\n") {
+			t.Errorf("Unexpected armor: %s", armored)
+		}
+		got, err := UnarmorBlessings("Please add these:\n\n" + armored + "\nThanks!")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, b) {
+			t.Errorf("Got %v, want %v", got, b)
+		}
+	}
+	if _, err := UnarmorBlessings("nothing to see here"); err == nil {
+		t.Errorf("Expected error for text without armor")
+	}
+	if _, err := UnarmorBlessings("-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n"); err == nil {
+		t.Errorf("Expected error for armor of the wrong type")
+	}
+	// Tampering with the blessings is detected.
+	armored, err := ArmorBlessings(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode([]byte(armored))
+	block.Bytes = bytes.Replace(block.Bytes, []byte("alice"), []byte("alicf"), -1)
+	if _, err := UnarmorBlessings(string(pem.EncodeToMemory(block))); err == nil {
+		t.Errorf("Expected error for tampered blessings")
+	}
+}
+
+func mustUnion(t *testing.T, blessings ...Blessings) Blessings {
+	b, err := UnionOfBlessings(blessings...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
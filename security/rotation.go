@@ -0,0 +1,203 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	"v.io/v23/verror"
+)
+
+var (
+	errBadRotationOverlap  = verror.Register(pkgPath+".errBadRotationOverlap", verror.NoRetry, "{1:}{2:}key rotation requires a positive overlap, not {3}{:_}")
+	errCantMigrateBlessing = verror.Register(pkgPath+".errCantMigrateBlessing", verror.NoRetry, "{1:}{2:}failed to migrate blessings {3} to the new key{:_}")
+	errBadRotationKey      = verror.Register(pkgPath+".errBadRotationKey", verror.NoRetry, "{1:}{2:}invalid public key in rotation record{:_}")
+	errBadRotationRecord   = verror.Register(pkgPath+".errBadRotationRecord", verror.NoRetry, "{1:}{2:}signature on rotation record from {3} to {4} is invalid{:_}")
+	errRotationExpired     = verror.Register(pkgPath+".errRotationExpired", verror.NoRetry, "{1:}{2:}rotation record from {3} to {4} expired at {5}{:_}")
+)
+
+// RotationRecord attests that the principal with public key OldKey has
+// rotated its signing key to NewKey.
+//
+// A RotationRecord is signed with the old key, so any peer that knows the old
+// key can verify it, and can be transmitted using vom.
+type RotationRecord struct {
+	// OldKey and NewKey are the DER-encoded public keys of the principal
+	// before and after the rotation.
+	OldKey, NewKey []byte
+	// NotAfter is the end of the overlap window, i.e., the time at which the
+	// blessings that the old key granted to the new key during the rotation
+	// expire. ApplyRotation refuses the record after this time.
+	NotAfter time.Time
+	// Signature is the signature, created with Principal.Sign by the old key,
+	// of the contents of the record.
+	Signature Signature
+}
+
+// RotateKey rotates the signing key of the principal old to the one used by
+// signer, returning the principal for the new key and a RotationRecord that
+// can be sent to peers.
+//
+// The returned principal uses store and roots (which must be empty and not
+// be nil) and is set up as follows:
+//   - Every blessing root recognized by old is also recognized by it.
+//   - Old blesses the new key using each of the blessings in old's store,
+//     with the provided extension and an expiry caveat at the end of the
+//     overlap window, so that the new key can be used in lieu of the old one,
+//     under names that extend those of old, until the new key is blessed by
+//     the same blessers as the old one. For example, if old has the blessing
+//     "dev:alice" and extension is "rotated", the new key gets
+//     "dev:alice:rotated".
+//   - The resulting blessings are set as the default and for each of the peer
+//     patterns of the store of old.
+//
+// The old principal remains usable throughout and beyond the overlap window.
+func RotateKey(old Principal, signer Signer, store BlessingStore, roots BlessingRoots, extension string, overlap time.Duration) (Principal, RotationRecord, error) {
+	if overlap <= 0 {
+		return nil, RotationRecord{}, verror.New(errBadRotationOverlap, nil, overlap)
+	}
+	p, err := CreatePrincipal(signer, store, roots)
+	if err != nil {
+		return nil, RotationRecord{}, err
+	}
+	for pattern, keys := range old.Roots().Dump() {
+		for _, key := range keys {
+			der, err := key.MarshalBinary()
+			if err != nil {
+				return nil, RotationRecord{}, err
+			}
+			if err := p.Roots().Add(der, pattern); err != nil {
+				return nil, RotationRecord{}, err
+			}
+		}
+	}
+	notAfter := time.Now().Add(overlap)
+	expiry, err := NewExpiryCaveat(notAfter)
+	if err != nil {
+		return nil, RotationRecord{}, err
+	}
+	migrated := make(map[string]Blessings)
+	migrate := func(b Blessings) (Blessings, error) {
+		if b.IsZero() || b.isNamelessBlessing() {
+			return Blessings{}, nil
+		}
+		if ret, ok := migrated[string(b.UniqueID())]; ok {
+			return ret, nil
+		}
+		ret, err := old.Bless(p.PublicKey(), b, extension, expiry)
+		if err != nil {
+			return Blessings{}, verror.New(errCantMigrateBlessing, nil, b, err)
+		}
+		migrated[string(b.UniqueID())] = ret
+		return ret, nil
+	}
+	def, _ := old.BlessingStore().Default()
+	if def, err = migrate(def); err != nil {
+		return nil, RotationRecord{}, err
+	}
+	if !def.IsZero() {
+		if err := p.BlessingStore().SetDefault(def); err != nil {
+			return nil, RotationRecord{}, err
+		}
+	}
+	for pattern, b := range old.BlessingStore().PeerBlessings() {
+		if b, err = migrate(b); err != nil {
+			return nil, RotationRecord{}, err
+		}
+		if b.IsZero() {
+			continue
+		}
+		if _, err := p.BlessingStore().Set(b, pattern); err != nil {
+			return nil, RotationRecord{}, err
+		}
+	}
+	record := RotationRecord{NotAfter: notAfter}
+	if record.OldKey, err = old.PublicKey().MarshalBinary(); err != nil {
+		return nil, RotationRecord{}, err
+	}
+	if record.NewKey, err = p.PublicKey().MarshalBinary(); err != nil {
+		return nil, RotationRecord{}, err
+	}
+	if record.Signature, err = old.Sign(record.message()); err != nil {
+		return nil, RotationRecord{}, err
+	}
+	return p, record, nil
+}
+
+// Verify returns nil iff r was signed by the old key it names.
+func (r RotationRecord) Verify() error {
+	oldKey, err := UnmarshalPublicKey(r.OldKey)
+	if err != nil {
+		return verror.New(errBadRotationKey, nil, err)
+	}
+	newKey, err := UnmarshalPublicKey(r.NewKey)
+	if err != nil {
+		return verror.New(errBadRotationKey, nil, err)
+	}
+	if !bytes.Equal(r.Signature.Purpose, signPurpose) || !r.Signature.Verify(oldKey, r.message()) {
+		return verror.New(errBadRotationRecord, nil, oldKey, newKey)
+	}
+	return nil
+}
+
+// ApplyRotation verifies r and, if it is valid and its overlap window has not
+// ended, makes roots recognize the new key of r as an authority on every
+// blessing pattern that the old key is recognized for.
+//
+// Peers use ApplyRotation so that blessings granted by a rotated blessing
+// root remain recognized. A record is only accepted until its NotAfter time,
+// so a record leaked after the rotation cannot be used to introduce the new
+// key later on. ApplyRotation does not remove the old key from roots, as
+// BlessingRoots provides no way of doing so.
+func ApplyRotation(roots BlessingRoots, r RotationRecord) error {
+	if err := r.Verify(); err != nil {
+		return err
+	}
+	if now := time.Now(); now.After(r.NotAfter) {
+		oldKey, _ := UnmarshalPublicKey(r.OldKey)
+		newKey, _ := UnmarshalPublicKey(r.NewKey)
+		return verror.New(errRotationExpired, nil, oldKey, newKey, r.NotAfter)
+	}
+	for pattern, keys := range roots.Dump() {
+		for _, key := range keys {
+			der, err := key.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(der, r.OldKey) {
+				continue
+			}
+			if err := roots.Add(r.NewKey, pattern); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r RotationRecord) String() string {
+	oldKey, err := UnmarshalPublicKey(r.OldKey)
+	if err != nil {
+		return fmt.Sprintf("invalid rotation record: %v", err)
+	}
+	newKey, err := UnmarshalPublicKey(r.NewKey)
+	if err != nil {
+		return fmt.Sprintf("invalid rotation record: %v", err)
+	}
+	return fmt.Sprintf("rotation from %v to %v, overlapping until %v", oldKey, newKey, r.NotAfter)
+}
+
+// message returns the bytes signed by the old key of r.
+func (r RotationRecord) message() []byte {
+	notAfter := []byte(strconv.FormatInt(r.NotAfter.UnixNano(), 10))
+	var fields []byte
+	for _, data := range [][]byte{[]byte("RotationRecord"), r.OldKey, r.NewKey, notAfter} {
+		fields = append(fields, SHA256Hash.sum(data)...)
+	}
+	return fields
+}
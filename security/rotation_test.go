@@ -0,0 +1,158 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"crypto/elliptic"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func rotateKey(t *testing.T, old Principal, overlap time.Duration) (Principal, RotationRecord) {
+	signer := newECDSASigner(t, elliptic.P256())
+	p, record, err := RotateKey(old, signer, newMemStore(signer.PublicKey()), &roots{}, "rotated", overlap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, record
+}
+
+func TestRotateKey(t *testing.T) {
+	var (
+		proot   = newPrincipalWithStore(t)
+		pold    = newPrincipalWithStore(t)
+		pserver = newPrincipal(t)
+		root    = blessSelf(t, proot, "root")
+		now     = time.Now()
+		later   = now.Add(2 * time.Hour)
+	)
+	alice, err := proot.Bless(pold.PublicKey(), root, "alice", UnconstrainedUse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := proot.Bless(pold.PublicKey(), root, "alice:server", UnconstrainedUse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pold.BlessingStore().SetDefault(alice); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pold.BlessingStore().Set(server, "root:server"); err != nil {
+		t.Fatal(err)
+	}
+	addToRoots(t, pold, root)
+	addToRoots(t, pserver, root)
+
+	if _, _, err := RotateKey(pold, newECDSASigner(t, elliptic.P256()), nil, nil, "rotated", 0); err == nil {
+		t.Errorf("Expected error for a zero overlap")
+	}
+	pnew, record := rotateKey(t, pold, time.Hour)
+	if reflect.DeepEqual(pnew.PublicKey(), pold.PublicKey()) {
+		t.Fatalf("Key was not rotated")
+	}
+	// The new principal has blessings from the old key that are valid during
+	// the overlap window only, while the blessings of the old principal are
+	// valid throughout.
+	def, _ := pnew.BlessingStore().Default()
+	for _, p := range []Principal{pserver, pold, pnew} {
+		if err := checkBlessings(def, CallParams{LocalPrincipal: p, Timestamp: now}, "root:alice:rotated"); err != nil {
+			t.Error(err)
+		}
+		if err := checkBlessings(def, CallParams{LocalPrincipal: p, Timestamp: later}); err != nil {
+			t.Error(err)
+		}
+		if err := checkBlessings(alice, CallParams{LocalPrincipal: p, Timestamp: later}, "root:alice"); err != nil {
+			t.Error(err)
+		}
+	}
+	// Peer mappings are migrated.
+	if err := checkBlessings(pnew.BlessingStore().ForPeer("root:server"), CallParams{LocalPrincipal: pserver, Timestamp: now}, "root:alice:server:rotated"); err != nil {
+		t.Error(err)
+	}
+	if b := pnew.BlessingStore().ForPeer("root:other"); !b.IsZero() {
+		t.Errorf("Got %v, want no blessings for root:other", b)
+	}
+	// And the new key can be used to bless others during the overlap window.
+	pother := newPrincipal(t)
+	phone, err := pnew.Bless(pother.PublicKey(), def, "phone", UnconstrainedUse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkBlessings(phone, CallParams{LocalPrincipal: pserver, Timestamp: now}, "root:alice:rotated:phone"); err != nil {
+		t.Error(err)
+	}
+
+	// The rotation record can be verified by peers, also after being
+	// transmitted.
+	var decoded RotationRecord
+	if err := roundTrip(record, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []RotationRecord{record, decoded} {
+		if err := r.Verify(); err != nil {
+			t.Error(err)
+		}
+	}
+	tampered := record
+	tampered.NotAfter = later
+	if merr := matchesError(tampered.Verify(), "signature on rotation record"); merr != nil {
+		t.Error(merr)
+	}
+	tampered = record
+	tampered.NewKey = record.OldKey
+	if merr := matchesError(tampered.Verify(), "signature on rotation record"); merr != nil {
+		t.Error(merr)
+	}
+}
+
+func TestApplyRotation(t *testing.T) {
+	var (
+		proot   = newPrincipalWithStore(t)
+		pserver = newPrincipal(t)
+		pclient = newPrincipal(t)
+		root    = blessSelf(t, proot, "root")
+		now     = time.Now()
+	)
+	addToRoots(t, pserver, root)
+	if err := proot.BlessingStore().SetDefault(root); err != nil {
+		t.Fatal(err)
+	}
+	pnewroot, record := rotateKey(t, proot, time.Hour)
+	// A blessing granted by the new root key is only recognized once the
+	// rotation has been applied.
+	client, err := pnewroot.Bless(pclient.PublicKey(), blessSelf(t, pnewroot, "root"), "client", UnconstrainedUse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkBlessings(client, CallParams{LocalPrincipal: pserver, Timestamp: now}); err != nil {
+		t.Error(err)
+	}
+	tampered := record
+	tampered.NotAfter = now
+	if err := ApplyRotation(pserver.Roots(), tampered); err == nil {
+		t.Errorf("Expected error applying a tampered rotation record")
+	}
+	if err := ApplyRotation(pserver.Roots(), record); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkBlessings(client, CallParams{LocalPrincipal: pserver, Timestamp: now}, "root:client"); err != nil {
+		t.Error(err)
+	}
+	// Roots not recognizing the old key are unaffected.
+	pother := newPrincipal(t)
+	if err := ApplyRotation(pother.Roots(), record); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkBlessings(client, CallParams{LocalPrincipal: pother, Timestamp: now}); err != nil {
+		t.Error(err)
+	}
+	// Records are refused once their overlap window has ended.
+	_, expired := rotateKey(t, proot, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if merr := matchesError(ApplyRotation(pserver.Roots(), expired), "expired"); merr != nil {
+		t.Error(merr)
+	}
+}
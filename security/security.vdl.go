@@ -50,6 +50,185 @@ func (x *nonce) VDLRead(dec vdl.Decoder) error {
 	return nil
 }
 
+// TimeOfDayWindow describes a window of time that recurs on specific days of
+// the week, for example 09:00 to 18:00 on weekdays in "America/New_York".
+type TimeOfDayWindow struct {
+	// Location is the name of the time zone (e.g. "Europe/Zurich") in the IANA
+	// Time Zone database in which Start and End are interpreted. The empty
+	// string is interpreted as UTC.
+	Location string
+	// Start is the offset from midnight at which the window opens.
+	Start time.Duration
+	// End is the offset from midnight at which the window closes. If End is not
+	// after Start, the window extends past midnight into the following day.
+	End time.Duration
+	// Weekdays lists the days (0 for Sunday through 6 for Saturday) on which
+	// the window opens. An empty list implies every day of the week.
+	Weekdays []int8
+}
+
+func (TimeOfDayWindow) __VDLReflect(struct {
+	Name string `vdl:"v.io/v23/security.TimeOfDayWindow"`
+}) {
+}
+
+func (x TimeOfDayWindow) VDLIsZero() bool {
+	if x.Location != "" {
+		return false
+	}
+	if x.Start != 0 {
+		return false
+	}
+	if x.End != 0 {
+		return false
+	}
+	if len(x.Weekdays) != 0 {
+		return false
+	}
+	return true
+}
+
+func (x TimeOfDayWindow) VDLWrite(enc vdl.Encoder) error {
+	if err := enc.StartValue(__VDLType_struct_2); err != nil {
+		return err
+	}
+	if x.Location != "" {
+		if err := enc.NextFieldValueString(0, vdl.StringType, x.Location); err != nil {
+			return err
+		}
+	}
+	if x.Start != 0 {
+		if err := enc.NextField(1); err != nil {
+			return err
+		}
+		var wire vdltime.Duration
+		if err := vdltime.DurationFromNative(&wire, x.Start); err != nil {
+			return err
+		}
+		if err := wire.VDLWrite(enc); err != nil {
+			return err
+		}
+	}
+	if x.End != 0 {
+		if err := enc.NextField(2); err != nil {
+			return err
+		}
+		var wire vdltime.Duration
+		if err := vdltime.DurationFromNative(&wire, x.End); err != nil {
+			return err
+		}
+		if err := wire.VDLWrite(enc); err != nil {
+			return err
+		}
+	}
+	if len(x.Weekdays) != 0 {
+		if err := enc.NextField(3); err != nil {
+			return err
+		}
+		if err := __VDLWriteAnon_list_1(enc, x.Weekdays); err != nil {
+			return err
+		}
+	}
+	if err := enc.NextField(-1); err != nil {
+		return err
+	}
+	return enc.FinishValue()
+}
+
+func __VDLWriteAnon_list_1(enc vdl.Encoder, x []int8) error {
+	if err := enc.StartValue(__VDLType_list_3); err != nil {
+		return err
+	}
+	if err := enc.SetLenHint(len(x)); err != nil {
+		return err
+	}
+	for _, elem := range x {
+		if err := enc.NextEntryValueInt(vdl.Int8Type, int64(elem)); err != nil {
+			return err
+		}
+	}
+	if err := enc.NextEntry(true); err != nil {
+		return err
+	}
+	return enc.FinishValue()
+}
+
+func (x *TimeOfDayWindow) VDLRead(dec vdl.Decoder) error {
+	*x = TimeOfDayWindow{}
+	if err := dec.StartValue(__VDLType_struct_2); err != nil {
+		return err
+	}
+	decType := dec.Type()
+	for {
+		index, err := dec.NextField()
+		switch {
+		case err != nil:
+			return err
+		case index == -1:
+			return dec.FinishValue()
+		}
+		if decType != __VDLType_struct_2 {
+			index = __VDLType_struct_2.FieldIndexByName(decType.Field(index).Name)
+			if index == -1 {
+				if err := dec.SkipValue(); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		switch index {
+		case 0:
+			switch value, err := dec.ReadValueString(); {
+			case err != nil:
+				return err
+			default:
+				x.Location = value
+			}
+		case 1:
+			var wire vdltime.Duration
+			if err := wire.VDLRead(dec); err != nil {
+				return err
+			}
+			if err := vdltime.DurationToNative(wire, &x.Start); err != nil {
+				return err
+			}
+		case 2:
+			var wire vdltime.Duration
+			if err := wire.VDLRead(dec); err != nil {
+				return err
+			}
+			if err := vdltime.DurationToNative(wire, &x.End); err != nil {
+				return err
+			}
+		case 3:
+			if err := __VDLReadAnon_list_1(dec, &x.Weekdays); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func __VDLReadAnon_list_1(dec vdl.Decoder, x *[]int8) error {
+	if err := dec.StartValue(__VDLType_list_3); err != nil {
+		return err
+	}
+	if len := dec.LenHint(); len > 0 {
+		*x = make([]int8, 0, len)
+	} else {
+		*x = nil
+	}
+	for {
+		switch done, elem, err := dec.NextEntryValueInt(8); {
+		case err != nil:
+			return err
+		case done:
+			return dec.FinishValue()
+		default:
+			*x = append(*x, int8(elem))
+		}
+	}
+}
+
 // Caveat is a condition on the validity of a blessing/discharge.
 //
 // These conditions are provided when asking a principal to create
@@ -79,16 +258,16 @@ func (x Caveat) VDLIsZero() bool {
 }
 
 func (x Caveat) VDLWrite(enc vdl.Encoder) error {
-	if err := enc.StartValue(__VDLType_struct_2); err != nil {
+	if err := enc.StartValue(__VDLType_struct_4); err != nil {
 		return err
 	}
 	if x.Id != (uniqueid.Id{}) {
-		if err := enc.NextFieldValueBytes(0, __VDLType_array_3, x.Id[:]); err != nil {
+		if err := enc.NextFieldValueBytes(0, __VDLType_array_5, x.Id[:]); err != nil {
 			return err
 		}
 	}
 	if len(x.ParamVom) != 0 {
-		if err := enc.NextFieldValueBytes(1, __VDLType_list_4, x.ParamVom); err != nil {
+		if err := enc.NextFieldValueBytes(1, __VDLType_list_6, x.ParamVom); err != nil {
 			return err
 		}
 	}
@@ -100,7 +279,7 @@ func (x Caveat) VDLWrite(enc vdl.Encoder) error {
 
 func (x *Caveat) VDLRead(dec vdl.Decoder) error {
 	*x = Caveat{}
-	if err := dec.StartValue(__VDLType_struct_2); err != nil {
+	if err := dec.StartValue(__VDLType_struct_4); err != nil {
 		return err
 	}
 	decType := dec.Type()
@@ -112,8 +291,8 @@ func (x *Caveat) VDLRead(dec vdl.Decoder) error {
 		case index == -1:
 			return dec.FinishValue()
 		}
-		if decType != __VDLType_struct_2 {
-			index = __VDLType_struct_2.FieldIndexByName(decType.Field(index).Name)
+		if decType != __VDLType_struct_4 {
+			index = __VDLType_struct_4.FieldIndexByName(decType.Field(index).Name)
 			if index == -1 {
 				if err := dec.SkipValue(); err != nil {
 					return err
@@ -156,7 +335,7 @@ func (x ThirdPartyRequirements) VDLIsZero() bool {
 }
 
 func (x ThirdPartyRequirements) VDLWrite(enc vdl.Encoder) error {
-	if err := enc.StartValue(__VDLType_struct_5); err != nil {
+	if err := enc.StartValue(__VDLType_struct_7); err != nil {
 		return err
 	}
 	if x.ReportServer {
@@ -182,7 +361,7 @@ func (x ThirdPartyRequirements) VDLWrite(enc vdl.Encoder) error {
 
 func (x *ThirdPartyRequirements) VDLRead(dec vdl.Decoder) error {
 	*x = ThirdPartyRequirements{}
-	if err := dec.StartValue(__VDLType_struct_5); err != nil {
+	if err := dec.StartValue(__VDLType_struct_7); err != nil {
 		return err
 	}
 	decType := dec.Type()
@@ -194,8 +373,8 @@ func (x *ThirdPartyRequirements) VDLRead(dec vdl.Decoder) error {
 		case index == -1:
 			return dec.FinishValue()
 		}
-		if decType != __VDLType_struct_5 {
-			index = __VDLType_struct_5.FieldIndexByName(decType.Field(index).Name)
+		if decType != __VDLType_struct_7 {
+			index = __VDLType_struct_7.FieldIndexByName(decType.Field(index).Name)
 			if index == -1 {
 				if err := dec.SkipValue(); err != nil {
 					return err
@@ -284,7 +463,7 @@ func (x publicKeyThirdPartyCaveatParam) VDLIsZero() bool {
 }
 
 func (x publicKeyThirdPartyCaveatParam) VDLWrite(enc vdl.Encoder) error {
-	if err := enc.StartValue(__VDLType_struct_6); err != nil {
+	if err := enc.StartValue(__VDLType_struct_8); err != nil {
 		return err
 	}
 	if x.Nonce != (nonce{}) {
@@ -296,12 +475,12 @@ func (x publicKeyThirdPartyCaveatParam) VDLWrite(enc vdl.Encoder) error {
 		if err := enc.NextField(1); err != nil {
 			return err
 		}
-		if err := __VDLWriteAnon_list_1(enc, x.Caveats); err != nil {
+		if err := __VDLWriteAnon_list_2(enc, x.Caveats); err != nil {
 			return err
 		}
 	}
 	if len(x.DischargerKey) != 0 {
-		if err := enc.NextFieldValueBytes(2, __VDLType_list_4, x.DischargerKey); err != nil {
+		if err := enc.NextFieldValueBytes(2, __VDLType_list_6, x.DischargerKey); err != nil {
 			return err
 		}
 	}
@@ -324,8 +503,8 @@ func (x publicKeyThirdPartyCaveatParam) VDLWrite(enc vdl.Encoder) error {
 	return enc.FinishValue()
 }
 
-func __VDLWriteAnon_list_1(enc vdl.Encoder, x []Caveat) error {
-	if err := enc.StartValue(__VDLType_list_7); err != nil {
+func __VDLWriteAnon_list_2(enc vdl.Encoder, x []Caveat) error {
+	if err := enc.StartValue(__VDLType_list_9); err != nil {
 		return err
 	}
 	if err := enc.SetLenHint(len(x)); err != nil {
@@ -347,7 +526,7 @@ func __VDLWriteAnon_list_1(enc vdl.Encoder, x []Caveat) error {
 
 func (x *publicKeyThirdPartyCaveatParam) VDLRead(dec vdl.Decoder) error {
 	*x = publicKeyThirdPartyCaveatParam{}
-	if err := dec.StartValue(__VDLType_struct_6); err != nil {
+	if err := dec.StartValue(__VDLType_struct_8); err != nil {
 		return err
 	}
 	decType := dec.Type()
@@ -359,8 +538,8 @@ func (x *publicKeyThirdPartyCaveatParam) VDLRead(dec vdl.Decoder) error {
 		case index == -1:
 			return dec.FinishValue()
 		}
-		if decType != __VDLType_struct_6 {
-			index = __VDLType_struct_6.FieldIndexByName(decType.Field(index).Name)
+		if decType != __VDLType_struct_8 {
+			index = __VDLType_struct_8.FieldIndexByName(decType.Field(index).Name)
 			if index == -1 {
 				if err := dec.SkipValue(); err != nil {
 					return err
@@ -375,7 +554,7 @@ func (x *publicKeyThirdPartyCaveatParam) VDLRead(dec vdl.Decoder) error {
 				return err
 			}
 		case 1:
-			if err := __VDLReadAnon_list_1(dec, &x.Caveats); err != nil {
+			if err := __VDLReadAnon_list_2(dec, &x.Caveats); err != nil {
 				return err
 			}
 		case 2:
@@ -397,8 +576,8 @@ func (x *publicKeyThirdPartyCaveatParam) VDLRead(dec vdl.Decoder) error {
 	}
 }
 
-func __VDLReadAnon_list_1(dec vdl.Decoder, x *[]Caveat) error {
-	if err := dec.StartValue(__VDLType_list_7); err != nil {
+func __VDLReadAnon_list_2(dec vdl.Decoder, x *[]Caveat) error {
+	if err := dec.StartValue(__VDLType_list_9); err != nil {
 		return err
 	}
 	if len := dec.LenHint(); len > 0 {
@@ -435,7 +614,7 @@ func (x Hash) VDLIsZero() bool {
 }
 
 func (x Hash) VDLWrite(enc vdl.Encoder) error {
-	if err := enc.WriteValueString(__VDLType_string_8, string(x)); err != nil {
+	if err := enc.WriteValueString(__VDLType_string_10, string(x)); err != nil {
 		return err
 	}
 	return nil
@@ -486,26 +665,26 @@ func (x Signature) VDLIsZero() bool {
 }
 
 func (x Signature) VDLWrite(enc vdl.Encoder) error {
-	if err := enc.StartValue(__VDLType_struct_9); err != nil {
+	if err := enc.StartValue(__VDLType_struct_11); err != nil {
 		return err
 	}
 	if len(x.Purpose) != 0 {
-		if err := enc.NextFieldValueBytes(0, __VDLType_list_4, x.Purpose); err != nil {
+		if err := enc.NextFieldValueBytes(0, __VDLType_list_6, x.Purpose); err != nil {
 			return err
 		}
 	}
 	if x.Hash != "" {
-		if err := enc.NextFieldValueString(1, __VDLType_string_8, string(x.Hash)); err != nil {
+		if err := enc.NextFieldValueString(1, __VDLType_string_10, string(x.Hash)); err != nil {
 			return err
 		}
 	}
 	if len(x.R) != 0 {
-		if err := enc.NextFieldValueBytes(2, __VDLType_list_4, x.R); err != nil {
+		if err := enc.NextFieldValueBytes(2, __VDLType_list_6, x.R); err != nil {
 			return err
 		}
 	}
 	if len(x.S) != 0 {
-		if err := enc.NextFieldValueBytes(3, __VDLType_list_4, x.S); err != nil {
+		if err := enc.NextFieldValueBytes(3, __VDLType_list_6, x.S); err != nil {
 			return err
 		}
 	}
@@ -517,7 +696,7 @@ func (x Signature) VDLWrite(enc vdl.Encoder) error {
 
 func (x *Signature) VDLRead(dec vdl.Decoder) error {
 	*x = Signature{}
-	if err := dec.StartValue(__VDLType_struct_9); err != nil {
+	if err := dec.StartValue(__VDLType_struct_11); err != nil {
 		return err
 	}
 	decType := dec.Type()
@@ -529,8 +708,8 @@ func (x *Signature) VDLRead(dec vdl.Decoder) error {
 		case index == -1:
 			return dec.FinishValue()
 		}
-		if decType != __VDLType_struct_9 {
-			index = __VDLType_struct_9.FieldIndexByName(decType.Field(index).Name)
+		if decType != __VDLType_struct_11 {
+			index = __VDLType_struct_11.FieldIndexByName(decType.Field(index).Name)
 			if index == -1 {
 				if err := dec.SkipValue(); err != nil {
 					return err
@@ -595,7 +774,7 @@ func (x PublicKeyDischarge) VDLIsZero() bool {
 }
 
 func (x PublicKeyDischarge) VDLWrite(enc vdl.Encoder) error {
-	if err := enc.StartValue(__VDLType_struct_10); err != nil {
+	if err := enc.StartValue(__VDLType_struct_12); err != nil {
 		return err
 	}
 	if x.ThirdPartyCaveatId != "" {
@@ -607,7 +786,7 @@ func (x PublicKeyDischarge) VDLWrite(enc vdl.Encoder) error {
 		if err := enc.NextField(1); err != nil {
 			return err
 		}
-		if err := __VDLWriteAnon_list_1(enc, x.Caveats); err != nil {
+		if err := __VDLWriteAnon_list_2(enc, x.Caveats); err != nil {
 			return err
 		}
 	}
@@ -627,7 +806,7 @@ func (x PublicKeyDischarge) VDLWrite(enc vdl.Encoder) error {
 
 func (x *PublicKeyDischarge) VDLRead(dec vdl.Decoder) error {
 	*x = PublicKeyDischarge{}
-	if err := dec.StartValue(__VDLType_struct_10); err != nil {
+	if err := dec.StartValue(__VDLType_struct_12); err != nil {
 		return err
 	}
 	decType := dec.Type()
@@ -639,8 +818,8 @@ func (x *PublicKeyDischarge) VDLRead(dec vdl.Decoder) error {
 		case index == -1:
 			return dec.FinishValue()
 		}
-		if decType != __VDLType_struct_10 {
-			index = __VDLType_struct_10.FieldIndexByName(decType.Field(index).Name)
+		if decType != __VDLType_struct_12 {
+			index = __VDLType_struct_12.FieldIndexByName(decType.Field(index).Name)
 			if index == -1 {
 				if err := dec.SkipValue(); err != nil {
 					return err
@@ -657,7 +836,7 @@ func (x *PublicKeyDischarge) VDLRead(dec vdl.Decoder) error {
 				x.ThirdPartyCaveatId = value
 			}
 		case 1:
-			if err := __VDLReadAnon_list_1(dec, &x.Caveats); err != nil {
+			if err := __VDLReadAnon_list_2(dec, &x.Caveats); err != nil {
 				return err
 			}
 		case 2:
@@ -693,7 +872,7 @@ func (x BlessingPattern) VDLIsZero() bool {
 }
 
 func (x BlessingPattern) VDLWrite(enc vdl.Encoder) error {
-	if err := enc.WriteValueString(__VDLType_string_11, string(x)); err != nil {
+	if err := enc.WriteValueString(__VDLType_string_13, string(x)); err != nil {
 		return err
 	}
 	return nil
@@ -744,14 +923,14 @@ func (x DischargeImpetus) VDLIsZero() bool {
 }
 
 func (x DischargeImpetus) VDLWrite(enc vdl.Encoder) error {
-	if err := enc.StartValue(__VDLType_struct_12); err != nil {
+	if err := enc.StartValue(__VDLType_struct_14); err != nil {
 		return err
 	}
 	if len(x.Server) != 0 {
 		if err := enc.NextField(0); err != nil {
 			return err
 		}
-		if err := __VDLWriteAnon_list_2(enc, x.Server); err != nil {
+		if err := __VDLWriteAnon_list_3(enc, x.Server); err != nil {
 			return err
 		}
 	}
@@ -764,7 +943,7 @@ func (x DischargeImpetus) VDLWrite(enc vdl.Encoder) error {
 		if err := enc.NextField(2); err != nil {
 			return err
 		}
-		if err := __VDLWriteAnon_list_3(enc, x.Arguments); err != nil {
+		if err := __VDLWriteAnon_list_4(enc, x.Arguments); err != nil {
 			return err
 		}
 	}
@@ -774,15 +953,15 @@ func (x DischargeImpetus) VDLWrite(enc vdl.Encoder) error {
 	return enc.FinishValue()
 }
 
-func __VDLWriteAnon_list_2(enc vdl.Encoder, x []BlessingPattern) error {
-	if err := enc.StartValue(__VDLType_list_13); err != nil {
+func __VDLWriteAnon_list_3(enc vdl.Encoder, x []BlessingPattern) error {
+	if err := enc.StartValue(__VDLType_list_15); err != nil {
 		return err
 	}
 	if err := enc.SetLenHint(len(x)); err != nil {
 		return err
 	}
 	for _, elem := range x {
-		if err := enc.NextEntryValueString(__VDLType_string_11, string(elem)); err != nil {
+		if err := enc.NextEntryValueString(__VDLType_string_13, string(elem)); err != nil {
 			return err
 		}
 	}
@@ -792,8 +971,8 @@ func __VDLWriteAnon_list_2(enc vdl.Encoder, x []BlessingPattern) error {
 	return enc.FinishValue()
 }
 
-func __VDLWriteAnon_list_3(enc vdl.Encoder, x []*vom.RawBytes) error {
-	if err := enc.StartValue(__VDLType_list_14); err != nil {
+func __VDLWriteAnon_list_4(enc vdl.Encoder, x []*vom.RawBytes) error {
+	if err := enc.StartValue(__VDLType_list_16); err != nil {
 		return err
 	}
 	if err := enc.SetLenHint(len(x)); err != nil {
@@ -821,7 +1000,7 @@ func __VDLWriteAnon_list_3(enc vdl.Encoder, x []*vom.RawBytes) error {
 
 func (x *DischargeImpetus) VDLRead(dec vdl.Decoder) error {
 	*x = DischargeImpetus{}
-	if err := dec.StartValue(__VDLType_struct_12); err != nil {
+	if err := dec.StartValue(__VDLType_struct_14); err != nil {
 		return err
 	}
 	decType := dec.Type()
@@ -833,8 +1012,8 @@ func (x *DischargeImpetus) VDLRead(dec vdl.Decoder) error {
 		case index == -1:
 			return dec.FinishValue()
 		}
-		if decType != __VDLType_struct_12 {
-			index = __VDLType_struct_12.FieldIndexByName(decType.Field(index).Name)
+		if decType != __VDLType_struct_14 {
+			index = __VDLType_struct_14.FieldIndexByName(decType.Field(index).Name)
 			if index == -1 {
 				if err := dec.SkipValue(); err != nil {
 					return err
@@ -844,7 +1023,7 @@ func (x *DischargeImpetus) VDLRead(dec vdl.Decoder) error {
 		}
 		switch index {
 		case 0:
-			if err := __VDLReadAnon_list_2(dec, &x.Server); err != nil {
+			if err := __VDLReadAnon_list_3(dec, &x.Server); err != nil {
 				return err
 			}
 		case 1:
@@ -855,15 +1034,15 @@ func (x *DischargeImpetus) VDLRead(dec vdl.Decoder) error {
 				x.Method = value
 			}
 		case 2:
-			if err := __VDLReadAnon_list_3(dec, &x.Arguments); err != nil {
+			if err := __VDLReadAnon_list_4(dec, &x.Arguments); err != nil {
 				return err
 			}
 		}
 	}
 }
 
-func __VDLReadAnon_list_2(dec vdl.Decoder, x *[]BlessingPattern) error {
-	if err := dec.StartValue(__VDLType_list_13); err != nil {
+func __VDLReadAnon_list_3(dec vdl.Decoder, x *[]BlessingPattern) error {
+	if err := dec.StartValue(__VDLType_list_15); err != nil {
 		return err
 	}
 	if len := dec.LenHint(); len > 0 {
@@ -883,8 +1062,8 @@ func __VDLReadAnon_list_2(dec vdl.Decoder, x *[]BlessingPattern) error {
 	}
 }
 
-func __VDLReadAnon_list_3(dec vdl.Decoder, x *[]*vom.RawBytes) error {
-	if err := dec.StartValue(__VDLType_list_14); err != nil {
+func __VDLReadAnon_list_4(dec vdl.Decoder, x *[]*vom.RawBytes) error {
+	if err := dec.StartValue(__VDLType_list_16); err != nil {
 		return err
 	}
 	if len := dec.LenHint(); len > 0 {
@@ -945,7 +1124,7 @@ func (x Certificate) VDLIsZero() bool {
 }
 
 func (x Certificate) VDLWrite(enc vdl.Encoder) error {
-	if err := enc.StartValue(__VDLType_struct_15); err != nil {
+	if err := enc.StartValue(__VDLType_struct_17); err != nil {
 		return err
 	}
 	if x.Extension != "" {
@@ -954,7 +1133,7 @@ func (x Certificate) VDLWrite(enc vdl.Encoder) error {
 		}
 	}
 	if len(x.PublicKey) != 0 {
-		if err := enc.NextFieldValueBytes(1, __VDLType_list_4, x.PublicKey); err != nil {
+		if err := enc.NextFieldValueBytes(1, __VDLType_list_6, x.PublicKey); err != nil {
 			return err
 		}
 	}
@@ -962,7 +1141,7 @@ func (x Certificate) VDLWrite(enc vdl.Encoder) error {
 		if err := enc.NextField(2); err != nil {
 			return err
 		}
-		if err := __VDLWriteAnon_list_1(enc, x.Caveats); err != nil {
+		if err := __VDLWriteAnon_list_2(enc, x.Caveats); err != nil {
 			return err
 		}
 	}
@@ -982,7 +1161,7 @@ func (x Certificate) VDLWrite(enc vdl.Encoder) error {
 
 func (x *Certificate) VDLRead(dec vdl.Decoder) error {
 	*x = Certificate{}
-	if err := dec.StartValue(__VDLType_struct_15); err != nil {
+	if err := dec.StartValue(__VDLType_struct_17); err != nil {
 		return err
 	}
 	decType := dec.Type()
@@ -994,8 +1173,8 @@ func (x *Certificate) VDLRead(dec vdl.Decoder) error {
 		case index == -1:
 			return dec.FinishValue()
 		}
-		if decType != __VDLType_struct_15 {
-			index = __VDLType_struct_15.FieldIndexByName(decType.Field(index).Name)
+		if decType != __VDLType_struct_17 {
+			index = __VDLType_struct_17.FieldIndexByName(decType.Field(index).Name)
 			if index == -1 {
 				if err := dec.SkipValue(); err != nil {
 					return err
@@ -1016,7 +1195,7 @@ func (x *Certificate) VDLRead(dec vdl.Decoder) error {
 				return err
 			}
 		case 2:
-			if err := __VDLReadAnon_list_1(dec, &x.Caveats); err != nil {
+			if err := __VDLReadAnon_list_2(dec, &x.Caveats); err != nil {
 				return err
 			}
 		case 3:
@@ -1054,11 +1233,11 @@ func (x CaveatDescriptor) VDLIsZero() bool {
 }
 
 func (x CaveatDescriptor) VDLWrite(enc vdl.Encoder) error {
-	if err := enc.StartValue(__VDLType_struct_16); err != nil {
+	if err := enc.StartValue(__VDLType_struct_18); err != nil {
 		return err
 	}
 	if x.Id != (uniqueid.Id{}) {
-		if err := enc.NextFieldValueBytes(0, __VDLType_array_3, x.Id[:]); err != nil {
+		if err := enc.NextFieldValueBytes(0, __VDLType_array_5, x.Id[:]); err != nil {
 			return err
 		}
 	}
@@ -1077,7 +1256,7 @@ func (x *CaveatDescriptor) VDLRead(dec vdl.Decoder) error {
 	*x = CaveatDescriptor{
 		ParamType: vdl.AnyType,
 	}
-	if err := dec.StartValue(__VDLType_struct_16); err != nil {
+	if err := dec.StartValue(__VDLType_struct_18); err != nil {
 		return err
 	}
 	decType := dec.Type()
@@ -1089,8 +1268,8 @@ func (x *CaveatDescriptor) VDLRead(dec vdl.Decoder) error {
 		case index == -1:
 			return dec.FinishValue()
 		}
-		if decType != __VDLType_struct_16 {
-			index = __VDLType_struct_16.FieldIndexByName(decType.Field(index).Name)
+		if decType != __VDLType_struct_18 {
+			index = __VDLType_struct_18.FieldIndexByName(decType.Field(index).Name)
 			if index == -1 {
 				if err := dec.SkipValue(); err != nil {
 					return err
@@ -1142,14 +1321,14 @@ func (x WireBlessings) VDLIsZero() bool {
 }
 
 func (x WireBlessings) VDLWrite(enc vdl.Encoder) error {
-	if err := enc.StartValue(__VDLType_struct_17); err != nil {
+	if err := enc.StartValue(__VDLType_struct_19); err != nil {
 		return err
 	}
 	if len(x.CertificateChains) != 0 {
 		if err := enc.NextField(0); err != nil {
 			return err
 		}
-		if err := __VDLWriteAnon_list_4(enc, x.CertificateChains); err != nil {
+		if err := __VDLWriteAnon_list_5(enc, x.CertificateChains); err != nil {
 			return err
 		}
 	}
@@ -1159,8 +1338,8 @@ func (x WireBlessings) VDLWrite(enc vdl.Encoder) error {
 	return enc.FinishValue()
 }
 
-func __VDLWriteAnon_list_4(enc vdl.Encoder, x [][]Certificate) error {
-	if err := enc.StartValue(__VDLType_list_18); err != nil {
+func __VDLWriteAnon_list_5(enc vdl.Encoder, x [][]Certificate) error {
+	if err := enc.StartValue(__VDLType_list_20); err != nil {
 		return err
 	}
 	if err := enc.SetLenHint(len(x)); err != nil {
@@ -1170,7 +1349,7 @@ func __VDLWriteAnon_list_4(enc vdl.Encoder, x [][]Certificate) error {
 		if err := enc.NextEntry(false); err != nil {
 			return err
 		}
-		if err := __VDLWriteAnon_list_5(enc, elem); err != nil {
+		if err := __VDLWriteAnon_list_6(enc, elem); err != nil {
 			return err
 		}
 	}
@@ -1180,8 +1359,8 @@ func __VDLWriteAnon_list_4(enc vdl.Encoder, x [][]Certificate) error {
 	return enc.FinishValue()
 }
 
-func __VDLWriteAnon_list_5(enc vdl.Encoder, x []Certificate) error {
-	if err := enc.StartValue(__VDLType_list_19); err != nil {
+func __VDLWriteAnon_list_6(enc vdl.Encoder, x []Certificate) error {
+	if err := enc.StartValue(__VDLType_list_21); err != nil {
 		return err
 	}
 	if err := enc.SetLenHint(len(x)); err != nil {
@@ -1203,7 +1382,7 @@ func __VDLWriteAnon_list_5(enc vdl.Encoder, x []Certificate) error {
 
 func (x *WireBlessings) VDLRead(dec vdl.Decoder) error {
 	*x = WireBlessings{}
-	if err := dec.StartValue(__VDLType_struct_17); err != nil {
+	if err := dec.StartValue(__VDLType_struct_19); err != nil {
 		return err
 	}
 	decType := dec.Type()
@@ -1215,8 +1394,8 @@ func (x *WireBlessings) VDLRead(dec vdl.Decoder) error {
 		case index == -1:
 			return dec.FinishValue()
 		}
-		if decType != __VDLType_struct_17 {
-			index = __VDLType_struct_17.FieldIndexByName(decType.Field(index).Name)
+		if decType != __VDLType_struct_19 {
+			index = __VDLType_struct_19.FieldIndexByName(decType.Field(index).Name)
 			if index == -1 {
 				if err := dec.SkipValue(); err != nil {
 					return err
@@ -1226,15 +1405,15 @@ func (x *WireBlessings) VDLRead(dec vdl.Decoder) error {
 		}
 		switch index {
 		case 0:
-			if err := __VDLReadAnon_list_4(dec, &x.CertificateChains); err != nil {
+			if err := __VDLReadAnon_list_5(dec, &x.CertificateChains); err != nil {
 				return err
 			}
 		}
 	}
 }
 
-func __VDLReadAnon_list_4(dec vdl.Decoder, x *[][]Certificate) error {
-	if err := dec.StartValue(__VDLType_list_18); err != nil {
+func __VDLReadAnon_list_5(dec vdl.Decoder, x *[][]Certificate) error {
+	if err := dec.StartValue(__VDLType_list_20); err != nil {
 		return err
 	}
 	if len := dec.LenHint(); len > 0 {
@@ -1250,7 +1429,7 @@ func __VDLReadAnon_list_4(dec vdl.Decoder, x *[][]Certificate) error {
 			return dec.FinishValue()
 		default:
 			var elem []Certificate
-			if err := __VDLReadAnon_list_5(dec, &elem); err != nil {
+			if err := __VDLReadAnon_list_6(dec, &elem); err != nil {
 				return err
 			}
 			*x = append(*x, elem)
@@ -1258,8 +1437,8 @@ func __VDLReadAnon_list_4(dec vdl.Decoder, x *[][]Certificate) error {
 	}
 }
 
-func __VDLReadAnon_list_5(dec vdl.Decoder, x *[]Certificate) error {
-	if err := dec.StartValue(__VDLType_list_19); err != nil {
+func __VDLReadAnon_list_6(dec vdl.Decoder, x *[]Certificate) error {
+	if err := dec.StartValue(__VDLType_list_21); err != nil {
 		return err
 	}
 	if len := dec.LenHint(); len > 0 {
@@ -1322,7 +1501,7 @@ func (x WireDischargePublicKey) VDLIsZero() bool {
 }
 
 func (x WireDischargePublicKey) VDLWrite(enc vdl.Encoder) error {
-	if err := enc.StartValue(__VDLType_union_20); err != nil {
+	if err := enc.StartValue(__VDLType_union_22); err != nil {
 		return err
 	}
 	if err := enc.NextField(0); err != nil {
@@ -1338,7 +1517,7 @@ func (x WireDischargePublicKey) VDLWrite(enc vdl.Encoder) error {
 }
 
 func VDLReadWireDischarge(dec vdl.Decoder, x *WireDischarge) error {
-	if err := dec.StartValue(__VDLType_union_20); err != nil {
+	if err := dec.StartValue(__VDLType_union_22); err != nil {
 		return err
 	}
 	decType := dec.Type()
@@ -1349,9 +1528,9 @@ func VDLReadWireDischarge(dec vdl.Decoder, x *WireDischarge) error {
 	case index == -1:
 		return fmt.Errorf("missing field in union %T, from %v", x, decType)
 	}
-	if decType != __VDLType_union_20 {
+	if decType != __VDLType_union_22 {
 		name := decType.Field(index).Name
-		index = __VDLType_union_20.FieldIndexByName(name)
+		index = __VDLType_union_22.FieldIndexByName(name)
 		if index == -1 {
 			return fmt.Errorf("field %q not in union %T, from %v", name, x, decType)
 		}
@@ -1389,7 +1568,7 @@ func (x RejectedBlessing) VDLIsZero() bool {
 }
 
 func (x RejectedBlessing) VDLWrite(enc vdl.Encoder) error {
-	if err := enc.StartValue(__VDLType_struct_21); err != nil {
+	if err := enc.StartValue(__VDLType_struct_23); err != nil {
 		return err
 	}
 	if x.Blessing != "" {
@@ -1413,7 +1592,7 @@ func (x RejectedBlessing) VDLWrite(enc vdl.Encoder) error {
 
 func (x *RejectedBlessing) VDLRead(dec vdl.Decoder) error {
 	*x = RejectedBlessing{}
-	if err := dec.StartValue(__VDLType_struct_21); err != nil {
+	if err := dec.StartValue(__VDLType_struct_23); err != nil {
 		return err
 	}
 	decType := dec.Type()
@@ -1425,8 +1604,8 @@ func (x *RejectedBlessing) VDLRead(dec vdl.Decoder) error {
 		case index == -1:
 			return dec.FinishValue()
 		}
-		if decType != __VDLType_struct_21 {
-			index = __VDLType_struct_21.FieldIndexByName(decType.Field(index).Name)
+		if decType != __VDLType_struct_23 {
+			index = __VDLType_struct_23.FieldIndexByName(decType.Field(index).Name)
 			if index == -1 {
 				if err := dec.SkipValue(); err != nil {
 					return err
@@ -1450,6 +1629,107 @@ func (x *RejectedBlessing) VDLRead(dec vdl.Decoder) error {
 	}
 }
 
+// BlessingRequest is sent by a principal that wants to be blessed by another
+// principal (the granter), for example when a device is claimed by its owner.
+//
+// The granter replies with the WireBlessings it grants, which extend one of
+// its own blessings. See NewBlessingRequest, GrantBlessing and
+// AcceptBlessing.
+type BlessingRequest struct {
+	PublicKey []byte    // DER-encoded PKIX public key of the requester.
+	Extension string    // Extension that the requester would like to be blessed with.
+	Signature Signature // Signature by the requester, proving possession of the private key for PublicKey.
+}
+
+func (BlessingRequest) __VDLReflect(struct {
+	Name string `vdl:"v.io/v23/security.BlessingRequest"`
+}) {
+}
+
+func (x BlessingRequest) VDLIsZero() bool {
+	if len(x.PublicKey) != 0 {
+		return false
+	}
+	if x.Extension != "" {
+		return false
+	}
+	if !x.Signature.VDLIsZero() {
+		return false
+	}
+	return true
+}
+
+func (x BlessingRequest) VDLWrite(enc vdl.Encoder) error {
+	if err := enc.StartValue(__VDLType_struct_24); err != nil {
+		return err
+	}
+	if len(x.PublicKey) != 0 {
+		if err := enc.NextFieldValueBytes(0, __VDLType_list_6, x.PublicKey); err != nil {
+			return err
+		}
+	}
+	if x.Extension != "" {
+		if err := enc.NextFieldValueString(1, vdl.StringType, x.Extension); err != nil {
+			return err
+		}
+	}
+	if !x.Signature.VDLIsZero() {
+		if err := enc.NextField(2); err != nil {
+			return err
+		}
+		if err := x.Signature.VDLWrite(enc); err != nil {
+			return err
+		}
+	}
+	if err := enc.NextField(-1); err != nil {
+		return err
+	}
+	return enc.FinishValue()
+}
+
+func (x *BlessingRequest) VDLRead(dec vdl.Decoder) error {
+	*x = BlessingRequest{}
+	if err := dec.StartValue(__VDLType_struct_24); err != nil {
+		return err
+	}
+	decType := dec.Type()
+	for {
+		index, err := dec.NextField()
+		switch {
+		case err != nil:
+			return err
+		case index == -1:
+			return dec.FinishValue()
+		}
+		if decType != __VDLType_struct_24 {
+			index = __VDLType_struct_24.FieldIndexByName(decType.Field(index).Name)
+			if index == -1 {
+				if err := dec.SkipValue(); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		switch index {
+		case 0:
+			if err := dec.ReadValueBytes(-1, &x.PublicKey); err != nil {
+				return err
+			}
+		case 1:
+			switch value, err := dec.ReadValueString(); {
+			case err != nil:
+				return err
+			default:
+				x.Extension = value
+			}
+		case 2:
+			if err := x.Signature.VDLRead(dec); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 // Type-check native conversion functions.
 var (
 	_ func(WireBlessings, *Blessings) error = WireBlessingsToNative
@@ -1487,7 +1767,7 @@ var ExpiryCaveat = CaveatDescriptor{
 		128,
 		0,
 	},
-	ParamType: __VDLType_struct_22,
+	ParamType: __VDLType_struct_25,
 }
 
 // MethodCaveat represents a caveat that validates iff the method being
@@ -1511,7 +1791,7 @@ var MethodCaveat = CaveatDescriptor{
 		0,
 		3,
 	},
-	ParamType: __VDLType_list_23,
+	ParamType: __VDLType_list_26,
 }
 var PublicKeyThirdPartyCaveat = CaveatDescriptor{
 	Id: uniqueid.Id{
@@ -1532,7 +1812,7 @@ var PublicKeyThirdPartyCaveat = CaveatDescriptor{
 		128,
 		0,
 	},
-	ParamType: __VDLType_struct_6,
+	ParamType: __VDLType_struct_8,
 }
 
 // PeerBlessingsCaveat represents a caveat that validates iff the peer being communicated
@@ -1557,7 +1837,56 @@ var PeerBlessingsCaveat = CaveatDescriptor{
 		128,
 		0,
 	},
-	ParamType: __VDLType_list_13,
+	ParamType: __VDLType_list_15,
+}
+
+// NotBeforeCaveat represents a caveat that validates iff the current time is no
+// earlier than the specified time.Time.
+var NotBeforeCaveat = CaveatDescriptor{
+	Id: uniqueid.Id{
+		178,
+		198,
+		190,
+		29,
+		127,
+		60,
+		160,
+		45,
+		181,
+		161,
+		53,
+		31,
+		121,
+		176,
+		128,
+		0,
+	},
+	ParamType: __VDLType_struct_25,
+}
+
+// TimeOfDayCaveat represents a caveat that validates iff the current time,
+// as observed in the time zone of the TimeOfDayWindow, falls within the
+// window on one of the days of the week permitted by it.
+var TimeOfDayCaveat = CaveatDescriptor{
+	Id: uniqueid.Id{
+		54,
+		206,
+		135,
+		169,
+		139,
+		64,
+		92,
+		219,
+		129,
+		215,
+		75,
+		230,
+		84,
+		72,
+		128,
+		0,
+	},
+	ParamType: __VDLType_struct_2,
 }
 
 // NoExtension is an optional terminator for a blessing pattern indicating that the pattern
@@ -1592,6 +1921,8 @@ var (
 	ErrExpiryCaveatValidation        = verror.Register("v.io/v23/security.ExpiryCaveatValidation", verror.NoRetry, "{1:}{2:} now({3}) is after expiry({4})")
 	ErrMethodCaveatValidation        = verror.Register("v.io/v23/security.MethodCaveatValidation", verror.NoRetry, "{1:}{2:} method {3} not in list {4}")
 	ErrPeerBlessingsCaveatValidation = verror.Register("v.io/v23/security.PeerBlessingsCaveatValidation", verror.NoRetry, "{1:}{2:} patterns in peer blessings caveat {4} not matched by the peer {3}")
+	ErrNotBeforeCaveatValidation     = verror.Register("v.io/v23/security.NotBeforeCaveatValidation", verror.NoRetry, "{1:}{2:} now({3}) is before notBefore({4})")
+	ErrTimeOfDayCaveatValidation     = verror.Register("v.io/v23/security.TimeOfDayCaveatValidation", verror.NoRetry, "{1:}{2:} now({3}) is outside of the permitted window {4}")
 	ErrUnrecognizedRoot              = verror.Register("v.io/v23/security.UnrecognizedRoot", verror.NoRetry, "{1:}{2:} unrecognized public key {3} in root certificate{:4}")
 	ErrAuthorizationFailed           = verror.Register("v.io/v23/security.AuthorizationFailed", verror.NoRetry, "{1:}{2:} principal with blessings {3} (rejected {4}) is not authorized by principal with blessings {5}")
 	ErrInvalidSigningBlessingCaveat  = verror.Register("v.io/v23/security.InvalidSigningBlessingCaveat", verror.NoRetry, "{1:}{2:} blessing has caveat with UUID {3} which makes it unsuitable for signing -- please use blessings with just Expiry caveats")
@@ -1644,6 +1975,16 @@ func NewErrPeerBlessingsCaveatValidation(ctx *context.T, peerBlessings []string,
 	return verror.New(ErrPeerBlessingsCaveatValidation, ctx, peerBlessings, permittedPatterns)
 }
 
+// NewErrNotBeforeCaveatValidation returns an error with the ErrNotBeforeCaveatValidation ID.
+func NewErrNotBeforeCaveatValidation(ctx *context.T, currentTime time.Time, notBeforeTime time.Time) error {
+	return verror.New(ErrNotBeforeCaveatValidation, ctx, currentTime, notBeforeTime)
+}
+
+// NewErrTimeOfDayCaveatValidation returns an error with the ErrTimeOfDayCaveatValidation ID.
+func NewErrTimeOfDayCaveatValidation(ctx *context.T, currentTime time.Time, window TimeOfDayWindow) error {
+	return verror.New(ErrTimeOfDayCaveatValidation, ctx, currentTime, window)
+}
+
 // NewErrUnrecognizedRoot returns an error with the ErrUnrecognizedRoot ID.
 func NewErrUnrecognizedRoot(ctx *context.T, rootKey string, details error) error {
 	return verror.New(ErrUnrecognizedRoot, ctx, rootKey, details)
@@ -1673,27 +2014,31 @@ func NewErrEndpointAuthorizationFailed(ctx *context.T, endpoint string, remote [
 var (
 	__VDLType_array_1   *vdl.Type
 	__VDLType_struct_2  *vdl.Type
-	__VDLType_array_3   *vdl.Type
-	__VDLType_list_4    *vdl.Type
-	__VDLType_struct_5  *vdl.Type
-	__VDLType_struct_6  *vdl.Type
-	__VDLType_list_7    *vdl.Type
-	__VDLType_string_8  *vdl.Type
-	__VDLType_struct_9  *vdl.Type
-	__VDLType_struct_10 *vdl.Type
-	__VDLType_string_11 *vdl.Type
+	__VDLType_list_3    *vdl.Type
+	__VDLType_struct_4  *vdl.Type
+	__VDLType_array_5   *vdl.Type
+	__VDLType_list_6    *vdl.Type
+	__VDLType_struct_7  *vdl.Type
+	__VDLType_struct_8  *vdl.Type
+	__VDLType_list_9    *vdl.Type
+	__VDLType_string_10 *vdl.Type
+	__VDLType_struct_11 *vdl.Type
 	__VDLType_struct_12 *vdl.Type
-	__VDLType_list_13   *vdl.Type
-	__VDLType_list_14   *vdl.Type
-	__VDLType_struct_15 *vdl.Type
-	__VDLType_struct_16 *vdl.Type
+	__VDLType_string_13 *vdl.Type
+	__VDLType_struct_14 *vdl.Type
+	__VDLType_list_15   *vdl.Type
+	__VDLType_list_16   *vdl.Type
 	__VDLType_struct_17 *vdl.Type
-	__VDLType_list_18   *vdl.Type
-	__VDLType_list_19   *vdl.Type
-	__VDLType_union_20  *vdl.Type
-	__VDLType_struct_21 *vdl.Type
-	__VDLType_struct_22 *vdl.Type
-	__VDLType_list_23   *vdl.Type
+	__VDLType_struct_18 *vdl.Type
+	__VDLType_struct_19 *vdl.Type
+	__VDLType_list_20   *vdl.Type
+	__VDLType_list_21   *vdl.Type
+	__VDLType_union_22  *vdl.Type
+	__VDLType_struct_23 *vdl.Type
+	__VDLType_struct_24 *vdl.Type
+	__VDLType_struct_25 *vdl.Type
+	__VDLType_list_26   *vdl.Type
+	__VDLType_struct_27 *vdl.Type
 )
 
 var __VDLInitCalled bool
@@ -1702,7 +2047,7 @@ var __VDLInitCalled bool
 // If you have an init ordering issue, just insert the following line verbatim
 // into your source files in this package, right after the "package foo" clause:
 //
-//    var _ = __VDLInit()
+//	var _ = __VDLInit()
 //
 // The purpose of this function is to ensure that vdl initialization occurs in
 // the right order, and very early in the init sequence.  In particular, vdl
@@ -1723,6 +2068,7 @@ func __VDLInit() struct{} {
 
 	// Register types.
 	vdl.Register((*nonce)(nil))
+	vdl.Register((*TimeOfDayWindow)(nil))
 	vdl.Register((*Caveat)(nil))
 	vdl.Register((*ThirdPartyRequirements)(nil))
 	vdl.Register((*publicKeyThirdPartyCaveatParam)(nil))
@@ -1736,31 +2082,36 @@ func __VDLInit() struct{} {
 	vdl.Register((*WireBlessings)(nil))
 	vdl.Register((*WireDischarge)(nil))
 	vdl.Register((*RejectedBlessing)(nil))
+	vdl.Register((*BlessingRequest)(nil))
 
 	// Initialize type definitions.
 	__VDLType_array_1 = vdl.TypeOf((*nonce)(nil))
-	__VDLType_struct_2 = vdl.TypeOf((*Caveat)(nil)).Elem()
-	__VDLType_array_3 = vdl.TypeOf((*uniqueid.Id)(nil))
-	__VDLType_list_4 = vdl.TypeOf((*[]byte)(nil))
-	__VDLType_struct_5 = vdl.TypeOf((*ThirdPartyRequirements)(nil)).Elem()
-	__VDLType_struct_6 = vdl.TypeOf((*publicKeyThirdPartyCaveatParam)(nil)).Elem()
-	__VDLType_list_7 = vdl.TypeOf((*[]Caveat)(nil))
-	__VDLType_string_8 = vdl.TypeOf((*Hash)(nil))
-	__VDLType_struct_9 = vdl.TypeOf((*Signature)(nil)).Elem()
-	__VDLType_struct_10 = vdl.TypeOf((*PublicKeyDischarge)(nil)).Elem()
-	__VDLType_string_11 = vdl.TypeOf((*BlessingPattern)(nil))
-	__VDLType_struct_12 = vdl.TypeOf((*DischargeImpetus)(nil)).Elem()
-	__VDLType_list_13 = vdl.TypeOf((*[]BlessingPattern)(nil))
-	__VDLType_list_14 = vdl.TypeOf((*[]*vom.RawBytes)(nil))
-	__VDLType_struct_15 = vdl.TypeOf((*Certificate)(nil)).Elem()
-	__VDLType_struct_16 = vdl.TypeOf((*CaveatDescriptor)(nil)).Elem()
-	__VDLType_struct_17 = vdl.TypeOf((*WireBlessings)(nil)).Elem()
-	__VDLType_list_18 = vdl.TypeOf((*[][]Certificate)(nil))
-	__VDLType_list_19 = vdl.TypeOf((*[]Certificate)(nil))
-	__VDLType_union_20 = vdl.TypeOf((*WireDischarge)(nil))
-	__VDLType_struct_21 = vdl.TypeOf((*RejectedBlessing)(nil)).Elem()
-	__VDLType_struct_22 = vdl.TypeOf((*vdltime.Time)(nil)).Elem()
-	__VDLType_list_23 = vdl.TypeOf((*[]string)(nil))
+	__VDLType_struct_2 = vdl.TypeOf((*TimeOfDayWindow)(nil)).Elem()
+	__VDLType_list_3 = vdl.TypeOf((*[]int8)(nil))
+	__VDLType_struct_4 = vdl.TypeOf((*Caveat)(nil)).Elem()
+	__VDLType_array_5 = vdl.TypeOf((*uniqueid.Id)(nil))
+	__VDLType_list_6 = vdl.TypeOf((*[]byte)(nil))
+	__VDLType_struct_7 = vdl.TypeOf((*ThirdPartyRequirements)(nil)).Elem()
+	__VDLType_struct_8 = vdl.TypeOf((*publicKeyThirdPartyCaveatParam)(nil)).Elem()
+	__VDLType_list_9 = vdl.TypeOf((*[]Caveat)(nil))
+	__VDLType_string_10 = vdl.TypeOf((*Hash)(nil))
+	__VDLType_struct_11 = vdl.TypeOf((*Signature)(nil)).Elem()
+	__VDLType_struct_12 = vdl.TypeOf((*PublicKeyDischarge)(nil)).Elem()
+	__VDLType_string_13 = vdl.TypeOf((*BlessingPattern)(nil))
+	__VDLType_struct_14 = vdl.TypeOf((*DischargeImpetus)(nil)).Elem()
+	__VDLType_list_15 = vdl.TypeOf((*[]BlessingPattern)(nil))
+	__VDLType_list_16 = vdl.TypeOf((*[]*vom.RawBytes)(nil))
+	__VDLType_struct_17 = vdl.TypeOf((*Certificate)(nil)).Elem()
+	__VDLType_struct_18 = vdl.TypeOf((*CaveatDescriptor)(nil)).Elem()
+	__VDLType_struct_19 = vdl.TypeOf((*WireBlessings)(nil)).Elem()
+	__VDLType_list_20 = vdl.TypeOf((*[][]Certificate)(nil))
+	__VDLType_list_21 = vdl.TypeOf((*[]Certificate)(nil))
+	__VDLType_union_22 = vdl.TypeOf((*WireDischarge)(nil))
+	__VDLType_struct_23 = vdl.TypeOf((*RejectedBlessing)(nil)).Elem()
+	__VDLType_struct_24 = vdl.TypeOf((*BlessingRequest)(nil)).Elem()
+	__VDLType_struct_25 = vdl.TypeOf((*vdltime.Time)(nil)).Elem()
+	__VDLType_list_26 = vdl.TypeOf((*[]string)(nil))
+	__VDLType_struct_27 = vdl.TypeOf((*vdltime.Duration)(nil)).Elem()
 
 	// Set error format strings.
 	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrCaveatNotRegistered.ID), "{1:}{2:} no validation function registered for caveat id {3}")
@@ -1772,6 +2123,8 @@ func __VDLInit() struct{} {
 	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrExpiryCaveatValidation.ID), "{1:}{2:} now({3}) is after expiry({4})")
 	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrMethodCaveatValidation.ID), "{1:}{2:} method {3} not in list {4}")
 	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrPeerBlessingsCaveatValidation.ID), "{1:}{2:} patterns in peer blessings caveat {4} not matched by the peer {3}")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrNotBeforeCaveatValidation.ID), "{1:}{2:} now({3}) is before notBefore({4})")
+	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrTimeOfDayCaveatValidation.ID), "{1:}{2:} now({3}) is outside of the permitted window {4}")
 	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrUnrecognizedRoot.ID), "{1:}{2:} unrecognized public key {3} in root certificate{:4}")
 	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrAuthorizationFailed.ID), "{1:}{2:} principal with blessings {3} (rejected {4}) is not authorized by principal with blessings {5}")
 	i18n.Cat().SetWithBase(i18n.LangID("en"), i18n.MsgID(ErrInvalidSigningBlessingCaveat.ID), "{1:}{2:} blessing has caveat with UUID {3} which makes it unsuitable for signing -- please use blessings with just Expiry caveats")
@@ -14,7 +14,9 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"v.io/v23/context"
 	"v.io/v23/uniqueid"
@@ -56,7 +58,7 @@ func (r *roots) Dump() map[BlessingPattern][]PublicKey {
 	ret := make(map[BlessingPattern][]PublicKey)
 	for _, mr := range r.data {
 		key, err := UnmarshalPublicKey(mr.root)
-		if err != nil {
+		if err == nil {
 			ret[mr.pattern] = append(ret[mr.pattern], key)
 		}
 	}
@@ -103,6 +105,99 @@ func newPrincipal(t testing.TB) Principal {
 	return p
 }
 
+// newPrincipalWithStore returns a principal that uses a memStore.
+func newPrincipalWithStore(t testing.TB) Principal {
+	signer := newECDSASigner(t, elliptic.P256())
+	p, err := CreatePrincipal(signer, newMemStore(signer.PublicKey()), &roots{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// memStore is an in-memory BlessingStore that caches discharges by caveat ID,
+// ignoring the impetus.
+type memStore struct {
+	errStore
+	mu         sync.Mutex
+	def        Blessings
+	changed    chan struct{}
+	peers      map[BlessingPattern]Blessings
+	discharges map[string]Discharge
+	cached     map[string]time.Time
+}
+
+func newMemStore(key PublicKey) *memStore {
+	return &memStore{
+		errStore:   errStore{key},
+		changed:    make(chan struct{}),
+		peers:      make(map[BlessingPattern]Blessings),
+		discharges: make(map[string]Discharge),
+		cached:     make(map[string]time.Time),
+	}
+}
+
+func (s *memStore) SetDefault(b Blessings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.def = b
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return nil
+}
+
+func (s *memStore) Default() (Blessings, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.def, s.changed
+}
+
+func (s *memStore) Set(b Blessings, pattern BlessingPattern) (Blessings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.peers[pattern]
+	s.peers[pattern] = b
+	return old, nil
+}
+
+func (s *memStore) ForPeer(peerBlessings ...string) Blessings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ret []Blessings
+	for pattern, b := range s.peers {
+		if pattern == AllPrincipals || pattern.MatchedBy(peerBlessings...) {
+			ret = append(ret, b)
+		}
+	}
+	union, _ := UnionOfBlessings(ret...)
+	return union
+}
+
+func (s *memStore) PeerBlessings() map[BlessingPattern]Blessings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ret := make(map[BlessingPattern]Blessings)
+	for p, b := range s.peers {
+		ret[p] = b
+	}
+	return ret
+}
+
+func (s *memStore) CacheDischarge(d Discharge, cav Caveat, _ DischargeImpetus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := cav.ThirdPartyDetails().ID()
+	s.discharges[id] = d
+	s.cached[id] = time.Now()
+}
+
+func (s *memStore) Discharge(cav Caveat, _ DischargeImpetus) (Discharge, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := cav.ThirdPartyDetails().ID()
+	return s.discharges[id], s.cached[id]
+}
+
 func blessSelf(t *testing.T, p Principal, name string, caveats ...Caveat) Blessings {
 	b, err := p.BlessSelf(name, caveats...)
 	if err != nil {
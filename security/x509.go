@@ -0,0 +1,189 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"v.io/v23/verror"
+)
+
+// X509BlessingScheme is the scheme of the URIs in the subject alternative
+// names of an X.509 certificate that encode blessing names.
+// For example, the blessing name "dev:alice" is encoded as the URI
+// "blessing:dev:alice".
+const X509BlessingScheme = "blessing"
+
+var (
+	errNoX509BlessingNames   = verror.Register(pkgPath+".errNoX509BlessingNames", verror.NoRetry, "{1:}{2:}no blessing names in {3}{:_}")
+	errEmptyX509Chain        = verror.Register(pkgPath+".errEmptyX509Chain", verror.NoRetry, "{1:}{2:}empty X.509 certificate chain{:_}")
+	errInvalidX509Chain      = verror.Register(pkgPath+".errInvalidX509Chain", verror.NoRetry, "{1:}{2:}invalid X.509 certificate chain for {3}{:_}")
+	errUnrecognizedX509Root  = verror.Register(pkgPath+".errUnrecognizedX509Root", verror.NoRetry, "{1:}{2:}root of X.509 certificate chain is not recognized as an authority on {3}{:_}")
+	errCantExtendToX509Name  = verror.Register(pkgPath+".errCantExtendToX509Name", verror.NoRetry, "{1:}{2:}none of the blessings {3} can be extended to {4}{:_}")
+	errX509CertificateExpiry = verror.Register(pkgPath+".errX509CertificateExpiry", verror.NoRetry, "{1:}{2:}X.509 certificate must expire, but neither the blessings nor the caller set an expiry{:_}")
+)
+
+// NewX509Certificate returns an X.509 certificate for the public key that
+// blessings are bound to, whose subject and subject alternative names encode
+// the blessing names claimed by blessings.
+//
+// The common name of the subject is set to the first of these names and each
+// name is included as a URI with the X509BlessingScheme scheme in the subject
+// alternative names. Note that the names are claimed: neither caveats nor the
+// recognition of the roots of blessings are checked.
+//
+// The certificate expires at notAfter or at blessings.Expiry(), whichever is
+// earlier. It is signed by issuerKey on behalf of issuer, or is self-signed if
+// issuer is nil, in which case issuerKey must be the private counterpart of
+// blessings.PublicKey().
+func NewX509Certificate(blessings Blessings, notAfter time.Time, issuer *x509.Certificate, issuerKey crypto.Signer) (*x509.Certificate, error) {
+	if blessings.IsZero() || blessings.isNamelessBlessing() {
+		return nil, verror.New(errNoX509BlessingNames, nil, blessings)
+	}
+	if exp := blessings.Expiry(); !exp.IsZero() && (notAfter.IsZero() || exp.Before(notAfter)) {
+		notAfter = exp
+	}
+	if notAfter.IsZero() {
+		return nil, verror.New(errX509CertificateExpiry, nil)
+	}
+	pub, err := x509.ParsePKIXPublicKey(blessings.publicKeyDER())
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		PublicKey:             pub,
+		NotBefore:             time.Now(),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, chain := range blessings.chains {
+		name := claimedName(chain)
+		if template.Subject.CommonName == "" {
+			template.Subject = pkix.Name{CommonName: name}
+		}
+		template.URIs = append(template.URIs, &url.URL{Scheme: X509BlessingScheme, Opaque: url.PathEscape(name)})
+	}
+	parent := issuer
+	if parent == nil {
+		parent = template
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, issuerKey)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+// X509BlessingNames returns the blessing names encoded in the subject
+// alternative names of cert, as done by NewX509Certificate.
+func X509BlessingNames(cert *x509.Certificate) []string {
+	var names []string
+	for _, u := range cert.URIs {
+		if u.Scheme != X509BlessingScheme {
+			continue
+		}
+		if name, err := url.PathUnescape(u.Opaque); err == nil && name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// BlessingsFromX509 imports an X.509 certificate chain as blessings granted
+// by p.
+//
+// chain[0] is the certificate being imported and every other certificate must
+// have signed its predecessor, with the last one being the root of the chain.
+// The root must be recognized by p.Roots() as an authority on every blessing
+// name encoded in chain[0] (see X509BlessingNames) and p must hold, in with, a
+// blessing that each of these names extends. For example, if chain[0] encodes
+// the name "corp:alice", with must include the blessing "corp" (or "corp:a",
+// but not "corp:alice" itself).
+//
+// The returned blessings are bound to the public key of chain[0], have
+// exactly the names encoded in chain[0] and carry an expiry caveat derived
+// from the earliest NotAfter of the certificates in chain.
+func BlessingsFromX509(p Principal, with Blessings, chain []*x509.Certificate) (Blessings, error) {
+	if len(chain) == 0 {
+		return Blessings{}, verror.New(errEmptyX509Chain, nil)
+	}
+	leaf, root := chain[0], chain[len(chain)-1]
+	names := X509BlessingNames(leaf)
+	if len(names) == 0 {
+		return Blessings{}, verror.New(errNoX509BlessingNames, nil, leaf.Subject)
+	}
+	opts := x509.VerifyOptions{
+		Roots:         x509.NewCertPool(),
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	opts.Roots.AddCert(root)
+	for _, c := range chain[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	if _, err := leaf.Verify(opts); err != nil {
+		return Blessings{}, verror.New(errInvalidX509Chain, nil, names, err)
+	}
+	rootDER, err := x509.MarshalPKIXPublicKey(root.PublicKey)
+	if err != nil {
+		return Blessings{}, err
+	}
+	key, err := UnmarshalPublicKey(leaf.RawSubjectPublicKeyInfo)
+	if err != nil {
+		return Blessings{}, err
+	}
+	notAfter := leaf.NotAfter
+	for _, c := range chain[1:] {
+		if c.NotAfter.Before(notAfter) {
+			notAfter = c.NotAfter
+		}
+	}
+	expiry, err := NewExpiryCaveat(notAfter)
+	if err != nil {
+		return Blessings{}, err
+	}
+	var granted []Blessings
+	for _, name := range names {
+		if err := p.Roots().Recognized(rootDER, name); err != nil {
+			return Blessings{}, verror.New(errUnrecognizedX509Root, nil, name, err)
+		}
+		b, err := extendToName(p, with, key, name, expiry)
+		if err != nil {
+			return Blessings{}, err
+		}
+		granted = append(granted, b)
+	}
+	return UnionOfBlessings(granted...)
+}
+
+// extendToName uses a chain of with to bless key with name.
+func extendToName(p Principal, with Blessings, key PublicKey, name string, caveat Caveat) (Blessings, error) {
+	for i, chain := range with.chains {
+		if prefix := claimedName(chain) + ChainSeparator; strings.HasPrefix(name, prefix) {
+			single := Blessings{
+				chains:    [][]Certificate{chain},
+				publicKey: with.publicKey,
+				digests:   [][]byte{with.digests[i]},
+			}
+			single.init()
+			return p.Bless(key, single, strings.TrimPrefix(name, prefix), caveat)
+		}
+	}
+	return Blessings{}, verror.New(errCantExtendToX509Name, nil, with, name)
+}
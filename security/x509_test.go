@@ -0,0 +1,188 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func newX509CA(t *testing.T, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestX509Certificate(t *testing.T) {
+	var (
+		akey, _    = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		palice     = newPrincipal(t)
+		pother     = newPrincipal(t)
+		expiry     = time.Now().Add(time.Hour).Round(time.Second)
+		caveat     = newCaveat(NewExpiryCaveat(expiry))
+		alice      = blessSelf(t, palice, "corp:alice", caveat)
+		other, err = pother.Bless(palice.PublicKey(), blessSelf(t, pother, "other"), "alice", UnconstrainedUse())
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewX509Certificate(Blessings{}, expiry, nil, akey); err == nil {
+		t.Errorf("Expected error for empty blessings")
+	}
+	if _, err := NewX509Certificate(other, time.Time{}, nil, akey); err == nil {
+		t.Errorf("Expected error for certificate without expiry")
+	}
+	// Self-signed certificates must be signed by the private key of the
+	// blessings.
+	if _, err := NewX509Certificate(alice, time.Time{}, nil, akey); err == nil {
+		t.Errorf("Expected error for a self-signed certificate with the wrong key")
+	}
+	pself, err := CreatePrincipal(NewInMemoryECDSASigner(akey), nil, &roots{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	self := blessSelf(t, pself, "self", caveat)
+	cert, err := NewX509Certificate(self, time.Now().Add(24*time.Hour), nil, akey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cert.NotAfter, expiry; !got.Equal(want) {
+		t.Errorf("Got NotAfter %v, want %v", got, want)
+	}
+	if got, want := cert.Subject.CommonName, "self"; got != want {
+		t.Errorf("Got common name %q, want %q", got, want)
+	}
+
+	ca, cakey := newX509CA(t, "Corp CA")
+	union, err := UnionOfBlessings(alice, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = NewX509Certificate(union, time.Now().Add(24*time.Hour), ca, cakey); err != nil {
+		t.Fatal(err)
+	}
+	names := X509BlessingNames(cert)
+	sort.Strings(names)
+	if want := []string{"corp:alice", "other:alice"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Got names %v, want %v", names, want)
+	}
+	if got, want := cert.URIs[0].Scheme, X509BlessingScheme; got != want {
+		t.Errorf("Got scheme %q, want %q", got, want)
+	}
+	if err := cert.CheckSignatureFrom(ca); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBlessingsFromX509(t *testing.T) {
+	var (
+		pbridge  = newPrincipal(t)
+		palice   = newPrincipal(t)
+		corp     = blessSelf(t, pbridge, "corp")
+		ca, key  = newX509CA(t, "Corp CA")
+		other, _ = newX509CA(t, "Other CA")
+		notAfter = time.Now().Add(time.Hour).Round(time.Second)
+		mint     = func(name string) *x509.Certificate {
+			cert, err := NewX509Certificate(blessSelf(t, palice, name), notAfter, ca, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return cert
+		}
+		alice = mint("corp:alice")
+	)
+	rootDER, err := x509.MarshalPKIXPublicKey(ca.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addToRoots(t, pbridge, corp)
+	// The root of the X.509 certificate chain is not yet recognized.
+	if _, err := BlessingsFromX509(pbridge, corp, []*x509.Certificate{alice, ca}); err == nil {
+		t.Errorf("Expected error for unrecognized root")
+	}
+	if err := pbridge.Roots().Add(rootDER, "corp"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := BlessingsFromX509(pbridge, corp, []*x509.Certificate{alice, ca})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.PublicKey(), palice.PublicKey(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Got public key %v, want %v", got, want)
+	}
+	if got, want := b.Expiry(), notAfter; !got.Equal(want) {
+		t.Errorf("Got expiry %v, want %v", got, want)
+	}
+	if err := checkBlessings(b, CallParams{LocalPrincipal: pbridge, Timestamp: time.Now()}, "corp:alice"); err != nil {
+		t.Error(err)
+	}
+	if err := checkBlessings(b, CallParams{LocalPrincipal: pbridge, Timestamp: notAfter.Add(time.Second)}); err != nil {
+		t.Error(err)
+	}
+	// The imported blessings can be converted back to an equivalent X.509
+	// certificate.
+	cert, err := NewX509Certificate(b, time.Time{}, ca, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := X509BlessingNames(cert), []string{"corp:alice"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		chain  []*x509.Certificate
+		errmsg string
+	}{
+		{nil, "empty X.509 certificate chain"},
+		{[]*x509.Certificate{alice, other}, "invalid X.509 certificate chain"},
+		{[]*x509.Certificate{mint("dev:alice"), ca}, "not recognized as an authority on dev:alice"},
+		{[]*x509.Certificate{ca}, "no blessing names"},
+	}
+	for _, test := range tests {
+		_, err := BlessingsFromX509(pbridge, corp, test.chain)
+		if merr := matchesError(err, test.errmsg); merr != nil {
+			t.Errorf("%v: %v", test.chain, merr)
+		}
+	}
+	// The name must extend one of the blessings of the bridge.
+	if err := pbridge.Roots().Add(rootDER, "dev"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = BlessingsFromX509(pbridge, corp, []*x509.Certificate{mint("dev:alice"), ca})
+	if merr := matchesError(err, "none of the blessings corp can be extended to dev:alice"); merr != nil {
+		t.Error(merr)
+	}
+	_, err = BlessingsFromX509(pbridge, corp, []*x509.Certificate{mint("corp"), ca})
+	if merr := matchesError(err, "can be extended to corp"); merr != nil {
+		t.Error(merr)
+	}
+}
@@ -25,11 +25,15 @@ var (
 	errMultipleMethodTags = verror.Register(pkgPath+".errMultipleMethodTags", verror.NoRetry, "{1:}{2:}PermissionsAuthorizer on {3}.{4} cannot handle multiple tags of type {5} ({6}); this is likely unintentional{:_}")
 )
 
-func PermissionsAuthorizer(perms access.Permissions, tagType *vdl.Type) (security.Authorizer, error) {
+func PermissionsAuthorizer(perms access.Permissions, tagType *vdl.Type, opts ...access.AuthorizerOpt) (security.Authorizer, error) {
 	if tagType.Kind() != vdl.String {
 		return nil, errTagType(tagType)
 	}
-	return &authorizer{perms, tagType}, nil
+	hierarchy, err := access.NewTagHierarchy(tagType, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &authorizer{perms: perms, tagType: tagType, hierarchy: hierarchy}, nil
 }
 
 func errTagType(tt *vdl.Type) error {
@@ -37,8 +41,9 @@ func errTagType(tt *vdl.Type) error {
 }
 
 type authorizer struct {
-	perms   access.Permissions
-	tagType *vdl.Type
+	perms     access.Permissions
+	tagType   *vdl.Type
+	hierarchy access.TagHierarchy
 }
 
 func (a *authorizer) Authorize(ctx *context.T, call security.Call) error {
@@ -50,7 +55,7 @@ func (a *authorizer) Authorize(ctx *context.T, call security.Call) error {
 				return verror.New(errMultipleMethodTags, ctx, call.Suffix(), call.Method(), a.tagType, call.MethodTags())
 			}
 			hasTag = true
-			if acl, exists := a.perms[tag.RawString()]; !exists || !includes(ctx, acl, convertToSet(blessings...)) {
+			if !a.includesAny(ctx, tag.RawString(), blessings) {
 				return access.NewErrNoPermissions(ctx, blessings, invalid, tag.RawString())
 			}
 		}
@@ -61,6 +66,17 @@ func (a *authorizer) Authorize(ctx *context.T, call security.Call) error {
 	return nil
 }
 
+// includesAny returns true iff the AccessList of tag, or of any tag that
+// implies it as per a.hierarchy, includes blessings.
+func (a *authorizer) includesAny(ctx *context.T, tag string, blessings []string) bool {
+	for _, t := range a.hierarchy.ImplyingTags(tag) {
+		if acl, exists := a.perms[t]; exists && includes(ctx, acl, convertToSet(blessings...)) {
+			return true
+		}
+	}
+	return false
+}
+
 func includes(ctx *context.T, acl access.AccessList, blessings map[string]struct{}) bool {
 	pruneBlacklisted(ctx, acl, blessings)
 	for _, pattern := range acl.In {
@@ -54,3 +54,31 @@ func TestOpenAccessList(t *testing.T) {
 		t.Errorf("OpenAccessList should allow principals that present any blessings")
 	}
 }
+
+func TestIncludesAnyWithTagImplications(t *testing.T) {
+	tt := access.TypicalTagType()
+	cyclic := access.TagImplications{"Admin": {"Write"}, "Write": {"Admin"}}
+	if _, err := PermissionsAuthorizer(nil, tt, cyclic); err == nil {
+		t.Errorf("Expected error for cyclic implications")
+	}
+	a := &authorizer{
+		perms: access.Permissions{
+			"Admin": {In: []security.BlessingPattern{"alice"}},
+			"Read":  {In: []security.BlessingPattern{"bob"}},
+		},
+		tagType: tt,
+	}
+	if !a.includesAny(nil, "Read", []string{"bob"}) || a.includesAny(nil, "Read", []string{"alice"}) {
+		t.Errorf("Unexpected access to Read methods without tag implications")
+	}
+	var err error
+	if a.hierarchy, err = access.NewTagHierarchy(tt, access.TypicalTagImplications()); err != nil {
+		t.Fatal(err)
+	}
+	if !a.includesAny(nil, "Read", []string{"bob"}) || !a.includesAny(nil, "Read", []string{"alice"}) {
+		t.Errorf("Admin should imply Read")
+	}
+	if a.includesAny(nil, "Admin", []string{"bob"}) {
+		t.Errorf("Read should not imply Admin")
+	}
+}